@@ -2,9 +2,14 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/dustin/go-humanize"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/wagoodman/dive/dive"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
+	"github.com/wagoodman/dive/runtime/ci"
+	"github.com/wagoodman/dive/utils"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -15,7 +20,7 @@ import (
 // image analysis to the screen
 func doAnalyzeCmd(cmd *cobra.Command, args []string) {
 
-	if len(args) == 0 {
+	if len(args) == 0 && ciImagesFile == "" {
 		printVersionFlag, err := cmd.PersistentFlags().GetBool("version")
 		if err == nil && printVersionFlag {
 			printVersion(cmd, args)
@@ -26,10 +31,11 @@ func doAnalyzeCmd(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	userImage := args[0]
-	if userImage == "" {
-		fmt.Println("No image argument given")
-		os.Exit(1)
+	for _, userImage := range args {
+		if userImage == "" {
+			fmt.Println("No image argument given")
+			os.Exit(1)
+		}
 	}
 
 	initLogging()
@@ -41,10 +47,145 @@ func doAnalyzeCmd(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	images, err := configureCiImages(cmd, isCi, args)
+	if err != nil {
+		fmt.Printf("ci-images error: %v\n", err)
+		os.Exit(1)
+	}
+
 	var sourceType dive.ImageSource
 	var imageStr string
+	if images == nil {
+		sourceType, imageStr = deriveImageSource(args[0])
+	}
+
+	ignoreErrors, err := cmd.PersistentFlags().GetBool("ignore-errors")
+	if err != nil {
+		logrus.Error("unable to get 'ignore-errors' option:", err)
+	}
+
+	options, err := analysisOptions()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	options.Ci = isCi
+	options.Source = sourceType
+	options.Image = imageStr
+	options.ExportFile = exportFile
+	options.MarkdownFile = markdownFile
+	options.BaselineFile = baselineFile
+	options.BaselineLabel = baselineLabel
+	options.SnapshotFile = snapshotFile
+	options.CiConfig = ciConfig
+	options.Images = images
+	options.Dockerfile = dockerfile
+	options.IgnoreErrors = options.IgnoreErrors || ignoreErrors
+
+	runtime.Run(options)
+}
+
+// analysisOptions reads the options shared by every command that analyzes an image (analyze and build) from the
+// configuration: the tree cache size, the secret, removable, and path error options, and the CI reports.
+func analysisOptions() (runtime.Options, error) {
+	options := runtime.Options{
+		IgnoreErrors:  viper.GetBool("ignore-errors"),
+		TreeCacheSize: viper.GetInt("filetree.cache-size"),
+	}
+
+	var err error
+	options.CiReports, err = ci.ParseReportTargets(ciReports)
+	if err != nil {
+		return options, fmt.Errorf("ci-report error: %v", err)
+	}
+
+	options.Secrets, err = secretOptions()
+	if err != nil {
+		return options, fmt.Errorf("secrets configuration error: %v", err)
+	}
 
-	sourceType, imageStr = dive.DeriveImageSource(userImage)
+	options.RemovableRules, err = removableRules()
+	if err != nil {
+		return options, fmt.Errorf("removable configuration error: %v", err)
+	}
+
+	options.PathPolicies, err = filetree.ParsePathPolicies(viper.GetStringMapString("path-errors"))
+	if err != nil {
+		return options, fmt.Errorf("path-errors configuration error: %v", err)
+	}
+	return options, nil
+}
+
+// secretOptions reads the secret scanning options (and any user defined secret patterns) from the configuration.
+func secretOptions() (image.SecretOptions, error) {
+	var options image.SecretOptions
+	options.ScanContent = viper.GetBool("secrets.scan-content")
+
+	maxContentSize, err := humanize.ParseBytes(viper.GetString("secrets.max-content-size"))
+	if err != nil {
+		return options, fmt.Errorf("invalid 'secrets.max-content-size' value: %v", err)
+	}
+	options.MaxContentSize = int64(maxContentSize)
+
+	var patterns []struct {
+		Name     string
+		Path     string
+		Content  string
+		Severity string
+	}
+	err = viper.UnmarshalKey("secrets.patterns", &patterns)
+	if err != nil {
+		return options, fmt.Errorf("invalid 'secrets.patterns' value: %v", err)
+	}
+	for _, pattern := range patterns {
+		severity := filetree.SeverityHigh
+		if pattern.Severity != "" {
+			severity, err = filetree.ParseSeverity(pattern.Severity)
+			if err != nil {
+				return options, fmt.Errorf("invalid severity for secret pattern '%s': %v", pattern.Name, err)
+			}
+		}
+		options.Patterns = append(options.Patterns, image.SecretPattern{
+			Name:     pattern.Name,
+			Path:     pattern.Path,
+			Content:  pattern.Content,
+			Severity: severity,
+		})
+	}
+	return options, nil
+}
+
+// removableRules reads the rules for finding likely removable paths (along with the built-in rules, unless disabled)
+// from the configuration.
+func removableRules() (filetree.RemovableRules, error) {
+	var rules []struct {
+		Category string
+		Path     string
+	}
+	err := viper.UnmarshalKey("removable.rules", &rules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'removable.rules' value: %v", err)
+	}
+
+	var parsed []filetree.RemovableRule
+	for _, rule := range rules {
+		category := filetree.RemovableCustom
+		if rule.Category != "" {
+			category, err = filetree.ParseRemovableCategory(rule.Category)
+			if err != nil {
+				return nil, fmt.Errorf("invalid category for removable rule '%s': %v", rule.Path, err)
+			}
+		}
+		parsed = append(parsed, filetree.RemovableRule{Category: category, Path: rule.Path})
+	}
+	return filetree.NewRemovableRules(viper.GetBool("removable.builtin-rules"), parsed...)
+}
+
+// deriveImageSource determines the image source from the image scheme (e.g. docker-archive://), falling back to the
+// configured source when no scheme is given.
+func deriveImageSource(userImage string) (dive.ImageSource, string) {
+	sourceType, imageStr := dive.DeriveImageSource(userImage)
 
 	if sourceType == dive.SourceUnknown {
 		sourceStr := viper.GetString("source")
@@ -56,18 +197,25 @@ func doAnalyzeCmd(cmd *cobra.Command, args []string) {
 
 		imageStr = userImage
 	}
+	return sourceType, imageStr
+}
 
-	ignoreErrors, err := cmd.PersistentFlags().GetBool("ignore-errors")
+// fetchImage resolves and fetches the given image, exiting on failure.
+func fetchImage(userImage string) *image.Image {
+	sourceType, imageStr := deriveImageSource(userImage)
+
+	imageResolver, err := dive.GetImageResolver(sourceType)
 	if err != nil {
-		logrus.Error("unable to get 'ignore-errors' option:", err)
+		fmt.Printf("cannot determine image provider: %v\n", err)
+		os.Exit(1)
 	}
 
-	runtime.Run(runtime.Options{
-		Ci:           isCi,
-		Source:       sourceType,
-		Image:        imageStr,
-		ExportFile:   exportFile,
-		CiConfig:     ciConfig,
-		IgnoreErrors: viper.GetBool("ignore-errors") || ignoreErrors,
-	})
+	fmt.Println(utils.TitleFormat("Image Source: ") + sourceType.String() + "://" + imageStr)
+	fmt.Println(utils.TitleFormat("Fetching image...") + " (this can take a while for large images)")
+	img, err := imageResolver.Fetch(imageStr)
+	if err != nil {
+		fmt.Printf("cannot fetch image: %v\n", err)
+		os.Exit(1)
+	}
+	return img
 }
@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/wagoodman/dive/dive"
@@ -27,11 +30,16 @@ func doBuildCmd(cmd *cobra.Command, args []string) {
 	// todo: allow for an engine flag to be passed to dive but not the container engine
 	engine := viper.GetString("container-engine")
 
-	runtime.Run(runtime.Options{
-		Ci:         isCi,
-		Source:     dive.ParseImageSource(engine),
-		BuildArgs:  args,
-		ExportFile: exportFile,
-		CiConfig:   ciConfig,
-	})
+	options, err := analysisOptions()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	options.Ci = isCi
+	options.Source = dive.ParseImageSource(engine)
+	options.BuildArgs = args
+	options.ExportFile = exportFile
+	options.CiConfig = ciConfig
+
+	runtime.Run(options)
 }
@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"strconv"
+	"strings"
 
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/runtime"
 )
 
 func configureCi() (bool, *viper.Viper, error) {
@@ -21,12 +25,7 @@ func configureCi() (bool, *viper.Viper, error) {
 		if _, err := os.Stat(ciConfigFile); !os.IsNotExist(err) {
 			fmt.Printf("  Using CI config: %s\n", ciConfigFile)
 
-			fileBytes, err := ioutil.ReadFile(ciConfigFile)
-			if err != nil {
-				return isCi, nil, err
-			}
-
-			err = ciConfig.ReadConfig(bytes.NewBuffer(fileBytes))
+			err = readCiConfig(ciConfig, ciConfigFile)
 			if err != nil {
 				return isCi, nil, err
 			}
@@ -37,3 +36,110 @@ func configureCi() (bool, *viper.Viper, error) {
 
 	return isCi, ciConfig, nil
 }
+
+func readCiConfig(config *viper.Viper, path string) error {
+	fileBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return config.ReadConfig(bytes.NewBuffer(fileBytes))
+}
+
+// configureCiImages returns the images to evaluate together in CI, given as arguments and listed within the
+// --ci-images file, or nil when a single image is given as an argument (which is evaluated on its own).
+func configureCiImages(cmd *cobra.Command, isCi bool, args []string) ([]runtime.CiImage, error) {
+	if ciImagesFile == "" && len(args) <= 1 {
+		return nil, nil
+	}
+	if !isCi {
+		return nil, fmt.Errorf("multiple images can only be evaluated in CI (see --ci)")
+	}
+	for _, flag := range []struct {
+		name  string
+		value string
+		hint  string
+	}{
+		{"--json", exportFile, ""},
+		{"--markdown", markdownFile, " (use --ci-report markdown=path for a summary of every image)"},
+		{"--snapshot", snapshotFile, ""},
+		{"--baseline", baselineFile, " (name the baseline of each image within --ci-images instead)"},
+		{"--dockerfile", dockerfile, ""},
+	} {
+		if flag.value != "" {
+			return nil, fmt.Errorf("%s cannot be given with multiple images%s", flag.name, flag.hint)
+		}
+	}
+
+	var images []runtime.CiImage
+	for _, arg := range args {
+		sourceType, imageStr := deriveImageSource(arg)
+		images = append(images, runtime.CiImage{Source: sourceType, Image: imageStr})
+	}
+
+	if ciImagesFile != "" {
+		listed, err := readCiImages(cmd, ciImagesFile)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, listed...)
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images listed in %s", ciImagesFile)
+	}
+	return images, nil
+}
+
+// readCiImages reads the images listed within the given file: an image per line, optionally followed by the path of the
+// CI config the image is held to (read like --ci-config, or '-' for the shared config) and the path of its baseline
+// (read like --baseline). Blank lines and lines starting with '#' are skipped:
+//
+//	# image                      ci config      baseline
+//	docker-archive://app.tar     .dive-ci-app   app-main.json
+//	registry.example.com/api:1   -              api-main.json
+//	registry.example.com/base:1
+func readCiImages(cmd *cobra.Command, path string) ([]runtime.CiImage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// note: images sharing a config share the rules read from it
+	configs := make(map[string]*viper.Viper)
+	var images []runtime.CiImage
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("invalid line %d of %s: expected an image, optionally followed by a CI config (or '-') and a baseline", lineNum, path)
+		}
+
+		sourceType, imageStr := deriveImageSource(fields[0])
+		img := runtime.CiImage{Source: sourceType, Image: imageStr}
+		if len(fields) == 3 {
+			img.BaselineFile = fields[2]
+		}
+		if len(fields) >= 2 && fields[1] != "-" {
+			config, exists := configs[fields[1]]
+			if !exists {
+				fmt.Printf("  Using CI config: %s (for %s)\n", fields[1], imageStr)
+				config = viper.New()
+				config.SetConfigType("yaml")
+				if err := bindCiFlags(config, cmd); err != nil {
+					return nil, err
+				}
+				if err := readCiConfig(config, fields[1]); err != nil {
+					return nil, fmt.Errorf("unable to read the CI config of %s: %v", imageStr, err)
+				}
+				configs[fields[1]] = config
+			}
+			img.CiConfig = config
+		}
+		images = append(images, img)
+	}
+	return images, scanner.Err()
+}
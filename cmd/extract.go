@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+var extractLayer int
+var extractMerged bool
+
+// extractCmd represents the extract command
+var extractCmd = &cobra.Command{
+	Use:   "extract IMAGE PATH DEST",
+	Short: "Write a file or directory from the given image layer to the local DEST directory.",
+	Args:  cobra.ExactArgs(3),
+	Run:   doExtractCmd,
+}
+
+func init() {
+	extractCmd.Flags().IntVarP(&extractLayer, "layer", "l", -1, "The layer index to extract from (default is the last layer)")
+	extractCmd.Flags().BoolVarP(&extractMerged, "merged", "m", false, "Extract from the stacked view of all layers up to the given layer (instead of only the given layer)")
+	rootCmd.AddCommand(extractCmd)
+}
+
+// doExtractCmd implements the steps taken for the extract command
+func doExtractCmd(cmd *cobra.Command, args []string) {
+	initLogging()
+
+	img := fetchImage(args[0])
+
+	layerIdx := extractLayer
+	if layerIdx < 0 {
+		layerIdx = len(img.Trees) - 1
+	}
+
+	fmt.Println(utils.TitleFormat("Extracting..."))
+	result, err := image.Extract(img.Content, img.Trees, layerIdx, extractMerged, args[1], args[2])
+	if err != nil {
+		fmt.Printf("cannot extract '%s': %v\n", args[1], err)
+		os.Exit(1)
+	}
+
+	for _, skipped := range result.Skipped {
+		fmt.Printf("  skipped: %s\n", skipped)
+	}
+	fmt.Printf("  wrote %d entries to %s\n", result.Written, result.Destination)
+}
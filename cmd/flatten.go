@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+var flattenUpto int
+var flattenOutput string
+
+// flattenCmd represents the flatten command
+var flattenCmd = &cobra.Command{
+	Use:   "flatten IMAGE",
+	Short: "Write a tar of the merged root filesystem as seen at the given layer.",
+	Args:  cobra.ExactArgs(1),
+	Run:   doFlattenCmd,
+}
+
+func init() {
+	flattenCmd.Flags().IntVar(&flattenUpto, "upto", -1, "The last layer index to apply (default is the last layer)")
+	flattenCmd.Flags().StringVarP(&flattenOutput, "output", "o", "", "The tar file to write the merged root filesystem to")
+	if err := flattenCmd.MarkFlagRequired("output"); err != nil {
+		logrus.Fatalf("Unable to mark 'output' flag as required: %v", err)
+	}
+	rootCmd.AddCommand(flattenCmd)
+}
+
+// doFlattenCmd implements the steps taken for the flatten command
+func doFlattenCmd(cmd *cobra.Command, args []string) {
+	initLogging()
+
+	img := fetchImage(args[0])
+
+	upto := flattenUpto
+	if upto < 0 {
+		upto = len(img.Layers) - 1
+	}
+
+	fmt.Println(utils.TitleFormat(fmt.Sprintf("Flattening layers 0-%d...", upto)))
+	result, err := flattenTo(img, upto, flattenOutput)
+	if err != nil {
+		fmt.Printf("cannot flatten image: %v\n", err)
+		os.Exit(1)
+	}
+
+	if result.Materialized > 0 {
+		fmt.Printf("  %d hard links to replaced files were written as regular files\n", result.Materialized)
+	}
+	fmt.Printf("  wrote %d entries to %s\n", result.Entries, flattenOutput)
+}
+
+// flattenTo writes the merged root filesystem to a temporary file beside the given output, which only replaces the
+// output once the whole filesystem has been written (a failure never leaves a truncated tar behind).
+func flattenTo(img *image.Image, upto int, output string) (*image.FlattenResult, error) {
+	file, err := ioutil.TempFile(filepath.Dir(output), "."+filepath.Base(output)+".*")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create output file: %v", err)
+	}
+	// note: temporary files are only readable by the owner, the output is created as os.Create would
+	err = file.Chmod(0644)
+	var result *image.FlattenResult
+	if err == nil {
+		result, err = image.Flatten(img.Content, len(img.Layers), upto, file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), output)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+	return result, nil
+}
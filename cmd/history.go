@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history IMAGE PATH",
+	Short: "Show every layer's change to the given path (or path glob) within the image.",
+	Args:  cobra.ExactArgs(2),
+	Run:   doHistoryCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
+
+// doHistoryCmd implements the steps taken for the history command
+func doHistoryCmd(cmd *cobra.Command, args []string) {
+	initLogging()
+
+	img := fetchImage(args[0])
+
+	histories, err := filetree.History(img.Trees, args[1])
+	if err != nil {
+		fmt.Printf("invalid path: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(histories) == 0 {
+		fmt.Printf("no layer contains a path matching '%s'\n", args[1])
+		os.Exit(1)
+	}
+
+	// owner names are informational, so the raw ids are shown when the names cannot be read
+	owners, err := image.ReadOwners(img.Trees, img.Files)
+	if err != nil {
+		logrus.Warnf("unable to read owners: %+v", err)
+	}
+
+	for _, history := range histories {
+		history.SetOwners(owners)
+		fmt.Println(utils.TitleFormat(history.Path))
+		fmt.Printf(filetree.HistoryFormat+"  %s\n", "Layer", "Action", "Size", "Permission", "UID:GID", "Hash", "Command")
+		for _, entry := range history.Entries {
+			var command string
+			if entry.LayerIndex < len(img.Layers) {
+				command = img.Layers[entry.LayerIndex].Command
+			}
+			fmt.Printf("%s  %s\n", entry.String(), command)
+		}
+		fmt.Println()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/wagoodman/dive/dive"
 	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/runtime/ci"
 	"io/ioutil"
 	"os"
 	"path"
@@ -17,17 +18,25 @@ import (
 
 var cfgFile string
 var exportFile string
+var snapshotFile string
 var ciConfigFile string
+var ciImagesFile string
+var ciReports []string
+var dockerfile string
+var markdownFile string
+var baselineFile string
+var baselineLabel string
 var ciConfig = viper.New()
 var isCi bool
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "dive [IMAGE]",
+	Use:   "dive [IMAGE...]",
 	Short: "Docker Image Visualizer & Explorer",
 	Long: `This tool provides a way to discover and explore the contents of a docker image. Additionally the tool estimates
-the amount of wasted space and identifies the offending files from the image.`,
-	Args: cobra.MaximumNArgs(1),
+the amount of wasted space and identifies the offending files from the image. Several images can be given in CI
+(see --ci), which are evaluated together.`,
+	Args: cobra.ArbitraryArgs,
 	Run:  doAnalyzeCmd,
 }
 
@@ -51,21 +60,43 @@ func initCli() {
 	rootCmd.PersistentFlags().BoolP("ignore-errors", "i", false, "ignore image parsing errors and run the analysis anyway")
 	rootCmd.Flags().BoolVar(&isCi, "ci", false, "Skip the interactive TUI and validate against CI rules (same as env var CI=true)")
 	rootCmd.Flags().StringVarP(&exportFile, "json", "j", "", "Skip the interactive TUI and write the layer analysis statistics to a given file.")
+	rootCmd.Flags().StringVar(&snapshotFile, "snapshot", "", "Skip the interactive TUI and save the image (layers, history, config, and file trees) to a given file, which can be opened later with the snapshot:// source.")
+	rootCmd.Flags().StringVar(&markdownFile, "markdown", "", "Skip the interactive TUI and write a Markdown summary of the analysis (e.g. for a pull request comment) to a given file.")
+	rootCmd.Flags().StringVar(&baselineFile, "baseline", "", "A previous --json export to compare the image against (e.g. of the image built from the main branch), used by the baseline CI rules and Markdown summaries.")
+	rootCmd.Flags().StringVar(&baselineLabel, "baseline-label", "baseline", "The name of the --baseline image within Markdown summaries (e.g. \"main\" shows \"+12 MB vs main\").")
 	rootCmd.Flags().StringVar(&ciConfigFile, "ci-config", ".dive-ci", "If CI=true in the environment, use the given yaml to drive validation rules.")
+	rootCmd.Flags().StringVar(&ciImagesFile, "ci-images", "", "(only valid with --ci given) a file listing images to evaluate (in addition to any given as arguments), one per line, each optionally followed by the CI config of the image (the --ci-config rules are used otherwise, or when given as '-') and the --baseline of the image.")
+	rootCmd.Flags().StringArrayVar(&ciReports, "ci-report", nil, "(only valid with --ci given) also write the CI results to a file, given as format=path (e.g. junit=report.xml, sarif=report.sarif, or markdown=summary.md). Allowed formats: "+strings.Join(ci.ReportFormats(), ", ")+". May be given multiple times.")
+	rootCmd.Flags().StringVar(&dockerfile, "dockerfile", "", "(only valid with --ci-report given, for a single image) the Dockerfile the image was built from, so that report results point at the instruction that created each layer.")
+	rootCmd.Flags().Bool("fail-on-warn", false, "(only valid with --ci given) fail CI validation on any rule warning, as well as on any rule failure.")
+	rootCmd.Flags().Bool("scan-secret-content", false, "Check the contents of every file in every layer for secrets (in addition to checking file paths).")
 
-	rootCmd.Flags().String("lowestEfficiency", "0.9", "(only valid with --ci given) lowest allowable image efficiency (as a ratio between 0-1), otherwise CI validation will fail.")
-	rootCmd.Flags().String("highestWastedBytes", "disabled", "(only valid with --ci given) highest allowable bytes wasted, otherwise CI validation will fail.")
-	rootCmd.Flags().String("highestUserWastedPercent", "0.1", "(only valid with --ci given) highest allowable percentage of bytes wasted (as a ratio between 0-1), otherwise CI validation will fail.")
+	for _, rule := range ci.RegisteredRules() {
+		rootCmd.Flags().String(rule.Key, rule.Default, "(only valid with --ci given) "+rule.Description)
+	}
+
+	if err := bindCiFlags(ciConfig, rootCmd); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	for _, key := range []string{"lowestEfficiency", "highestWastedBytes", "highestUserWastedPercent"} {
-		if err := ciConfig.BindPFlag(fmt.Sprintf("rules.%s", key), rootCmd.Flags().Lookup(key)); err != nil {
-			log.Fatalf("Unable to bind '%s' flag: %v", key, err)
+// bindCiFlags binds the CI flags of the command (e.g. the rule thresholds) to the given CI config, so that the flags
+// (or their defaults) apply to any rule the config does not set.
+func bindCiFlags(config *viper.Viper, cmd *cobra.Command) error {
+	for _, rule := range ci.RegisteredRules() {
+		if err := config.BindPFlag(fmt.Sprintf("rules.%s", rule.Key), cmd.Flags().Lookup(rule.Key)); err != nil {
+			return fmt.Errorf("unable to bind '%s' flag: %v", rule.Key, err)
 		}
 	}
 
-	if err := ciConfig.BindPFlag("ignore-errors", rootCmd.PersistentFlags().Lookup("ignore-errors")); err != nil {
-		log.Fatalf("Unable to bind 'ignore-errors' flag: %v", err)
+	if err := config.BindPFlag("fail-on-warn", cmd.Flags().Lookup("fail-on-warn")); err != nil {
+		return fmt.Errorf("unable to bind 'fail-on-warn' flag: %v", err)
 	}
+
+	if err := config.BindPFlag("ignore-errors", cmd.PersistentFlags().Lookup("ignore-errors")); err != nil {
+		return fmt.Errorf("unable to bind 'ignore-errors' flag: %v", err)
+	}
+	return nil
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -79,17 +110,27 @@ func initConfig() {
 	viper.SetDefault("keybinding.quit", "ctrl+c")
 	viper.SetDefault("keybinding.toggle-view", "tab")
 	viper.SetDefault("keybinding.filter-files", "ctrl+f, ctrl+slash")
+	viper.SetDefault("keybinding.toggle-findings", "ctrl+o")
+	viper.SetDefault("keybinding.toggle-packages", "ctrl+p")
+	viper.SetDefault("keybinding.toggle-problems", "ctrl+y")
 	// keybindings: layer view
 	viper.SetDefault("keybinding.compare-all", "ctrl+a")
 	viper.SetDefault("keybinding.compare-layer", "ctrl+l")
+	viper.SetDefault("keybinding.toggle-simulation", "ctrl+s")
+	viper.SetDefault("keybinding.simulate-squash", "ctrl+w")
+	viper.SetDefault("keybinding.simulate-move", "ctrl+x")
 	// keybindings: filetree view
 	viper.SetDefault("keybinding.toggle-collapse-dir", "space")
 	viper.SetDefault("keybinding.toggle-collapse-all-dir", "ctrl+space")
 	viper.SetDefault("keybinding.toggle-filetree-attributes", "ctrl+b")
+	viper.SetDefault("keybinding.toggle-history", "ctrl+t")
+	viper.SetDefault("keybinding.extract-file", "ctrl+e")
+	viper.SetDefault("keybinding.goto-link-target", "ctrl+g")
 	viper.SetDefault("keybinding.toggle-added-files", "ctrl+a")
 	viper.SetDefault("keybinding.toggle-removed-files", "ctrl+r")
 	viper.SetDefault("keybinding.toggle-modified-files", "ctrl+m")
 	viper.SetDefault("keybinding.toggle-unmodified-files", "ctrl+u")
+	viper.SetDefault("keybinding.toggle-collisions", "ctrl+k")
 	viper.SetDefault("keybinding.page-up", "pgup")
 	viper.SetDefault("keybinding.page-down", "pgdn")
 
@@ -100,6 +141,13 @@ func initConfig() {
 	viper.SetDefault("filetree.collapse-dir", false)
 	viper.SetDefault("filetree.pane-width", 0.5)
 	viper.SetDefault("filetree.show-attributes", true)
+	viper.SetDefault("filetree.cache-size", filetree.DefaultTreeCacheSize)
+	viper.SetDefault("filetree.extract-dir", "dive-extract")
+
+	viper.SetDefault("secrets.scan-content", false)
+	viper.SetDefault("secrets.max-content-size", "1MB")
+
+	viper.SetDefault("removable.builtin-rules", true)
 
 	viper.SetDefault("container-engine", "docker")
 	viper.SetDefault("ignore-errors", false)
@@ -110,6 +158,12 @@ func initConfig() {
 		os.Exit(1)
 	}
 
+	err = viper.BindPFlag("secrets.scan-content", rootCmd.Flags().Lookup("scan-secret-content"))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	viper.SetEnvPrefix("DIVE")
 	// replace all - with _ when looking for matching environment variables
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
@@ -130,9 +184,6 @@ func initConfig() {
 		fmt.Println(err)
 		os.Exit(0)
 	}
-
-	// set global defaults (for performance)
-	filetree.GlobalFileTreeCollapse = viper.GetBool("filetree.collapse-dir")
 }
 
 // initLogging sets up the logging object with a formatter and location
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+const simulateFormat = "%-10s %9s  %s\n"
+
+var simulateGroups []string
+
+// simulateCmd represents the simulate command
+var simulateCmd = &cobra.Command{
+	Use:   "simulate IMAGE",
+	Short: "Show the size and efficiency of the image if the given layers were squashed (or reordered) together.",
+	Long: `Show the size and efficiency of the image if the given layers were squashed (or reordered) together.
+
+Each group is a set of layer indexes (or inclusive ranges) joined by "+". For example "--group 3-7" squashes layers
+3 through 7 into a single layer, and "--group 2+5" shows the image as if layer 5 ran within the same layer as layer 2.
+Layers that are not within a group are kept as-is.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doSimulateCmd,
+}
+
+func init() {
+	simulateCmd.Flags().StringArrayVarP(&simulateGroups, "group", "g", nil, "Layers to squash into a single layer (e.g. '3-7' or '2+5'), may be given more than once")
+	rootCmd.AddCommand(simulateCmd)
+}
+
+// doSimulateCmd implements the steps taken for the simulate command
+func doSimulateCmd(cmd *cobra.Command, args []string) {
+	initLogging()
+
+	var groups []filetree.LayerGroup
+	for _, spec := range simulateGroups {
+		group, err := filetree.ParseLayerGroup(spec)
+		if err != nil {
+			fmt.Printf("invalid group: %v\n", err)
+			os.Exit(1)
+		}
+		groups = append(groups, group)
+	}
+
+	img := fetchImage(args[0])
+
+	grouping, err := filetree.NewLayerGrouping(len(img.Trees), groups...)
+	if err != nil {
+		fmt.Printf("invalid group: %v\n", err)
+		os.Exit(1)
+	}
+	identity, err := filetree.NewLayerGrouping(len(img.Trees))
+	if err != nil {
+		fmt.Printf("cannot simulate image: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(utils.TitleFormat("Simulating layers..."))
+	original, err := filetree.Simulate(img.Trees, identity)
+	if err != nil {
+		fmt.Printf("cannot simulate image: %v\n", err)
+		os.Exit(1)
+	}
+	simulated, err := filetree.Simulate(img.Trees, grouping)
+	if err != nil {
+		fmt.Printf("cannot simulate image: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf(simulateFormat, "Layers", "Size", "Command")
+	for groupIdx, group := range simulated.Groups {
+		for memberIdx, layerIdx := range group {
+			if memberIdx == 0 {
+				fmt.Printf(simulateFormat, group.String(), humanize.Bytes(simulated.LayerSizes[groupIdx]), layerCommand(img.Layers, layerIdx))
+			} else {
+				fmt.Printf(simulateFormat, "", "", layerCommand(img.Layers, layerIdx))
+			}
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("  layers: %d -> %d\n", len(original.Groups), len(simulated.Groups))
+	fmt.Printf("  size: %s -> %s (%s)\n", humanize.Bytes(original.SizeBytes), humanize.Bytes(simulated.SizeBytes), byteDelta(original.SizeBytes, simulated.SizeBytes))
+	fmt.Printf("  wasted: %s -> %s (%s)\n", humanize.Bytes(original.WastedBytes), humanize.Bytes(simulated.WastedBytes), byteDelta(original.WastedBytes, simulated.WastedBytes))
+	fmt.Printf("  efficiency: %2.4f %% -> %2.4f %%\n", 100.0*original.Efficiency, 100.0*simulated.Efficiency)
+}
+
+// layerCommand describes the command that created the given layer.
+func layerCommand(layers []*image.Layer, layerIdx int) string {
+	if layerIdx >= len(layers) {
+		return ""
+	}
+	if layerIdx == 0 {
+		return "FROM " + layers[0].ShortId()
+	}
+	return layers[layerIdx].Command
+}
+
+// byteDelta describes the change from one size to another (e.g. "-12 MB").
+func byteDelta(before, after uint64) string {
+	if after > before {
+		return "+" + humanize.Bytes(after-before)
+	}
+	return "-" + humanize.Bytes(before-after)
+}
@@ -0,0 +1,338 @@
+package filetree
+
+import (
+	"archive/tar"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/phayes/permbits"
+)
+
+const (
+	FindingFormat = "%-8s %-16s %-11s %-11s %5s  %s"
+)
+
+const (
+	SeverityLow Severity = iota
+	SeverityMedium
+	SeverityHigh
+)
+
+// Severity indicates how concerning a finding is.
+type Severity int
+
+// ParseSeverity returns the severity with the given name (see Severity.String).
+func ParseSeverity(name string) (Severity, error) {
+	for _, severity := range []Severity{SeverityLow, SeverityMedium, SeverityHigh} {
+		if strings.EqualFold(name, severity.String()) {
+			return severity, nil
+		}
+	}
+	return SeverityLow, fmt.Errorf("unknown severity: '%s' (expected low, medium, or high)", name)
+}
+
+func (severity Severity) String() string {
+	switch severity {
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	default:
+		return "<unknown severity>"
+	}
+}
+
+const (
+	FindingSetuid          FindingKind = "setuid"
+	FindingSetgid          FindingKind = "setgid"
+	FindingWorldWritable   FindingKind = "world-writable"
+	FindingGroupWritable   FindingKind = "group-writable"
+	FindingUnexpectedOwner FindingKind = "unexpected-owner"
+	FindingUnknownOwner    FindingKind = "unknown-owner"
+	FindingPathCollision   FindingKind = "path-collision"
+	FindingSecret          FindingKind = "secret"
+)
+
+// FindingKind describes the concern raised by a finding.
+type FindingKind string
+
+var findingKindDescriptions = map[FindingKind]string{
+	FindingSetuid:          "setuid file",
+	FindingSetgid:          "setgid file",
+	FindingWorldWritable:   "world-writable path outside of the temporary directories",
+	FindingGroupWritable:   "root-owned path writable by another group",
+	FindingUnexpectedOwner: "path owned by a user other than root",
+	FindingUnknownOwner:    "path owned by a uid without a passwd entry",
+	FindingPathCollision:   "path colliding with a sibling by case or Unicode normalization",
+	FindingSecret:          "file likely to contain a secret",
+}
+
+// Description explains the concern raised by findings of the kind.
+func (kind FindingKind) Description() string {
+	if description, exists := findingKindDescriptions[kind]; exists {
+		return description
+	}
+	return string(kind)
+}
+
+// Finding is a single path within the final image filesystem that warrants review.
+type Finding struct {
+	Kind     FindingKind
+	Severity Severity
+	Path     string
+	// LayerIndex is the layer that introduced the path (as it exists in the final image)
+	LayerIndex int
+	Mode       os.FileMode
+	Uid        int
+	Gid        int
+	// User and Group are the owner names within the final image (see Findings.SetOwners)
+	User   string
+	Group  string
+	Detail string
+}
+
+// String returns the finding in a columnar format (see FindingFormat).
+func (finding *Finding) String() string {
+	dir := "-"
+	if finding.Mode.IsDir() {
+		dir = "d"
+	}
+	return fmt.Sprintf(FindingFormat,
+		finding.Severity.String(),
+		finding.Kind,
+		dir+permbits.FileMode(finding.Mode).String(),
+		finding.owner(),
+		fmt.Sprintf("%d", finding.LayerIndex),
+		finding.Path,
+	)
+}
+
+// owner returns the owner names for the UID:GID column (the raw ids when the names are not known).
+func (finding *Finding) owner() string {
+	if finding.User == "" && finding.Group == "" {
+		return fmt.Sprintf("%d:%d", finding.Uid, finding.Gid)
+	}
+	return fitOwnerColumn(finding.User, finding.Group, ownerColumnWidth)
+}
+
+// Findings is a set of findings, ordered by severity (most severe first) and then by path.
+type Findings []Finding
+
+// Sort orders the findings by severity (most severe first) and then by path.
+func (findings Findings) Sort() {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity > findings[j].Severity
+		}
+		return strings.Compare(findings[i].Path, findings[j].Path) < 0
+	})
+}
+
+// SetOwners names the owner of every finding (falling back to the raw ids without a mapping). Nothing is named when
+// there are no known owners.
+func (findings Findings) SetOwners(owners *Owners) {
+	if owners == nil {
+		return
+	}
+	for idx := range findings {
+		findings[idx].User = owners.User(findings[idx].Uid)
+		findings[idx].Group = owners.Group(findings[idx].Gid)
+	}
+}
+
+// Filter returns the findings of the given kinds that are not within any of the given paths (see PathAllowed).
+func (findings Findings) Filter(allowlist []string, kinds ...FindingKind) Findings {
+	var filtered Findings
+	for _, finding := range findings {
+		for _, kind := range kinds {
+			if finding.Kind == kind && !PathAllowed(finding.Path, allowlist) {
+				filtered = append(filtered, finding)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// PathAllowed indicates if the given path matches any of the given path.Match globs, or is beneath a matching
+// directory (e.g. "/tmp" allows "/tmp/cache/file").
+func PathAllowed(p string, allowlist []string) bool {
+	for _, pattern := range allowlist {
+		pattern = path.Clean("/" + pattern)
+		for candidate := p; ; candidate = path.Dir(candidate) {
+			if matched, _ := path.Match(pattern, candidate); matched {
+				return true
+			}
+			if candidate == "/" {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// AuditOptions adjusts what is reported by Audit.
+type AuditOptions struct {
+	// ExpectedUids are the file owners that are not reported (no owners are reported when empty)
+	ExpectedUids []int
+	// WorldWritablePaths are the paths where world-writable files are expected (see PathAllowed)
+	WorldWritablePaths []string
+	// Owners are the users of the final image, paths owned by a uid without a passwd entry are reported (nothing is
+	// reported when nil)
+	Owners *Owners
+}
+
+// DefaultAuditOptions expects every file to be owned by root, and only expects world-writable files within the
+// temporary directories.
+func DefaultAuditOptions() AuditOptions {
+	return AuditOptions{
+		ExpectedUids:       []int{0},
+		WorldWritablePaths: []string{"/tmp", "/var/tmp", "/dev/shm"},
+	}
+}
+
+// Audit reviews the permissions and ownership of every path within the final image filesystem (the given final tree,
+// which is all given layer trees stacked), reporting setuid/setgid binaries, world-writable paths, root-owned paths
+// writable by another group, paths owned by unexpected users (or by uids without a passwd entry), and paths that
+// collide once case and Unicode normalization are ignored (see FileTree.MarkCollisions). Files owned by an unexpected
+// (or unknown) user are reported once for the top-most path with that owner, not for every path beneath it.
+func Audit(trees []*FileTree, tree *FileTree, options AuditOptions) (Findings, error) {
+	if len(trees) == 0 || tree == nil {
+		return nil, nil
+	}
+
+	expectedUids := make(map[int]bool)
+	for _, uid := range options.ExpectedUids {
+		expectedUids[uid] = true
+	}
+
+	var findings Findings
+	add := func(node *FileNode, kind FindingKind, severity Severity, detail string) {
+		info := node.Data.FileInfo
+		findings = append(findings, Finding{
+			Kind:       kind,
+			Severity:   severity,
+			Path:       node.Path(),
+			LayerIndex: introducedBy(trees, node.Path()),
+			Mode:       info.Mode,
+			Uid:        info.Uid,
+			Gid:        info.Gid,
+			Detail:     detail,
+		})
+	}
+
+	// ownerFindings maps the paths already reported as having an unexpected (or unknown) owner to the number of paths
+	// beneath them that share the owner (which are not reported individually)
+	ownerFindings := map[FindingKind]map[string]int{
+		FindingUnexpectedOwner: make(map[string]int),
+		FindingUnknownOwner:    make(map[string]int),
+	}
+	// reportOwner indicates if the owner of the given path should be reported (it is not beneath a reported path with
+	// the same owner)
+	reportOwner := func(kind FindingKind, p string, uid int) bool {
+		for parent := path.Dir(p); ; parent = path.Dir(parent) {
+			if _, exists := ownerFindings[kind][parent]; exists {
+				if parentNode, err := tree.GetNode(parent); err == nil && parentNode.Data.FileInfo.Uid == uid {
+					ownerFindings[kind][parent]++
+					return false
+				}
+			}
+			if parent == "/" {
+				break
+			}
+		}
+		ownerFindings[kind][p] = 0
+		return true
+	}
+
+	err := tree.VisitDepthParentFirst(func(node *FileNode) error {
+		// implied parent directories carry no metadata of their own
+		if node.IsImplied() {
+			return nil
+		}
+		info := node.Data.FileInfo
+		mode := info.Mode
+		p := node.Path()
+
+		isFile := info.TypeFlag == tar.TypeReg || info.TypeFlag == tar.TypeRegA || info.TypeFlag == tar.TypeLink
+		isDir := info.IsDir
+
+		if isFile && mode&os.ModeSetuid != 0 {
+			severity := SeverityMedium
+			if info.Uid == 0 {
+				severity = SeverityHigh
+			}
+			add(node, FindingSetuid, severity, fmt.Sprintf("runs as uid %d", info.Uid))
+		}
+		if isFile && mode&os.ModeSetgid != 0 {
+			add(node, FindingSetgid, SeverityMedium, fmt.Sprintf("runs as gid %d", info.Gid))
+		}
+
+		// symlinks are always 0777 and devices are expected to be writable, so only files and directories are reviewed
+		if (isFile || isDir) && !PathAllowed(p, options.WorldWritablePaths) {
+			switch {
+			case mode&0002 != 0:
+				severity, detail := SeverityHigh, "writable by any user"
+				if isDir && mode&os.ModeSticky != 0 {
+					severity, detail = SeverityLow, "writable by any user (sticky)"
+				}
+				add(node, FindingWorldWritable, severity, detail)
+			case mode&0020 != 0 && info.Uid == 0 && info.Gid != 0:
+				add(node, FindingGroupWritable, SeverityMedium, fmt.Sprintf("root-owned, writable by gid %d", info.Gid))
+			}
+		}
+
+		if len(expectedUids) > 0 && !expectedUids[info.Uid] && reportOwner(FindingUnexpectedOwner, p, info.Uid) {
+			add(node, FindingUnexpectedOwner, SeverityLow, fmt.Sprintf("owned by uid %d", info.Uid))
+		}
+		if !options.Owners.HasUser(info.Uid) && reportOwner(FindingUnknownOwner, p, info.Uid) {
+			add(node, FindingUnknownOwner, SeverityMedium, fmt.Sprintf("uid %d has no passwd entry", info.Uid))
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, collision := range tree.MarkCollisions() {
+		for _, p := range collision.Paths {
+			node, err := tree.GetNode(p)
+			if err != nil {
+				return nil, err
+			}
+			var others []string
+			for _, other := range collision.Paths {
+				if other != p {
+					others = append(others, other)
+				}
+			}
+			add(node, FindingPathCollision, SeverityLow, fmt.Sprintf("collides with %s (%s)", strings.Join(others, ", "), collision.Kind))
+		}
+	}
+
+	for idx := range findings {
+		if counts, exists := ownerFindings[findings[idx].Kind]; exists && counts[findings[idx].Path] > 0 {
+			count := counts[findings[idx].Path]
+			findings[idx].Detail += fmt.Sprintf(" (along with %d paths beneath it)", count)
+		}
+	}
+
+	findings.Sort()
+	return findings, nil
+}
+
+// introducedBy returns the index of the highest layer providing the given path (-1 if no layer does).
+func introducedBy(trees []*FileTree, p string) int {
+	for idx := len(trees) - 1; idx >= 0; idx-- {
+		node, err := trees[idx].GetNode(p)
+		if err == nil && !node.IsImplied() {
+			return idx
+		}
+	}
+	return -1
+}
@@ -0,0 +1,119 @@
+package filetree
+
+import (
+	"archive/tar"
+	"os"
+	"testing"
+)
+
+// stackAll returns the final image filesystem of the given layer trees (as the analysis does).
+func stackAll(t *testing.T, trees []*FileTree) *FileTree {
+	final, _, err := StackTreeRange(trees, 0, len(trees)-1)
+	if err != nil {
+		t.Fatalf("could not stack trees: %+v", err)
+	}
+	return final
+}
+
+func TestAudit(t *testing.T) {
+	trees := make([]*FileTree, 2)
+	for idx := range trees {
+		trees[idx] = NewFileTree()
+	}
+
+	add := func(tree *FileTree, p string, typeFlag byte, mode os.FileMode, uid, gid int) {
+		_, _, err := tree.AddPath(p, FileInfo{Path: p, TypeFlag: typeFlag, Mode: mode, Uid: uid, Gid: gid, IsDir: typeFlag == tar.TypeDir})
+		checkError(t, err, "could not setup test")
+	}
+
+	add(trees[0], "/bin/su", tar.TypeReg, 0755|os.ModeSetuid, 0, 0)
+	add(trees[0], "/usr/bin/wall", tar.TypeReg, 0755|os.ModeSetgid, 0, 5)
+	add(trees[0], "/usr/bin/sh", tar.TypeSymlink, 0777, 0, 0)
+	add(trees[0], "/tmp", tar.TypeDir, 0777|os.ModeDir|os.ModeSticky, 0, 0)
+	add(trees[0], "/tmp/scratch", tar.TypeReg, 0666, 0, 0)
+	add(trees[0], "/etc/passwd", tar.TypeReg, 0666, 0, 0)
+	add(trees[0], "/srv", tar.TypeDir, 0777|os.ModeDir|os.ModeSticky, 0, 0)
+	add(trees[0], "/etc/app.conf", tar.TypeReg, 0664, 0, 50)
+	add(trees[0], "/home/app", tar.TypeDir, 0755|os.ModeDir, 1000, 1000)
+	add(trees[0], "/home/app/data", tar.TypeDir, 0755|os.ModeDir, 1000, 1000)
+	add(trees[0], "/home/app/data/file", tar.TypeReg, 0644, 1000, 1000)
+	add(trees[0], "/home/app/other", tar.TypeReg, 0644, 1001, 1001)
+
+	// the final image is audited: the layer replacing a path is reported, removed paths are not
+	add(trees[1], "/etc/passwd", tar.TypeReg, 0644, 0, 0)
+	add(trees[1], "/bin/su", tar.TypeReg, 0755|os.ModeSetuid, 0, 0)
+	add(trees[1], "/usr/bin/.wh.wall", tar.TypeReg, 0, 0, 0)
+
+	findings, err := Audit(trees, stackAll(t, trees), DefaultAuditOptions())
+	checkError(t, err, "could not audit")
+
+	expected := []struct {
+		kind       FindingKind
+		severity   Severity
+		path       string
+		layerIndex int
+	}{
+		{FindingSetuid, SeverityHigh, "/bin/su", 1},
+		{FindingGroupWritable, SeverityMedium, "/etc/app.conf", 0},
+		{FindingUnexpectedOwner, SeverityLow, "/home/app", 0},
+		{FindingUnexpectedOwner, SeverityLow, "/home/app/other", 0},
+		{FindingWorldWritable, SeverityLow, "/srv", 0},
+	}
+
+	if len(findings) != len(expected) {
+		t.Fatalf("expected %d findings, got %d: %+v", len(expected), len(findings), findings)
+	}
+	for idx, finding := range findings {
+		if finding.Kind != expected[idx].kind || finding.Severity != expected[idx].severity || finding.Path != expected[idx].path || finding.LayerIndex != expected[idx].layerIndex {
+			t.Errorf("finding %d: expected %+v, got %+v", idx, expected[idx], finding)
+		}
+	}
+
+	if detail := findings[2].Detail; detail != "owned by uid 1000 (along with 2 paths beneath it)" {
+		t.Errorf("unexpected owner detail: %q", detail)
+	}
+
+	filtered := findings.Filter([]string{"/home/app"}, FindingUnexpectedOwner, FindingWorldWritable)
+	if len(filtered) != 1 || filtered[0].Path != "/srv" {
+		t.Errorf("unexpected filtered findings: %+v", filtered)
+	}
+}
+
+func TestAuditUnknownOwner(t *testing.T) {
+	tree := NewFileTree()
+	for _, info := range []FileInfo{
+		{Path: "/etc/passwd", TypeFlag: tar.TypeReg, Mode: 0644},
+		{Path: "/app", TypeFlag: tar.TypeDir, Mode: 0755 | os.ModeDir, Uid: 1234, Gid: 1234, IsDir: true},
+		{Path: "/app/bin", TypeFlag: tar.TypeReg, Mode: 0755, Uid: 1234, Gid: 1234},
+		{Path: "/app/config", TypeFlag: tar.TypeReg, Mode: 0644, Uid: 33, Gid: 33},
+	} {
+		_, _, err := tree.AddPath(info.Path, info)
+		checkError(t, err, "could not setup test")
+	}
+
+	// without known owners no uid is considered unknown
+	findings, err := Audit([]*FileTree{tree}, stackAll(t, []*FileTree{tree}), AuditOptions{})
+	checkError(t, err, "could not audit")
+	if len(findings) != 0 {
+		t.Fatalf("expected no findings without known owners, got %+v", findings)
+	}
+
+	owners := ParseOwners([]byte("root:x:0:0::/root:/bin/sh\nwww-data:x:33:33::/var/www:/bin/false\n"), []byte("root:x:0:\n"))
+	findings, err = Audit([]*FileTree{tree}, stackAll(t, []*FileTree{tree}), AuditOptions{Owners: owners})
+	checkError(t, err, "could not audit")
+	findings.SetOwners(owners)
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %+v", findings)
+	}
+	finding := findings[0]
+	if finding.Kind != FindingUnknownOwner || finding.Path != "/app" || finding.Severity != SeverityMedium {
+		t.Errorf("unexpected finding: %+v", finding)
+	}
+	if finding.Detail != "uid 1234 has no passwd entry (along with 1 paths beneath it)" {
+		t.Errorf("unexpected detail: %q", finding.Detail)
+	}
+	if finding.User != "1234" || finding.Group != "1234" {
+		t.Errorf("expected the raw ids as names, got %q:%q", finding.User, finding.Group)
+	}
+}
@@ -0,0 +1,104 @@
+package filetree
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// CollisionKind describes why sibling paths would collide on a case-insensitive or normalization-insensitive
+// filesystem (e.g. when the image is unpacked on macOS or Windows).
+type CollisionKind uint8
+
+const (
+	NoCollision CollisionKind = iota
+	// CollisionCase are names that differ only by case (e.g. Makefile and makefile)
+	CollisionCase
+	// CollisionUnicode are names that differ only by Unicode normalization (e.g. NFC and NFD forms of "é")
+	CollisionUnicode
+)
+
+var collisionKindNames = map[CollisionKind]string{
+	NoCollision:      "",
+	CollisionCase:    "case",
+	CollisionUnicode: "unicode",
+}
+
+func (kind CollisionKind) String() string {
+	return collisionKindNames[kind]
+}
+
+// Collision is a set of sibling paths that would be written to the same file on a case-insensitive or
+// normalization-insensitive filesystem.
+type Collision struct {
+	Kind CollisionKind
+	// Paths are ordered, and always hold at least two paths
+	Paths []string
+}
+
+// collisionKey returns the name as seen by a case-insensitive and normalization-insensitive filesystem.
+func collisionKey(name string) string {
+	return strings.ToLower(norm.NFC.String(name))
+}
+
+// MarkCollisions tags every node whose name collides with a sibling once case and Unicode normalization are ignored (see
+// NodeData.Collision), returning the colliding paths ordered by path. Whiteouts and removed nodes are not considered.
+func (tree *FileTree) MarkCollisions() []Collision {
+	var collisions []Collision
+
+	visitor := func(node *FileNode) error {
+		node.Data.Collision = NoCollision
+		if len(node.Children) < 2 {
+			return nil
+		}
+
+		siblings := make(map[string][]*FileNode)
+		for name, child := range node.Children {
+			if child.IsWhiteout() || child.Data.DiffType == Removed {
+				continue
+			}
+			key := collisionKey(name)
+			siblings[key] = append(siblings[key], child)
+		}
+
+		for _, group := range siblings {
+			if len(group) < 2 {
+				continue
+			}
+			// names that are equal once normalized differ only by normalization, otherwise (at least) by case
+			kind := CollisionUnicode
+			normalized := norm.NFC.String(group[0].Name)
+			for _, child := range group[1:] {
+				if norm.NFC.String(child.Name) != normalized {
+					kind = CollisionCase
+					break
+				}
+			}
+
+			collision := Collision{Kind: kind, Paths: make([]string, len(group))}
+			for idx, child := range group {
+				collision.Paths[idx] = child.Path()
+			}
+			sort.Strings(collision.Paths)
+			collisions = append(collisions, collision)
+		}
+		return nil
+	}
+
+	// note: the root is not visited, but its children may collide as well
+	_ = visitor(tree.Root)
+	_ = tree.VisitDepthParentFirst(visitor, nil)
+	for _, collision := range collisions {
+		for _, p := range collision.Paths {
+			if node, err := tree.GetNode(p); err == nil {
+				node.Data.Collision = collision.Kind
+			}
+		}
+	}
+
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i].Paths[0] < collisions[j].Paths[0]
+	})
+	return collisions
+}
@@ -0,0 +1,73 @@
+package filetree
+
+import (
+	"archive/tar"
+	"reflect"
+	"testing"
+)
+
+func TestMarkCollisions(t *testing.T) {
+	trees := make([]*FileTree, 2)
+	for idx := range trees {
+		trees[idx] = NewFileTree()
+	}
+	add := func(tree *FileTree, p string, typeFlag byte) {
+		_, _, err := tree.AddPath(p, FileInfo{Path: p, TypeFlag: typeFlag, IsDir: typeFlag == tar.TypeDir})
+		checkError(t, err, "could not setup test")
+	}
+
+	add(trees[0], "/Makefile", tar.TypeReg)
+	add(trees[0], "/src/README", tar.TypeReg)
+	add(trees[0], "/src/café.txt", tar.TypeReg)
+	add(trees[0], "/src/Docs", tar.TypeDir)
+	add(trees[0], "/etc/hosts", tar.TypeReg)
+	add(trees[1], "/makefile", tar.TypeReg)
+	add(trees[1], "/src/readme", tar.TypeReg)
+	add(trees[1], "/src/README.md", tar.TypeReg)
+	// the decomposed (NFD) form of the name added by the previous layer
+	add(trees[1], "/src/cafe\u0301.txt", tar.TypeReg)
+	add(trees[1], "/src/docs", tar.TypeDir)
+	add(trees[1], "/src/.wh.Docs", tar.TypeReg)
+	add(trees[1], "/etc/.wh.HOSTS", tar.TypeReg)
+
+	tree, _, err := StackTreeRange(trees, 0, 1)
+	checkError(t, err, "could not stack trees")
+
+	collisions := tree.MarkCollisions()
+	expected := []Collision{
+		{Kind: CollisionCase, Paths: []string{"/Makefile", "/makefile"}},
+		{Kind: CollisionCase, Paths: []string{"/src/README", "/src/readme"}},
+		{Kind: CollisionUnicode, Paths: []string{"/src/cafe\u0301.txt", "/src/café.txt"}},
+	}
+	if !reflect.DeepEqual(collisions, expected) {
+		t.Fatalf("expected collisions %+v, got %+v", expected, collisions)
+	}
+
+	for p, kind := range map[string]CollisionKind{
+		"/Makefile":           CollisionCase,
+		"/src/readme":         CollisionCase,
+		"/src/café.txt":       CollisionUnicode,
+		"/src/README.md":      NoCollision,
+		"/src/docs":           NoCollision,
+		"/etc/hosts":          NoCollision,
+		"/src/cafe\u0301.txt": CollisionUnicode,
+	} {
+		node, err := tree.GetNode(p)
+		checkError(t, err, "could not get node")
+		if node.Data.Collision != kind {
+			t.Errorf("%s: expected collision %q, got %q", p, kind, node.Data.Collision)
+		}
+	}
+
+	findings, err := Audit(trees, stackAll(t, trees), AuditOptions{})
+	checkError(t, err, "could not audit")
+	collided := findings.Filter(nil, FindingPathCollision)
+	if len(collided) != 6 {
+		t.Fatalf("expected 6 collision findings, got %+v", collided)
+	}
+	for _, finding := range collided {
+		if finding.Path == "/makefile" && (finding.LayerIndex != 1 || finding.Detail != "collides with /Makefile (case)") {
+			t.Errorf("unexpected finding: %+v", finding)
+		}
+	}
+}
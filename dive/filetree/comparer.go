@@ -2,6 +2,9 @@ package filetree
 
 import (
 	"fmt"
+	"runtime"
+	"sync"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -29,59 +32,264 @@ func (index TreeIndexKey) String() string {
 	return fmt.Sprintf("Index(%d-%d:%d-%d)", index.bottomTreeStart, index.bottomTreeStop, index.topTreeStart, index.topTreeStop)
 }
 
+// DefaultTreeCacheSize is the default number of built trees a Comparer holds at any one time.
+const DefaultTreeCacheSize = 12
+
+// Comparer builds (and caches) the stacked and compared trees for any TreeIndexKey. Trees are built lazily on request
+// and may be prefetched in the background (see Close); at most a bounded number of built trees are held at once.
 type Comparer struct {
-	refTrees   []*FileTree
-	trees      map[TreeIndexKey]*FileTree
-	pathErrors map[TreeIndexKey][]PathError
+	refTrees []*FileTree
+	lock     sync.Mutex
+	cache    *treeCache
+	pending  map[cacheKey]chan struct{}
+	workers  int
+
+	// stop is closed to cancel prefetching, prefetching is done once every prefetch worker has returned
+	stop        chan struct{}
+	stopOnce    sync.Once
+	prefetching sync.WaitGroup
+
+	// removableRules and owners mark each compared tree as it is built (see mark)
+	removableRules RemovableRules
+	owners         []*Owners
+
+	// reported are the path errors returned by BuildCache, any other path error found while building a tree is kept
+	// as a lazy path error (see LazyPathErrors)
+	reported   map[PathError]bool
+	lazyErrors PathErrors
 }
 
-func NewComparer(refTrees []*FileTree) Comparer {
-	return Comparer{
-		refTrees:   refTrees,
-		trees:      make(map[TreeIndexKey]*FileTree),
-		pathErrors: make(map[TreeIndexKey][]PathError),
+func NewComparer(refTrees []*FileTree) *Comparer {
+	workers := runtime.NumCPU()
+	if workers > 4 {
+		workers = 4
+	}
+	return &Comparer{
+		refTrees: refTrees,
+		cache:    newTreeCache(DefaultTreeCacheSize),
+		pending:  make(map[cacheKey]chan struct{}),
+		workers:  workers,
+		stop:     make(chan struct{}),
+		reported: make(map[PathError]bool),
 	}
 }
 
+// Close cancels any background prefetching and waits for the trees being prefetched to finish building. Trees may
+// still be requested after closing, these are built on request.
+func (cmp *Comparer) Close() {
+	cmp.stopOnce.Do(func() {
+		close(cmp.stop)
+	})
+	cmp.prefetching.Wait()
+}
+
+// SetCacheSize bounds the number of built trees held by the comparer (a size of zero or less is unbounded).
+func (cmp *Comparer) SetCacheSize(size int) {
+	cmp.lock.Lock()
+	defer cmp.lock.Unlock()
+	cmp.cache.resize(size)
+}
+
+// SetRemovableRules sets the rules used to tag the likely removable nodes of each compared tree. This must be set
+// before any tree is built (i.e. before BuildCache).
+func (cmp *Comparer) SetRemovableRules(rules RemovableRules) {
+	cmp.lock.Lock()
+	defer cmp.lock.Unlock()
+	cmp.removableRules = rules
+}
+
+// SetOwners sets the user and group names as of each layer, each compared tree names the owners of its top layer.
+// This must be set before any tree is built (i.e. before BuildCache).
+func (cmp *Comparer) SetOwners(owners []*Owners) {
+	cmp.lock.Lock()
+	defer cmp.lock.Unlock()
+	cmp.owners = owners
+}
+
 func (cmp *Comparer) GetPathErrors(key TreeIndexKey) ([]PathError, error) {
-	_, pathErrors, err := cmp.get(key)
+	entry, err := cmp.getEntry(newComparedCacheKey(key))
 	if err != nil {
 		return nil, err
 	}
-	return pathErrors, nil
+	return entry.pathErrors, nil
 }
 
 func (cmp *Comparer) GetTree(key TreeIndexKey) (*FileTree, error) {
-	//func (cmp *Comparer) GetTree(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop int) (*FileTree, []PathError, error) {
-	//key := TreeIndexKey{bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop}
+	entry, err := cmp.getEntry(newComparedCacheKey(key))
+	if err != nil {
+		return nil, err
+	}
+	return entry.tree, nil
+}
 
-	if value, exists := cmp.trees[key]; exists {
-		return value, nil
+// IsReady indicates if the tree for the given key has been built and can be fetched without waiting.
+func (cmp *Comparer) IsReady(key TreeIndexKey) bool {
+	cmp.lock.Lock()
+	defer cmp.lock.Unlock()
+	return cmp.cache.contains(newComparedCacheKey(key))
+}
+
+// getEntry fetches the cached entry for the given key, building it if necessary. Concurrent requests for the same
+// key wait on a single build.
+func (cmp *Comparer) getEntry(key cacheKey) (*cacheEntry, error) {
+	for {
+		cmp.lock.Lock()
+		if entry, exists := cmp.cache.get(key); exists {
+			cmp.lock.Unlock()
+			return entry, nil
+		}
+		if done, exists := cmp.pending[key]; exists {
+			cmp.lock.Unlock()
+			<-done
+			continue
+		}
+		done := make(chan struct{})
+		cmp.pending[key] = done
+		cmp.lock.Unlock()
+
+		entry, err := cmp.build(key)
+
+		cmp.lock.Lock()
+		if err == nil {
+			cmp.cache.add(entry)
+			cmp.notePathErrors(entry.pathErrors)
+		}
+		delete(cmp.pending, key)
+		close(done)
+		cmp.lock.Unlock()
+
+		// note: the entry is returned even if it has been immediately evicted from the cache
+		return entry, err
 	}
+}
 
-	value, pathErrors, err := cmp.get(key)
-	if err != nil {
-		return nil, err
+// notePathErrors keeps the given path errors that have not been seen before as lazy path errors. Note: the caller
+// must hold the lock.
+func (cmp *Comparer) notePathErrors(pathErrors []PathError) {
+	for _, pathErr := range pathErrors {
+		key := pathErrorKey(pathErr)
+		if cmp.reported[key] {
+			continue
+		}
+		cmp.reported[key] = true
+		cmp.lazyErrors = append(cmp.lazyErrors, pathErr)
 	}
-	cmp.trees[key] = value
-	cmp.pathErrors[key] = pathErrors
-	return value, nil
 }
 
-func (cmp *Comparer) get(key TreeIndexKey) (*FileTree, []PathError, error) {
-	newTree, pathErrors, err := StackTreeRange(cmp.refTrees, key.bottomTreeStart, key.bottomTreeStop)
+// pathErrorKey identifies a path error regardless of the (possibly incomparable) underlying error.
+func pathErrorKey(pathErr PathError) PathError {
+	return PathError{LayerIndex: pathErr.LayerIndex, Path: pathErr.Path, Kind: pathErr.Kind, Action: pathErr.Action}
+}
+
+// LazyPathErrors returns the path errors found while building trees on request (or while prefetching) that were not
+// already returned by BuildCache, ordered by layer and path.
+func (cmp *Comparer) LazyPathErrors() PathErrors {
+	cmp.lock.Lock()
+	defer cmp.lock.Unlock()
+	lazyErrors := append(PathErrors{}, cmp.lazyErrors...)
+	lazyErrors.Sort()
+	return lazyErrors
+}
+
+// build creates the tree for the given key. Compared trees are derived from a copy of the (shared) stacked tree
+// of the bottom range, stacked trees are derived from the largest already-built stacked tree of the same range.
+func (cmp *Comparer) build(key cacheKey) (*cacheEntry, error) {
+	if key.stacked {
+		return cmp.buildStacked(key.index.bottomTreeStart, key.index.bottomTreeStop)
+	}
+
+	index := key.index
+	base, err := cmp.getEntry(newStackedCacheKey(index.bottomTreeStart, index.bottomTreeStop))
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	for idx := key.topTreeStart; idx <= key.topTreeStop; idx++ {
+
+	newTree := base.tree.Copy()
+	pathErrors := append([]PathError{}, base.pathErrors...)
+	for idx := index.topTreeStart; idx <= index.topTreeStop; idx++ {
 		markPathErrors, err := newTree.CompareAndMark(cmp.refTrees[idx])
-		pathErrors = append(pathErrors, markPathErrors...)
+		for _, pathErr := range markPathErrors {
+			pathErr.LayerIndex = idx
+			pathErrors = append(pathErrors, pathErr)
+		}
 		if err != nil {
 			logrus.Errorf("error while building tree: %+v", err)
-			return nil, nil, err
+			return nil, err
 		}
 	}
-	return newTree, pathErrors, nil
+	cmp.mark(newTree, index.topTreeStop)
+	return &cacheEntry{key: key, tree: newTree, pathErrors: pathErrors}, nil
+}
+
+// mark resolves the links of a newly built compared tree, tags its removable and colliding nodes, and names its
+// owners. This is done once per built tree (not each time the tree is shown). Note: the statuses are kept on the
+// tree (not the view tree), so links to hidden nodes are not reported as broken.
+func (cmp *Comparer) mark(tree *FileTree, topTreeStop int) {
+	tree.ResolveLinks()
+	if len(cmp.removableRules) > 0 {
+		tree.MarkRemovable(cmp.removableRules)
+	}
+	tree.MarkCollisions()
+	if topTreeStop < len(cmp.owners) {
+		tree.Owners = cmp.owners[topTreeStop]
+	}
+}
+
+// buildStacked creates the stacked tree for the given range (equivalent to StackTreeRange), reusing a previously
+// stacked tree for a prefix of the range when one is available.
+func (cmp *Comparer) buildStacked(start, stop int) (*cacheEntry, error) {
+	var newTree *FileTree
+	var pathErrors []PathError
+	next := start
+
+	if prefix := cmp.stackedPrefix(start, stop-1); prefix != nil {
+		newTree = prefix.tree.Copy()
+		pathErrors = append(pathErrors, prefix.pathErrors...)
+		next = prefix.key.index.bottomTreeStop + 1
+	} else {
+		newTree = cmp.refTrees[0].Copy()
+	}
+
+	for idx := next; idx <= stop; idx++ {
+		failedPaths, err := newTree.Stack(cmp.refTrees[idx])
+		for _, pathErr := range failedPaths {
+			pathErr.LayerIndex = idx
+			pathErrors = append(pathErrors, pathErr)
+		}
+		if err != nil {
+			logrus.Errorf("could not stack tree range: %v", err)
+			return nil, err
+		}
+	}
+	return &cacheEntry{key: newStackedCacheKey(start, stop), tree: newTree, pathErrors: pathErrors}, nil
+}
+
+// stackedPrefix returns the largest cached (or currently building) stacked tree of the range start-stop (or a
+// prefix of it), or nil if there is none.
+func (cmp *Comparer) stackedPrefix(start, stop int) *cacheEntry {
+	for {
+		var done chan struct{}
+
+		cmp.lock.Lock()
+		for idx := stop; idx >= start; idx-- {
+			key := newStackedCacheKey(start, idx)
+			if entry, exists := cmp.cache.get(key); exists {
+				cmp.lock.Unlock()
+				return entry
+			}
+			if pending, exists := cmp.pending[key]; exists {
+				done = pending
+				break
+			}
+		}
+		cmp.lock.Unlock()
+
+		if done == nil {
+			return nil
+		}
+		// another request is building a usable prefix, wait on it instead of duplicating the work
+		<-done
+	}
 }
 
 // case 1: layer compare (top tree SIZE is fixed (BUT floats forward), Bottom tree SIZE changes)
@@ -149,27 +357,98 @@ func (cmp *Comparer) AggregatedIndexes() <-chan TreeIndexKey {
 
 }
 
+// BuildCache discovers the path errors of every layer (returned as PathError values) and builds the first layer tree.
+// All remaining trees are built lazily on request, while the trees most likely to be requested next are prefetched in
+// the background. Building a tree may still run into path errors that stacking the layers does not (e.g. while
+// comparing a layer), these are kept apart (see LazyPathErrors).
 func (cmp *Comparer) BuildCache() (errors []error) {
-	for index := range cmp.NaturalIndexes() {
-		pathError, _ := cmp.GetPathErrors(index)
-		if len(pathError) > 0 {
-			for _, path := range pathError {
-				errors = append(errors, fmt.Errorf("path error at layer index %s: %s", index, path))
-			}
-		}
-		_, err := cmp.GetTree(index)
+	if len(cmp.refTrees) == 0 {
+		return nil
+	}
+
+	// stacking each layer onto a single running tree surfaces the same path errors that building each natural
+	// index would, without materializing every tree up front
+	running := cmp.refTrees[0].Copy()
+	for index := range cmp.refTrees {
+		pathErrors, err := running.Stack(cmp.refTrees[index])
 		if err != nil {
 			errors = append(errors, err)
 			return errors
 		}
+		for _, pathErr := range pathErrors {
+			pathErr.LayerIndex = index
+			errors = append(errors, pathErr)
+			cmp.lock.Lock()
+			cmp.reported[pathErrorKey(pathErr)] = true
+			cmp.lock.Unlock()
+		}
 	}
 
-	for index := range cmp.AggregatedIndexes() {
-		_, err := cmp.GetTree(index)
+	// the first tree is always shown first, so there is no point in deferring it
+	for key := range cmp.NaturalIndexes() {
+		_, err := cmp.GetTree(key)
 		if err != nil {
 			errors = append(errors, err)
 			return errors
 		}
+		break
 	}
+
+	cmp.prefetch()
+
 	return errors
 }
+
+// prefetch builds the prefetchKeys trees in the background, stopping once the comparer is closed.
+func (cmp *Comparer) prefetch() {
+	keys := cmp.prefetchKeys()
+	queue := make(chan TreeIndexKey, len(keys))
+	for _, key := range keys {
+		queue <- key
+	}
+	close(queue)
+
+	for worker := 0; worker < cmp.workers; worker++ {
+		cmp.prefetching.Add(1)
+		go func() {
+			defer cmp.prefetching.Done()
+			for key := range queue {
+				select {
+				case <-cmp.stop:
+					return
+				default:
+				}
+				if _, err := cmp.GetTree(key); err != nil {
+					logrus.Errorf("unable to prefetch tree %s: %+v", key, err)
+				}
+			}
+		}()
+	}
+}
+
+// prefetchKeys returns the trees to prefetch, in the order a user is likely to navigate them: each layer as compared
+// with the layers below it (the natural index) and with the first layer (the aggregated index). No more trees are
+// prefetched than half of the cache can hold (or half of the default cache size when the cache is unbounded), leaving
+// room for the stacked trees they are derived from.
+func (cmp *Comparer) prefetchKeys() []TreeIndexKey {
+	cmp.lock.Lock()
+	limit := cmp.cache.capacity / 2
+	if cmp.cache.capacity <= 0 {
+		limit = DefaultTreeCacheSize / 2
+	}
+	cmp.lock.Unlock()
+
+	// the first layers share the same natural and aggregated index, which are only prefetched once
+	var keys []TreeIndexKey
+	seen := make(map[TreeIndexKey]bool)
+	natural, aggregated := cmp.NaturalIndexes(), cmp.AggregatedIndexes()
+	for range cmp.refTrees {
+		for _, key := range []TreeIndexKey{<-natural, <-aggregated} {
+			if !seen[key] && len(keys) < limit {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
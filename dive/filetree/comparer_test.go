@@ -0,0 +1,323 @@
+package filetree
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// syntheticImage creates a set of layer trees resembling a large image: a wide and deep base layer followed by
+// layers that modify, add, and remove a fraction of the existing paths.
+func syntheticImage(layers, dirs, filesPerDir int) []*FileTree {
+	trees := make([]*FileTree, layers)
+	for layerIdx := range trees {
+		tree := NewFileTree()
+		tree.Name = fmt.Sprintf("layer-%d", layerIdx)
+		for dirIdx := 0; dirIdx < dirs; dirIdx++ {
+			dir := fmt.Sprintf("/usr/share/pkg-%d/lib", dirIdx)
+			for fileIdx := 0; fileIdx < filesPerDir; fileIdx++ {
+				switch {
+				case layerIdx == 0:
+				case (dirIdx+fileIdx)%layers != layerIdx:
+					continue
+				case fileIdx%7 == 0:
+					_, _, _ = tree.AddPath(fmt.Sprintf("%s/.wh.file-%d.so", dir, fileIdx), FileInfo{})
+					continue
+				}
+				filePath := fmt.Sprintf("%s/file-%d.so", dir, fileIdx)
+				_, _, _ = tree.AddPath(filePath, FileInfo{
+					Path:     filePath,
+					TypeFlag: 0,
+					hash:     uint64(layerIdx*filesPerDir + fileIdx),
+					Size:     int64(1024 * (fileIdx + 1)),
+					Mode:     0644,
+				})
+			}
+		}
+		trees[layerIdx] = tree
+	}
+	return trees
+}
+
+func heapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+func TestComparerBuildCache(t *testing.T) {
+	trees := syntheticImage(5, 10, 20)
+	cmp := NewComparer(trees)
+	defer cmp.Close()
+	errors := cmp.BuildCache()
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors building cache: %+v", errors)
+	}
+
+	for index := range cmp.NaturalIndexes() {
+		tree, err := cmp.GetTree(index)
+		if err != nil {
+			t.Fatalf("unable to get tree %s: %+v", index, err)
+		}
+		if tree.Size == 0 {
+			t.Errorf("expected a populated tree for %s", index)
+		}
+	}
+
+	// trees built from the same layers share file metadata instead of copying it
+	first, _ := cmp.GetTree(NewTreeIndexKey(0, 0, 0, 0))
+	second, _ := cmp.GetTree(NewTreeIndexKey(0, 0, 1, 1))
+	firstNode, _ := first.GetNode("/usr/share/pkg-1/lib/file-1.so")
+	secondNode, _ := second.GetNode("/usr/share/pkg-1/lib/file-1.so")
+	if firstNode == nil || secondNode == nil {
+		t.Fatalf("expected path to exist in both trees")
+	}
+	if firstNode == secondNode {
+		t.Errorf("expected distinct nodes per tree")
+	}
+	if firstNode.Data.FileInfo != trees[0].Root.Children["usr"].Children["share"].Children["pkg-1"].Children["lib"].Children["file-1.so"].Data.FileInfo {
+		t.Errorf("expected file info to be shared with the reference tree")
+	}
+}
+
+func BenchmarkStackTreeRange(b *testing.B) {
+	trees := syntheticImage(10, 100, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, err := StackTreeRange(trees, 0, len(trees)-1)
+		if err != nil {
+			b.Fatalf("unable to stack trees: %+v", err)
+		}
+	}
+}
+
+// BenchmarkComparerBuildCache measures the heap held by a comparer once the cache is built and prefetching is done,
+// for a synthetic large image (a base layer of 100k files).
+func BenchmarkComparerBuildCache(b *testing.B) {
+	trees := syntheticImage(20, 1000, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		before := heapInUse()
+		cmp := NewComparer(trees)
+		errors := cmp.BuildCache()
+		if len(errors) > 0 {
+			b.Fatalf("unexpected errors building cache: %+v", errors)
+		}
+		// the prefetched trees are part of the cache, wait for them (and for the workers to stop) before measuring
+		cmp.Close()
+		after := heapInUse()
+		b.ReportMetric(float64(int64(after)-int64(before)), "heap-B/op")
+		runtime.KeepAlive(cmp)
+	}
+}
+
+func TestComparerLazyBuild(t *testing.T) {
+	trees := syntheticImage(6, 5, 10)
+	cmp := NewComparer(trees)
+
+	key := NewTreeIndexKey(0, 3, 4, 4)
+	if cmp.IsReady(key) {
+		t.Fatalf("expected tree %s to not be built before it is requested", key)
+	}
+
+	lazyTree, err := cmp.GetTree(key)
+	if err != nil {
+		t.Fatalf("unable to get tree: %+v", err)
+	}
+	if !cmp.IsReady(key) {
+		t.Errorf("expected tree %s to be cached after it was requested", key)
+	}
+
+	// the lazily built tree must match a tree built from scratch
+	expectedTree, _, err := StackTreeRange(trees, 0, 3)
+	if err != nil {
+		t.Fatalf("unable to stack trees: %+v", err)
+	}
+	_, err = expectedTree.CompareAndMark(trees[4])
+	if err != nil {
+		t.Fatalf("unable to compare trees: %+v", err)
+	}
+	if expectedTree.String(true) != lazyTree.String(true) {
+		t.Errorf("lazy tree does not match:\n%s\nexpected:\n%s", lazyTree.String(true), expectedTree.String(true))
+	}
+
+	// requesting a later tree reuses the stacked tree built for the earlier one
+	_, err = cmp.GetTree(NewTreeIndexKey(0, 4, 5, 5))
+	if err != nil {
+		t.Fatalf("unable to get tree: %+v", err)
+	}
+	if !cmp.cache.contains(newStackedCacheKey(0, 3)) || !cmp.cache.contains(newStackedCacheKey(0, 4)) {
+		t.Errorf("expected stacked trees to be cached for reuse")
+	}
+}
+
+func TestComparerMarksTrees(t *testing.T) {
+	trees := syntheticImage(4, 5, 10)
+	owners := []*Owners{
+		ParseOwners([]byte("root:x:0:0::/root:/bin/sh\n"), nil),
+		ParseOwners([]byte("app:x:0:0::/app:/bin/sh\n"), nil),
+		nil,
+		ParseOwners([]byte("svc:x:0:0::/svc:/bin/sh\n"), nil),
+	}
+	rules, err := NewRemovableRules(false, RemovableRule{Category: RemovableCustom, Path: "/usr/share/pkg-1"})
+	if err != nil {
+		t.Fatalf("unable to create rules: %+v", err)
+	}
+
+	cmp := NewComparer(trees)
+	cmp.SetRemovableRules(rules)
+	cmp.SetOwners(owners)
+
+	for _, key := range []TreeIndexKey{NewTreeIndexKey(0, 0, 1, 1), NewTreeIndexKey(0, 2, 3, 3)} {
+		tree, err := cmp.GetTree(key)
+		if err != nil {
+			t.Fatalf("unable to get tree: %+v", err)
+		}
+		if tree.Owners != owners[key.topTreeStop] {
+			t.Errorf("%s: expected the owners of layer %d", key, key.topTreeStop)
+		}
+		node, err := tree.GetNode("/usr/share/pkg-1/lib")
+		if err != nil {
+			t.Fatalf("%s: unable to get node: %+v", key, err)
+		}
+		if node.Data.Removable != RemovableCustom {
+			t.Errorf("%s: expected the removable path to be tagged, got %v", key, node.Data.Removable)
+		}
+	}
+
+	// the stacked trees the compared trees are derived from are left untouched
+	base, err := cmp.getEntry(newStackedCacheKey(0, 2))
+	if err != nil {
+		t.Fatalf("unable to get stacked tree: %+v", err)
+	}
+	if base.tree.Owners != nil {
+		t.Errorf("expected the stacked tree to have no owners")
+	}
+	node, err := base.tree.GetNode("/usr/share/pkg-1/lib")
+	if err != nil {
+		t.Fatalf("unable to get node: %+v", err)
+	}
+	if node.Data.Removable != NotRemovable {
+		t.Errorf("expected the stacked tree to not be tagged, got %v", node.Data.Removable)
+	}
+}
+
+func TestComparerLazyPathErrors(t *testing.T) {
+	// the path errors returned by BuildCache are not reported again when building the remaining trees
+	cmp := NewComparer(pathErrorTrees(t))
+	errors := cmp.BuildCache()
+	cmp.Close()
+	if len(errors) != 1 {
+		t.Fatalf("expected a single path error, got %+v", errors)
+	}
+	for _, indexes := range []<-chan TreeIndexKey{cmp.NaturalIndexes(), cmp.AggregatedIndexes()} {
+		for key := range indexes {
+			if _, err := cmp.GetTree(key); err != nil {
+				t.Fatalf("unable to get tree %s: %+v", key, err)
+			}
+		}
+	}
+	if lazyErrors := cmp.LazyPathErrors(); len(lazyErrors) != 0 {
+		t.Errorf("expected no lazy path errors, got %+v", lazyErrors)
+	}
+
+	// path errors first found while building a tree on request are kept as lazy path errors
+	cmp = NewComparer(pathErrorTrees(t))
+	if _, err := cmp.GetTree(NewTreeIndexKey(0, 0, 1, 1)); err != nil {
+		t.Fatalf("unable to get tree: %+v", err)
+	}
+	lazyErrors := cmp.LazyPathErrors()
+	if len(lazyErrors) != 1 || lazyErrors[0].Path != "/etc/missing" || lazyErrors[0].LayerIndex != 1 {
+		t.Errorf("expected the missing whiteout target as a lazy path error, got %+v", lazyErrors)
+	}
+}
+
+func TestComparerCacheBound(t *testing.T) {
+	trees := syntheticImage(8, 5, 10)
+	cmp := NewComparer(trees)
+	cmp.SetCacheSize(3)
+
+	for index := range cmp.NaturalIndexes() {
+		_, err := cmp.GetTree(index)
+		if err != nil {
+			t.Fatalf("unable to get tree %s: %+v", index, err)
+		}
+		if cmp.cache.len() > 3 {
+			t.Fatalf("expected at most 3 cached trees, found %d", cmp.cache.len())
+		}
+	}
+
+	// the least recently used trees have been evicted, but can still be fetched
+	first := NewTreeIndexKey(0, 0, 0, 0)
+	if cmp.IsReady(first) {
+		t.Errorf("expected tree %s to be evicted", first)
+	}
+	tree, err := cmp.GetTree(first)
+	if err != nil || tree == nil {
+		t.Errorf("unable to rebuild evicted tree: %+v", err)
+	}
+}
+
+func TestComparerPrefetch(t *testing.T) {
+	table := map[string]struct {
+		cacheSize int
+		expected  []TreeIndexKey
+	}{
+		"natural and aggregated indexes": {
+			cacheSize: 8,
+			expected: []TreeIndexKey{
+				NewTreeIndexKey(0, 0, 0, 0),
+				NewTreeIndexKey(0, 0, 1, 1),
+				NewTreeIndexKey(0, 1, 2, 2),
+				NewTreeIndexKey(0, 0, 1, 2),
+			},
+		},
+		"no room": {
+			cacheSize: 1,
+			expected:  nil,
+		},
+		"unbounded cache": {
+			cacheSize: 0,
+			expected: []TreeIndexKey{
+				NewTreeIndexKey(0, 0, 0, 0),
+				NewTreeIndexKey(0, 0, 1, 1),
+				NewTreeIndexKey(0, 1, 2, 2),
+				NewTreeIndexKey(0, 0, 1, 2),
+				NewTreeIndexKey(0, 2, 3, 3),
+				NewTreeIndexKey(0, 0, 1, 3),
+			},
+		},
+	}
+
+	for name, test := range table {
+		cmp := NewComparer(syntheticImage(8, 5, 10))
+		cmp.SetCacheSize(test.cacheSize)
+		keys := cmp.prefetchKeys()
+		if !reflect.DeepEqual(keys, test.expected) {
+			t.Errorf("%s: expected prefetched trees %v, got %v", name, test.expected, keys)
+		}
+	}
+
+	// closing waits on the prefetching workers, after which no more trees are built in the background
+	cmp := NewComparer(syntheticImage(8, 5, 10))
+	errors := cmp.BuildCache()
+	if len(errors) > 0 {
+		t.Fatalf("unexpected errors building cache: %+v", errors)
+	}
+	cmp.Close()
+	cmp.Close()
+	cmp.lock.Lock()
+	pending := len(cmp.pending)
+	cmp.lock.Unlock()
+	if pending != 0 {
+		t.Errorf("expected no trees to be building after closing, found %d", pending)
+	}
+	key := NewTreeIndexKey(0, 6, 7, 7)
+	if _, err := cmp.GetTree(key); err != nil || !cmp.IsReady(key) {
+		t.Errorf("expected tree %s to be built on request after closing: %+v", key, err)
+	}
+}
@@ -1,6 +1,7 @@
 package filetree
 
 import (
+	"path"
 	"sort"
 
 	"github.com/sirupsen/logrus"
@@ -132,3 +133,47 @@ func Efficiency(trees []*FileTree) (float64, EfficiencySlice) {
 
 	return score, inefficientMatches
 }
+
+// LayerEfficiency returns the score of each of the given FileTrees (layers): the ratio of the bytes added by the layer
+// that are still within the final image, as opposed to being replaced or removed by a later layer. Layers that add no
+// bytes score 1.
+func LayerEfficiency(trees []*FileTree) []float64 {
+	scores := make([]float64, len(trees))
+	for idx, tree := range trees {
+		var addedBytes, wastedBytes int64
+		_ = tree.VisitDepthChildFirst(func(node *FileNode) error {
+			if node.IsWhiteout() {
+				return nil
+			}
+			addedBytes += node.Data.FileInfo.Size
+			if isShadowed(node.Path(), trees[idx+1:]) {
+				wastedBytes += node.Data.FileInfo.Size
+			}
+			return nil
+		}, func(node *FileNode) bool {
+			return node.IsLeaf()
+		})
+
+		if addedBytes == 0 {
+			scores[idx] = 1.0
+		} else {
+			scores[idx] = float64(addedBytes-wastedBytes) / float64(addedBytes)
+		}
+	}
+	return scores
+}
+
+// isShadowed indicates if any of the given (later) trees replaces the given path, or removes it (or any of its parents).
+func isShadowed(p string, trees []*FileTree) bool {
+	for _, tree := range trees {
+		if _, err := tree.GetNode(p); err == nil {
+			return true
+		}
+		for current := p; current != "/"; current = path.Dir(current) {
+			if _, err := tree.GetNode(path.Join(path.Dir(current), whiteoutPrefix+path.Base(current))); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
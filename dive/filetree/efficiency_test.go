@@ -78,3 +78,36 @@ func TestEfficency_ScratchImage(t *testing.T) {
 	}
 
 }
+
+func TestLayerEfficiency(t *testing.T) {
+	trees := make([]*FileTree, 4)
+	for idx := range trees {
+		trees[idx] = NewFileTree()
+	}
+
+	add := func(tree *FileTree, p string, size int64) {
+		_, _, err := tree.AddPath(p, FileInfo{Path: p, Size: size})
+		checkError(t, err, "could not setup test")
+	}
+
+	add(trees[0], "/etc/nginx/nginx.conf", 2000)
+	add(trees[0], "/etc/nginx/public", 3000)
+	add(trees[0], "/etc/hosts", 5000)
+	add(trees[1], "/etc/nginx/nginx.conf", 5000)
+	add(trees[1], "/var/cache/index", 4000)
+	add(trees[1], "/var/cache/data", 1000)
+	add(trees[2], "/etc/.wh.nginx", 0)
+	add(trees[2], "/var/.wh.cache", 0)
+	add(trees[2], "/var/log/app.log", 1000)
+
+	expected := []float64{0.5, 0, 1, 1}
+	actual := LayerEfficiency(trees)
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d scores, got %v", len(expected), actual)
+	}
+	for idx := range expected {
+		if actual[idx] != expected[idx] {
+			t.Errorf("layer %d: expected score %v, got %v", idx, expected[idx], actual[idx])
+		}
+	}
+}
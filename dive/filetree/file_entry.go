@@ -0,0 +1,77 @@
+package filetree
+
+import (
+	"os"
+)
+
+// FileEntry is the serializable form of a FileInfo (including the content hash), used to save a tree and rebuild it
+// later without the original layer.
+type FileEntry struct {
+	Path     string      `json:"path"`
+	TypeFlag byte        `json:"type"`
+	Linkname string      `json:"linkname,omitempty"`
+	Hash     uint64      `json:"hash,omitempty"`
+	Size     int64       `json:"size,omitempty"`
+	Mode     os.FileMode `json:"mode"`
+	Uid      int         `json:"uid"`
+	Gid      int         `json:"gid"`
+	IsDir    bool        `json:"isDir,omitempty"`
+}
+
+// NewFileEntry describes the given FileInfo.
+func NewFileEntry(info *FileInfo) FileEntry {
+	return FileEntry{
+		Path:     info.Path,
+		TypeFlag: info.TypeFlag,
+		Linkname: info.Linkname,
+		Hash:     info.hash,
+		Size:     info.Size,
+		Mode:     info.Mode,
+		Uid:      info.Uid,
+		Gid:      info.Gid,
+		IsDir:    info.IsDir,
+	}
+}
+
+// FileInfo returns the FileInfo the entry describes.
+func (entry FileEntry) FileInfo() FileInfo {
+	return FileInfo{
+		Path:     entry.Path,
+		TypeFlag: entry.TypeFlag,
+		Linkname: entry.Linkname,
+		hash:     entry.Hash,
+		Size:     entry.Size,
+		Mode:     entry.Mode,
+		Uid:      entry.Uid,
+		Gid:      entry.Gid,
+		IsDir:    entry.IsDir,
+	}
+}
+
+// Entries describes every entry of the tree (parents before children). Implied parent directories are not entries of
+// their own, they are implied again when the tree is rebuilt (see NewFileTreeFromEntries).
+func (tree *FileTree) Entries() []FileEntry {
+	var entries []FileEntry
+	// note: the visitor does not return errors
+	_ = tree.VisitDepthParentFirst(func(node *FileNode) error {
+		if !node.IsImplied() {
+			entries = append(entries, NewFileEntry(node.Data.FileInfo))
+		}
+		return nil
+	}, nil)
+	return entries
+}
+
+// NewFileTreeFromEntries rebuilds a tree from the given entries (see FileTree.Entries).
+func NewFileTreeFromEntries(name string, entries []FileEntry) (*FileTree, error) {
+	tree := NewFileTree()
+	tree.Name = name
+	for _, entry := range entries {
+		tree.FileSize += uint64(entry.Size)
+		_, _, err := tree.AddPath(entry.Path, entry.FileInfo())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tree, nil
+}
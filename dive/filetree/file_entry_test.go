@@ -0,0 +1,47 @@
+package filetree
+
+import (
+	"archive/tar"
+	"testing"
+)
+
+func TestFileEntriesRoundTrip(t *testing.T) {
+	tree := NewFileTree()
+	tree.Name = "layer.tar"
+	for _, info := range []FileInfo{
+		{Path: "etc", TypeFlag: tar.TypeDir, Mode: 0755 | 1<<31, IsDir: true},
+		{Path: "etc/hosts", TypeFlag: tar.TypeReg, Size: 120, Mode: 0644, hash: 42},
+		{Path: "usr/bin/sh", TypeFlag: tar.TypeSymlink, Linkname: "busybox", Mode: 0777, Uid: 1000, Gid: 100},
+		{Path: "var/.wh.cache", TypeFlag: tar.TypeReg},
+	} {
+		_, _, err := tree.AddPath(info.Path, info)
+		checkError(t, err, "could not setup test")
+	}
+
+	entries := tree.Entries()
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries (without implied directories), got %d: %+v", len(entries), entries)
+	}
+
+	rebuilt, err := NewFileTreeFromEntries(tree.Name, entries)
+	checkError(t, err, "unable to rebuild tree")
+
+	if rebuilt.Name != tree.Name || rebuilt.FileSize != 120 {
+		t.Errorf("unexpected tree name (%q) or file size (%d)", rebuilt.Name, rebuilt.FileSize)
+	}
+	if expected, actual := tree.String(true), rebuilt.String(true); expected != actual {
+		t.Errorf("expected rebuilt tree:\n%s\ngot:\n%s", expected, actual)
+	}
+	for _, p := range []string{"/etc/hosts", "/usr/bin/sh", "/usr/bin", "/var/.wh.cache"} {
+		original, err := tree.GetNode(p)
+		checkError(t, err, "could not get node")
+		actual, err := rebuilt.GetNode(p)
+		if err != nil {
+			t.Errorf("%s: missing from rebuilt tree", p)
+			continue
+		}
+		if *original.Data.FileInfo != *actual.Data.FileInfo || original.IsImplied() != actual.IsImplied() {
+			t.Errorf("%s: expected %+v, got %+v", p, *original.Data.FileInfo, *actual.Data.FileInfo)
+		}
+	}
+}
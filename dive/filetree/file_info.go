@@ -8,7 +8,11 @@ import (
 	"os"
 )
 
-// FileInfo contains tar metadata for a specific FileNode
+// emptyFileInfo is the shared payload for nodes without metadata of their own (e.g. implied parent directories).
+var emptyFileInfo = &FileInfo{}
+
+// FileInfo contains tar metadata for a specific FileNode. Once attached to a tree a FileInfo is shared between nodes
+// (across tree copies and stacked trees) and must not be modified in place.
 type FileInfo struct {
 	Path     string
 	TypeFlag byte
@@ -22,7 +26,7 @@ type FileInfo struct {
 }
 
 // NewFileInfoFromTarHeader extracts the metadata from a tar header and file contents and generates a new FileInfo object.
-func NewFileInfoFromTarHeader(reader *tar.Reader, header *tar.Header, path string) FileInfo {
+func NewFileInfoFromTarHeader(reader io.Reader, header *tar.Header, path string) FileInfo {
 	var hash uint64
 	if header.Typeflag != tar.TypeDir {
 		hash = getHashFromReader(reader)
@@ -89,6 +93,14 @@ func NewFileInfo(realPath, path string, info os.FileInfo) FileInfo {
 	}
 }
 
+// newSharedFileInfo returns a FileInfo reference suitable for attaching to a node, reusing the empty payload when possible.
+func newSharedFileInfo(data FileInfo) *FileInfo {
+	if data == (FileInfo{}) {
+		return emptyFileInfo
+	}
+	return &data
+}
+
 // Copy duplicates a FileInfo
 func (data *FileInfo) Copy() *FileInfo {
 	if data == nil {
@@ -107,6 +119,11 @@ func (data *FileInfo) Copy() *FileInfo {
 	}
 }
 
+// Hash returns the content hash of the file (zero for directories).
+func (data *FileInfo) Hash() uint64 {
+	return data.hash
+}
+
 // Compare determines the DiffType between two FileInfos based on the type and contents of each given FileInfo
 func (data *FileInfo) Compare(other FileInfo) DiffType {
 	if data.TypeFlag == other.TypeFlag {
@@ -31,17 +31,21 @@ type FileNode struct {
 	Name     string
 	Data     NodeData
 	Children map[string]*FileNode
-	path     string
 }
 
 // NewNode creates a new FileNode relative to the given parent node with a payload.
 func NewNode(parent *FileNode, name string, data FileInfo) (node *FileNode) {
+	return newNode(parent, name, newSharedFileInfo(data))
+}
+
+// newNode creates a new FileNode relative to the given parent node that shares the given payload. Note: the
+// children map is only allocated once a child is added, since most nodes are leaves.
+func newNode(parent *FileNode, name string, data *FileInfo) (node *FileNode) {
 	node = new(FileNode)
 	node.Name = name
 	node.Data = *NewNodeData()
-	node.Data.FileInfo = *data.Copy()
+	node.Data.FileInfo = data
 
-	node.Children = make(map[string]*FileNode)
 	node.Parent = parent
 	if parent != nil {
 		node.Tree = parent.Tree
@@ -76,28 +80,41 @@ func (node *FileNode) renderTreeLine(spaces []bool, last bool, collapsed bool) s
 
 // Copy duplicates the existing node relative to a new parent node.
 func (node *FileNode) Copy(parent *FileNode) *FileNode {
-	newNode := NewNode(parent, node.Name, node.Data.FileInfo)
-	newNode.Data.ViewInfo = node.Data.ViewInfo
+	newNode := newNode(parent, node.Name, node.Data.FileInfo)
 	newNode.Data.DiffType = node.Data.DiffType
-	for name, child := range node.Children {
-		newNode.Children[name] = child.Copy(newNode)
-		child.Parent = newNode
+	newNode.Data.LinkStatus = node.Data.LinkStatus
+	newNode.Data.Removable = node.Data.Removable
+	newNode.Data.Collision = node.Data.Collision
+	if len(node.Children) > 0 {
+		newNode.Children = make(map[string]*FileNode, len(node.Children))
+		for name, child := range node.Children {
+			newNode.Children[name] = child.Copy(newNode)
+		}
 	}
 	return newNode
 }
 
 // AddChild creates a new node relative to the current FileNode.
 func (node *FileNode) AddChild(name string, data FileInfo) (child *FileNode) {
+	return node.addChild(name, newSharedFileInfo(data))
+}
+
+// addChild creates a new node relative to the current FileNode that shares the given payload.
+func (node *FileNode) addChild(name string, data *FileInfo) (child *FileNode) {
 	// never allow processing of purely whiteout flag files (for now)
 	if strings.HasPrefix(name, doubleWhiteoutPrefix) {
 		return nil
 	}
 
-	child = NewNode(node, name, data)
+	// note: copied nodes keep the (already interned) name of the original, only added nodes are interned
+	child = newNode(node, node.Tree.segments.intern(name), data)
 	if node.Children[name] != nil {
 		// tree node already exists, replace the payload, keep the children
-		node.Children[name].Data.FileInfo = *data.Copy()
+		node.Children[name].Data.FileInfo = data
 	} else {
+		if node.Children == nil {
+			node.Children = make(map[string]*FileNode)
+		}
 		node.Children[name] = child
 		node.Tree.Size++
 	}
@@ -121,7 +138,10 @@ func (node *FileNode) Remove() error {
 	return nil
 }
 
-// String shows the filename formatted into the proper color (by DiffType), additionally indicating if it is a symlink.
+// String shows the filename formatted into the proper color (by DiffType), additionally indicating if it is a symlink
+// (and if the symlink is known to be broken, see FileTree.ResolveLinks), if it is likely removable (noted on the
+// topmost removable node only, see FileTree.MarkRemovable), and if it collides with a sibling (see
+// FileTree.MarkCollisions).
 func (node *FileNode) String() string {
 	var display string
 	if node == nil {
@@ -132,10 +152,19 @@ func (node *FileNode) String() string {
 	if node.Data.FileInfo.TypeFlag == tar.TypeSymlink || node.Data.FileInfo.TypeFlag == tar.TypeLink {
 		display += " → " + node.Data.FileInfo.Linkname
 	}
+	if node.Data.LinkStatus == LinkDangling || node.Data.LinkStatus == LinkCyclic {
+		display += " (" + node.Data.LinkStatus.String() + ")"
+	}
+	if node.Data.Removable != NotRemovable && (node.Parent == nil || node.Parent.Data.Removable == NotRemovable) {
+		display += " [removable: " + node.Data.Removable.String() + "]"
+	}
+	if node.Data.Collision != NoCollision {
+		display += " [collides: " + node.Data.Collision.String() + "]"
+	}
 	return diffTypeColor[node.Data.DiffType].Sprint(display)
 }
 
-// MetadatString returns the FileNode metadata in a columnar string.
+// MetadatString returns the FileNode metadata in a columnar string (naming the owners when the tree has Owners).
 func (node *FileNode) MetadataString() string {
 	if node == nil {
 		return ""
@@ -146,9 +175,11 @@ func (node *FileNode) MetadataString() string {
 	if node.Data.FileInfo.IsDir {
 		dir = "d"
 	}
-	user := node.Data.FileInfo.Uid
-	group := node.Data.FileInfo.Gid
-	userGroup := fmt.Sprintf("%d:%d", user, group)
+	var owners *Owners
+	if node.Tree != nil {
+		owners = node.Tree.Owners
+	}
+	userGroup := owners.column(node.Data.FileInfo.Uid, node.Data.FileInfo.Gid)
 
 	var sizeBytes int64
 
@@ -234,7 +265,12 @@ func (node *FileNode) VisitDepthParentFirst(visitor Visitor, evaluator VisitEval
 
 // IsWhiteout returns an indication if this file may be a overlay-whiteout file.
 func (node *FileNode) IsWhiteout() bool {
-	return strings.HasPrefix(node.Name, whiteoutPrefix)
+	return IsWhiteoutPath(node.Name)
+}
+
+// IsImplied indicates the node was not an entry within its layer, but a parent directory implied by a descendant path.
+func (node *FileNode) IsImplied() bool {
+	return node.Data.FileInfo == emptyFileInfo
 }
 
 // IsLeaf returns true is the current node has no child nodes.
@@ -243,27 +279,31 @@ func (node *FileNode) IsLeaf() bool {
 }
 
 // Path returns a slash-delimited string from the root of the greater tree to the current node (e.g. /a/path/to/here)
+// Note: the path is derived on each call (not cached on the node) to keep the per-node footprint small.
 func (node *FileNode) Path() string {
-	if node.path == "" {
-		var path []string
-		curNode := node
-		for {
-			if curNode.Parent == nil {
-				break
-			}
+	var length int
+	var names []string
+	for curNode := node; curNode.Parent != nil; curNode = curNode.Parent {
+		name := curNode.Name
+		if curNode == node {
+			// white out prefixes are fictitious on leaf nodes
+			name = strings.TrimPrefix(name, whiteoutPrefix)
+		}
+		names = append(names, name)
+		length += len(name) + 1
+	}
 
-			name := curNode.Name
-			if curNode == node {
-				// white out prefixes are fictitious on leaf nodes
-				name = strings.TrimPrefix(name, whiteoutPrefix)
-			}
+	if len(names) == 0 {
+		return "/"
+	}
 
-			path = append([]string{name}, path...)
-			curNode = curNode.Parent
-		}
-		node.path = "/" + strings.Join(path, "/")
+	var sb strings.Builder
+	sb.Grow(length)
+	for idx := len(names) - 1; idx >= 0; idx-- {
+		sb.WriteString("/")
+		sb.WriteString(names[idx])
 	}
-	return strings.Replace(node.path, "//", "/", -1)
+	return strings.Replace(sb.String(), "//", "/", -1)
 }
 
 // deriveDiffType determines a DiffType to the current FileNode. Note: the DiffType of a node is always the DiffType of
@@ -321,5 +361,5 @@ func (node *FileNode) compare(other *FileNode) DiffType {
 		panic("comparing mismatched nodes")
 	}
 
-	return node.Data.FileInfo.Compare(other.Data.FileInfo)
+	return node.Data.FileInfo.Compare(*other.Data.FileInfo)
 }
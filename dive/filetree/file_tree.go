@@ -18,6 +18,7 @@ const (
 	lastItem             = "└─"
 	whiteoutPrefix       = ".wh."
 	doubleWhiteoutPrefix = ".wh..wh.."
+	opaqueWhiteout       = ".wh..wh..opq"
 	uncollapsedItem      = "─ "
 	collapsedItem        = "⊕ "
 )
@@ -29,6 +30,10 @@ type FileTree struct {
 	FileSize uint64
 	Name     string
 	Id       uuid.UUID
+	// Owners names the file owners within the attributes (nil shows the raw uid:gid)
+	Owners *Owners
+	// segments interns the names of the nodes added to the tree (shared with every copy of the tree)
+	segments *segmentPool
 }
 
 // NewFileTree creates an empty FileTree
@@ -37,8 +42,10 @@ func NewFileTree() (tree *FileTree) {
 	tree.Size = 0
 	tree.Root = new(FileNode)
 	tree.Root.Tree = tree
+	tree.Root.Data = *NewNodeData()
 	tree.Root.Children = make(map[string]*FileNode)
 	tree.Id = uuid.New()
+	tree.segments = newSegmentPool()
 	return tree
 }
 
@@ -54,7 +61,7 @@ type renderParams struct {
 
 // renderStringTreeBetween returns a string representing the given tree between the given rows. Since each node
 // is rendered on its own line, the returned string shows the visible nodes not affected by a collapsed parent.
-func (tree *FileTree) renderStringTreeBetween(startRow, stopRow int, showAttributes bool) string {
+func (tree *FileTree) renderStringTreeBetween(view *ViewState, startRow, stopRow int, showAttributes bool) string {
 	// generate a list of nodes to render
 	var params = make([]renderParams, 0)
 	var result string
@@ -74,23 +81,25 @@ func (tree *FileTree) renderStringTreeBetween(startRow, stopRow int, showAttribu
 		// we should always visit nodes in order
 		sort.Strings(keys)
 
+		parentCollapsed := view.IsCollapsed(currentParams.node)
+
 		var childParams = make([]renderParams, 0)
 		for idx, name := range keys {
 			child := currentParams.node.Children[name]
 			// don't visit this node...
-			if child.Data.ViewInfo.Hidden || currentParams.node.Data.ViewInfo.Collapsed {
+			if view.IsHidden(child) || parentCollapsed {
 				continue
 			}
 
 			// visit this node...
 			isLast := idx == (len(currentParams.node.Children) - 1)
-			showCollapsed := child.Data.ViewInfo.Collapsed && len(child.Children) > 0
+			showCollapsed := len(child.Children) > 0 && view.IsCollapsed(child)
 
 			// completely copy the reference slice
 			childSpaces := make([]bool, len(currentParams.childSpaces))
 			copy(childSpaces, currentParams.childSpaces)
 
-			if len(child.Children) > 0 && !child.Data.ViewInfo.Collapsed {
+			if len(child.Children) > 0 && !showCollapsed {
 				childSpaces = append(childSpaces, isLast)
 			}
 
@@ -130,7 +139,8 @@ func (tree *FileTree) renderStringTreeBetween(startRow, stopRow int, showAttribu
 	return result
 }
 
-func (tree *FileTree) VisibleSize() int {
+// VisibleSize returns the number of nodes shown in the given view (not affected by a collapsed parent).
+func (tree *FileTree) VisibleSize(view *ViewState) int {
 	var size int
 
 	visitor := func(node *FileNode) error {
@@ -140,12 +150,12 @@ func (tree *FileTree) VisibleSize() int {
 	visitEvaluator := func(node *FileNode) bool {
 		if node.Data.FileInfo.IsDir {
 			// we won't visit a collapsed dir, but we need to count it
-			if node.Data.ViewInfo.Collapsed {
+			if view.IsCollapsed(node) {
 				size++
+				return false
 			}
-			return !node.Data.ViewInfo.Collapsed && !node.Data.ViewInfo.Hidden
 		}
-		return !node.Data.ViewInfo.Hidden
+		return !view.IsHidden(node)
 	}
 	err := tree.VisitDepthParentFirst(visitor, visitEvaluator)
 	if err != nil {
@@ -158,14 +168,14 @@ func (tree *FileTree) VisibleSize() int {
 	return size
 }
 
-// String returns the entire tree in an ASCII representation.
+// String returns the entire tree (every node expanded) in an ASCII representation.
 func (tree *FileTree) String(showAttributes bool) string {
-	return tree.renderStringTreeBetween(0, tree.Size, showAttributes)
+	return tree.renderStringTreeBetween(nil, 0, tree.Size, showAttributes)
 }
 
-// StringBetween returns a partial tree in an ASCII representation.
-func (tree *FileTree) StringBetween(start, stop int, showAttributes bool) string {
-	return tree.renderStringTreeBetween(start, stop, showAttributes)
+// StringBetween returns a partial tree, as shown in the given view, in an ASCII representation.
+func (tree *FileTree) StringBetween(view *ViewState, start, stop int, showAttributes bool) string {
+	return tree.renderStringTreeBetween(view, start, stop, showAttributes)
 }
 
 // Copy returns a copy of the given FileTree
@@ -173,6 +183,8 @@ func (tree *FileTree) Copy() *FileTree {
 	newTree := NewFileTree()
 	newTree.Size = tree.Size
 	newTree.FileSize = tree.FileSize
+	newTree.Owners = tree.Owners
+	newTree.segments = tree.segments
 	newTree.Root = tree.Root.Copy(newTree.Root)
 
 	// update the tree pointers
@@ -204,18 +216,42 @@ func (tree *FileTree) VisitDepthParentFirst(visitor Visitor, evaluator VisitEval
 	return tree.Root.VisitDepthParentFirst(visitor, evaluator)
 }
 
+// PathVisitor is a function that visits a node along with its path (as given by FileNode.Path).
+type PathVisitor func(node *FileNode, path string) error
+
+// VisitPaths iterates the given tree parent first (siblings in no particular order), deriving the path of each node
+// from the path of its parent instead of walking up to the root for every node.
+func (tree *FileTree) VisitPaths(visitor PathVisitor) error {
+	return visitPaths(tree.Root, "", visitor)
+}
+
+func visitPaths(node *FileNode, nodePath string, visitor PathVisitor) error {
+	for name, child := range node.Children {
+		// white out prefixes are fictitious on the node visited, but not on the paths beneath it
+		err := visitor(child, nodePath+"/"+strings.TrimPrefix(name, whiteoutPrefix))
+		if err != nil {
+			return err
+		}
+		err = visitPaths(child, nodePath+"/"+name, visitor)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Stack takes two trees and combines them together. This is done by "stacking" the given tree on top of the owning tree.
 func (tree *FileTree) Stack(upper *FileTree) (failed []PathError, stackErr error) {
 	graft := func(node *FileNode) error {
 		if node.IsWhiteout() {
 			err := tree.RemovePath(node.Path())
 			if err != nil {
-				failed = append(failed, NewPathError(node.Path(), ActionAdd, err))
+				failed = append(failed, NewPathError(node.Path(), PathErrorMissingWhiteoutTarget, ActionRemove, err))
 			}
 		} else {
-			_, _, err := tree.AddPath(node.Path(), node.Data.FileInfo)
+			_, _, err := tree.addPath(node.Path(), node.Data.FileInfo)
 			if err != nil {
-				failed = append(failed, NewPathError(node.Path(), ActionRemove, err))
+				failed = append(failed, NewPathError(node.Path(), PathErrorInvalidPath, ActionAdd, err))
 			}
 		}
 		return nil
@@ -242,6 +278,11 @@ func (tree *FileTree) GetNode(path string) (*FileNode, error) {
 
 // AddPath adds a new node to the tree with the given payload
 func (tree *FileTree) AddPath(filepath string, data FileInfo) (*FileNode, []*FileNode, error) {
+	return tree.addPath(filepath, newSharedFileInfo(data))
+}
+
+// addPath adds a new node to the tree that shares the given payload
+func (tree *FileTree) addPath(filepath string, data *FileInfo) (*FileNode, []*FileNode, error) {
 	filepath = path.Clean(filepath)
 	if filepath == "." {
 		return nil, nil, fmt.Errorf("cannot add relative path '%s'", filepath)
@@ -264,7 +305,7 @@ func (tree *FileTree) AddPath(filepath string, data FileInfo) (*FileNode, []*Fil
 
 			// don't attach the payload. The payload is destined for the
 			// Path's end node, not any intermediary node.
-			node = node.AddChild(name, FileInfo{})
+			node = node.addChild(name, emptyFileInfo)
 			addedNodes = append(addedNodes, node)
 
 			if node == nil {
@@ -310,7 +351,7 @@ func (tree *FileTree) CompareAndMark(upper *FileTree) ([]PathError, error) {
 		if upperNode.IsWhiteout() {
 			err := tree.markRemoved(upperNode.Path())
 			if err != nil {
-				failed = append(failed, NewPathError(upperNode.Path(), ActionRemove, err))
+				failed = append(failed, NewPathError(upperNode.Path(), PathErrorMissingWhiteoutTarget, ActionRemove, err))
 			}
 			return nil
 		}
@@ -320,9 +361,9 @@ func (tree *FileTree) CompareAndMark(upper *FileTree) ([]PathError, error) {
 		originalLowerNode, _ := originalTree.GetNode(upperNode.Path())
 
 		if originalLowerNode == nil {
-			_, newNodes, err := tree.AddPath(upperNode.Path(), upperNode.Data.FileInfo)
+			_, newNodes, err := tree.addPath(upperNode.Path(), upperNode.Data.FileInfo)
 			if err != nil {
-				failed = append(failed, NewPathError(upperNode.Path(), ActionAdd, err))
+				failed = append(failed, NewPathError(upperNode.Path(), PathErrorInvalidPath, ActionAdd, err))
 				return nil
 			}
 			for idx := len(newNodes) - 1; idx >= 0; idx-- {
@@ -360,7 +401,7 @@ func (tree *FileTree) CompareAndMark(upper *FileTree) ([]PathError, error) {
 		}
 
 		// persist the upper's payload on the owning tree
-		pair.lowerNode.Data.FileInfo = *pair.upperNode.Data.FileInfo.Copy()
+		pair.lowerNode.Data.FileInfo = pair.upperNode.Data.FileInfo
 	}
 	return failed, nil
 }
@@ -380,8 +421,9 @@ func StackTreeRange(trees []*FileTree, start, stop int) (*FileTree, []PathError,
 	tree := trees[0].Copy()
 	for idx := start; idx <= stop; idx++ {
 		failedPaths, err := tree.Stack(trees[idx])
-		if len(failedPaths) > 0 {
-			errors = append(errors, failedPaths...)
+		for _, failedPath := range failedPaths {
+			failedPath.LayerIndex = idx
+			errors = append(errors, failedPath)
 		}
 		if err != nil {
 			logrus.Errorf("could not stack tree range: %v", err)
@@ -23,11 +23,12 @@ func AssertDiffType(node *FileNode, expectedDiffType DiffType) error {
 
 func TestStringCollapsed(t *testing.T) {
 	tree := NewFileTree()
+	view := NewViewState(false)
 	tree.Root.AddChild("1 node!", FileInfo{})
 	two := tree.Root.AddChild("2 node!", FileInfo{})
 	subTwo := two.AddChild("2 child!", FileInfo{})
 	subTwo.AddChild("2 grandchild!", FileInfo{})
-	subTwo.Data.ViewInfo.Collapsed = true
+	view.SetCollapsed(subTwo, true)
 	three := tree.Root.AddChild("3 node!", FileInfo{})
 	subThree := three.AddChild("3 child!", FileInfo{})
 	three.AddChild("3 nested child 1!", FileInfo{})
@@ -35,7 +36,7 @@ func TestStringCollapsed(t *testing.T) {
 	threeGc1.AddChild("3 greatgrandchild 1!", FileInfo{})
 	subThree.AddChild("3 grandchild 2!", FileInfo{})
 	four := tree.Root.AddChild("4 node!", FileInfo{})
-	four.Data.ViewInfo.Collapsed = true
+	view.SetCollapsed(four, true)
 	tree.Root.AddChild("5 node!", FileInfo{})
 	four.AddChild("6, one level down...", FileInfo{})
 
@@ -52,7 +53,7 @@ func TestStringCollapsed(t *testing.T) {
 ├─⊕ 4 node!
 └── 5 node!
 `
-	actual := tree.String(false)
+	actual := tree.StringBetween(view, 0, tree.Size, false)
 
 	if expected != actual {
 		t.Errorf("Expected tree string:\n--->%s<---\nGot:\n--->%s<---", expected, actual)
@@ -117,7 +118,7 @@ func TestStringBetween(t *testing.T) {
 ├── tmp
 │   └── nonsense
 `
-	actual := tree.StringBetween(3, 5, false)
+	actual := tree.StringBetween(nil, 3, 5, false)
 
 	if expected != actual {
 		t.Errorf("Expected tree string:\n--->%s<---\nGot:\n--->%s<---", expected, actual)
@@ -806,6 +807,7 @@ func TestStackRange(t *testing.T) {
 func TestRemoveOnIterate(t *testing.T) {
 
 	tree := NewFileTree()
+	view := NewViewState(false)
 	paths := [...]string{"/etc", "/usr", "/etc/hosts", "/etc/sudoers", "/usr/bin", "/usr/something"}
 
 	for _, value := range paths {
@@ -816,12 +818,12 @@ func TestRemoveOnIterate(t *testing.T) {
 		}
 		node, _, err := tree.AddPath(value, fakeData)
 		if err == nil && stringInSlice(node.Path(), []string{"/etc"}) {
-			node.Data.ViewInfo.Hidden = true
+			view.SetHidden(node, true)
 		}
 	}
 
 	err := tree.VisitDepthChildFirst(func(node *FileNode) error {
-		if node.Data.ViewInfo.Hidden {
+		if view.IsHidden(node) {
 			err := tree.RemovePath(node.Path())
 			if err != nil {
 				t.Errorf("could not setup test: %v", err)
@@ -844,3 +846,73 @@ func TestRemoveOnIterate(t *testing.T) {
 	}
 
 }
+
+func TestViewStateCarriesOverCopies(t *testing.T) {
+	tree := NewFileTree()
+	for _, value := range []string{"/etc", "/etc/hosts", "/usr", "/usr/bin"} {
+		if _, _, err := tree.AddPath(value, FileInfo{Path: value, IsDir: value != "/etc/hosts"}); err != nil {
+			t.Fatalf("could not setup test: %v", err)
+		}
+	}
+	view := NewViewState(false)
+	etc, err := tree.GetNode("/etc")
+	if err != nil {
+		t.Fatalf("could not setup test: %v", err)
+	}
+	view.SetCollapsed(etc, true)
+
+	// the collapsed state is kept by path, so it applies to any tree shown (e.g. a rebuilt copy)
+	copied := tree.Copy()
+	expected :=
+		`├─⊕ etc
+└── usr
+    └── bin
+`
+	if actual := copied.StringBetween(view, 0, copied.Size, false); expected != actual {
+		t.Errorf("Expected tree string:\n--->%s<---\nGot:\n--->%s<---", expected, actual)
+	}
+
+	// expanding the node of one tree expands the node of the same path in the trees already shown
+	view.SetCollapsed(etc, false)
+	expected =
+		`├── etc
+│   └── hosts
+└── usr
+    └── bin
+`
+	if actual := copied.StringBetween(view, 0, copied.Size, false); expected != actual {
+		t.Errorf("Expected tree string:\n--->%s<---\nGot:\n--->%s<---", expected, actual)
+	}
+
+	// the copies intern the names of added nodes within the pool of the original tree
+	if copied.segments != tree.segments {
+		t.Errorf("expected the copy to share the segment pool of the original tree")
+	}
+	if NewFileTree().segments == tree.segments {
+		t.Errorf("expected every new tree to have its own segment pool")
+	}
+}
+
+func TestVisitPaths(t *testing.T) {
+	tree := NewFileTree()
+	for _, value := range []string{"/etc/hosts", "/usr/bin/env", "/usr/.wh.lib", "/var/.wh..wh..opq", "/opt/.wh.app/bin"} {
+		if _, _, err := tree.AddPath(value, FileInfo{Path: value}); err != nil {
+			t.Fatalf("could not setup test: %v", err)
+		}
+	}
+
+	visited := 0
+	err := tree.VisitPaths(func(node *FileNode, p string) error {
+		visited++
+		if expected := node.Path(); p != expected {
+			t.Errorf("expected path %q, got %q", expected, p)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unable to visit tree: %v", err)
+	}
+	if visited != tree.Size {
+		t.Errorf("expected %d nodes visited, got %d", tree.Size, visited)
+	}
+}
@@ -0,0 +1,205 @@
+package filetree
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/phayes/permbits"
+)
+
+const (
+	HistoryFormat = "%5s  %-18s %9s  %-11s %-11s %-16s"
+)
+
+const (
+	HistoryAdded HistoryAction = iota
+	HistoryModified
+	HistoryRemoved
+	HistoryImplicitlyRemoved
+)
+
+// HistoryAction describes what a single layer did to a path.
+type HistoryAction int
+
+func (action HistoryAction) String() string {
+	switch action {
+	case HistoryAdded:
+		return "added"
+	case HistoryModified:
+		return "modified"
+	case HistoryRemoved:
+		return "removed"
+	case HistoryImplicitlyRemoved:
+		return "implicitly removed"
+	default:
+		return "<unknown history action>"
+	}
+}
+
+// PathHistoryEntry is a single change to a path. For removals the FileInfo is the last metadata seen before the path
+// was removed, otherwise it is the metadata introduced by the layer.
+type PathHistoryEntry struct {
+	LayerIndex int
+	Action     HistoryAction
+	FileInfo   FileInfo
+	// Owners names the owner as of the layer (nil shows the raw uid:gid), see PathHistory.SetOwners
+	Owners *Owners
+}
+
+// String returns the entry in a columnar format (see HistoryFormat).
+func (entry *PathHistoryEntry) String() string {
+	dir := "-"
+	if entry.FileInfo.IsDir {
+		dir = "d"
+	}
+	var hash string
+	if !entry.FileInfo.IsDir {
+		hash = fmt.Sprintf("%016x", entry.FileInfo.hash)
+	}
+	return fmt.Sprintf(HistoryFormat,
+		fmt.Sprintf("%d", entry.LayerIndex),
+		entry.Action.String(),
+		humanize.Bytes(uint64(entry.FileInfo.Size)),
+		dir+permbits.FileMode(entry.FileInfo.Mode).String(),
+		entry.Owners.column(entry.FileInfo.Uid, entry.FileInfo.Gid),
+		hash,
+	)
+}
+
+// PathHistory is the chronological set of changes made to a single path across all layers.
+type PathHistory struct {
+	Path    string
+	Entries []PathHistoryEntry
+}
+
+// SetOwners names the owner of every entry by the owners as of the entry's layer (see image.ReadOwners).
+func (history *PathHistory) SetOwners(owners []*Owners) {
+	for idx := range history.Entries {
+		if layerIdx := history.Entries[idx].LayerIndex; layerIdx < len(owners) {
+			history.Entries[idx].Owners = owners[layerIdx]
+		}
+	}
+}
+
+// History returns the timeline of every path matching the given pattern (an absolute path or a path.Match glob)
+// across the given layer trees, ordered by path. A path is considered removed when it is whited out directly, and
+// implicitly removed when a parent directory is whited out or replaced by a non-directory.
+func History(trees []*FileTree, pattern string) ([]PathHistory, error) {
+	pattern = path.Clean("/" + pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return history(trees, func(p string) bool {
+		matched, _ := path.Match(pattern, p)
+		return matched
+	})
+}
+
+// HistoryOf returns the timeline of the given absolute path across the given layer trees. Unlike History, the path is
+// taken literally (a file name may well contain glob characters, e.g. "[id].js").
+func HistoryOf(trees []*FileTree, p string) (PathHistory, error) {
+	p = path.Clean("/" + p)
+	histories, err := history(trees, func(candidate string) bool {
+		return candidate == p
+	})
+	if err != nil || len(histories) == 0 {
+		return PathHistory{Path: p}, err
+	}
+	return histories[0], nil
+}
+
+// history returns the timeline of every path accepted by the given matcher across the given layer trees.
+func history(trees []*FileTree, matches func(string) bool) ([]PathHistory, error) {
+	histories := make(map[string]*PathHistory)
+	present := make(map[string]*FileInfo)
+
+	record := func(p string, layerIdx int, action HistoryAction, info *FileInfo) {
+		history, exists := histories[p]
+		if !exists {
+			history = &PathHistory{Path: p}
+			histories[p] = history
+		}
+		history.Entries = append(history.Entries, PathHistoryEntry{
+			LayerIndex: layerIdx,
+			Action:     action,
+			FileInfo:   *info,
+		})
+	}
+
+	// removeBelow marks every present path beneath the given directory path as implicitly removed
+	removeBelow := func(dir string, layerIdx int) {
+		prefix := strings.TrimSuffix(dir, "/") + "/"
+		var removed []string
+		for p := range present {
+			if strings.HasPrefix(p, prefix) {
+				removed = append(removed, p)
+			}
+		}
+		sort.Strings(removed)
+		for _, p := range removed {
+			record(p, layerIdx, HistoryImplicitlyRemoved, present[p])
+			delete(present, p)
+		}
+	}
+
+	for layerIdx, tree := range trees {
+		// whiteouts apply to the lower layers, so they must be processed before anything this layer adds
+		err := tree.VisitDepthParentFirst(func(node *FileNode) error {
+			if !node.IsWhiteout() {
+				return nil
+			}
+			p := node.Path()
+			if info, exists := present[p]; exists {
+				record(p, layerIdx, HistoryRemoved, info)
+				delete(present, p)
+			}
+			removeBelow(p, layerIdx)
+			return nil
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		err = tree.VisitDepthParentFirst(func(node *FileNode) error {
+			// implied parent directories carry no metadata of their own and are not changes made by this layer
+			if node.IsWhiteout() || node.IsImplied() {
+				return nil
+			}
+			p := node.Path()
+			info := node.Data.FileInfo
+
+			if !info.IsDir {
+				removeBelow(p, layerIdx)
+			}
+
+			if !matches(p) {
+				return nil
+			}
+
+			previous, exists := present[p]
+			switch {
+			case !exists:
+				record(p, layerIdx, HistoryAdded, info)
+			case previous.Compare(*info) == Modified:
+				record(p, layerIdx, HistoryModified, info)
+			}
+			present[p] = info
+			return nil
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]PathHistory, 0, len(histories))
+	for _, history := range histories {
+		result = append(result, *history)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
@@ -0,0 +1,142 @@
+package filetree
+
+import (
+	"path"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	trees := make([]*FileTree, 5)
+	for idx := range trees {
+		trees[idx] = NewFileTree()
+	}
+
+	certPath := "/etc/ssl/certs/ca-certificates.crt"
+
+	_, _, err := trees[0].AddPath(certPath, FileInfo{Path: certPath, Size: 100, Mode: 0644, hash: 1})
+	checkError(t, err, "could not setup test")
+	_, _, err = trees[0].AddPath("/etc/passwd", FileInfo{Path: "/etc/passwd", Size: 10, Mode: 0644, hash: 2})
+	checkError(t, err, "could not setup test")
+
+	// modified contents
+	_, _, err = trees[1].AddPath(certPath, FileInfo{Path: certPath, Size: 200, Mode: 0644, hash: 3})
+	checkError(t, err, "could not setup test")
+	// rewritten, but unchanged
+	_, _, err = trees[1].AddPath("/etc/passwd", FileInfo{Path: "/etc/passwd", Size: 10, Mode: 0644, hash: 2})
+	checkError(t, err, "could not setup test")
+
+	_, _, err = trees[2].AddPath("/etc/ssl/certs/.wh.ca-certificates.crt", FileInfo{Path: "/etc/ssl/certs/.wh.ca-certificates.crt"})
+	checkError(t, err, "could not setup test")
+
+	_, _, err = trees[3].AddPath(certPath, FileInfo{Path: certPath, Size: 300, Mode: 0600, Uid: 1, Gid: 2, hash: 4})
+	checkError(t, err, "could not setup test")
+
+	_, _, err = trees[4].AddPath("/etc/.wh.ssl", FileInfo{Path: "/etc/.wh.ssl"})
+	checkError(t, err, "could not setup test")
+
+	histories, err := History(trees, certPath)
+	if err != nil {
+		t.Fatalf("unable to get history: %+v", err)
+	}
+	if len(histories) != 1 {
+		t.Fatalf("expected 1 history, got %d", len(histories))
+	}
+
+	expected := []struct {
+		layer  int
+		action HistoryAction
+		size   int64
+	}{
+		{0, HistoryAdded, 100},
+		{1, HistoryModified, 200},
+		{2, HistoryRemoved, 200},
+		{3, HistoryAdded, 300},
+		{4, HistoryImplicitlyRemoved, 300},
+	}
+
+	entries := histories[0].Entries
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(expected), len(entries), entries)
+	}
+	for idx, entry := range entries {
+		if entry.LayerIndex != expected[idx].layer || entry.Action != expected[idx].action || entry.FileInfo.Size != expected[idx].size {
+			t.Errorf("entry %d: expected %+v, got layer=%d action=%v size=%d", idx, expected[idx], entry.LayerIndex, entry.Action, entry.FileInfo.Size)
+		}
+	}
+	if entries[3].FileInfo.Uid != 1 || entries[3].FileInfo.Gid != 2 || entries[3].FileInfo.Hash() != 4 {
+		t.Errorf("expected metadata to be captured, got %+v", entries[3].FileInfo)
+	}
+}
+
+func TestHistory_Glob(t *testing.T) {
+	trees := make([]*FileTree, 2)
+	for idx := range trees {
+		trees[idx] = NewFileTree()
+	}
+
+	_, _, err := trees[0].AddPath("/etc/passwd", FileInfo{Path: "/etc/passwd", Size: 10, hash: 1})
+	checkError(t, err, "could not setup test")
+	_, _, err = trees[0].AddPath("/etc/group", FileInfo{Path: "/etc/group", Size: 10, hash: 2})
+	checkError(t, err, "could not setup test")
+	_, _, err = trees[1].AddPath("/etc/passwd", FileInfo{Path: "/etc/passwd", Size: 20, hash: 3})
+	checkError(t, err, "could not setup test")
+	_, _, err = trees[1].AddPath("/var/passwd", FileInfo{Path: "/var/passwd", Size: 20, hash: 3})
+	checkError(t, err, "could not setup test")
+
+	histories, err := History(trees, "/etc/*")
+	if err != nil {
+		t.Fatalf("unable to get history: %+v", err)
+	}
+
+	if len(histories) != 2 || histories[0].Path != "/etc/group" || histories[1].Path != "/etc/passwd" {
+		t.Fatalf("unexpected histories: %+v", histories)
+	}
+	if len(histories[1].Entries) != 2 {
+		t.Errorf("expected 2 entries for /etc/passwd, got %d", len(histories[1].Entries))
+	}
+
+	_, err = History(trees, "/etc/[")
+	if err == nil {
+		t.Errorf("expected an error for a malformed pattern")
+	}
+}
+
+func TestHistoryOf(t *testing.T) {
+	trees := make([]*FileTree, 2)
+	for idx := range trees {
+		trees[idx] = NewFileTree()
+	}
+
+	// file names may contain glob characters, which must be matched literally
+	for _, p := range []string{"/app/[id].js", "/app/i.js", "/app/*"} {
+		_, _, err := trees[0].AddPath(p, FileInfo{Path: p, Size: 10, hash: 1})
+		checkError(t, err, "could not setup test")
+	}
+	_, _, err := trees[1].AddPath("/app/[", FileInfo{Path: "/app/[", Size: 20, hash: 2})
+	checkError(t, err, "could not setup test")
+
+	table := map[string]struct {
+		path    string
+		entries int
+	}{
+		"brackets":       {path: "/app/[id].js", entries: 1},
+		"star":           {path: "/app/*", entries: 1},
+		"malformed glob": {path: "/app/[", entries: 1},
+		"unknown path":   {path: "/app/missing", entries: 0},
+		"unclean path":   {path: "app//[id].js", entries: 1},
+	}
+	for name, test := range table {
+		t.Run(name, func(t *testing.T) {
+			history, err := HistoryOf(trees, test.path)
+			if err != nil {
+				t.Fatalf("unable to get history: %+v", err)
+			}
+			if len(history.Entries) != test.entries {
+				t.Errorf("expected %d entries, got %+v", test.entries, history)
+			}
+			if history.Path != path.Clean("/"+test.path) {
+				t.Errorf("expected the history of %q, got %q", test.path, history.Path)
+			}
+		})
+	}
+}
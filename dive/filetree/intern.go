@@ -0,0 +1,27 @@
+package filetree
+
+import "sync"
+
+// segmentPool interns path segment names so that identical names across nodes, layers, and tree copies share a
+// single backing string (instead of each name pinning the full path string it was split from). A pool is created
+// along with a tree and is shared by every copy of the tree (and every tree stacked onto a copy), so it is released
+// along with the last of these trees. Note: the pool is read far more than it is written to (most names are repeated
+// across copies and layers), which suits a sync.Map when several trees are built concurrently.
+type segmentPool struct {
+	names sync.Map
+}
+
+func newSegmentPool() *segmentPool {
+	return &segmentPool{}
+}
+
+// intern returns the canonical instance of the given name.
+func (pool *segmentPool) intern(name string) string {
+	if canonical, exists := pool.names.Load(name); exists {
+		return canonical.(string)
+	}
+	// detach the name from any larger string it may be a slice of
+	detached := string([]byte(name))
+	canonical, _ := pool.names.LoadOrStore(detached, detached)
+	return canonical.(string)
+}
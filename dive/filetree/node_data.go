@@ -1,28 +1,33 @@
 package filetree
 
-var GlobalFileTreeCollapse bool
-
-// NodeData is the payload for a FileNode
+// NodeData is the payload for a FileNode. The FileInfo is shared between every tree (and tree copy) that references
+// the same layer entry and must be treated as immutable; the LinkStatus, Removable, Collision, and DiffType are owned
+// by the node. Note: the UI state of a node is kept by the view (see ViewState), not by the node.
 type NodeData struct {
-	ViewInfo ViewInfo
-	FileInfo FileInfo
-	DiffType DiffType
+	// note: the link status, removable category, and collision kind take a single byte each, so they share a word
+	// ahead of the FileInfo
+	LinkStatus LinkStatus
+	Removable  RemovableCategory
+	Collision  CollisionKind
+	FileInfo   *FileInfo
+	DiffType   DiffType
 }
 
 // NewNodeData creates an empty NodeData struct for a FileNode
 func NewNodeData() *NodeData {
 	return &NodeData{
-		ViewInfo: *NewViewInfo(),
-		FileInfo: FileInfo{},
+		FileInfo: emptyFileInfo,
 		DiffType: Unmodified,
 	}
 }
 
-// Copy duplicates a NodeData
+// Copy duplicates a NodeData (the FileInfo remains shared)
 func (data *NodeData) Copy() *NodeData {
 	return &NodeData{
-		ViewInfo: *data.ViewInfo.Copy(),
-		FileInfo: *data.FileInfo.Copy(),
-		DiffType: data.DiffType,
+		LinkStatus: data.LinkStatus,
+		Removable:  data.Removable,
+		Collision:  data.Collision,
+		FileInfo:   data.FileInfo,
+		DiffType:   data.DiffType,
 	}
 }
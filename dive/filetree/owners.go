@@ -0,0 +1,120 @@
+package filetree
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ownerColumnWidth is the width of the UID:GID attribute column (see AttributeFormat).
+const ownerColumnWidth = 11
+
+// Owners maps uids and gids to the user and group names of an image (from /etc/passwd and /etc/group).
+type Owners struct {
+	Users  map[int]string
+	Groups map[int]string
+}
+
+// ParseOwners reads the user names from the given /etc/passwd contents and the group names from the given /etc/group
+// contents (either may be empty). Malformed lines are ignored, and the first entry for an id wins.
+func ParseOwners(passwd, group []byte) *Owners {
+	return &Owners{
+		Users:  parseIDNames(passwd),
+		Groups: parseIDNames(group),
+	}
+}
+
+// parseIDNames reads "name:password:id:..." lines (the format shared by /etc/passwd and /etc/group).
+func parseIDNames(contents []byte) map[int]string {
+	names := make(map[int]string)
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) < 3 || fields[0] == "" {
+			continue
+		}
+		id, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		if _, exists := names[id]; !exists {
+			names[id] = fields[0]
+		}
+	}
+	return names
+}
+
+// HasUser indicates if the given uid has a passwd entry (always true when there are no known owners).
+func (owners *Owners) HasUser(uid int) bool {
+	if owners == nil {
+		return true
+	}
+	_, exists := owners.Users[uid]
+	return exists
+}
+
+// User returns the name of the given uid (the uid itself when there is no mapping).
+func (owners *Owners) User(uid int) string {
+	if owners != nil {
+		if name, exists := owners.Users[uid]; exists {
+			return name
+		}
+	}
+	return strconv.Itoa(uid)
+}
+
+// Group returns the name of the given gid (the gid itself when there is no mapping).
+func (owners *Owners) Group(gid int) string {
+	if owners != nil {
+		if name, exists := owners.Groups[gid]; exists {
+			return name
+		}
+	}
+	return strconv.Itoa(gid)
+}
+
+// Format returns the "user:group" names of the given ids (falling back to the ids without a mapping).
+func (owners *Owners) Format(uid, gid int) string {
+	return owners.User(uid) + ":" + owners.Group(gid)
+}
+
+// column returns the "user:group" names for a UID:GID column (the raw ids when there are no known owners).
+func (owners *Owners) column(uid, gid int) string {
+	if owners == nil {
+		return fmt.Sprintf("%d:%d", uid, gid)
+	}
+	return owners.formatColumn(uid, gid, ownerColumnWidth)
+}
+
+// formatColumn returns the "user:group" names of the given ids shortened to fit within the given width (see
+// fitOwnerColumn).
+func (owners *Owners) formatColumn(uid, gid, width int) string {
+	return fitOwnerColumn(owners.User(uid), owners.Group(gid), width)
+}
+
+// fitOwnerColumn returns "user:group" shortened to fit within the given width, the longer name is shortened first (and
+// marked with an ellipsis).
+func fitOwnerColumn(userName, groupName string, width int) string {
+	user, group := []rune(userName), []rune(groupName)
+	fullUser, fullGroup := len(user), len(group)
+	for len(user)+len(group)+1 > width && len(user)+len(group) > 2 {
+		if len(user) >= len(group) {
+			user = user[:len(user)-1]
+		} else {
+			group = group[:len(group)-1]
+		}
+	}
+	if len(user) < fullUser {
+		user[len(user)-1] = '…'
+	}
+	if len(group) < fullGroup {
+		group[len(group)-1] = '…'
+	}
+	return string(user) + ":" + string(group)
+}
@@ -0,0 +1,65 @@
+package filetree
+
+import (
+	"testing"
+)
+
+func TestParseOwners(t *testing.T) {
+	passwd := []byte(`root:x:0:0:root:/root:/bin/bash
+# comment
+daemon:x:1:1:daemon:/usr/sbin:/usr/sbin/nologin
+malformed
+badid:x:abc:0::/:/bin/false
+toor:x:0:0:duplicate:/root:/bin/sh
+www-data:x:33:33:www-data:/var/www:/usr/sbin/nologin
+`)
+	group := []byte("root:x:0:\nwww-data:x:33:\nusers:x:100:app\n")
+
+	owners := ParseOwners(passwd, group)
+
+	table := map[string]struct {
+		uid, gid int
+		expected string
+	}{
+		"root":       {uid: 0, gid: 0, expected: "root:root"},
+		"named":      {uid: 33, gid: 100, expected: "www-data:users"},
+		"unknown":    {uid: 1000, gid: 1000, expected: "1000:1000"},
+		"no-group":   {uid: 1, gid: 1, expected: "daemon:1"},
+		"first-wins": {uid: 0, gid: 33, expected: "root:www-data"},
+	}
+
+	for name, test := range table {
+		if actual := owners.Format(test.uid, test.gid); actual != test.expected {
+			t.Errorf("%s: expected %q, got %q", name, test.expected, actual)
+		}
+	}
+
+	if !owners.HasUser(33) || owners.HasUser(1000) {
+		t.Errorf("unexpected passwd entries: %+v", owners.Users)
+	}
+
+	var none *Owners
+	if !none.HasUser(1000) || none.Format(1000, 100) != "1000:100" || none.column(1000, 100) != "1000:100" {
+		t.Errorf("expected raw ids without known owners")
+	}
+}
+
+func TestOwnersColumn(t *testing.T) {
+	table := map[string]struct {
+		user, group string
+		expected    string
+	}{
+		"fits":          {user: "root", group: "root", expected: "root:root"},
+		"exact":         {user: "nobody", group: "user", expected: "nobody:user"},
+		"long-user":     {user: "www-data-user", group: "web", expected: "www-da…:web"},
+		"long-group":    {user: "app", group: "developers", expected: "app:develo…"},
+		"both-long":     {user: "systemd-network", group: "systemd-journal", expected: "syst…:syst…"},
+		"unicode-names": {user: "ünïcödé-user", group: "grp", expected: "ünïcöd…:grp"},
+	}
+
+	for name, test := range table {
+		if actual := fitOwnerColumn(test.user, test.group, ownerColumnWidth); actual != test.expected {
+			t.Errorf("%s: expected %q, got %q", name, test.expected, actual)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package filetree
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
 
 const (
 	ActionAdd FileAction = iota
@@ -20,20 +24,215 @@ func (fa FileAction) String() string {
 	}
 }
 
+// PathErrorKind describes the problem found with a layer entry while stacking the layers.
+type PathErrorKind string
+
+const (
+	// PathErrorMissingWhiteoutTarget is a whiteout of a path that no lower layer has
+	PathErrorMissingWhiteoutTarget PathErrorKind = "missing-whiteout-target"
+	// PathErrorMissingParent is a directory that no lower layer has, but that is implied by an entry of the layer
+	// (the layer has entries beneath it, without an entry for the directory itself)
+	PathErrorMissingParent PathErrorKind = "missing-parent"
+	// PathErrorInvalidPath is an entry that cannot be added to the tree at all
+	PathErrorInvalidPath PathErrorKind = "invalid-path"
+)
+
+// PathErrorKinds are every kind of path error (in the order they are listed).
+var PathErrorKinds = []PathErrorKind{PathErrorMissingWhiteoutTarget, PathErrorMissingParent, PathErrorInvalidPath}
+
+// PathPolicy describes how a path error is recovered from (see CheckPaths).
+type PathPolicy string
+
+const (
+	// PathPolicyFail keeps the offending entry out of the trees, and marks the image as failed
+	PathPolicyFail PathPolicy = "fail"
+	// PathPolicySkip keeps the offending entry (and anything beneath it) out of the trees
+	PathPolicySkip PathPolicy = "skip"
+	// PathPolicySynthesize adds a missing parent directory (with no metadata of its own), keeping the entries beneath it
+	PathPolicySynthesize PathPolicy = "synthesize"
+)
+
+// PathPolicies are the recovery policy for each kind of path error.
+type PathPolicies map[PathErrorKind]PathPolicy
+
+// DefaultPathPolicies fails on entries that cannot be stacked, while synthesizing missing parent directories (as the
+// container runtime would when extracting the layer).
+func DefaultPathPolicies() PathPolicies {
+	return PathPolicies{
+		PathErrorMissingWhiteoutTarget: PathPolicyFail,
+		PathErrorMissingParent:         PathPolicySynthesize,
+		PathErrorInvalidPath:           PathPolicyFail,
+	}
+}
+
+// ParsePathPolicies returns the default policies, overridden by the given kind/policy names (e.g.
+// "missing-whiteout-target": "skip").
+func ParsePathPolicies(values map[string]string) (PathPolicies, error) {
+	policies := DefaultPathPolicies()
+	for kindName, policyName := range values {
+		kind := PathErrorKind(strings.ToLower(kindName))
+		if _, exists := policies[kind]; !exists {
+			return nil, fmt.Errorf("unknown path error kind: '%s'", kindName)
+		}
+		policy := PathPolicy(strings.ToLower(policyName))
+		switch policy {
+		case PathPolicyFail, PathPolicySkip:
+		case PathPolicySynthesize:
+			if kind != PathErrorMissingParent {
+				return nil, fmt.Errorf("the '%s' policy only applies to '%s' path errors", policy, PathErrorMissingParent)
+			}
+		default:
+			return nil, fmt.Errorf("unknown path error policy for '%s': '%s'", kindName, policyName)
+		}
+		policies[kind] = policy
+	}
+	return policies, nil
+}
+
+// policy returns the policy for the given kind (failing on kinds without a policy).
+func (policies PathPolicies) policy(kind PathErrorKind) PathPolicy {
+	if policy, exists := policies[kind]; exists {
+		return policy
+	}
+	return PathPolicyFail
+}
+
+// PathError is a layer entry that could not be stacked as given.
 type PathError struct {
-	Path   string
-	Action FileAction
+	LayerIndex int
+	Path       string
+	Kind       PathErrorKind
+	Action     FileAction
+	// Policy is how the error was recovered from (empty when no policy was applied, see CheckPaths)
+	Policy PathPolicy
 	Err    error
 }
 
-func NewPathError(path string, action FileAction, err error) PathError {
+func NewPathError(path string, kind PathErrorKind, action FileAction, err error) PathError {
 	return PathError{
 		Path:   path,
+		Kind:   kind,
 		Action: action,
 		Err:    err,
 	}
 }
 
 func (pe PathError) String() string {
-	return fmt.Sprintf("unable to %s '%s': %+v", pe.Action.String(), pe.Path, pe.Err)
+	return fmt.Sprintf("unable to %s '%s' (layer %d, %s): %+v", pe.Action.String(), pe.Path, pe.LayerIndex, pe.Kind, pe.Err)
+}
+
+func (pe PathError) Error() string {
+	return pe.String()
+}
+
+// PathErrors are the path errors of an image, ordered by layer and path.
+type PathErrors []PathError
+
+// Sort orders the errors by layer, then by path.
+func (errs PathErrors) Sort() {
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].LayerIndex != errs[j].LayerIndex {
+			return errs[i].LayerIndex < errs[j].LayerIndex
+		}
+		return errs[i].Path < errs[j].Path
+	})
+}
+
+// Failed returns the errors recovered from with the fail policy.
+func (errs PathErrors) Failed() PathErrors {
+	var failed PathErrors
+	for _, pathErr := range errs {
+		if pathErr.Policy == PathPolicyFail {
+			failed = append(failed, pathErr)
+		}
+	}
+	return failed
+}
+
+// CheckPaths stacks the given layer trees in order, reporting every entry that cannot be stacked as given and
+// recovering from each according to the policy of its kind. Entries that are failed or skipped are removed, so stacking
+// the trees afterwards does not run into the same errors again. The given layer trees are not modified, since they may
+// be shared (e.g. between the images of an image.LayerCache): a layer with entries to remove is replaced within the
+// given slice by a copy without them.
+func CheckPaths(trees []*FileTree, policies PathPolicies) (PathErrors, error) {
+	var pathErrors PathErrors
+	stacked := NewFileTree()
+
+	for layerIdx, tree := range trees {
+		var removals []*FileNode
+		removed := make(map[*FileNode]bool)
+		report := func(node *FileNode, kind PathErrorKind, action FileAction, err error) {
+			pathErr := NewPathError(node.Path(), kind, action, err)
+			pathErr.LayerIndex = layerIdx
+			pathErr.Policy = policies.policy(kind)
+			pathErrors = append(pathErrors, pathErr)
+			if pathErr.Policy != PathPolicySynthesize {
+				removals = append(removals, node)
+				removed[node] = true
+			}
+		}
+
+		err := tree.VisitDepthParentFirst(func(node *FileNode) error {
+			if node.IsWhiteout() {
+				if _, err := stacked.GetNode(node.Path()); err != nil {
+					report(node, PathErrorMissingWhiteoutTarget, ActionRemove, err)
+				}
+				return nil
+			}
+			if node.IsImplied() && node.Parent != nil {
+				// only the top-most missing directory is reported (the directories beneath it are missing as well)
+				if _, err := stacked.GetNode(node.Path()); err != nil {
+					if _, err := stacked.GetNode(node.Parent.Path()); node.Parent == tree.Root || err == nil {
+						report(node, PathErrorMissingParent, ActionAdd, fmt.Errorf("no layer has the directory: %s", node.Path()))
+					}
+				}
+			}
+			return nil
+		}, func(node *FileNode) bool {
+			// entries beneath a removed entry are removed along with it
+			return !removed[node.Parent]
+		})
+		if err != nil {
+			return pathErrors, err
+		}
+
+		if len(removals) > 0 {
+			copied := tree.Copy()
+			copied.Name = tree.Name
+			for _, node := range removals {
+				if err := counterpart(copied, node).Remove(); err != nil {
+					return pathErrors, err
+				}
+			}
+			trees[layerIdx] = copied
+			tree = copied
+		}
+
+		failed, err := stacked.Stack(tree)
+		if err != nil {
+			return pathErrors, err
+		}
+		for _, pathErr := range failed {
+			pathErr.LayerIndex = layerIdx
+			pathErr.Policy = policies.policy(pathErr.Kind)
+			pathErrors = append(pathErrors, pathErr)
+		}
+	}
+
+	pathErrors.Sort()
+	return pathErrors, nil
+}
+
+// counterpart returns the node of the given tree (a copy of the tree of the given node) at the place of the given node.
+// Note: the nodes are matched by name rather than by path, since the path of a whiteout does not name the whiteout.
+func counterpart(tree *FileTree, node *FileNode) *FileNode {
+	var names []string
+	for curNode := node; curNode != curNode.Tree.Root; curNode = curNode.Parent {
+		names = append(names, curNode.Name)
+	}
+	found := tree.Root
+	for idx := len(names) - 1; idx >= 0; idx-- {
+		found = found.Children[names[idx]]
+	}
+	return found
 }
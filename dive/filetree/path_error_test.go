@@ -0,0 +1,153 @@
+package filetree
+
+import (
+	"archive/tar"
+	"strings"
+	"testing"
+)
+
+// pathErrorTrees are layers with a whiteout of a path that no lower layer has, and entries beneath a directory that no
+// layer has.
+func pathErrorTrees(t *testing.T) []*FileTree {
+	trees := make([]*FileTree, 2)
+	for idx := range trees {
+		trees[idx] = NewFileTree()
+	}
+	add := func(tree *FileTree, p string, typeFlag byte) {
+		_, _, err := tree.AddPath(p, FileInfo{Path: p, TypeFlag: typeFlag, IsDir: typeFlag == tar.TypeDir})
+		checkError(t, err, "could not setup test")
+	}
+
+	add(trees[0], "/etc", tar.TypeDir)
+	add(trees[0], "/etc/hosts", tar.TypeReg)
+	add(trees[0], "/app/bin/run", tar.TypeReg)
+	add(trees[1], "/etc/.wh.hosts", tar.TypeReg)
+	add(trees[1], "/etc/.wh.missing", tar.TypeReg)
+	add(trees[1], "/opt/tool/bin/tool", tar.TypeReg)
+	add(trees[1], "/opt/tool/lib/lib.so", tar.TypeReg)
+	return trees
+}
+
+func TestCheckPaths(t *testing.T) {
+	table := map[string]struct {
+		policies PathPolicies
+		failed   int
+		// present and missing are paths of the final image
+		present []string
+		missing []string
+	}{
+		"defaults": {
+			policies: DefaultPathPolicies(),
+			failed:   1,
+			present:  []string{"/app/bin/run", "/opt/tool/bin/tool", "/opt/tool/lib/lib.so"},
+			missing:  []string{"/etc/hosts", "/etc/missing"},
+		},
+		"skip": {
+			policies: PathPolicies{PathErrorMissingWhiteoutTarget: PathPolicySkip, PathErrorMissingParent: PathPolicySkip},
+			failed:   0,
+			present:  []string{"/etc"},
+			missing:  []string{"/etc/hosts", "/app", "/opt"},
+		},
+		"no-policies": {
+			policies: PathPolicies{},
+			failed:   3,
+			missing:  []string{"/app", "/opt"},
+		},
+	}
+
+	for name, test := range table {
+		trees := pathErrorTrees(t)
+		given := append([]*FileTree{}, trees...)
+		pathErrors, err := CheckPaths(trees, test.policies)
+		checkError(t, err, "could not check paths")
+
+		// the given layer trees may be shared, so entries are removed from copies
+		if _, err := given[1].GetNode("/etc/.wh.missing"); err != nil {
+			t.Errorf("%s: expected the given layer tree to be unmodified", name)
+		}
+		if trees[1] == given[1] {
+			t.Errorf("%s: expected the layer with removed entries to be replaced by a copy", name)
+		}
+
+		expected := []struct {
+			layer int
+			path  string
+			kind  PathErrorKind
+		}{
+			{0, "/app", PathErrorMissingParent},
+			{1, "/etc/missing", PathErrorMissingWhiteoutTarget},
+			{1, "/opt", PathErrorMissingParent},
+		}
+		if len(pathErrors) != len(expected) {
+			t.Fatalf("%s: expected %d path errors, got %+v", name, len(expected), pathErrors)
+		}
+		for idx, pathErr := range pathErrors {
+			if pathErr.LayerIndex != expected[idx].layer || pathErr.Path != expected[idx].path || pathErr.Kind != expected[idx].kind {
+				t.Errorf("%s: path error %d: expected %+v, got %+v", name, idx, expected[idx], pathErr)
+			}
+			if pathErr.Policy != test.policies.policy(pathErr.Kind) {
+				t.Errorf("%s: %s: expected the %q policy, got %q", name, pathErr.Path, test.policies.policy(pathErr.Kind), pathErr.Policy)
+			}
+		}
+
+		if failed := len(pathErrors.Failed()); failed != test.failed {
+			t.Errorf("%s: expected %d failed path errors, got %d", name, test.failed, failed)
+		}
+
+		// the recovered trees stack without any further errors
+		final, stackErrors, err := StackTreeRange(trees, 0, len(trees)-1)
+		checkError(t, err, "could not stack trees")
+		if len(stackErrors) > 0 {
+			t.Errorf("%s: expected no errors stacking the recovered trees, got %+v", name, stackErrors)
+		}
+		for _, p := range test.present {
+			if _, err := final.GetNode(p); err != nil {
+				t.Errorf("%s: expected %s in the final image", name, p)
+			}
+		}
+		for _, p := range test.missing {
+			if _, err := final.GetNode(p); err == nil {
+				t.Errorf("%s: expected %s to not be in the final image", name, p)
+			}
+		}
+	}
+}
+
+func TestParsePathPolicies(t *testing.T) {
+	table := map[string]struct {
+		values   map[string]string
+		expected PathPolicies
+		err      string
+	}{
+		"defaults": {
+			values:   nil,
+			expected: DefaultPathPolicies(),
+		},
+		"override": {
+			values:   map[string]string{"missing-whiteout-target": "skip", "Missing-Parent": "FAIL"},
+			expected: PathPolicies{PathErrorMissingWhiteoutTarget: PathPolicySkip, PathErrorMissingParent: PathPolicyFail, PathErrorInvalidPath: PathPolicyFail},
+		},
+		"unknown-kind":   {values: map[string]string{"broken": "skip"}, err: "unknown path error kind"},
+		"unknown-policy": {values: map[string]string{"invalid-path": "retry"}, err: "unknown path error policy"},
+		"synthesize":     {values: map[string]string{"missing-whiteout-target": "synthesize"}, err: "only applies to 'missing-parent'"},
+	}
+
+	for name, test := range table {
+		policies, err := ParsePathPolicies(test.values)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: expected error containing %q, got %v", name, test.err, err)
+			}
+			continue
+		}
+		checkError(t, err, "could not parse policies")
+		if len(policies) != len(test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", name, test.expected, policies)
+		}
+		for kind, policy := range test.expected {
+			if policies[kind] != policy {
+				t.Errorf("%s: %s: expected %q, got %q", name, kind, policy, policies[kind])
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package filetree
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// RemovableCategory describes why a path is likely removable (e.g. a package manager cache).
+type RemovableCategory uint8
+
+const (
+	NotRemovable RemovableCategory = iota
+	RemovablePackageCache
+	RemovableLanguageCache
+	RemovableStaticLibrary
+	RemovableToolchain
+	RemovableCustom
+)
+
+var removableCategoryNames = map[RemovableCategory]string{
+	NotRemovable:           "",
+	RemovablePackageCache:  "package-cache",
+	RemovableLanguageCache: "language-cache",
+	RemovableStaticLibrary: "static-library",
+	RemovableToolchain:     "toolchain",
+	RemovableCustom:        "custom",
+}
+
+func (category RemovableCategory) String() string {
+	return removableCategoryNames[category]
+}
+
+// ParseRemovableCategory returns the category of the given name (e.g. "package-cache").
+func ParseRemovableCategory(name string) (RemovableCategory, error) {
+	for category, categoryName := range removableCategoryNames {
+		if category != NotRemovable && categoryName == strings.ToLower(name) {
+			return category, nil
+		}
+	}
+	return NotRemovable, fmt.Errorf("unknown removable category: '%s'", name)
+}
+
+// MatchPath indicates if the given path matches the given path.Match glob. Patterns without a slash are matched against
+// the file name, relative patterns with a slash are matched against the trailing path components (e.g.
+// "node_modules/.cache"), and absolute patterns against the entire path.
+func MatchPath(pattern, p string) bool {
+	return matchNames(pattern, p, strings.Split(strings.TrimPrefix(p, "/"), "/"))
+}
+
+// matchNames is MatchPath for a path that is already split into names.
+func matchNames(pattern, p string, names []string) bool {
+	if strings.HasPrefix(pattern, "/") {
+		matched, _ := path.Match(pattern, p)
+		return matched
+	}
+	components := strings.Count(pattern, "/") + 1
+	if len(names) < components {
+		return false
+	}
+	var matched bool
+	if components == 1 {
+		matched, _ = path.Match(pattern, names[len(names)-1])
+	} else {
+		matched, _ = path.Match(pattern, strings.Join(names[len(names)-components:], "/"))
+	}
+	return matched
+}
+
+// RemovableRule marks the paths matching the given glob (see MatchPath), and everything beneath them, as likely
+// removable.
+type RemovableRule struct {
+	Category RemovableCategory
+	Path     string
+}
+
+// BuiltinRemovableRules are the well-known caches, and build leftovers, that are rarely needed at runtime.
+var BuiltinRemovableRules = []RemovableRule{
+	{Category: RemovablePackageCache, Path: "/var/lib/apt/lists"},
+	{Category: RemovablePackageCache, Path: "/var/cache/apt"},
+	{Category: RemovablePackageCache, Path: "/var/cache/apk"},
+	{Category: RemovablePackageCache, Path: "/var/cache/yum"},
+	{Category: RemovablePackageCache, Path: "/var/cache/dnf"},
+	{Category: RemovablePackageCache, Path: "/var/cache/zypp"},
+	{Category: RemovableLanguageCache, Path: ".cache/pip"},
+	{Category: RemovableLanguageCache, Path: ".cache/go-build"},
+	{Category: RemovableLanguageCache, Path: ".cache/yarn"},
+	{Category: RemovableLanguageCache, Path: ".npm/_cacache"},
+	{Category: RemovableLanguageCache, Path: "node_modules/.cache"},
+	{Category: RemovableLanguageCache, Path: "__pycache__"},
+	{Category: RemovableLanguageCache, Path: ".gradle/caches"},
+	{Category: RemovableStaticLibrary, Path: "*.a"},
+	{Category: RemovableToolchain, Path: "/usr/lib/gcc"},
+	{Category: RemovableToolchain, Path: "/usr/libexec/gcc"},
+	{Category: RemovableToolchain, Path: "/usr/bin/gcc*"},
+	{Category: RemovableToolchain, Path: "/usr/bin/g++*"},
+	{Category: RemovableToolchain, Path: "/usr/include"},
+}
+
+// RemovableRules are validated rules, the first matching rule determines the category of a path.
+type RemovableRules []RemovableRule
+
+// NewRemovableRules validates the given rules, appending the BuiltinRemovableRules when requested (so the given rules
+// take precedence).
+func NewRemovableRules(includeBuiltin bool, rules ...RemovableRule) (RemovableRules, error) {
+	result := make(RemovableRules, 0, len(rules)+len(BuiltinRemovableRules))
+	for _, rule := range rules {
+		if rule.Path == "" {
+			return nil, fmt.Errorf("removable rule must have a path")
+		}
+		if _, err := path.Match(rule.Path, ""); err != nil {
+			return nil, fmt.Errorf("invalid removable rule path '%s': %v", rule.Path, err)
+		}
+		if rule.Category == NotRemovable {
+			rule.Category = RemovableCustom
+		}
+		result = append(result, rule)
+	}
+	if includeBuiltin {
+		result = append(result, BuiltinRemovableRules...)
+	}
+	return result, nil
+}
+
+// Match returns the category of the first rule matching the given path (NotRemovable otherwise).
+func (rules RemovableRules) Match(p string) RemovableCategory {
+	names := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	for _, rule := range rules {
+		if matchNames(rule.Path, p, names) {
+			return rule.Category
+		}
+	}
+	return NotRemovable
+}
+
+// RemovablePath is the topmost path matching a removable rule, along with the files beneath it.
+type RemovablePath struct {
+	Path      string
+	Category  RemovableCategory
+	Size      int64
+	FileCount int
+}
+
+// RemovableSlice are the removable paths of a tree (ordered by path).
+type RemovableSlice []*RemovablePath
+
+// TotalSize is the sum of every removable path.
+func (paths RemovableSlice) TotalSize() uint64 {
+	var total uint64
+	for _, removable := range paths {
+		total += uint64(removable.Size)
+	}
+	return total
+}
+
+// MarkRemovable tags every node matching the given rules (and every node beneath it) with the category of the rule.
+// The topmost matching paths are returned with the size of the (not removed) files beneath each, paths without any
+// files (e.g. an empty cache directory) are not returned.
+func (tree *FileTree) MarkRemovable(rules RemovableRules) RemovableSlice {
+	var result []*RemovablePath
+	topmost := make(map[*FileNode]*RemovablePath)
+
+	visitor := func(node *FileNode) error {
+		node.Data.Removable = NotRemovable
+		if node.Parent != nil && node.Parent.Data.Removable != NotRemovable {
+			node.Data.Removable = node.Parent.Data.Removable
+		} else if category := rules.Match(node.Path()); category != NotRemovable {
+			node.Data.Removable = category
+			removable := &RemovablePath{Path: node.Path(), Category: category}
+			topmost[node] = removable
+			result = append(result, removable)
+		}
+
+		if node.Data.Removable == NotRemovable || node.Data.FileInfo.IsDir || node.IsImplied() || node.Data.DiffType == Removed {
+			return nil
+		}
+		for ancestor := node; ancestor != nil; ancestor = ancestor.Parent {
+			if removable, exists := topmost[ancestor]; exists {
+				removable.Size += node.Data.FileInfo.Size
+				removable.FileCount++
+				break
+			}
+		}
+		return nil
+	}
+	// note: the visitor does not return errors
+	_ = tree.VisitDepthParentFirst(visitor, nil)
+
+	nonEmpty := make(RemovableSlice, 0, len(result))
+	for _, removable := range result {
+		if removable.FileCount > 0 {
+			nonEmpty = append(nonEmpty, removable)
+		}
+	}
+	sort.Slice(nonEmpty, func(i, j int) bool {
+		return nonEmpty[i].Path < nonEmpty[j].Path
+	})
+	return nonEmpty
+}
@@ -0,0 +1,83 @@
+package filetree
+
+import (
+	"archive/tar"
+	"testing"
+)
+
+func TestMarkRemovable(t *testing.T) {
+	tree := NewFileTree()
+
+	files := map[string]int64{
+		"/var/lib/apt/lists/deb.debian.org_Packages": 4000,
+		"/var/lib/apt/lists/lock":                    0,
+		"/app/node_modules/.cache/babel/entry":       300,
+		"/app/node_modules/react/index.js":           20,
+		"/app/src/__pycache__/main.cpython-38.pyc":   50,
+		"/usr/lib/libssl.a":                          700,
+		"/usr/lib/libssl.so":                         500,
+		"/opt/build/output":                          60,
+		"/opt/build/removed":                         80,
+	}
+	for p, size := range files {
+		_, _, err := tree.AddPath(p, FileInfo{Path: p, TypeFlag: tar.TypeReg, Size: size})
+		checkError(t, err, "could not setup test")
+	}
+	// an empty cache directory is not reported
+	_, _, err := tree.AddPath("/var/cache/apk", FileInfo{Path: "/var/cache/apk", TypeFlag: tar.TypeDir, IsDir: true})
+	checkError(t, err, "could not setup test")
+
+	removed, err := tree.GetNode("/opt/build/removed")
+	checkError(t, err, "could not setup test")
+	removed.Data.DiffType = Removed
+
+	// the user rules take precedence over the builtin rules
+	rules, err := NewRemovableRules(true,
+		RemovableRule{Path: "/opt/build"},
+		RemovableRule{Category: RemovableToolchain, Path: "/usr/lib/libssl.a"},
+	)
+	checkError(t, err, "could not create rules")
+
+	expected := []RemovablePath{
+		{Path: "/app/node_modules/.cache", Category: RemovableLanguageCache, Size: 300, FileCount: 1},
+		{Path: "/app/src/__pycache__", Category: RemovableLanguageCache, Size: 50, FileCount: 1},
+		{Path: "/opt/build", Category: RemovableCustom, Size: 60, FileCount: 1},
+		{Path: "/usr/lib/libssl.a", Category: RemovableToolchain, Size: 700, FileCount: 1},
+		{Path: "/var/lib/apt/lists", Category: RemovablePackageCache, Size: 4000, FileCount: 2},
+	}
+
+	actual := tree.MarkRemovable(rules)
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d removable paths, got %d: %+v", len(expected), len(actual), actual)
+	}
+	for idx, removable := range actual {
+		if *removable != expected[idx] {
+			t.Errorf("removable %d: expected %+v, got %+v", idx, expected[idx], *removable)
+		}
+	}
+	if total := actual.TotalSize(); total != 5110 {
+		t.Errorf("expected a total of 5110 bytes, got %d", total)
+	}
+
+	// every node beneath a matching path is tagged, but only the topmost node is noted
+	for p, category := range map[string]RemovableCategory{
+		"/app/node_modules/.cache/babel/entry": RemovableLanguageCache,
+		"/app/node_modules/react/index.js":     NotRemovable,
+		"/usr/lib/libssl.so":                   NotRemovable,
+	} {
+		node, err := tree.GetNode(p)
+		checkError(t, err, "could not get node")
+		if node.Data.Removable != category {
+			t.Errorf("%s: expected category %q, got %q", p, category, node.Data.Removable)
+		}
+	}
+	node, err := tree.GetNode("/opt/build")
+	checkError(t, err, "could not get node")
+	if node.String() != "build [removable: custom]" || node.Children["output"].String() != "output" {
+		t.Errorf("unexpected display: %q / %q", node.String(), node.Children["output"].String())
+	}
+
+	if _, err := NewRemovableRules(false, RemovableRule{Path: "["}); err == nil {
+		t.Errorf("expected an error for an invalid path")
+	}
+}
@@ -0,0 +1,208 @@
+package filetree
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LayerGroup is a set of layers (by index) squashed into a single layer, applied in the given order.
+type LayerGroup []int
+
+// String describes the group in the same form ParseLayerGroup reads (consecutive layers are shown as a range).
+func (group LayerGroup) String() string {
+	var names []string
+	for start := 0; start < len(group); {
+		stop := start
+		for stop+1 < len(group) && group[stop+1] == group[stop]+1 {
+			stop++
+		}
+		if stop > start {
+			names = append(names, fmt.Sprintf("%d-%d", group[start], group[stop]))
+		} else {
+			names = append(names, strconv.Itoa(group[start]))
+		}
+		start = stop + 1
+	}
+	return strings.Join(names, "+")
+}
+
+// ParseLayerGroup reads a group of layer indexes joined by "+", where each is a single index or an inclusive range
+// (e.g. "3-7" squashes layers 3 through 7, and "2+5" applies layer 5 right after layer 2).
+func ParseLayerGroup(spec string) (LayerGroup, error) {
+	var group LayerGroup
+	for _, part := range strings.Split(spec, "+") {
+		part = strings.TrimSpace(part)
+		bounds := strings.SplitN(part, "-", 2)
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid layer index in group '%s': '%s'", spec, part)
+		}
+		stop := start
+		if len(bounds) == 2 {
+			stop, err = strconv.Atoi(bounds[1])
+			if err != nil || stop < start {
+				return nil, fmt.Errorf("invalid layer range in group '%s': '%s'", spec, part)
+			}
+		}
+		for layerIdx := start; layerIdx <= stop; layerIdx++ {
+			group = append(group, layerIdx)
+		}
+	}
+	return group, nil
+}
+
+// NewLayerGrouping arranges every layer of an image into groups: the given groups are kept as-is and every layer not
+// within a group becomes a group of its own. Groups are ordered by their lowest layer index, so a layer grouped with
+// an earlier layer is moved down to it.
+func NewLayerGrouping(layerCount int, groups ...LayerGroup) ([]LayerGroup, error) {
+	grouped := make(map[int]bool)
+	result := make([]LayerGroup, 0, layerCount)
+	for _, group := range groups {
+		if len(group) == 0 {
+			return nil, fmt.Errorf("layer group must have at least one layer")
+		}
+		for _, layerIdx := range group {
+			if layerIdx < 0 || layerIdx >= layerCount {
+				return nil, fmt.Errorf("invalid layer index: %d (the image has %d layers)", layerIdx, layerCount)
+			}
+			if grouped[layerIdx] {
+				return nil, fmt.Errorf("layer %d is in more than one group", layerIdx)
+			}
+			grouped[layerIdx] = true
+		}
+		result = append(result, group)
+	}
+	for layerIdx := 0; layerIdx < layerCount; layerIdx++ {
+		if !grouped[layerIdx] {
+			result = append(result, LayerGroup{layerIdx})
+		}
+	}
+
+	lowest := func(group LayerGroup) int {
+		min := group[0]
+		for _, layerIdx := range group {
+			if layerIdx < min {
+				min = layerIdx
+			}
+		}
+		return min
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return lowest(result[i]) < lowest(result[j])
+	})
+	return result, nil
+}
+
+// Simulation describes an image rebuilt with its layers regrouped (see Simulate).
+type Simulation struct {
+	Groups         []LayerGroup
+	Trees          []*FileTree // the squashed tree of each group
+	LayerSizes     []uint64    // the size of each group
+	SizeBytes      uint64
+	Efficiency     float64
+	WastedBytes    uint64
+	Inefficiencies EfficiencySlice
+}
+
+// Simulate squashes the given trees (layers) by the given grouping (see NewLayerGrouping), reporting the resulting
+// layer sizes, total size, and efficiency. Within a group, files removed by a later layer of the same group no longer
+// take space; a removal is only kept when the file exists within an earlier group. Note: moving a layer does not
+// account for the commands in between depending on it, the layers are replayed as-is.
+func Simulate(trees []*FileTree, groups []LayerGroup) (*Simulation, error) {
+	simulation := &Simulation{
+		Groups:     groups,
+		Trees:      make([]*FileTree, len(groups)),
+		LayerSizes: make([]uint64, len(groups)),
+	}
+
+	// lower is every group below the current group stacked together
+	lower := NewFileTree()
+	for groupIdx, group := range groups {
+		squashed := NewFileTree()
+		for _, layerIdx := range group {
+			if layerIdx < 0 || layerIdx >= len(trees) {
+				return nil, fmt.Errorf("invalid layer index: %d (the image has %d layers)", layerIdx, len(trees))
+			}
+			err := squashTree(squashed, lower, trees[layerIdx])
+			if err != nil {
+				return nil, err
+			}
+		}
+		squashed.Name = fmt.Sprintf("squashed layers %s", group)
+
+		size := treeSize(squashed)
+		simulation.Trees[groupIdx] = squashed
+		simulation.LayerSizes[groupIdx] = size
+		simulation.SizeBytes += size
+
+		_, err := lower.Stack(squashed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	simulation.Efficiency, simulation.Inefficiencies = Efficiency(simulation.Trees)
+	for _, file := range simulation.Inefficiencies {
+		simulation.WastedBytes += uint64(file.CumulativeSize)
+	}
+	return simulation, nil
+}
+
+// squashTree applies the given layer onto the squashed tree of a group. Whiteouts remove the path from the group, and
+// are only kept when the path exists below the group (otherwise there is nothing to hide).
+func squashTree(squashed, lower, upper *FileTree) error {
+	graft := func(node *FileNode) error {
+		if node.IsWhiteout() {
+			p := node.Path()
+			if existing, err := squashed.GetNode(p); err == nil {
+				err = existing.Remove()
+				if err != nil {
+					return err
+				}
+			}
+			if _, err := lower.GetNode(p); err == nil {
+				_, _, err = squashed.addPath(path.Join(path.Dir(p), node.Name), node.Data.FileInfo)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if node.IsImplied() {
+			// only the entries of the layer are applied (the parent directories are implied by the entries)
+			_, err := squashed.GetNode(node.Path())
+			if err == nil {
+				return nil
+			}
+		}
+		p := node.Path()
+		_, _, err := squashed.addPath(p, node.Data.FileInfo)
+		if err != nil {
+			return err
+		}
+		// a file replaces the path below the group without a whiteout (only directories are merged)
+		if !node.Data.FileInfo.IsDir && p != "/" {
+			if whiteout, err := squashed.GetNode(path.Join(path.Dir(p), whiteoutPrefix+node.Name)); err == nil {
+				return whiteout.Remove()
+			}
+		}
+		return nil
+	}
+	return upper.VisitDepthChildFirst(graft, nil)
+}
+
+// treeSize is the sum of the size of every entry within the given tree (the size of a layer tar's contents).
+func treeSize(tree *FileTree) uint64 {
+	var size uint64
+	// note: the visitor does not return errors
+	_ = tree.VisitDepthChildFirst(func(node *FileNode) error {
+		if !node.IsImplied() {
+			size += uint64(node.Data.FileInfo.Size)
+		}
+		return nil
+	}, nil)
+	return size
+}
@@ -0,0 +1,128 @@
+package filetree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLayerGroup(t *testing.T) {
+	table := map[string]struct {
+		expected LayerGroup
+		hasError bool
+	}{
+		"3":       {expected: LayerGroup{3}},
+		"3-7":     {expected: LayerGroup{3, 4, 5, 6, 7}},
+		"2+5":     {expected: LayerGroup{2, 5}},
+		"1-2 + 9": {expected: LayerGroup{1, 2, 9}},
+		"7-3":     {hasError: true},
+		"a":       {hasError: true},
+		"2+":      {hasError: true},
+	}
+
+	for spec, test := range table {
+		actual, err := ParseLayerGroup(spec)
+		if test.hasError {
+			if err == nil {
+				t.Errorf("%s: expected an error", spec)
+			}
+			continue
+		}
+		checkError(t, err, spec)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected %v, got %v", spec, test.expected, actual)
+		}
+	}
+
+	for expected, group := range map[string]LayerGroup{"3-7": {3, 4, 5, 6, 7}, "2+5": {2, 5}, "1-2+9": {1, 2, 9}, "5+2-3": {5, 2, 3}} {
+		if actual := group.String(); actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+}
+
+func TestNewLayerGrouping(t *testing.T) {
+	actual, err := NewLayerGrouping(6, LayerGroup{3, 4, 5}, LayerGroup{1, 2}[1:], LayerGroup{1})
+	checkError(t, err, "unable to group layers")
+	expected := []LayerGroup{{0}, {1}, {2}, {3, 4, 5}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	// a group with an earlier layer moves the later layers down to it
+	actual, err = NewLayerGrouping(4, LayerGroup{1, 3})
+	checkError(t, err, "unable to group layers")
+	expected = []LayerGroup{{0}, {1, 3}, {2}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	for _, groups := range [][]LayerGroup{{{1, 4}}, {{1}, {1}}, {{}}} {
+		if _, err := NewLayerGrouping(4, groups...); err == nil {
+			t.Errorf("expected an error for groups %v", groups)
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	trees := make([]*FileTree, 4)
+	for idx := range trees {
+		trees[idx] = NewFileTree()
+	}
+	add := func(tree *FileTree, p string, size int64) {
+		_, _, err := tree.AddPath(p, FileInfo{Path: p, Size: size})
+		checkError(t, err, "could not setup test")
+	}
+	whiteout := func(tree *FileTree, p string) {
+		_, _, err := tree.AddPath(p, *BlankFileChangeInfo(p))
+		checkError(t, err, "could not setup test")
+	}
+
+	// base
+	add(trees[0], "/etc/os-release", 10)
+	add(trees[0], "/etc/motd", 5)
+	// install
+	add(trees[1], "/var/cache/apt/curl.deb", 1000)
+	add(trees[1], "/usr/bin/curl", 100)
+	// configure
+	add(trees[2], "/etc/app.conf", 20)
+	whiteout(trees[2], "/etc/.wh.motd")
+	// clean
+	whiteout(trees[3], "/var/cache/apt/.wh.curl.deb")
+
+	original, err := Simulate(trees, []LayerGroup{{0}, {1}, {2}, {3}})
+	checkError(t, err, "unable to simulate")
+	if original.SizeBytes != 1135 || !reflect.DeepEqual(original.LayerSizes, []uint64{15, 1100, 20, 0}) {
+		t.Errorf("unexpected original sizes: %d %v", original.SizeBytes, original.LayerSizes)
+	}
+	if original.WastedBytes != 1005 {
+		t.Errorf("expected 1005 wasted bytes, got %d", original.WastedBytes)
+	}
+	if score, _ := Efficiency(trees); score != original.Efficiency {
+		t.Errorf("expected the efficiency of the original layers (%v), got %v", score, original.Efficiency)
+	}
+
+	// run the clean within the same layer as the install
+	groups, err := NewLayerGrouping(len(trees), LayerGroup{1, 3})
+	checkError(t, err, "unable to group layers")
+	simulated, err := Simulate(trees, groups)
+	checkError(t, err, "unable to simulate")
+	if simulated.SizeBytes != 135 || !reflect.DeepEqual(simulated.LayerSizes, []uint64{15, 100, 20}) {
+		t.Errorf("unexpected simulated sizes: %d %v", simulated.SizeBytes, simulated.LayerSizes)
+	}
+	if simulated.WastedBytes != 5 {
+		t.Errorf("expected 5 wasted bytes, got %d", simulated.WastedBytes)
+	}
+	if _, err := simulated.Trees[1].GetNode("/var/cache/apt/.wh.curl.deb"); err == nil {
+		t.Errorf("expected the whiteout of a file added within the group to be dropped")
+	}
+
+	// squashing everything leaves only the final files (the whiteout of a base file remains within the upper layer)
+	squashed, err := Simulate(trees, []LayerGroup{{0}, {1, 2, 3}})
+	checkError(t, err, "unable to simulate")
+	if squashed.SizeBytes != 135 || squashed.Efficiency != 130.0/135.0 {
+		t.Errorf("unexpected squashed results: %d %v", squashed.SizeBytes, squashed.Efficiency)
+	}
+	if _, err := squashed.Trees[1].GetNode("/etc/.wh.motd"); err != nil {
+		t.Errorf("expected the whiteout of a base file to be kept: %v", err)
+	}
+}
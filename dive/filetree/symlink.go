@@ -0,0 +1,140 @@
+package filetree
+
+import (
+	"archive/tar"
+	"path"
+	"strings"
+)
+
+// maxLinkHops is the most symlinks followed while resolving a single path (the same limit as linux's MAXSYMLINKS).
+const maxLinkHops = 40
+
+const (
+	LinkUnresolved LinkStatus = iota
+	LinkResolved
+	LinkDangling
+	LinkCyclic
+)
+
+// LinkStatus indicates if (and how) a symlink resolves within a tree.
+type LinkStatus uint8
+
+func (status LinkStatus) String() string {
+	switch status {
+	case LinkUnresolved:
+		return "unresolved"
+	case LinkResolved:
+		return "resolved"
+	case LinkDangling:
+		return "dangling"
+	case LinkCyclic:
+		return "cyclic"
+	default:
+		return "<unknown link status>"
+	}
+}
+
+// LinkResolution is the outcome of following a path through any symlinks within a tree.
+type LinkResolution struct {
+	// Path is the path that was resolved
+	Path string
+	// Target is the final path reached. For dangling links this is the first path that does not exist, and for cyclic
+	// links this is the link that was revisited.
+	Target string
+	// Chain is every symlink followed, in order
+	Chain  []string
+	Status LinkStatus
+}
+
+// ResolvePath returns the node for the given path, following symlinks within every component of the path (including
+// the last), as the kernel would. The node is nil when the path is dangling or cyclic. Nodes marked as Removed are
+// considered to not exist.
+func (tree *FileTree) ResolvePath(p string) (*FileNode, LinkResolution) {
+	return tree.resolve(p, true)
+}
+
+// ResolveLink returns the node the given symlink ultimately refers to (see ResolvePath).
+func (tree *FileTree) ResolveLink(node *FileNode) (*FileNode, LinkResolution) {
+	return tree.resolve(node.Path(), true)
+}
+
+// ResolveLinks resolves every symlink within the tree, annotating each node with its LinkStatus. The dangling and
+// cyclic links are returned (ordered by path).
+func (tree *FileTree) ResolveLinks() []LinkResolution {
+	var broken []LinkResolution
+	visitor := func(node *FileNode) error {
+		if node.Data.FileInfo.TypeFlag != tar.TypeSymlink || node.Data.DiffType == Removed {
+			return nil
+		}
+		_, resolution := tree.ResolveLink(node)
+		node.Data.LinkStatus = resolution.Status
+		if resolution.Status != LinkResolved {
+			broken = append(broken, resolution)
+		}
+		return nil
+	}
+	// note: the visitor does not return errors
+	_ = tree.VisitDepthParentFirst(visitor, nil)
+	return broken
+}
+
+func (tree *FileTree) resolve(p string, followLast bool) (*FileNode, LinkResolution) {
+	resolution := LinkResolution{Path: p}
+
+	// a link is only considered revisited when the remaining path is the same, otherwise it may be a valid (but
+	// repetitive) traversal
+	visited := make(map[string]bool)
+	node := tree.Root
+	pending := splitPath(p)
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if node.Parent != nil {
+				node = node.Parent
+			}
+			continue
+		}
+
+		child := node.Children[name]
+		if child == nil || child.Data.DiffType == Removed {
+			resolution.Target = path.Join(append([]string{node.Path(), name}, pending...)...)
+			resolution.Status = LinkDangling
+			return nil, resolution
+		}
+
+		if child.Data.FileInfo.TypeFlag == tar.TypeSymlink && (len(pending) > 0 || followLast) {
+			linkPath := child.Path()
+			key := linkPath + "\x00" + strings.Join(pending, "/")
+			if visited[key] || len(resolution.Chain) >= maxLinkHops {
+				resolution.Target = linkPath
+				resolution.Status = LinkCyclic
+				return nil, resolution
+			}
+			visited[key] = true
+			resolution.Chain = append(resolution.Chain, linkPath)
+
+			// relative targets are relative to the directory containing the link (the current node)
+			target := child.Data.FileInfo.Linkname
+			if strings.HasPrefix(target, "/") {
+				node = tree.Root
+			}
+			pending = append(splitPath(target), pending...)
+			continue
+		}
+
+		node = child
+	}
+
+	resolution.Target = node.Path()
+	resolution.Status = LinkResolved
+	return node, resolution
+}
+
+func splitPath(p string) []string {
+	return strings.Split(strings.Trim(p, "/"), "/")
+}
@@ -0,0 +1,105 @@
+package filetree
+
+import (
+	"archive/tar"
+	"reflect"
+	"testing"
+)
+
+func TestResolveLinks(t *testing.T) {
+	tree := NewFileTree()
+
+	files := []string{"/usr/bin/busybox", "/etc/passwd", "/lib/libc.so.6"}
+	for _, p := range files {
+		_, _, err := tree.AddPath(p, FileInfo{Path: p, TypeFlag: tar.TypeReg})
+		checkError(t, err, "could not setup test")
+	}
+
+	links := map[string]string{
+		"/bin/sh":            "/usr/bin/busybox",
+		"/bin/ash":           "sh",
+		"/usr/local/bin/sh":  "../../../bin/ash",
+		"/usr/lib":           "../lib",
+		"/lib/libc.so":       "libc.so.6",
+		"/etc/shadow":        "/etc/missing",
+		"/etc/alternatives":  "/usr/lib/alternatives/editor",
+		"/loop/a":            "b",
+		"/loop/b":            "./a",
+		"/loop/self":         "self",
+		"/removed/link":      "/etc/passwd",
+		"/removed/target-ln": "/removed/target",
+	}
+	for p, target := range links {
+		_, _, err := tree.AddPath(p, FileInfo{Path: p, TypeFlag: tar.TypeSymlink, Linkname: target})
+		checkError(t, err, "could not setup test")
+	}
+	_, _, err := tree.AddPath("/removed/target", FileInfo{Path: "/removed/target", TypeFlag: tar.TypeReg})
+	checkError(t, err, "could not setup test")
+
+	for _, p := range []string{"/removed/link", "/removed/target"} {
+		node, err := tree.GetNode(p)
+		checkError(t, err, "could not setup test")
+		node.Data.DiffType = Removed
+	}
+
+	table := map[string]struct {
+		path   string
+		target string
+		chain  []string
+		status LinkStatus
+	}{
+		"absolute":          {"/bin/sh", "/usr/bin/busybox", []string{"/bin/sh"}, LinkResolved},
+		"relative":          {"/lib/libc.so", "/lib/libc.so.6", []string{"/lib/libc.so"}, LinkResolved},
+		"chain":             {"/usr/local/bin/sh", "/usr/bin/busybox", []string{"/usr/local/bin/sh", "/bin/ash", "/bin/sh"}, LinkResolved},
+		"parent link":       {"/usr/lib/libc.so", "/lib/libc.so.6", []string{"/usr/lib", "/lib/libc.so"}, LinkResolved},
+		"dangling":          {"/etc/shadow", "/etc/missing", []string{"/etc/shadow"}, LinkDangling},
+		"dangling via link": {"/etc/alternatives", "/lib/alternatives/editor", []string{"/etc/alternatives", "/usr/lib"}, LinkDangling},
+		"cycle":             {"/loop/a", "/loop/a", []string{"/loop/a", "/loop/b"}, LinkCyclic},
+		"self":              {"/loop/self", "/loop/self", []string{"/loop/self"}, LinkCyclic},
+		"removed target":    {"/removed/target-ln", "/removed/target", []string{"/removed/target-ln"}, LinkDangling},
+	}
+
+	for name, test := range table {
+		node, resolution := tree.ResolvePath(test.path)
+		if resolution.Status != test.status {
+			t.Errorf("%s: expected status %v, got %v", name, test.status, resolution.Status)
+		}
+		if resolution.Target != test.target {
+			t.Errorf("%s: expected target %q, got %q", name, test.target, resolution.Target)
+		}
+		if !reflect.DeepEqual(resolution.Chain, test.chain) {
+			t.Errorf("%s: expected chain %v, got %v", name, test.chain, resolution.Chain)
+		}
+		if (node != nil) != (test.status == LinkResolved) {
+			t.Errorf("%s: unexpected node for status %v: %v", name, resolution.Status, node)
+		}
+		if node != nil && node.Path() != test.target {
+			t.Errorf("%s: expected node %q, got %q", name, test.target, node.Path())
+		}
+	}
+
+	broken := tree.ResolveLinks()
+	var brokenPaths []string
+	for _, resolution := range broken {
+		brokenPaths = append(brokenPaths, resolution.Path)
+	}
+	expectedBroken := []string{"/etc/alternatives", "/etc/shadow", "/loop/a", "/loop/b", "/loop/self", "/removed/target-ln"}
+	if !reflect.DeepEqual(brokenPaths, expectedBroken) {
+		t.Errorf("expected broken links %v, got %v", expectedBroken, brokenPaths)
+	}
+
+	expectedStatus := map[string]LinkStatus{
+		"/bin/sh":       LinkResolved,
+		"/etc/shadow":   LinkDangling,
+		"/loop/self":    LinkCyclic,
+		"/removed/link": LinkUnresolved,
+		"/etc/passwd":   LinkUnresolved,
+	}
+	for p, status := range expectedStatus {
+		node, err := tree.GetNode(p)
+		checkError(t, err, "could not get node")
+		if node.Data.LinkStatus != status {
+			t.Errorf("%s: expected link status %v, got %v", p, status, node.Data.LinkStatus)
+		}
+	}
+}
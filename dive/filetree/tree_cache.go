@@ -0,0 +1,91 @@
+package filetree
+
+import "container/list"
+
+// cacheKey identifies a tree held by the treeCache. Stacked-only trees (no comparison applied) are kept as reusable
+// building blocks for any TreeIndexKey that shares the same bottom range.
+type cacheKey struct {
+	index   TreeIndexKey
+	stacked bool
+}
+
+func newStackedCacheKey(start, stop int) cacheKey {
+	return cacheKey{
+		index:   TreeIndexKey{bottomTreeStart: start, bottomTreeStop: stop},
+		stacked: true,
+	}
+}
+
+func newComparedCacheKey(index TreeIndexKey) cacheKey {
+	return cacheKey{index: index}
+}
+
+// cacheEntry is a built tree along with the path errors encountered while building it.
+type cacheEntry struct {
+	key        cacheKey
+	tree       *FileTree
+	pathErrors []PathError
+}
+
+// treeCache is a least-recently-used cache of built trees, bounded by the number of trees held. Note: this is not
+// safe for concurrent use, the owning Comparer is responsible for synchronization.
+type treeCache struct {
+	capacity int
+	order    *list.List
+	entries  map[cacheKey]*list.Element
+}
+
+func newTreeCache(capacity int) *treeCache {
+	return &treeCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[cacheKey]*list.Element),
+	}
+}
+
+// get fetches an entry, marking it as the most recently used.
+func (cache *treeCache) get(key cacheKey) (*cacheEntry, bool) {
+	element, exists := cache.entries[key]
+	if !exists {
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+	return element.Value.(*cacheEntry), true
+}
+
+// contains indicates if the entry is cached without affecting the recently used order.
+func (cache *treeCache) contains(key cacheKey) bool {
+	_, exists := cache.entries[key]
+	return exists
+}
+
+// add stores an entry, evicting the least recently used entries beyond the capacity.
+func (cache *treeCache) add(entry *cacheEntry) {
+	if element, exists := cache.entries[entry.key]; exists {
+		element.Value = entry
+		cache.order.MoveToFront(element)
+		return
+	}
+	cache.entries[entry.key] = cache.order.PushFront(entry)
+	cache.evict()
+}
+
+// resize changes the capacity, evicting entries as needed.
+func (cache *treeCache) resize(capacity int) {
+	cache.capacity = capacity
+	cache.evict()
+}
+
+// evict removes the least recently used entries beyond the capacity (a capacity of zero or less is unbounded).
+func (cache *treeCache) evict() {
+	for cache.capacity > 0 && cache.order.Len() > cache.capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// len returns the number of trees currently held.
+func (cache *treeCache) len() int {
+	return cache.order.Len()
+}
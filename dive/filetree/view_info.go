@@ -1,22 +1,109 @@
 package filetree
 
-// ViewInfo contains UI specific detail for a specific FileNode
-type ViewInfo struct {
-	Collapsed bool
-	Hidden    bool
+// ViewState contains the UI specific detail (collapsed and hidden nodes) for the trees shown by a view. The state is
+// owned by the view rather than the trees: the collapsed state is kept by path, so it carries over between the trees
+// shown (e.g. when selecting another layer, or when an evicted tree is rebuilt), while the hidden state is derived
+// from the tree being shown (see SetHidden). A nil ViewState shows every node expanded.
+type ViewState struct {
+	// collapseAll is the collapsed state of every directory not collapsed or expanded individually
+	collapseAll bool
+	collapsed   map[string]bool
+	// nodes are the individually collapsed (or expanded) paths resolved to the nodes of each tree shown, so the state
+	// of a node is found without deriving its path
+	nodes  map[*FileTree]map[*FileNode]bool
+	hidden map[*FileNode]struct{}
 }
 
-// NewViewInfo creates a default ViewInfo
-func NewViewInfo() (view *ViewInfo) {
-	return &ViewInfo{
-		Collapsed: GlobalFileTreeCollapse,
-		Hidden:    false,
+// NewViewState creates a ViewState where directories are collapsed by default (or not).
+func NewViewState(collapseAll bool) *ViewState {
+	return &ViewState{
+		collapseAll: collapseAll,
+		collapsed:   make(map[string]bool),
+		nodes:       make(map[*FileTree]map[*FileNode]bool),
+		hidden:      make(map[*FileNode]struct{}),
 	}
 }
 
-// Copy duplicates a ViewInfo
-func (view *ViewInfo) Copy() (newView *ViewInfo) {
-	newView = NewViewInfo()
-	*newView = *view
-	return newView
+// IsCollapsed indicates if the given node is shown collapsed (the tree root never is).
+func (view *ViewState) IsCollapsed(node *FileNode) bool {
+	if view == nil || node == node.Tree.Root {
+		return false
+	}
+	// note: most views never collapse a directory individually, avoid resolving the paths in that case
+	if len(view.collapsed) == 0 {
+		return view.collapseAll
+	}
+	if collapsed, exists := view.resolve(node.Tree)[node]; exists {
+		return collapsed
+	}
+	return view.collapseAll
+}
+
+// resolve returns the nodes of the given tree that are collapsed (or expanded) individually, looking up each path
+// the first time the tree is shown.
+func (view *ViewState) resolve(tree *FileTree) map[*FileNode]bool {
+	if nodes, exists := view.nodes[tree]; exists {
+		return nodes
+	}
+	nodes := make(map[*FileNode]bool, len(view.collapsed))
+	for p, collapsed := range view.collapsed {
+		if node, err := tree.GetNode(p); err == nil {
+			nodes[node] = collapsed
+		}
+	}
+	view.nodes[tree] = nodes
+	return nodes
+}
+
+// SetCollapsed collapses (or expands) the given node, along with the node of the same path in any other tree shown.
+func (view *ViewState) SetCollapsed(node *FileNode, collapsed bool) {
+	p := node.Path()
+	if collapsed == view.collapseAll {
+		delete(view.collapsed, p)
+	} else {
+		view.collapsed[p] = collapsed
+	}
+	for tree, nodes := range view.nodes {
+		counterpart, err := tree.GetNode(p)
+		if err != nil {
+			continue
+		}
+		if collapsed == view.collapseAll {
+			delete(nodes, counterpart)
+		} else {
+			nodes[counterpart] = collapsed
+		}
+	}
+}
+
+// SetCollapseAll collapses (or expands) every directory, forgetting any directory collapsed or expanded individually.
+func (view *ViewState) SetCollapseAll(collapsed bool) {
+	view.collapseAll = collapsed
+	view.collapsed = make(map[string]bool)
+	view.nodes = make(map[*FileTree]map[*FileNode]bool)
+}
+
+// IsHidden indicates if the given node is not shown.
+func (view *ViewState) IsHidden(node *FileNode) bool {
+	if view == nil {
+		return false
+	}
+	_, hidden := view.hidden[node]
+	return hidden
+}
+
+// SetHidden hides (or shows) the given node.
+func (view *ViewState) SetHidden(node *FileNode, hidden bool) {
+	if hidden {
+		view.hidden[node] = struct{}{}
+	} else {
+		delete(view.hidden, node)
+	}
+}
+
+// ResetHidden shows every node, releasing the nodes of any tree previously shown (the collapsed paths are resolved
+// again for the trees shown next).
+func (view *ViewState) ResetHidden() {
+	view.hidden = make(map[*FileNode]struct{})
+	view.nodes = make(map[*FileTree]map[*FileNode]bool)
 }
@@ -0,0 +1,32 @@
+package filetree
+
+import (
+	"path"
+	"strings"
+)
+
+// IsWhiteoutPath indicates if the given layer entry path is an overlay whiteout file of any kind (a whiteout of a lower
+// layer path, an opaque directory marker, or any other ".wh..wh." marker). Whiteout files are never part of the image
+// filesystem themselves.
+func IsWhiteoutPath(p string) bool {
+	return strings.HasPrefix(path.Base(p), whiteoutPrefix)
+}
+
+// WhiteoutTarget returns the lower layer path removed by the given whiteout entry path (e.g. "/etc/.wh.hosts" removes
+// "/etc/hosts"). False is returned for any other path, including opaque directory markers (see OpaqueWhiteoutDir).
+func WhiteoutTarget(p string) (string, bool) {
+	name := path.Base(p)
+	if !strings.HasPrefix(name, whiteoutPrefix) || strings.HasPrefix(name, doubleWhiteoutPrefix) {
+		return "", false
+	}
+	return path.Join(path.Dir(p), strings.TrimPrefix(name, whiteoutPrefix)), true
+}
+
+// OpaqueWhiteoutDir returns the directory made opaque by the given opaque marker entry path (e.g. "/etc/.wh..wh..opq"
+// hides the contents of "/etc" within the lower layers). False is returned for any other path.
+func OpaqueWhiteoutDir(p string) (string, bool) {
+	if path.Base(p) != opaqueWhiteout {
+		return "", false
+	}
+	return path.Dir(p), true
+}
@@ -0,0 +1,34 @@
+package filetree
+
+import "testing"
+
+func TestWhiteoutPaths(t *testing.T) {
+	table := map[string]struct {
+		path      string
+		whiteout  bool
+		target    string
+		opaqueDir string
+	}{
+		"regular file":  {path: "/etc/hosts"},
+		"whiteout":      {path: "/etc/.wh.hosts", whiteout: true, target: "/etc/hosts"},
+		"root whiteout": {path: "/.wh.etc", whiteout: true, target: "/etc"},
+		"opaque dir":    {path: "/etc/nginx/.wh..wh..opq", whiteout: true, opaqueDir: "/etc/nginx"},
+		"other marker":  {path: "/etc/.wh..wh..plnk", whiteout: true},
+		"similar name":  {path: "/etc/hosts.wh.bak"},
+	}
+	for name, test := range table {
+		t.Run(name, func(t *testing.T) {
+			if actual := IsWhiteoutPath(test.path); actual != test.whiteout {
+				t.Errorf("expected whiteout=%v, got %v", test.whiteout, actual)
+			}
+			target, isWhiteout := WhiteoutTarget(test.path)
+			if target != test.target || isWhiteout != (test.target != "") {
+				t.Errorf("expected whiteout target %q, got %q (%v)", test.target, target, isWhiteout)
+			}
+			opaqueDir, isOpaque := OpaqueWhiteoutDir(test.path)
+			if opaqueDir != test.opaqueDir || isOpaque != (test.opaqueDir != "") {
+				t.Errorf("expected opaque dir %q, got %q (%v)", test.opaqueDir, opaqueDir, isOpaque)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/wagoodman/dive/dive/image"
 	"github.com/wagoodman/dive/dive/image/docker"
 	"github.com/wagoodman/dive/dive/image/podman"
+	"github.com/wagoodman/dive/dive/image/snapshot"
 	"net/url"
 	"strings"
 )
@@ -14,14 +15,15 @@ const (
 	SourceDockerEngine
 	SourcePodmanEngine
 	SourceDockerArchive
+	SourceSnapshot
 )
 
 type ImageSource int
 
-var ImageSources = []string{SourceDockerEngine.String(), SourcePodmanEngine.String(), SourceDockerArchive.String()}
+var ImageSources = []string{SourceDockerEngine.String(), SourcePodmanEngine.String(), SourceDockerArchive.String(), SourceSnapshot.String()}
 
 func (r ImageSource) String() string {
-	return [...]string{"unknown", "docker", "podman", "docker-archive"}[r]
+	return [...]string{"unknown", "docker", "podman", "docker-archive", "snapshot"}[r]
 }
 
 func ParseImageSource(r string) ImageSource {
@@ -34,6 +36,8 @@ func ParseImageSource(r string) ImageSource {
 		return SourceDockerArchive
 	case "docker-tar":
 		return SourceDockerArchive
+	case SourceSnapshot.String():
+		return SourceSnapshot
 	default:
 		return SourceUnknown
 	}
@@ -56,6 +60,8 @@ func DeriveImageSource(image string) (ImageSource, string) {
 		return SourceDockerArchive, imageSource
 	case "docker-tar":
 		return SourceDockerArchive, imageSource
+	case SourceSnapshot.String():
+		return SourceSnapshot, imageSource
 
 	}
 	return SourceUnknown, ""
@@ -69,6 +75,8 @@ func GetImageResolver(r ImageSource) (image.Resolver, error) {
 		return podman.NewResolverFromEngine(), nil
 	case SourceDockerArchive:
 		return docker.NewResolverFromArchive(), nil
+	case SourceSnapshot:
+		return snapshot.NewResolver(), nil
 	}
 
 	return nil, fmt.Errorf("unable to determine image resolver")
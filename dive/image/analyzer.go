@@ -11,10 +11,29 @@ type Analyzer interface {
 type AnalysisResult struct {
 	Layers            []*Layer
 	RefTrees          []*filetree.FileTree
+	FinalTree         *filetree.FileTree // the final image filesystem (all layer trees stacked once, shared by the analyses)
 	Efficiency        float64
 	SizeBytes         uint64
 	UserSizeByes      uint64  // this is all bytes except for the base image
 	WastedUserPercent float64 // = wasted-bytes/user-size-bytes
 	WastedBytes       uint64
 	Inefficiencies    filetree.EfficiencySlice
+	Findings          filetree.Findings       // permission and ownership concerns within the final image filesystem
+	Packages          *PackageReport          // nil when the image has no (readable) OS package database
+	Owners            []*filetree.Owners      // the user and group names as of each layer (nil when unknown)
+	PathErrors        filetree.PathErrors     // layer entries that could not be stacked as given (and how each was recovered from)
+	Removable         filetree.RemovableSlice // likely removable paths (e.g. caches) within the final image
+	RemovableBytes    uint64
+	RemovableRules    filetree.RemovableRules // the rules used to find the removable paths
+	Content           ContentReader           // may be nil when the image source cannot be re-read
+	Config            []byte                  // the raw image config, nil when the source does not provide one
+	ContainerConfig   *ContainerConfig        // how containers of the image are run (e.g. the user), nil when not known
+}
+
+// FinalOwners returns the user and group names within the final image (nil when unknown).
+func (result *AnalysisResult) FinalOwners() *filetree.Owners {
+	if len(result.Owners) == 0 {
+		return nil
+	}
+	return result.Owners[len(result.Owners)-1]
 }
@@ -0,0 +1,71 @@
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ContainerConfig is how containers of the image are run, as given by the "config" of the image config (the same for
+// docker and OCI images).
+type ContainerConfig struct {
+	// User is the user (name or uid, optionally with a group) that runs the container, the default (root) when empty
+	User         string              `json:"User"`
+	Env          []string            `json:"Env"`
+	Labels       map[string]string   `json:"Labels"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+	// Healthcheck is nil when the image does not give one (it may be inherited by a base image)
+	Healthcheck *HealthConfig `json:"Healthcheck"`
+	Entrypoint  []string      `json:"Entrypoint"`
+	Cmd         []string      `json:"Cmd"`
+	WorkingDir  string        `json:"WorkingDir"`
+}
+
+// HealthConfig is the health check of the image (the durations are in nanoseconds).
+type HealthConfig struct {
+	// Test is the check command, where ["NONE"] disables any inherited health check
+	Test     []string `json:"Test"`
+	Interval int64    `json:"Interval"`
+	Timeout  int64    `json:"Timeout"`
+	Retries  int      `json:"Retries"`
+}
+
+// ParseContainerConfig reads the container config from the given raw image config, nil when the image config has none.
+func ParseContainerConfig(raw []byte) (*ContainerConfig, error) {
+	var imageConfig struct {
+		Config *ContainerConfig `json:"config"`
+	}
+	if err := json.Unmarshal(raw, &imageConfig); err != nil {
+		return nil, fmt.Errorf("unable to read image config: %v", err)
+	}
+	return imageConfig.Config, nil
+}
+
+// HasHealthcheck indicates if the image gives a health check (that is not disabled).
+func (config *ContainerConfig) HasHealthcheck() bool {
+	if config == nil || config.Healthcheck == nil || len(config.Healthcheck.Test) == 0 {
+		return false
+	}
+	return config.Healthcheck.Test[0] != "NONE"
+}
+
+// EnvKeys returns the names of the environment variables, in order.
+func (config *ContainerConfig) EnvKeys() []string {
+	if config == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(config.Env))
+	for _, entry := range config.Env {
+		keys = append(keys, strings.SplitN(entry, "=", 2)[0])
+	}
+	return keys
+}
+
+// UserName returns the user part of the configured user (without any group, e.g. "app" of "app:staff"), empty when
+// running as the default user (root).
+func (config *ContainerConfig) UserName() string {
+	if config == nil {
+		return ""
+	}
+	return strings.SplitN(config.User, ":", 2)[0]
+}
@@ -0,0 +1,46 @@
+package image
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseContainerConfig(t *testing.T) {
+	table := map[string]struct {
+		raw                 string
+		expectedNil         bool
+		expectedUser        string
+		expectedEnvKeys     []string
+		expectedHealthcheck bool
+		expectedErr         bool
+	}{
+		"noConfig":           {raw: `{"os":"linux"}`, expectedNil: true},
+		"defaults":           {raw: `{"config":{"Env":["PATH=/bin"],"Cmd":["sh"]}}`, expectedEnvKeys: []string{"PATH"}},
+		"userAndGroup":       {raw: `{"config":{"User":"app:staff","Env":["A=1","B"]}}`, expectedUser: "app", expectedEnvKeys: []string{"A", "B"}},
+		"healthcheck":        {raw: `{"config":{"Healthcheck":{"Test":["CMD-SHELL","true"],"Retries":3}}}`, expectedEnvKeys: []string{}, expectedHealthcheck: true},
+		"disableHealthcheck": {raw: `{"config":{"Healthcheck":{"Test":["NONE"]}}}`, expectedEnvKeys: []string{}},
+		"invalid":            {raw: `{"config":`, expectedErr: true},
+	}
+
+	for name, test := range table {
+		config, err := ParseContainerConfig([]byte(test.raw))
+		if (err != nil) != test.expectedErr {
+			t.Fatalf("%s: expected error=%v, got %v", name, test.expectedErr, err)
+		}
+		if test.expectedErr {
+			continue
+		}
+		if (config == nil) != test.expectedNil {
+			t.Fatalf("%s: expected nil config=%v, got %+v", name, test.expectedNil, config)
+		}
+		if config.UserName() != test.expectedUser {
+			t.Errorf("%s: expected user %q, got %q", name, test.expectedUser, config.UserName())
+		}
+		if keys := config.EnvKeys(); !reflect.DeepEqual(keys, test.expectedEnvKeys) {
+			t.Errorf("%s: expected env keys %v, got %v", name, test.expectedEnvKeys, keys)
+		}
+		if config.HasHealthcheck() != test.expectedHealthcheck {
+			t.Errorf("%s: expected healthcheck=%v, got %v", name, test.expectedHealthcheck, config.HasHealthcheck())
+		}
+	}
+}
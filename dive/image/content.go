@@ -0,0 +1,18 @@
+package image
+
+import (
+	"archive/tar"
+	"io"
+)
+
+// LayerEntryVisitor is called for each tar entry within a layer. The content reader is only valid for the duration of
+// the call.
+type LayerEntryVisitor func(layerIdx int, header *tar.Header, content io.Reader) error
+
+// ContentReader provides access to the file contents of image layers. Only file metadata is kept in memory after
+// analysis, so implementations re-read the layer blobs from the image source on each call.
+type ContentReader interface {
+	// ReadLayers visits every entry of the given layers (by index). Layers are visited in the order they appear in the
+	// source, which is not necessarily the order of the layers in the image.
+	ReadLayers(layerIndexes []int, visitor LayerEntryVisitor) error
+}
@@ -3,10 +3,13 @@ package docker
 import (
 	"fmt"
 	"github.com/wagoodman/dive/dive/image"
+	"io"
 	"os"
 )
 
-type archiveResolver struct{}
+type archiveResolver struct {
+	layers *image.LayerCache
+}
 
 func NewResolverFromArchive() *archiveResolver {
 	return &archiveResolver{}
@@ -19,11 +22,25 @@ func (r *archiveResolver) Fetch(path string) (*image.Image, error) {
 	}
 	defer reader.Close()
 
-	img, err := NewImageArchive(reader)
+	archive, err := NewImageArchiveWithLayerCache(reader, r.layers)
 	if err != nil {
 		return nil, err
 	}
-	return img.ToImage()
+
+	img, err := archive.ToImage()
+	if err != nil {
+		return nil, err
+	}
+	img.Content = archive.ContentReader(func() (io.ReadCloser, error) {
+		return os.Open(path)
+	})
+	return img, nil
+}
+
+// ShareLayers parses the layers of the images fetched from now on once, taking them from the given cache when shared
+// with a previously fetched image.
+func (r *archiveResolver) ShareLayers(layers *image.LayerCache) {
+	r.layers = layers
 }
 
 func (r *archiveResolver) Build(args []string) (*image.Image, error) {
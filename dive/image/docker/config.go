@@ -3,11 +3,14 @@ package docker
 import (
 	"encoding/json"
 	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/dive/image"
 )
 
 type config struct {
 	History []historyEntry `json:"history"`
 	RootFs  rootFs         `json:"rootfs"`
+	// Config is how containers of the image are run (e.g. the user, labels, and health check)
+	Config *image.ContainerConfig `json:"config"`
 }
 
 type rootFs struct {
@@ -0,0 +1,120 @@
+package docker
+
+import (
+	"archive/tar"
+	"fmt"
+	"github.com/wagoodman/dive/dive/image"
+	"io"
+	"path"
+)
+
+// ArchiveOpener opens a new stream of the image archive (e.g. the output of 'docker save').
+type ArchiveOpener func() (io.ReadCloser, error)
+
+// archiveContent re-reads layer contents from an image archive, opening a fresh stream from the source on each read.
+type archiveContent struct {
+	open          ArchiveOpener
+	layerTarPaths []string
+}
+
+// ContentReader returns a reader for the layer contents of this image, which are re-read from the given archive source.
+func (img *ImageArchive) ContentReader(open ArchiveOpener) *archiveContent {
+	return &archiveContent{
+		open:          open,
+		layerTarPaths: img.manifest.LayerTarPaths,
+	}
+}
+
+// ReadLayers visits every entry of the given layers. Layer tars that are symlinks to other layer tars are followed,
+// which may require more than one pass over the archive.
+func (c *archiveContent) ReadLayers(layerIndexes []int, visitor image.LayerEntryVisitor) error {
+	// tar path -> indexes of the layers backed by the tar
+	wanted := make(map[string][]int)
+	for _, layerIdx := range layerIndexes {
+		if layerIdx < 0 || layerIdx >= len(c.layerTarPaths) {
+			return fmt.Errorf("invalid layer index: %d", layerIdx)
+		}
+		name := c.layerTarPaths[layerIdx]
+		wanted[name] = append(wanted[name], layerIdx)
+	}
+
+	// bound the number of passes to guard against cycles of layer symlinks
+	for pass := 0; len(wanted) > 0; pass++ {
+		if pass > len(c.layerTarPaths) {
+			return fmt.Errorf("unable to resolve layer tars: %v", wanted)
+		}
+		var err error
+		wanted, err = c.readPass(wanted, visitor)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readPass makes a single pass over the archive, visiting the wanted layer tars. Layers that could not be visited in
+// this pass (the targets of layer tar symlinks, or additional layers backed by an already visited tar) are returned.
+func (c *archiveContent) readPass(wanted map[string][]int, visitor image.LayerEntryVisitor) (map[string][]int, error) {
+	archive, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	remaining := make(map[string][]int)
+	found := make(map[string]bool)
+	tarReader := tar.NewReader(archive)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		layerIdxs, exists := wanted[header.Name]
+		if !exists {
+			continue
+		}
+		found[header.Name] = true
+
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			target := path.Join(path.Dir(header.Name), header.Linkname)
+			remaining[target] = append(remaining[target], layerIdxs...)
+		case tar.TypeReg:
+			// the tar stream can only be read once, any other layer backed by the same tar is read in another pass
+			if len(layerIdxs) > 1 {
+				remaining[header.Name] = append(remaining[header.Name], layerIdxs[1:]...)
+			}
+			err = visitLayerTar(tar.NewReader(tarReader), layerIdxs[0], visitor)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected layer tar type: type=%v name=%s", header.Typeflag, header.Name)
+		}
+	}
+
+	for name := range wanted {
+		if !found[name] {
+			return nil, fmt.Errorf("could not find '%s' in image archive", name)
+		}
+	}
+	return remaining, nil
+}
+
+func visitLayerTar(reader *tar.Reader, layerIdx int, visitor image.LayerEntryVisitor) error {
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		err = visitor(layerIdx, header, reader)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package docker
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wagoodman/dive/dive/image"
+)
+
+const testArchivePath = "../../../.data/test-docker-image.tar"
+
+func testImageWithContent(t *testing.T) *image.Image {
+	archive, err := TestLoadArchive(testArchivePath)
+	if err != nil {
+		t.Fatalf("unable to fetch archive: %v", err)
+	}
+	img, err := archive.ToImage()
+	if err != nil {
+		t.Fatalf("unable to convert to image: %v", err)
+	}
+	img.Content = archive.ContentReader(func() (io.ReadCloser, error) {
+		return os.Open(testArchivePath)
+	})
+	return img
+}
+
+func TestArchiveContent_ReadLayers(t *testing.T) {
+	img := testImageWithContent(t)
+
+	entries := make(map[int]int)
+	err := img.Content.ReadLayers([]int{7, 13}, func(layerIdx int, header *tar.Header, content io.Reader) error {
+		entries[layerIdx]++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unable to read layers: %+v", err)
+	}
+	if len(entries) != 2 || entries[7] == 0 || entries[13] == 0 {
+		t.Errorf("expected entries from layers 7 and 13, got %+v", entries)
+	}
+
+	err = img.Content.ReadLayers([]int{len(img.Layers)}, func(int, *tar.Header, io.Reader) error { return nil })
+	if err == nil {
+		t.Errorf("expected an error for an invalid layer index")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	img := testImageWithContent(t)
+
+	table := map[string]struct {
+		layer    int
+		merged   bool
+		path     string
+		expected map[string]os.FileMode
+	}{
+		"single layer file": {
+			layer:    7,
+			path:     "/root/saved.txt",
+			expected: map[string]os.FileMode{"saved.txt": 0644},
+		},
+		"single layer modification": {
+			layer:    13,
+			path:     "/root/saved.txt",
+			expected: map[string]os.FileMode{"saved.txt": 0755},
+		},
+		"single layer directory": {
+			layer: 8,
+			path:  "/root",
+			expected: map[string]os.FileMode{
+				"root":            os.ModeDir | 0700,
+				"root/.saved.txt": 0644,
+			},
+		},
+		"merged directory": {
+			layer:  13,
+			merged: true,
+			path:   "/root",
+			expected: map[string]os.FileMode{
+				"root":                        os.ModeDir | 0700,
+				"root/.saved.txt":             0644,
+				"root/saved.txt":              0755,
+				"root/.data":                  os.ModeDir | 0755,
+				"root/.data/tag.sh":           0755,
+				"root/.data/test.sh":          0755,
+				"root/.data/saved.again2.txt": 0644,
+			},
+		},
+		"merged hard link": {
+			layer:    13,
+			merged:   true,
+			path:     "/bin/chgrp",
+			expected: map[string]os.FileMode{"chgrp": 0755},
+		},
+	}
+
+	for name, test := range table {
+		destination, err := ioutil.TempDir("", "dive-extract")
+		if err != nil {
+			t.Fatalf("unable to create temp dir: %+v", err)
+		}
+		defer os.RemoveAll(destination)
+
+		_, err = image.Extract(img.Content, img.Trees, test.layer, test.merged, test.path, destination)
+		if err != nil {
+			t.Errorf("%s: unable to extract: %+v", name, err)
+			continue
+		}
+
+		actual := make(map[string]os.FileMode)
+		err = filepath.Walk(destination, func(p string, info os.FileInfo, err error) error {
+			if err != nil || p == destination {
+				return err
+			}
+			rel, _ := filepath.Rel(destination, p)
+			actual[rel] = info.Mode()
+			if info.Mode().IsRegular() && info.Size() == 0 {
+				t.Errorf("%s: expected %s to have content", name, rel)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unable to walk destination: %+v", err)
+		}
+
+		if len(actual) != len(test.expected) {
+			t.Errorf("%s: expected %d paths, got %d: %+v", name, len(test.expected), len(actual), actual)
+		}
+		for p, mode := range test.expected {
+			if actual[p] != mode {
+				t.Errorf("%s: expected %s to have mode %v, got %v", name, p, mode, actual[p])
+			}
+		}
+	}
+}
+
+func TestExtract_MissingPath(t *testing.T) {
+	img := testImageWithContent(t)
+
+	destination, err := ioutil.TempDir("", "dive-extract")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %+v", err)
+	}
+	defer os.RemoveAll(destination)
+
+	// the path was removed in layer 9, so it is not within the merged view after it
+	_, err = image.Extract(img.Content, img.Trees, 10, true, "/root/example", destination)
+	if err == nil {
+		t.Errorf("expected an error extracting a removed path")
+	}
+}
@@ -13,7 +13,9 @@ import (
 	"golang.org/x/net/context"
 )
 
-type engineResolver struct{}
+type engineResolver struct {
+	layers *image.LayerCache
+}
 
 func NewResolverFromEngine() *engineResolver {
 	return &engineResolver{}
@@ -27,11 +29,25 @@ func (r *engineResolver) Fetch(id string) (*image.Image, error) {
 	}
 	defer reader.Close()
 
-	img, err := NewImageArchive(reader)
+	archive, err := NewImageArchiveWithLayerCache(reader, r.layers)
 	if err != nil {
 		return nil, err
 	}
-	return img.ToImage()
+
+	img, err := archive.ToImage()
+	if err != nil {
+		return nil, err
+	}
+	// note: the image contents are not kept on disk, they are saved from the engine again when needed
+	img.Content = archive.ContentReader(func() (io.ReadCloser, error) {
+		return r.fetchArchive(id)
+	})
+	return img, nil
+}
+
+// ShareLayers takes the layers of saved images from the given cache when already parsed for another image.
+func (r *engineResolver) ShareLayers(layers *image.LayerCache) {
+	r.layers = layers
 }
 
 func (r *engineResolver) Build(args []string) (*image.Image, error) {
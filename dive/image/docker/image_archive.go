@@ -2,6 +2,7 @@ package docker
 
 import (
 	"archive/tar"
+	"bytes"
 	"fmt"
 	"github.com/wagoodman/dive/dive/filetree"
 	"github.com/wagoodman/dive/dive/image"
@@ -13,14 +14,24 @@ import (
 )
 
 type ImageArchive struct {
-	manifest manifest
-	config   config
-	layerMap map[string]*filetree.FileTree
+	manifest  manifest
+	config    config
+	rawConfig []byte
+	layerMap  map[string]*filetree.FileTree
+	// layerFiles are the files captured while parsing each layer (see image.IsLayerFile), by layer tar name
+	layerFiles map[string][]image.LayerFile
 }
 
 func NewImageArchive(tarFile io.ReadCloser) (*ImageArchive, error) {
+	return NewImageArchiveWithLayerCache(tarFile, nil)
+}
+
+// NewImageArchiveWithLayerCache reads the image archive, taking the layers parsed for a previous image from the given
+// cache (and adding the others to it). The cache may be nil.
+func NewImageArchiveWithLayerCache(tarFile io.ReadCloser, layers *image.LayerCache) (*ImageArchive, error) {
 	img := &ImageArchive{
-		layerMap: make(map[string]*filetree.FileTree),
+		layerMap:   make(map[string]*filetree.FileTree),
+		layerFiles: make(map[string][]image.LayerFile),
 	}
 
 	tarReader := tar.NewReader(tarFile)
@@ -51,15 +62,29 @@ func NewImageArchive(tarFile io.ReadCloser) (*ImageArchive, error) {
 				if err != nil {
 					return img, err
 				}
+
+				// note: only regular layer tars are cached, symlinks are resolved by name within this archive
+				if header.Typeflag == tar.TypeReg {
+					if tree, files, exists := layers.Get(name, header.Size); exists {
+						img.layerMap[tree.Name] = tree
+						img.layerFiles[tree.Name] = files
+						continue
+					}
+				}
+
 				layerReader := tar.NewReader(tarReader)
-				tree, err := processLayerTar(name, layerReader)
+				tree, files, err := processLayerTar(name, layerReader)
 
 				if err != nil {
 					return img, err
 				}
+				if header.Typeflag == tar.TypeReg {
+					layers.Add(name, header.Size, tree, files)
+				}
 
 				// add the layer to the image
 				img.layerMap[tree.Name] = tree
+				img.layerFiles[tree.Name] = files
 
 			} else if strings.HasSuffix(name, ".json") {
 				fileBuffer, err := ioutil.ReadAll(tarReader)
@@ -84,17 +109,20 @@ func NewImageArchive(tarFile io.ReadCloser) (*ImageArchive, error) {
 	}
 
 	img.config = newConfig(configContent)
+	img.rawConfig = configContent
 
 	return img, nil
 }
 
-func processLayerTar(name string, reader *tar.Reader) (*filetree.FileTree, error) {
+// processLayerTar builds the tree of the given layer tar, capturing the files read by the analysis along the way (see
+// image.IsLayerFile), so that the image does not need to be read again.
+func processLayerTar(name string, reader *tar.Reader) (*filetree.FileTree, []image.LayerFile, error) {
 	tree := filetree.NewFileTree()
 	tree.Name = name
 
-	fileInfos, err := getFileList(reader)
+	fileInfos, captured, err := getFileList(reader)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	for _, element := range fileInfos {
@@ -102,22 +130,23 @@ func processLayerTar(name string, reader *tar.Reader) (*filetree.FileTree, error
 
 		_, _, err := tree.AddPath(element.Path, element)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
-	return tree, nil
+	return tree, captured, nil
 }
 
-func getFileList(tarReader *tar.Reader) ([]filetree.FileInfo, error) {
+func getFileList(tarReader *tar.Reader) ([]filetree.FileInfo, []image.LayerFile, error) {
 	var files []filetree.FileInfo
+	var captured []image.LayerFile
 
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		// always ensure relative path notations are not parsed as part of the filename
@@ -128,14 +157,25 @@ func getFileList(tarReader *tar.Reader) ([]filetree.FileInfo, error) {
 
 		switch header.Typeflag {
 		case tar.TypeXGlobalHeader:
-			return nil, fmt.Errorf("unexptected tar file: (XGlobalHeader): type=%v name=%s", header.Typeflag, name)
+			return nil, nil, fmt.Errorf("unexptected tar file: (XGlobalHeader): type=%v name=%s", header.Typeflag, name)
 		case tar.TypeXHeader:
-			return nil, fmt.Errorf("unexptected tar file (XHeader): type=%v name=%s", header.Typeflag, name)
+			return nil, nil, fmt.Errorf("unexptected tar file (XHeader): type=%v name=%s", header.Typeflag, name)
+		case tar.TypeReg, tar.TypeRegA:
+			if !image.IsLayerFile(path.Clean("/"+name)) || header.Size > image.MaxLayerFileSize {
+				files = append(files, filetree.NewFileInfoFromTarHeader(tarReader, header, name))
+				continue
+			}
+			content, err := ioutil.ReadAll(tarReader)
+			if err != nil {
+				return nil, nil, err
+			}
+			captured = append(captured, image.LayerFile{Header: *header, Content: content})
+			files = append(files, filetree.NewFileInfoFromTarHeader(bytes.NewReader(content), header, name))
 		default:
 			files = append(files, filetree.NewFileInfoFromTarHeader(tarReader, header, name))
 		}
 	}
-	return files, nil
+	return files, captured, nil
 }
 
 func (img *ImageArchive) ToImage() (*image.Image, error) {
@@ -184,9 +224,17 @@ func (img *ImageArchive) ToImage() (*image.Image, error) {
 		layers = append(layers, dockerLayer.ToLayer())
 	}
 
+	files := make(image.LayerFiles, 0, len(trees))
+	for _, treeName := range img.manifest.LayerTarPaths {
+		files = append(files, img.layerFiles[treeName])
+	}
+
 	return &image.Image{
-		Trees:  trees,
-		Layers: layers,
+		Trees:           trees,
+		Layers:          layers,
+		Files:           files,
+		Config:          img.rawConfig,
+		ContainerConfig: img.config.Config,
 	}, nil
 
 }
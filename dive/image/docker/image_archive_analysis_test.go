@@ -1,7 +1,10 @@
 package docker
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/wagoodman/dive/dive/image"
 )
 
 func Test_Analysis(t *testing.T) {
@@ -41,3 +44,69 @@ func Test_Analysis(t *testing.T) {
 		}
 	}
 }
+
+func Test_AnalysisWithSharedLayers(t *testing.T) {
+
+	layers := image.NewLayerCache()
+	resolver := NewResolverFromArchive()
+	resolver.ShareLayers(layers)
+
+	var results []*image.AnalysisResult
+	for idx := 0; idx < 2; idx++ {
+		img, err := resolver.Fetch("../../../.data/test-docker-image.tar")
+		if err != nil {
+			t.Fatalf("unable to fetch archive: %v", err)
+		}
+		result, err := img.Analyze()
+		if err != nil {
+			t.Fatalf("unable to analyze: %v", err)
+		}
+		results = append(results, result)
+	}
+
+	if hits := layers.Hits(); hits != len(results[0].Layers) {
+		t.Errorf("expected all %d layers to be taken from the cache, got %d", len(results[0].Layers), hits)
+	}
+
+	parsed, cached := results[0], results[1]
+	if cached.SizeBytes != parsed.SizeBytes || cached.WastedBytes != parsed.WastedBytes || cached.Efficiency != parsed.Efficiency {
+		t.Errorf("expected the same analysis of the cached layers, got size=%d wasted=%d efficiency=%v (expected size=%d wasted=%d efficiency=%v)",
+			cached.SizeBytes, cached.WastedBytes, cached.Efficiency, parsed.SizeBytes, parsed.WastedBytes, parsed.Efficiency)
+	}
+	for idx := range parsed.RefTrees {
+		if parsed.RefTrees[idx] != cached.RefTrees[idx] {
+			t.Errorf("layer %d: expected the images to share the tree", idx)
+		}
+		if parsed.RefTrees[idx].Name != cached.RefTrees[idx].Name || parsed.RefTrees[idx].Size != cached.RefTrees[idx].Size {
+			t.Errorf("layer %d: expected tree %s (%d nodes), got %s (%d nodes)", idx, parsed.RefTrees[idx].Name, parsed.RefTrees[idx].Size, cached.RefTrees[idx].Name, cached.RefTrees[idx].Size)
+		}
+	}
+}
+
+func Test_AnalysisCapturedFiles(t *testing.T) {
+
+	img, err := NewResolverFromArchive().Fetch("../../../.data/test-docker-image.tar")
+	if err != nil {
+		t.Fatalf("unable to fetch archive: %v", err)
+	}
+	if len(img.Files) != len(img.Trees) {
+		t.Fatalf("expected captured files for %d layers, got %d", len(img.Trees), len(img.Files))
+	}
+	var captured []string
+	for _, file := range img.Files[0] {
+		captured = append(captured, file.Header.Name)
+	}
+	if !reflect.DeepEqual(captured, []string{"etc/group", "etc/passwd"}) {
+		t.Errorf("expected the owner files to be captured from layer 0, got %v", captured)
+	}
+
+	// the analysis only reads the captured files, never the image again
+	img.Content = nil
+	result, err := img.Analyze()
+	if err != nil {
+		t.Fatalf("unable to analyze: %v", err)
+	}
+	if owners := result.FinalOwners(); owners == nil || owners.Format(0, 0) != "root:root" {
+		t.Errorf("expected the owners to be resolved from the captured files, got %+v", owners)
+	}
+}
@@ -0,0 +1,153 @@
+package image
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// DockerfileInstruction is a single (possibly continued) instruction of a Dockerfile.
+type DockerfileInstruction struct {
+	// StartLine and EndLine are the (1-based) lines the instruction spans
+	StartLine int
+	EndLine   int
+	// Command is the upper-cased instruction (e.g. RUN)
+	Command string
+	Args    string
+}
+
+// layerCommands are the instructions that add a layer to the image (any other instruction only changes the config).
+var layerCommands = map[string]bool{"RUN": true, "COPY": true, "ADD": true}
+
+// ParseDockerfile reads the instructions of the given Dockerfile, joining continued lines.
+func ParseDockerfile(reader io.Reader) ([]DockerfileInstruction, error) {
+	var instructions []DockerfileInstruction
+	escape := `\`
+	var current *DockerfileInstruction
+	var args []string
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "#") {
+			// the escape parser directive may only be given before the first instruction
+			if len(instructions) == 0 && current == nil {
+				directive := strings.ToLower(strings.Join(strings.Fields(strings.TrimPrefix(line, "#")), ""))
+				if strings.HasPrefix(directive, "escape=") && len(directive) > len("escape=") {
+					escape = directive[len("escape="):]
+				}
+			}
+			continue
+		}
+		if line == "" {
+			// note: empty lines do not end a continued instruction
+			continue
+		}
+
+		continued := strings.HasSuffix(line, escape)
+		line = strings.TrimSuffix(line, escape)
+
+		if current == nil {
+			fields := strings.SplitN(line, " ", 2)
+			current = &DockerfileInstruction{StartLine: lineNumber, Command: strings.ToUpper(fields[0])}
+			args = nil
+			if len(fields) > 1 {
+				line = fields[1]
+			} else {
+				line = ""
+			}
+		}
+		args = append(args, strings.TrimSpace(line))
+		current.EndLine = lineNumber
+
+		if !continued {
+			current.Args = strings.Join(strings.Fields(strings.Join(args, " ")), " ")
+			instructions = append(instructions, *current)
+			current = nil
+		}
+	}
+	if current != nil {
+		current.Args = strings.Join(strings.Fields(strings.Join(args, " ")), " ")
+		instructions = append(instructions, *current)
+	}
+	return instructions, scanner.Err()
+}
+
+// layerInstruction returns the instruction that created a layer, as given by the layer history (e.g.
+// "#(nop) ADD file:... in /", "mkdir -p /app", or "RUN /bin/sh -c mkdir -p /app # buildkit").
+func layerInstruction(layerCommand string) (string, string) {
+	command := shellCommand(strings.TrimSuffix(strings.TrimSpace(layerCommand), "# buildkit"))
+
+	if strings.HasPrefix(command, "#(nop) ") {
+		fields := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(command, "#(nop) ")), " ", 2)
+		return strings.ToUpper(fields[0]), ""
+	}
+
+	fields := strings.SplitN(command, " ", 2)
+	if name := strings.ToUpper(fields[0]); fields[0] == name && layerCommands[name] {
+		command = ""
+		if len(fields) > 1 {
+			command = shellCommand(fields[1])
+		}
+		return name, strings.Join(strings.Fields(command), " ")
+	}
+	return "RUN", strings.Join(strings.Fields(command), " ")
+}
+
+// shellCommand strips the shell (and any build arguments listed ahead of it, e.g. "|1 VERSION=1 /bin/sh -c make") from
+// a layer command.
+func shellCommand(command string) string {
+	command = strings.TrimSpace(command)
+	if strings.HasPrefix(command, "|") {
+		if idx := strings.Index(command, "/bin/sh -c "); idx >= 0 {
+			command = command[idx:]
+		}
+	}
+	return strings.TrimPrefix(command, "/bin/sh -c ")
+}
+
+// instructionArgs returns the arguments of an instruction without its flags (e.g. "--mount=type=cache,target=/root").
+func instructionArgs(args string) string {
+	fields := strings.Fields(args)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+		fields = fields[1:]
+	}
+	return strings.Join(fields, " ")
+}
+
+// MatchDockerfile returns the instruction of the final Dockerfile stage that created each of the given layers, or nil
+// for layers that could not be matched (such as the layers of the base image). Layers are matched from the last layer
+// backwards, stopping at the first layer that does not match the next instruction.
+func MatchDockerfile(layers []*Layer, instructions []DockerfileInstruction) []*DockerfileInstruction {
+	var stage []DockerfileInstruction
+	for _, instruction := range instructions {
+		if instruction.Command == "FROM" {
+			stage = nil
+			continue
+		}
+		if layerCommands[instruction.Command] {
+			stage = append(stage, instruction)
+		}
+	}
+
+	matches := make([]*DockerfileInstruction, len(layers))
+	next := len(stage) - 1
+	for idx := len(layers) - 1; idx >= 0 && next >= 0; idx-- {
+		command, args := layerInstruction(layers[idx].Command)
+		instruction := stage[next]
+		if command != instruction.Command {
+			break
+		}
+		// note: only shell form RUN instructions can be compared, the sources of COPY and ADD are not kept
+		if command == "RUN" && args != "" && !strings.HasPrefix(instruction.Args, "[") && args != instructionArgs(instruction.Args) {
+			break
+		}
+		matches[idx] = &stage[next]
+		next--
+	}
+	return matches
+}
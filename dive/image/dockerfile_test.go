@@ -0,0 +1,114 @@
+package image
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDockerfile = `# syntax=docker/dockerfile:1
+FROM golang:1.13 AS build
+RUN go build ./...
+
+FROM alpine:3.11
+ENV APP=/app
+COPY --from=build /go/bin/app /app/bin/app
+RUN apk add --no-cache \
+    # certificates for the client
+    ca-certificates \
+
+    tzdata
+RUN --mount=type=cache,target=/root/.cache make   install
+ADD ["config.yaml", "/app/"]
+CMD ["/app/bin/app"]
+`
+
+func TestParseDockerfile(t *testing.T) {
+	instructions, err := ParseDockerfile(strings.NewReader(testDockerfile))
+	if err != nil {
+		t.Fatalf("unable to parse: %v", err)
+	}
+
+	expected := []DockerfileInstruction{
+		{StartLine: 2, EndLine: 2, Command: "FROM", Args: "golang:1.13 AS build"},
+		{StartLine: 3, EndLine: 3, Command: "RUN", Args: "go build ./..."},
+		{StartLine: 5, EndLine: 5, Command: "FROM", Args: "alpine:3.11"},
+		{StartLine: 6, EndLine: 6, Command: "ENV", Args: "APP=/app"},
+		{StartLine: 7, EndLine: 7, Command: "COPY", Args: "--from=build /go/bin/app /app/bin/app"},
+		{StartLine: 8, EndLine: 12, Command: "RUN", Args: "apk add --no-cache ca-certificates tzdata"},
+		{StartLine: 13, EndLine: 13, Command: "RUN", Args: "--mount=type=cache,target=/root/.cache make install"},
+		{StartLine: 14, EndLine: 14, Command: "ADD", Args: `["config.yaml", "/app/"]`},
+		{StartLine: 15, EndLine: 15, Command: "CMD", Args: `["/app/bin/app"]`},
+	}
+	if !reflect.DeepEqual(instructions, expected) {
+		t.Errorf("expected %+v, got %+v", expected, instructions)
+	}
+
+	instructions, err = ParseDockerfile(strings.NewReader("# escape=`\nFROM scratch\nCOPY a `\n  b /\n"))
+	if err != nil {
+		t.Fatalf("unable to parse: %v", err)
+	}
+	if len(instructions) != 2 || instructions[1].Args != "a b /" || instructions[1].EndLine != 4 {
+		t.Errorf("unexpected instructions with an escape directive: %+v", instructions)
+	}
+}
+
+func TestMatchDockerfile(t *testing.T) {
+	instructions, err := ParseDockerfile(strings.NewReader(testDockerfile))
+	if err != nil {
+		t.Fatalf("unable to parse: %v", err)
+	}
+
+	table := map[string]struct {
+		commands []string
+		expected []int
+	}{
+		"classic": {
+			commands: []string{
+				"#(nop) ADD file:0c4555f363c2672e350001f1293e689875a3760afe7b3f9146886afe67121cba in / ",
+				"#(nop) COPY file:3e3f0a4f6fde1c4c7a4a1b2d8f0e1ae2f87d3b1b6e6e6f4a6d0c8c1e4f1a3c2b in /app/bin/app ",
+				"apk add --no-cache     ca-certificates     tzdata",
+				"make install",
+				"#(nop) ADD multi:5b6c9c8e0f3e4d2e1e6b6e2a9b1d2f3e4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d in /app/ ",
+			},
+			expected: []int{0, 7, 8, 13, 14},
+		},
+		"buildkit": {
+			commands: []string{
+				"/bin/sh -c #(nop) ADD file:0c4555f363c2672e350001f1293e689875a3760afe7b3f9146886afe67121cba in / ",
+				"COPY /go/bin/app /app/bin/app # buildkit",
+				"RUN /bin/sh -c apk add --no-cache     ca-certificates     tzdata # buildkit",
+				"RUN |1 VERSION=1 /bin/sh -c make install # buildkit",
+				"ADD config.yaml /app/ # buildkit",
+			},
+			expected: []int{0, 7, 8, 13, 14},
+		},
+		"mismatch": {
+			commands: []string{
+				"#(nop) COPY file:3e3f0a4f in /app/bin/app ",
+				"apk add --no-cache ca-certificates",
+				"make install",
+				"#(nop) ADD multi:5b6c9c8e in /app/ ",
+			},
+			expected: []int{0, 0, 13, 14},
+		},
+	}
+
+	for name, test := range table {
+		layers := make([]*Layer, len(test.commands))
+		for idx, command := range test.commands {
+			layers[idx] = &Layer{Index: idx, Command: command}
+		}
+
+		matches := MatchDockerfile(layers, instructions)
+		actual := make([]int, len(matches))
+		for idx, match := range matches {
+			if match != nil {
+				actual[idx] = match.StartLine
+			}
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected lines %v, got %v", name, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package image
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wagoodman/dive/dive/filetree"
+)
+
+// ExtractResult summarizes the outcome of extracting a path to disk.
+type ExtractResult struct {
+	Destination string
+	Written     int
+	Skipped     []string
+}
+
+// extractor writes the tar entries of a single path (a file or directory subtree) beneath a destination directory.
+type extractor struct {
+	target      string
+	destination string
+
+	// owners maps every path to extract to the index of the layer providing its entry
+	owners map[string]int
+	// written maps every extracted path to the file written on disk
+	written map[string]string
+	// symlinks are extracted paths that may not be written through
+	symlinks map[string]bool
+	// pendingLinks are hard links (by layer, then link target) whose target was not extracted alongside them
+	pendingLinks map[int]map[string][]string
+	dirs         []*tar.Header
+	result       ExtractResult
+}
+
+// Extract writes the given path (a file or directory subtree) to the destination directory, keeping the base name of
+// the path. When merged is set the path is taken from the image as it exists at the given layer (with all lower layers
+// stacked beneath it), otherwise only the entries within the given layer are written.
+func Extract(content ContentReader, trees []*filetree.FileTree, layerIdx int, merged bool, target, destination string) (*ExtractResult, error) {
+	if content == nil {
+		return nil, fmt.Errorf("the image source does not support reading file contents")
+	}
+	if layerIdx < 0 || layerIdx >= len(trees) {
+		return nil, fmt.Errorf("invalid layer index: %d (the image has %d layers)", layerIdx, len(trees))
+	}
+
+	e := &extractor{
+		target:       path.Clean("/" + target),
+		destination:  destination,
+		owners:       make(map[string]int),
+		written:      make(map[string]string),
+		symlinks:     make(map[string]bool),
+		pendingLinks: make(map[int]map[string][]string),
+		result: ExtractResult{
+			Destination: filepath.Join(destination, filepath.FromSlash(path.Base(path.Clean("/"+target)))),
+		},
+	}
+
+	var err error
+	if merged {
+		err = e.planMerged(trees, layerIdx)
+	} else {
+		err = e.planLayer(trees[layerIdx], layerIdx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	layerIdxs := make([]int, 0)
+	seen := make(map[int]bool)
+	for _, owner := range e.owners {
+		if !seen[owner] {
+			seen[owner] = true
+			layerIdxs = append(layerIdxs, owner)
+		}
+	}
+	sort.Ints(layerIdxs)
+
+	err = os.MkdirAll(destination, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	err = content.ReadLayers(layerIdxs, e.visit)
+	if err != nil {
+		return nil, err
+	}
+
+	// hard links are only written after their target, which may have been outside of the extracted path
+	if len(e.pendingLinks) > 0 {
+		layerIdxs = layerIdxs[:0]
+		for layerIdx := range e.pendingLinks {
+			layerIdxs = append(layerIdxs, layerIdx)
+		}
+		err = content.ReadLayers(layerIdxs, e.visitLinkTargets)
+		if err != nil {
+			return nil, err
+		}
+		for _, links := range e.pendingLinks {
+			for linkTarget := range links {
+				e.result.Skipped = append(e.result.Skipped, fmt.Sprintf("hard link target %s was not found", linkTarget))
+			}
+		}
+	}
+
+	// directory modes are applied last, a read-only directory would otherwise prevent writing its contents
+	sort.Slice(e.dirs, func(i, j int) bool {
+		return len(e.dirs[i].Name) > len(e.dirs[j].Name)
+	})
+	for _, header := range e.dirs {
+		dest := e.written[entryPath(header)]
+		err = os.Chmod(dest, header.FileInfo().Mode().Perm())
+		if err != nil {
+			return nil, err
+		}
+		_ = os.Chtimes(dest, header.ModTime, header.ModTime)
+	}
+
+	return &e.result, nil
+}
+
+// planLayer selects the entries within a single layer.
+func (e *extractor) planLayer(tree *filetree.FileTree, layerIdx int) error {
+	node, err := tree.GetNode(e.target)
+	if err != nil || node.IsWhiteout() {
+		return fmt.Errorf("path %s does not exist in layer %d", e.target, layerIdx)
+	}
+	return node.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+		if !node.IsImplied() {
+			e.owners[node.Path()] = layerIdx
+		}
+		return nil
+	}, func(node *filetree.FileNode) bool {
+		return !node.IsWhiteout()
+	})
+}
+
+// planMerged selects the entries of the stacked image at the given layer, each taken from the highest layer providing it.
+func (e *extractor) planMerged(trees []*filetree.FileTree, layerIdx int) error {
+	stacked, _, err := filetree.StackTreeRange(trees, 0, layerIdx)
+	if err != nil {
+		return err
+	}
+	node, err := stacked.GetNode(e.target)
+	if err != nil {
+		return fmt.Errorf("path %s does not exist at layer %d", e.target, layerIdx)
+	}
+	return node.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+		p := node.Path()
+		for idx := layerIdx; idx >= 0; idx-- {
+			layerNode, err := trees[idx].GetNode(p)
+			if err == nil && !layerNode.IsImplied() {
+				e.owners[p] = idx
+				return nil
+			}
+		}
+		return nil
+	}, func(node *filetree.FileNode) bool {
+		return !node.IsWhiteout()
+	})
+}
+
+// entryPath returns the absolute path of the given layer tar entry (as used within the file trees).
+func entryPath(header *tar.Header) string {
+	return path.Clean("/" + header.Name)
+}
+
+// destinationPath returns the file path on disk for the given (extracted) path.
+func (e *extractor) destinationPath(p string) (string, error) {
+	// symlinks within the extracted entries must not be written through (to a location outside the destination)
+	for parent := path.Dir(p); parent != "/" && parent != "."; parent = path.Dir(parent) {
+		if e.symlinks[parent] {
+			return "", fmt.Errorf("path %s is beneath a symlink", p)
+		}
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(p, path.Dir(e.target)), "/")
+	return filepath.Join(e.destination, filepath.FromSlash(rel)), nil
+}
+
+func (e *extractor) visit(layerIdx int, header *tar.Header, content io.Reader) error {
+	p := entryPath(header)
+	if owner, exists := e.owners[p]; !exists || owner != layerIdx {
+		return nil
+	}
+
+	dest, err := e.destinationPath(p)
+	if err != nil {
+		e.result.Skipped = append(e.result.Skipped, err.Error())
+		return nil
+	}
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		err = os.MkdirAll(dest, 0755)
+		dir := *header
+		e.dirs = append(e.dirs, &dir)
+	case tar.TypeReg, tar.TypeRegA:
+		err = writeFile(dest, header, content)
+	case tar.TypeSymlink:
+		err = replace(dest, func() error {
+			return os.Symlink(header.Linkname, dest)
+		})
+		e.symlinks[p] = true
+	case tar.TypeLink:
+		linkTarget := path.Clean("/" + header.Linkname)
+		if existing, exists := e.written[linkTarget]; exists && e.owners[linkTarget] == layerIdx {
+			err = replace(dest, func() error {
+				return os.Link(existing, dest)
+			})
+		} else {
+			if _, exists := e.pendingLinks[layerIdx]; !exists {
+				e.pendingLinks[layerIdx] = make(map[string][]string)
+			}
+			e.pendingLinks[layerIdx][linkTarget] = append(e.pendingLinks[layerIdx][linkTarget], dest)
+			return nil
+		}
+	default:
+		e.result.Skipped = append(e.result.Skipped, fmt.Sprintf("%s is not a regular file, directory, or link", p))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	e.written[p] = dest
+	e.result.Written++
+	return nil
+}
+
+// visitLinkTargets writes the contents of hard link targets that were not extracted themselves to each link.
+func (e *extractor) visitLinkTargets(layerIdx int, header *tar.Header, content io.Reader) error {
+	links, exists := e.pendingLinks[layerIdx][entryPath(header)]
+	if !exists || (header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA) {
+		return nil
+	}
+	delete(e.pendingLinks[layerIdx], entryPath(header))
+	if len(e.pendingLinks[layerIdx]) == 0 {
+		delete(e.pendingLinks, layerIdx)
+	}
+
+	err := writeFile(links[0], header, content)
+	if err != nil {
+		return err
+	}
+	for _, link := range links[1:] {
+		err = replace(link, func() error {
+			return os.Link(links[0], link)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	e.result.Written += len(links)
+	return nil
+}
+
+// writeFile writes a regular file with the permissions and modification time of the given entry. Note: ownership and
+// special mode bits (e.g. setuid) are not preserved.
+func writeFile(dest string, header *tar.Header, content io.Reader) error {
+	return replace(dest, func() error {
+		file, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, header.FileInfo().Mode().Perm())
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(file, content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		modTime := header.ModTime
+		if modTime.IsZero() {
+			modTime = time.Now()
+		}
+		return os.Chtimes(dest, modTime, modTime)
+	})
+}
+
+// replace creates a non-directory file (via the given function) in place of anything already at the destination.
+func replace(dest string, create func() error) error {
+	err := os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(dest)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return create()
+}
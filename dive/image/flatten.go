@@ -0,0 +1,312 @@
+package image
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/wagoodman/dive/dive/filetree"
+)
+
+// FlattenResult summarizes the merged root filesystem written by Flatten.
+type FlattenResult struct {
+	Entries      int
+	Materialized int
+}
+
+// flatEntry is the metadata kept for every entry of a layer tar while resolving the merged filesystem.
+type flatEntry struct {
+	path     string
+	typeflag byte
+	// target is the index (within the same layer) of the entry a hard link refers to
+	target int
+}
+
+// flatNode is a path within the merged filesystem, referencing the layer entry that provides it (if any).
+type flatNode struct {
+	children map[string]*flatNode
+	layerIdx int
+	entryIdx int
+	present  bool
+}
+
+func newFlatNode() *flatNode {
+	return &flatNode{
+		children: make(map[string]*flatNode),
+	}
+}
+
+// get returns the node at the given path, creating any missing nodes when requested (nil otherwise).
+func (node *flatNode) get(p string, create bool) *flatNode {
+	for _, name := range strings.Split(strings.Trim(p, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		child, exists := node.children[name]
+		if !exists {
+			if !create {
+				return nil
+			}
+			child = newFlatNode()
+			node.children[name] = child
+		}
+		node = child
+	}
+	return node
+}
+
+// visit calls the given function for every present node (parents before children).
+func (node *flatNode) visit(visitor func(*flatNode)) {
+	if node.present {
+		visitor(node)
+	}
+	for _, child := range node.children {
+		child.visit(visitor)
+	}
+}
+
+// flattener merges the layer tars of an image, in the same manner as an overlay filesystem would.
+type flattener struct {
+	content ContentReader
+	upto    int
+	// entries are the entries of every layer, in tar order
+	entries [][]flatEntry
+	root    *flatNode
+	// selected are the entries (by layer, then entry index) that are part of the merged filesystem
+	selected []map[int]bool
+	// materialized are hard links (by layer, then target entry index) to targets that are not part of the merged
+	// filesystem, which must be written as regular files instead.
+	materialized []map[int][]int
+}
+
+// Flatten writes a tar of the merged root filesystem as seen after applying all layers up to (and including) the given
+// layer. Whiteouts (including opaque directories) are applied, and modes, ownership, links, and extended attributes are
+// preserved. Note: the layer contents are read twice, once to resolve the merged filesystem and once to write it.
+func Flatten(content ContentReader, layerCount, upto int, writer io.Writer) (*FlattenResult, error) {
+	if content == nil {
+		return nil, fmt.Errorf("the image source does not support reading file contents")
+	}
+	if upto < 0 || upto >= layerCount {
+		return nil, fmt.Errorf("invalid layer index: %d (the image has %d layers)", upto, layerCount)
+	}
+
+	f := &flattener{
+		content:      content,
+		upto:         upto,
+		entries:      make([][]flatEntry, upto+1),
+		root:         newFlatNode(),
+		selected:     make([]map[int]bool, upto+1),
+		materialized: make([]map[int][]int, upto+1),
+	}
+
+	err := f.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	return f.write(writer)
+}
+
+func (f *flattener) layerIndexes() []int {
+	layerIdxs := make([]int, f.upto+1)
+	for idx := range layerIdxs {
+		layerIdxs[idx] = idx
+	}
+	return layerIdxs
+}
+
+// resolve determines which layer entries make up the merged filesystem.
+func (f *flattener) resolve() error {
+	err := f.content.ReadLayers(f.layerIndexes(), func(layerIdx int, header *tar.Header, _ io.Reader) error {
+		entry := flatEntry{
+			path:     path.Clean("/" + header.Name),
+			typeflag: header.Typeflag,
+			target:   -1,
+		}
+		if header.Typeflag == tar.TypeLink {
+			// hard links refer to the most recent entry with the target path (which may be a link itself)
+			target := path.Clean("/" + header.Linkname)
+			entries := f.entries[layerIdx]
+			for idx := len(entries) - 1; idx >= 0; idx-- {
+				if entries[idx].path == target {
+					entry.target = idx
+					if entries[idx].typeflag == tar.TypeLink {
+						entry.target = entries[idx].target
+					}
+					break
+				}
+			}
+		}
+		f.entries[layerIdx] = append(f.entries[layerIdx], entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// replay the layers in order: whiteouts only apply to lower layers, so they are applied before any additions
+	for layerIdx, entries := range f.entries {
+		for _, entry := range entries {
+			if opaqueDir, isOpaque := filetree.OpaqueWhiteoutDir(entry.path); isOpaque {
+				if dir := f.root.get(opaqueDir, false); dir != nil {
+					dir.children = make(map[string]*flatNode)
+				}
+			} else if target, isWhiteout := filetree.WhiteoutTarget(entry.path); isWhiteout {
+				if parent := f.root.get(path.Dir(target), false); parent != nil {
+					delete(parent.children, path.Base(target))
+				}
+			}
+		}
+		for entryIdx, entry := range entries {
+			if filetree.IsWhiteoutPath(entry.path) || entry.path == "/" {
+				continue
+			}
+			node := f.root.get(entry.path, true)
+			node.layerIdx, node.entryIdx, node.present = layerIdx, entryIdx, true
+			// a non-directory replaces everything beneath it
+			if entry.typeflag != tar.TypeDir {
+				node.children = make(map[string]*flatNode)
+			}
+		}
+	}
+
+	for layerIdx := range f.selected {
+		f.selected[layerIdx] = make(map[int]bool)
+		f.materialized[layerIdx] = make(map[int][]int)
+	}
+	f.root.visit(func(node *flatNode) {
+		f.selected[node.layerIdx][node.entryIdx] = true
+	})
+
+	// hard links must be written as regular files when their target has been replaced or removed by a later layer
+	for layerIdx, entries := range f.entries {
+		for entryIdx, entry := range entries {
+			if !f.selected[layerIdx][entryIdx] || entry.typeflag != tar.TypeLink {
+				continue
+			}
+			if entry.target < 0 || f.selected[layerIdx][entry.target] {
+				continue
+			}
+			f.materialized[layerIdx][entry.target] = append(f.materialized[layerIdx][entry.target], entryIdx)
+		}
+	}
+	return nil
+}
+
+// write reads the selected entries of each layer, spooling them to temporary files so that the merged filesystem can
+// be written in layer order (no matter the order the layers appear in the source).
+func (f *flattener) write(writer io.Writer) (*FlattenResult, error) {
+	result := &FlattenResult{}
+
+	spoolDir, err := ioutil.TempDir("", "dive-flatten")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(spoolDir)
+
+	spools := make([]*os.File, f.upto+1)
+	spoolWriters := make([]*tar.Writer, f.upto+1)
+	for layerIdx := range spools {
+		spools[layerIdx], err = os.Create(filepath.Join(spoolDir, fmt.Sprintf("layer-%d.tar", layerIdx)))
+		if err != nil {
+			return nil, err
+		}
+		defer spools[layerIdx].Close()
+		spoolWriters[layerIdx] = tar.NewWriter(spools[layerIdx])
+	}
+
+	entryIdxs := make([]int, f.upto+1)
+	err = f.content.ReadLayers(f.layerIndexes(), func(layerIdx int, header *tar.Header, content io.Reader) error {
+		entryIdx := entryIdxs[layerIdx]
+		entryIdxs[layerIdx]++
+		spool := spoolWriters[layerIdx]
+
+		if f.selected[layerIdx][entryIdx] {
+			target := f.entries[layerIdx][entryIdx].target
+			if header.Typeflag == tar.TypeLink && target >= 0 && !f.selected[layerIdx][target] {
+				// written in place of the target entry (see below)
+				return nil
+			}
+			result.Entries++
+			return copyEntry(spool, header, content)
+		}
+
+		links, exists := f.materialized[layerIdx][entryIdx]
+		if !exists {
+			return nil
+		}
+		// the first link takes the contents of the (removed) target, the remaining links refer to the first link
+		for idx, linkIdx := range links {
+			linkHeader := *f.linkHeader(layerIdx, linkIdx, header)
+			if idx == 0 {
+				err := copyEntry(spool, &linkHeader, content)
+				if err != nil {
+					return err
+				}
+			} else {
+				linkHeader.Typeflag = tar.TypeLink
+				linkHeader.Linkname = strings.TrimPrefix(f.entries[layerIdx][links[0]].path, "/")
+				linkHeader.Size = 0
+				err := spool.WriteHeader(&linkHeader)
+				if err != nil {
+					return err
+				}
+			}
+			result.Entries++
+			result.Materialized++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	output := tar.NewWriter(writer)
+	for layerIdx, spool := range spools {
+		err = spoolWriters[layerIdx].Close()
+		if err != nil {
+			return nil, err
+		}
+		_, err = spool.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+		reader := tar.NewReader(spool)
+		for {
+			header, err := reader.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			err = copyEntry(output, header, reader)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, output.Close()
+}
+
+// linkHeader returns the header for a hard link that is written as a copy of the given target entry.
+func (f *flattener) linkHeader(layerIdx, linkIdx int, target *tar.Header) *tar.Header {
+	header := *target
+	header.Name = strings.TrimPrefix(f.entries[layerIdx][linkIdx].path, "/")
+	return &header
+}
+
+func copyEntry(writer *tar.Writer, header *tar.Header, content io.Reader) error {
+	err := writer.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	if header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA {
+		_, err = io.Copy(writer, content)
+	}
+	return err
+}
@@ -0,0 +1,193 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+type testEntry struct {
+	header  tar.Header
+	content string
+}
+
+// testContent serves layers from memory, visiting the layers in reverse to ensure no ordering is assumed.
+type testContent [][]testEntry
+
+func (layers testContent) ReadLayers(layerIndexes []int, visitor LayerEntryVisitor) error {
+	for idx := len(layerIndexes) - 1; idx >= 0; idx-- {
+		layerIdx := layerIndexes[idx]
+		for _, entry := range layers[layerIdx] {
+			header := entry.header
+			err := visitor(layerIdx, &header, strings.NewReader(entry.content))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func dir(name string) testEntry {
+	return testEntry{header: tar.Header{Typeflag: tar.TypeDir, Name: name, Mode: 0755}}
+}
+
+func file(name, content string) testEntry {
+	return testEntry{header: tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: int64(len(content))}, content: content}
+}
+
+func link(name, target string) testEntry {
+	return testEntry{header: tar.Header{Typeflag: tar.TypeLink, Name: name, Linkname: target, Mode: 0644}}
+}
+
+func TestFlatten(t *testing.T) {
+	setuid := file("bin/tool", "tool")
+	setuid.header.Mode = 04755
+	setuid.header.Uid, setuid.header.Gid = 1000, 2000
+	setuid.header.PAXRecords = map[string]string{"SCHILY.xattr.security.capability": "cap"}
+
+	content := testContent{
+		{
+			dir("bin/"),
+			setuid,
+			dir("etc/"),
+			file("etc/hosts", "hosts-0"),
+			file("etc/passwd", "passwd-0"),
+			link("etc/passwd-link", "etc/passwd"),
+			dir("opt/"),
+			file("opt/a", "a"),
+			file("opt/b", "b"),
+			dir("var/"),
+			file("var/log", "log"),
+			testEntry{header: tar.Header{Typeflag: tar.TypeSymlink, Name: "etc/localtime", Linkname: "/usr/share/zoneinfo/UTC"}},
+		},
+		{
+			file("etc/.wh.hosts", ""),
+			file("etc/passwd", "passwd-1"),
+			dir("opt/"),
+			file("opt/.wh..wh..opq", ""),
+			file("opt/c", "c"),
+			dir("var/log/"),
+			file("var/log/messages", "messages"),
+		},
+		{
+			file("etc/hosts", "hosts-2"),
+		},
+	}
+
+	table := map[string]struct {
+		upto     int
+		expected map[string]string
+	}{
+		"base layer": {
+			upto: 0,
+			expected: map[string]string{
+				"bin/":            "",
+				"bin/tool":        "tool",
+				"etc/":            "",
+				"etc/hosts":       "hosts-0",
+				"etc/passwd":      "passwd-0",
+				"etc/passwd-link": "->etc/passwd",
+				"etc/localtime":   "",
+				"opt/":            "",
+				"opt/a":           "a",
+				"opt/b":           "b",
+				"var/":            "",
+				"var/log":         "log",
+			},
+		},
+		"whiteouts applied": {
+			upto: 1,
+			expected: map[string]string{
+				"bin/":       "",
+				"bin/tool":   "tool",
+				"etc/":       "",
+				"etc/passwd": "passwd-1",
+				// the link refers to the original (replaced) file, so it becomes a regular file
+				"etc/passwd-link":  "passwd-0",
+				"etc/localtime":    "",
+				"opt/":             "",
+				"opt/c":            "c",
+				"var/":             "",
+				"var/log/":         "",
+				"var/log/messages": "messages",
+			},
+		},
+		"re-added after whiteout": {
+			upto: 2,
+			expected: map[string]string{
+				"bin/":             "",
+				"bin/tool":         "tool",
+				"etc/":             "",
+				"etc/hosts":        "hosts-2",
+				"etc/passwd":       "passwd-1",
+				"etc/passwd-link":  "passwd-0",
+				"etc/localtime":    "",
+				"opt/":             "",
+				"opt/c":            "c",
+				"var/":             "",
+				"var/log/":         "",
+				"var/log/messages": "messages",
+			},
+		},
+	}
+
+	for name, test := range table {
+		buffer := &bytes.Buffer{}
+		_, err := Flatten(content, len(content), test.upto, buffer)
+		if err != nil {
+			t.Fatalf("%s: unable to flatten: %+v", name, err)
+		}
+
+		actual := make(map[string]string)
+		reader := tar.NewReader(buffer)
+		for {
+			header, err := reader.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: unable to read flattened tar: %+v", name, err)
+			}
+			var body bytes.Buffer
+			_, _ = io.Copy(&body, reader)
+			value := body.String()
+			if header.Typeflag == tar.TypeLink {
+				value = "->" + header.Linkname
+			}
+			if _, exists := actual[header.Name]; exists {
+				t.Errorf("%s: duplicate entry %s", name, header.Name)
+			}
+			actual[header.Name] = value
+
+			if header.Name == "bin/tool" {
+				if header.Mode != 04755 || header.Uid != 1000 || header.Gid != 2000 {
+					t.Errorf("%s: expected mode and ownership to be preserved, got mode=%o uid=%d gid=%d", name, header.Mode, header.Uid, header.Gid)
+				}
+				if header.PAXRecords["SCHILY.xattr.security.capability"] != "cap" {
+					t.Errorf("%s: expected xattrs to be preserved, got %+v", name, header.PAXRecords)
+				}
+			}
+			if header.Name == "etc/localtime" && header.Linkname != "/usr/share/zoneinfo/UTC" {
+				t.Errorf("%s: expected symlink to be preserved, got %q", name, header.Linkname)
+			}
+		}
+
+		if len(actual) != len(test.expected) {
+			t.Errorf("%s: expected %d entries, got %d: %+v", name, len(test.expected), len(actual), actual)
+		}
+		for path, value := range test.expected {
+			if actualValue, exists := actual[path]; !exists || actualValue != value {
+				t.Errorf("%s: expected %s=%q, got %q (exists=%v)", name, path, value, actualValue, exists)
+			}
+		}
+	}
+}
+
+func TestFlatten_InvalidLayer(t *testing.T) {
+	_, err := Flatten(testContent{{dir("etc/")}}, 1, 1, &bytes.Buffer{})
+	if err == nil {
+		t.Errorf("expected an error for an invalid layer")
+	}
+}
@@ -1,16 +1,53 @@
 package image
 
 import (
+	"github.com/sirupsen/logrus"
 	"github.com/wagoodman/dive/dive/filetree"
 )
 
 type Image struct {
-	Trees  []*filetree.FileTree
-	Layers []*Layer
+	Trees   []*filetree.FileTree
+	Layers  []*Layer
+	Content ContentReader
+	// Files are the owner files and package databases captured while parsing each layer (see IsLayerFile), nil when the
+	// source does not capture them
+	Files LayerFiles
+	// Config is the raw image config (e.g. the history and environment), nil when the source does not provide one
+	Config []byte
+	// ContainerConfig is how containers of the image are run (e.g. the user and labels), nil when not known
+	ContainerConfig *ContainerConfig
+	// PathPolicies decide how entries that cannot be stacked are recovered from during analysis (see
+	// filetree.CheckPaths), the defaults are used when nil
+	PathPolicies filetree.PathPolicies
 }
 
 func (img *Image) Analyze() (*AnalysisResult, error) {
 
+	// entries that cannot be stacked are dealt with before anything else stacks the trees
+	policies := img.PathPolicies
+	if policies == nil {
+		policies = filetree.DefaultPathPolicies()
+	}
+	pathErrors, err := filetree.CheckPaths(img.Trees, policies)
+	if err != nil {
+		return nil, err
+	}
+	// the layer trees with entries removed are copies (see CheckPaths)
+	for idx, layer := range img.Layers {
+		if idx < len(img.Trees) && layer.Tree != nil {
+			layer.Tree = img.Trees[idx]
+		}
+	}
+
+	// the final image filesystem is stacked once and shared by every analysis below (and the CI rules)
+	var final *filetree.FileTree
+	if len(img.Trees) > 0 {
+		final, _, err = filetree.StackTreeRange(img.Trees, 0, len(img.Trees)-1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	efficiency, inefficiencies := filetree.Efficiency(img.Trees)
 	var sizeBytes, userSizeBytes uint64
 
@@ -26,14 +63,49 @@ func (img *Image) Analyze() (*AnalysisResult, error) {
 		wastedBytes += uint64(file.CumulativeSize)
 	}
 
+	// owner names and package attribution are informational, so files that cannot be read do not prevent the analysis.
+	// Note: only the files captured while parsing the layers are read, the image is not read again.
+	var files ContentReader
+	if img.Files != nil {
+		files = img.Files
+	}
+	owners, err := ReadOwners(img.Trees, files)
+	if err != nil {
+		logrus.Warnf("unable to read owners: %+v", err)
+	}
+	packages, err := AnalyzePackages(img.Trees, final, files)
+	if err != nil {
+		logrus.Warnf("unable to analyze packages: %+v", err)
+	}
+	var finalOwners *filetree.Owners
+	if len(owners) > 0 {
+		finalOwners = owners[len(owners)-1]
+	}
+
+	auditOptions := filetree.DefaultAuditOptions()
+	auditOptions.Owners = finalOwners
+	findings, err := filetree.Audit(img.Trees, final, auditOptions)
+	if err != nil {
+		return nil, err
+	}
+	findings.SetOwners(finalOwners)
+
 	return &AnalysisResult{
 		Layers:            img.Layers,
 		RefTrees:          img.Trees,
+		FinalTree:         final,
+		Content:           img.Content,
 		Efficiency:        efficiency,
 		UserSizeByes:      userSizeBytes,
 		SizeBytes:         sizeBytes,
 		WastedBytes:       wastedBytes,
 		WastedUserPercent: float64(wastedBytes) / float64(userSizeBytes),
 		Inefficiencies:    inefficiencies,
+		Findings:          findings,
+		Packages:          packages,
+		Owners:            owners,
+		PathErrors:        pathErrors,
+		Config:            img.Config,
+		ContainerConfig:   img.ContainerConfig,
 	}, nil
 }
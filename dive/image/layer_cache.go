@@ -0,0 +1,73 @@
+package image
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/wagoodman/dive/dive/filetree"
+)
+
+// LayerCache holds the parsed file trees of layers, so that the layers shared by several images (e.g. a common base
+// image) are parsed once. Layers are keyed by their name within the image source along with their size (e.g. the layer
+// tar path of a docker archive, which is derived from the layer contents and the layers beneath it). The trees are
+// shared by every image given them, so they must not be modified (filetree.CheckPaths copies a layer tree before
+// removing any of its entries).
+type LayerCache struct {
+	lock   sync.Mutex
+	layers map[string]cachedLayer
+	hits   int
+}
+
+// cachedLayer is a parsed layer: the tree along with the files captured while parsing it (see IsLayerFile).
+type cachedLayer struct {
+	tree  *filetree.FileTree
+	files []LayerFile
+}
+
+func NewLayerCache() *LayerCache {
+	return &LayerCache{
+		layers: make(map[string]cachedLayer),
+	}
+}
+
+func layerCacheKey(name string, size int64) string {
+	return fmt.Sprintf("%s:%d", name, size)
+}
+
+// Get returns the (shared) tree of the given layer, along with the files captured while parsing it, when it has been
+// parsed before.
+func (cache *LayerCache) Get(name string, size int64) (*filetree.FileTree, []LayerFile, bool) {
+	if cache == nil {
+		return nil, nil, false
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	layer, exists := cache.layers[layerCacheKey(name, size)]
+	if !exists {
+		return nil, nil, false
+	}
+	cache.hits++
+	return layer.tree, layer.files, true
+}
+
+// Add keeps the given (just parsed) layer tree and captured files for the images fetched later.
+func (cache *LayerCache) Add(name string, size int64, tree *filetree.FileTree, files []LayerFile) {
+	if cache == nil {
+		return
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.layers[layerCacheKey(name, size)] = cachedLayer{tree: tree, files: files}
+}
+
+// Hits returns the number of layers that were taken from the cache rather than parsed again.
+func (cache *LayerCache) Hits() int {
+	if cache == nil {
+		return 0
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	return cache.hits
+}
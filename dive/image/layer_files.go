@@ -0,0 +1,47 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+)
+
+// MaxLayerFileSize is the largest file captured while parsing a layer (see IsLayerFile), larger files are not captured.
+const MaxLayerFileSize = 64 * 1024 * 1024
+
+// IsLayerFile indicates if the given layer entry path (e.g. "/etc/passwd") is one of the few small files read by the
+// analysis: the owner files (see ReadOwners) and the package databases (see AnalyzePackages). Image sources capture
+// these while parsing each layer, so that the analysis does not read the image again.
+func IsLayerFile(p string) bool {
+	if _, exists := ownerFiles[p]; exists {
+		return true
+	}
+	return isPackageFile(p)
+}
+
+// LayerFile is a file captured while parsing a layer (see IsLayerFile).
+type LayerFile struct {
+	Header  tar.Header
+	Content []byte
+}
+
+// LayerFiles are the files captured from each layer (by layer index), which are read like the layer contents of the
+// image (with only the captured files as entries).
+type LayerFiles [][]LayerFile
+
+// ReadLayers visits the captured files of the given layers (see ContentReader).
+func (files LayerFiles) ReadLayers(layerIndexes []int, visitor LayerEntryVisitor) error {
+	for _, layerIdx := range layerIndexes {
+		if layerIdx < 0 || layerIdx >= len(files) {
+			return fmt.Errorf("invalid layer index: %d", layerIdx)
+		}
+		for _, file := range files[layerIdx] {
+			header := file.Header
+			err := visitor(layerIdx, &header, bytes.NewReader(file.Content))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
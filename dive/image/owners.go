@@ -0,0 +1,123 @@
+package image
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/dive/filetree"
+)
+
+const (
+	passwdPath = "/etc/passwd"
+	groupPath  = "/etc/group"
+)
+
+// ownerFiles are the paths read to resolve file owners to names, along with the whiteouts that remove each path.
+var ownerFiles = map[string][]string{
+	passwdPath: {"/etc/.wh.passwd", "/.wh.etc"},
+	groupPath:  {"/etc/.wh.group", "/.wh.etc"},
+}
+
+// ReadOwners reads /etc/passwd and /etc/group as of each layer, so uids and gids can be shown as user and group names
+// (index N are the names within the image stacked from layer 0 to N). Layers without either file have no entry (nil),
+// and nothing is returned when the owner files were not captured (see IsLayerFile).
+func ReadOwners(trees []*filetree.FileTree, content ContentReader) ([]*filetree.Owners, error) {
+	reader := newOwnersReader(trees)
+	if len(reader.layerIdxs) == 0 {
+		return nil, nil
+	}
+	if content == nil {
+		logrus.Debug("the image source does not capture the owner files, owners are not resolved to names")
+		return nil, nil
+	}
+	err := content.ReadLayers(reader.layerIdxs, reader.visit)
+	if err != nil {
+		return nil, err
+	}
+	return reader.owners(), nil
+}
+
+// ownersReader collects the owner files of the layers that change them (see ReadOwners).
+type ownersReader struct {
+	trees []*filetree.FileTree
+	// changes are the owner files added (true) or removed (false) by each layer
+	changes []map[string]bool
+	// layerIdxs are the layers that add an owner file, which are the only layers read
+	layerIdxs []int
+	contents  map[int]map[string][]byte
+}
+
+func newOwnersReader(trees []*filetree.FileTree) *ownersReader {
+	reader := &ownersReader{
+		trees:    trees,
+		changes:  make([]map[string]bool, len(trees)),
+		contents: make(map[int]map[string][]byte),
+	}
+
+	// determine which layers add (or remove) the owner files
+	for layerIdx, tree := range trees {
+		reader.changes[layerIdx] = make(map[string]bool)
+		for p, whiteouts := range ownerFiles {
+			for _, whiteout := range whiteouts {
+				if _, err := tree.GetNode(whiteout); err == nil {
+					reader.changes[layerIdx][p] = false
+				}
+			}
+			// a layer may remove a file (or its directory) and add it again
+			if node, err := tree.GetNode(p); err == nil && !node.IsImplied() {
+				reader.changes[layerIdx][p] = true
+			}
+		}
+		for _, added := range reader.changes[layerIdx] {
+			if added {
+				reader.layerIdxs = append(reader.layerIdxs, layerIdx)
+				break
+			}
+		}
+	}
+	return reader
+}
+
+// wanted indicates if the given path is an owner file.
+func (reader *ownersReader) wanted(p string) bool {
+	_, exists := ownerFiles[p]
+	return exists
+}
+
+// visit keeps the contents of the owner files (see LayerEntryVisitor).
+func (reader *ownersReader) visit(layerIdx int, header *tar.Header, content io.Reader) error {
+	p := entryPath(header)
+	if !reader.wanted(p) || (header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA) {
+		return nil
+	}
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	if _, exists := reader.contents[layerIdx]; !exists {
+		reader.contents[layerIdx] = make(map[string][]byte)
+	}
+	reader.contents[layerIdx][p] = data
+	return nil
+}
+
+// owners returns the names as of each layer from the owner files read.
+func (reader *ownersReader) owners() []*filetree.Owners {
+	owners := make([]*filetree.Owners, len(reader.trees))
+	current := make(map[string][]byte)
+	for layerIdx := range reader.trees {
+		for p, added := range reader.changes[layerIdx] {
+			if added {
+				current[p] = reader.contents[layerIdx][p]
+			} else {
+				delete(current, p)
+			}
+		}
+		if len(current) > 0 {
+			owners[layerIdx] = filetree.ParseOwners(current[passwdPath], current[groupPath])
+		}
+	}
+	return owners
+}
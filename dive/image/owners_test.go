@@ -0,0 +1,56 @@
+package image
+
+import (
+	"testing"
+)
+
+func TestReadOwners(t *testing.T) {
+	content := testContent{
+		{
+			file("bin/sh", "#!"),
+		},
+		{
+			file("etc/passwd", "root:x:0:0::/root:/bin/sh\n"),
+			file("etc/group", "root:x:0:\n"),
+		},
+		{
+			file("etc/passwd", "root:x:0:0::/root:/bin/sh\napp:x:1000:1000::/app:/bin/sh\n"),
+		},
+		{
+			file("app/data", "data"),
+		},
+		{
+			file("etc/.wh.group", ""),
+		},
+		{
+			file(".wh.etc", ""),
+		},
+	}
+	trees := testTrees(t, content)
+
+	owners, err := ReadOwners(trees, content)
+	if err != nil {
+		t.Fatalf("unable to read owners: %+v", err)
+	}
+	if len(owners) != len(trees) {
+		t.Fatalf("expected owners for %d layers, got %d", len(trees), len(owners))
+	}
+
+	// layers without either file have no owners (nil)
+	expected := []string{"", "1000:root", "app:root", "app:root", "app:0", ""}
+	for layerIdx, owner := range owners {
+		var actual string
+		if owner != nil {
+			actual = owner.Format(1000, 0)
+		}
+		if actual != expected[layerIdx] {
+			t.Errorf("layer %d: expected %q, got %q", layerIdx, expected[layerIdx], actual)
+		}
+	}
+
+	// the layer contents are required to read the names
+	owners, err = ReadOwners(trees, nil)
+	if err != nil || owners != nil {
+		t.Errorf("expected no owners without content, got %+v (%v)", owners, err)
+	}
+}
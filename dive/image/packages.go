@@ -0,0 +1,484 @@
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/dive/filetree"
+)
+
+// PackageManager is the OS package manager that installed a package.
+type PackageManager string
+
+const (
+	PackageManagerDpkg PackageManager = "dpkg"
+	PackageManagerApk  PackageManager = "apk"
+	PackageManagerRpm  PackageManager = "rpm"
+)
+
+// PackageFormat is the column layout used when showing packages.
+const PackageFormat = "%-5s %-6s %9s %7s  %-32s %s"
+
+// Package is an OS package installed within the final image, along with the files it owns.
+type Package struct {
+	Manager PackageManager
+	Name    string
+	Version string
+	// LayerIndex is the layer that installed the package (the layer whose package database first lists the package)
+	LayerIndex int
+	// Size is the sum of the owned files (as found in the final image)
+	Size uint64
+	// Files are the owned paths within the final image (excluding directories)
+	Files []string
+}
+
+// String shows the package using the PackageFormat column layout.
+func (pkg *Package) String() string {
+	return fmt.Sprintf(PackageFormat,
+		pkg.Manager,
+		fmt.Sprintf("%d", pkg.LayerIndex),
+		humanize.Bytes(pkg.Size),
+		fmt.Sprintf("%d", len(pkg.Files)),
+		pkg.Name,
+		pkg.Version)
+}
+
+// PackageReport attributes the files of the final image to the OS packages that installed them.
+type PackageReport struct {
+	// Packages are ordered largest first
+	Packages []*Package
+	// UnownedFiles are the files within the final image that no package claims, excluding the package databases
+	// (ordered by path)
+	UnownedFiles []string
+	UnownedSize  uint64
+	// UnsupportedDatabases are the package databases within the final image that cannot be read (e.g. the rpm sqlite
+	// database), their packages are not listed (nor are the unowned files when no other package database is found)
+	UnsupportedDatabases []string
+}
+
+// packageEntry is a single package as listed within a package database.
+type packageEntry struct {
+	name    string
+	version string
+	arch    string
+	files   []string
+}
+
+// packageDatabase describes where a package manager keeps the list of installed packages and how to read it.
+type packageDatabase struct {
+	manager PackageManager
+	path    string
+	parse   func([]byte) ([]packageEntry, error)
+}
+
+var packageDatabases = []packageDatabase{
+	{manager: PackageManagerDpkg, path: "/var/lib/dpkg/status", parse: parseDpkgStatus},
+	{manager: PackageManagerApk, path: "/lib/apk/db/installed", parse: parseApkInstalled},
+	{manager: PackageManagerRpm, path: "/var/lib/rpm/Packages", parse: parseRpmDatabase},
+}
+
+// unsupportedPackageDatabases are package databases that are recognized but cannot be read.
+var unsupportedPackageDatabases = []string{
+	"/var/lib/rpm/rpmdb.sqlite",
+	"/usr/lib/sysimage/rpm/rpmdb.sqlite",
+	"/var/lib/rpm/Packages.db",
+	"/usr/lib/sysimage/rpm/Packages.db",
+}
+
+// dpkgFileListDir holds a "<package>.list" (or "<package>:<arch>.list") file for each installed dpkg package.
+const dpkgFileListDir = "/var/lib/dpkg/info/"
+
+// AnalyzePackages reads the package databases (dpkg, apk, and rpm) of every layer to determine which package owns
+// each file of the final image (the given final tree) and which layer installed each package. No report is returned
+// when the final image has no package database (or when the package databases were not captured, see IsLayerFile).
+func AnalyzePackages(trees []*filetree.FileTree, final *filetree.FileTree, content ContentReader) (*PackageReport, error) {
+	reader, err := newPackageReader(trees, final)
+	if err != nil || reader == nil {
+		return nil, err
+	}
+	if len(reader.databases) == 0 {
+		return &PackageReport{UnsupportedDatabases: reader.unsupported}, nil
+	}
+	if content == nil {
+		logrus.Warn("the image source does not capture the package databases, files are not attributed to packages")
+		return nil, nil
+	}
+	err = content.ReadLayers(reader.layerIdxs, reader.visit)
+	if err != nil {
+		return nil, err
+	}
+	return reader.report()
+}
+
+// packageReader collects the package databases (and dpkg file lists) of the layers that change them (see
+// AnalyzePackages).
+type packageReader struct {
+	final *filetree.FileTree
+	// databases are the package databases within the final image, unsupported are those that cannot be read
+	databases   []packageDatabase
+	unsupported []string
+	// layerIdxs are the layers that change a package database (or the dpkg file lists), which are the only layers read
+	layerIdxs []int
+	// entries are the packages listed by each layer's copy of each database, lists are the dpkg file lists
+	entries map[int]map[PackageManager][]packageEntry
+	lists   map[string]map[int][]string
+	// parseErr is the first package database that could not be parsed (noted while reading, reported with the result)
+	parseErr error
+}
+
+// newPackageReader determines the package databases of the final image and the layers to read, nil is returned when
+// the final image has no package database (no layers are read when only unsupported package databases are found).
+func newPackageReader(trees []*filetree.FileTree, final *filetree.FileTree) (*packageReader, error) {
+	if len(trees) == 0 || final == nil {
+		return nil, nil
+	}
+
+	reader := &packageReader{
+		final:   final,
+		entries: make(map[int]map[PackageManager][]packageEntry),
+		lists:   make(map[string]map[int][]string),
+	}
+	for _, p := range unsupportedPackageDatabases {
+		if _, err := final.GetNode(p); err == nil {
+			logrus.Warnf("unsupported package database format: %s", p)
+			reader.unsupported = append(reader.unsupported, p)
+		}
+	}
+	for _, database := range packageDatabases {
+		if _, err := final.GetNode(database.path); err == nil {
+			reader.databases = append(reader.databases, database)
+		}
+	}
+	if len(reader.databases) == 0 {
+		if len(reader.unsupported) == 0 {
+			return nil, nil
+		}
+		return reader, nil
+	}
+
+	// determine which layers change a package database (or the dpkg file lists)
+	for layerIdx, tree := range trees {
+		changed := false
+		err := tree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+			if !changed && !node.IsWhiteout() && reader.wanted(node.Path()) {
+				changed = true
+			}
+			return nil
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			reader.layerIdxs = append(reader.layerIdxs, layerIdx)
+		}
+	}
+	return reader, nil
+}
+
+// isPackageFile indicates if the given path is a (supported) package database or a dpkg file list.
+func isPackageFile(p string) bool {
+	if strings.HasPrefix(p, dpkgFileListDir) && strings.HasSuffix(p, ".list") {
+		return true
+	}
+	for _, database := range packageDatabases {
+		if p == database.path {
+			return true
+		}
+	}
+	return false
+}
+
+// wanted indicates if the given path is a package database (or a dpkg file list) to read.
+func (reader *packageReader) wanted(p string) bool {
+	if strings.HasPrefix(p, dpkgFileListDir) && strings.HasSuffix(p, ".list") {
+		return true
+	}
+	for _, database := range reader.databases {
+		if p == database.path {
+			return true
+		}
+	}
+	return false
+}
+
+// visit parses the package databases and dpkg file lists (see LayerEntryVisitor). A database that cannot be parsed
+// does not stop the read, the error is reported along with the result.
+func (reader *packageReader) visit(layerIdx int, header *tar.Header, content io.Reader) error {
+	p := entryPath(header)
+	if (header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA) || !reader.wanted(p) {
+		return nil
+	}
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(p, dpkgFileListDir) {
+		name := strings.TrimSuffix(strings.TrimPrefix(p, dpkgFileListDir), ".list")
+		if _, exists := reader.lists[name]; !exists {
+			reader.lists[name] = make(map[int][]string)
+		}
+		reader.lists[name][layerIdx] = parseDpkgFileList(data)
+		return nil
+	}
+	for _, database := range reader.databases {
+		if p != database.path {
+			continue
+		}
+		parsed, err := database.parse(data)
+		if err != nil {
+			if reader.parseErr == nil {
+				reader.parseErr = fmt.Errorf("unable to read %s (layer %d): %v", p, layerIdx, err)
+			}
+			return nil
+		}
+		if _, exists := reader.entries[layerIdx]; !exists {
+			reader.entries[layerIdx] = make(map[PackageManager][]packageEntry)
+		}
+		reader.entries[layerIdx][database.manager] = parsed
+	}
+	return nil
+}
+
+// report attributes the files of the final image to the packages read.
+func (reader *packageReader) report() (*PackageReport, error) {
+	if reader.parseErr != nil {
+		return nil, reader.parseErr
+	}
+	report := &PackageReport{UnsupportedDatabases: reader.unsupported}
+	owners := make(map[string]*Package)
+	for _, database := range reader.databases {
+		for _, pkg := range installedPackages(database.manager, reader.layerIdxs, reader.entries, reader.lists) {
+			pkg.entry.files = append(pkg.entry.files, pkg.listedFiles...)
+			for _, p := range pkg.entry.files {
+				node := lookupPackagePath(reader.final, p)
+				if node == nil || node.Data.FileInfo.IsDir || node.IsImplied() {
+					continue
+				}
+				if _, exists := owners[node.Path()]; exists {
+					continue
+				}
+				owners[node.Path()] = pkg.Package
+				pkg.Size += uint64(node.Data.FileInfo.Size)
+				pkg.Files = append(pkg.Files, node.Path())
+			}
+			sort.Strings(pkg.Files)
+			report.Packages = append(report.Packages, pkg.Package)
+		}
+	}
+
+	// the package databases are maintained by the package managers themselves
+	managed := func(p string) bool {
+		for _, database := range reader.databases {
+			if strings.HasPrefix(p, path.Dir(database.path)+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	err := reader.final.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+		if node.Data.FileInfo.IsDir || node.IsImplied() || managed(node.Path()) {
+			return nil
+		}
+		if _, exists := owners[node.Path()]; !exists {
+			report.UnownedFiles = append(report.UnownedFiles, node.Path())
+			report.UnownedSize += uint64(node.Data.FileInfo.Size)
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(report.UnownedFiles)
+
+	sort.SliceStable(report.Packages, func(i, j int) bool {
+		if report.Packages[i].Size != report.Packages[j].Size {
+			return report.Packages[i].Size > report.Packages[j].Size
+		}
+		return report.Packages[i].Name < report.Packages[j].Name
+	})
+
+	return report, nil
+}
+
+// installedPackage is a package of the final image along with the package database entry that describes it.
+type installedPackage struct {
+	*Package
+	entry       packageEntry
+	listedFiles []string
+}
+
+// installedPackages returns the packages listed by the topmost copy of the given package database, noting the layer
+// that installed each package: the lowest layer after which every copy of the database lists the package.
+func installedPackages(manager PackageManager, layerIdxs []int, entries map[int]map[PackageManager][]packageEntry, lists map[string]map[int][]string) []*installedPackage {
+	installedBy := make(map[string]int)
+	var current []packageEntry
+	for _, layerIdx := range layerIdxs {
+		listed, exists := entries[layerIdx][manager]
+		if !exists {
+			continue
+		}
+		next := make(map[string]int)
+		for _, entry := range listed {
+			if layer, exists := installedBy[entry.name]; exists {
+				next[entry.name] = layer
+			} else {
+				next[entry.name] = layerIdx
+			}
+		}
+		installedBy = next
+		current = listed
+	}
+
+	packages := make([]*installedPackage, 0, len(current))
+	for _, entry := range current {
+		pkg := &installedPackage{
+			Package: &Package{
+				Manager:    manager,
+				Name:       entry.name,
+				Version:    entry.version,
+				LayerIndex: installedBy[entry.name],
+			},
+			entry: entry,
+		}
+		if manager == PackageManagerDpkg {
+			pkg.listedFiles = topmostList(lists, entry.name+":"+entry.arch)
+			if pkg.listedFiles == nil {
+				pkg.listedFiles = topmostList(lists, entry.name)
+			}
+		}
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// topmostList returns the dpkg file list of the given name from the highest layer that provides it.
+func topmostList(lists map[string]map[int][]string, name string) []string {
+	topmost := -1
+	for layerIdx := range lists[name] {
+		if layerIdx > topmost {
+			topmost = layerIdx
+		}
+	}
+	if topmost < 0 {
+		return nil
+	}
+	return lists[name][topmost]
+}
+
+// lookupPackagePath returns the node for the given package path within the final image. Package databases list paths
+// as installed, which may be beneath a directory symlink (e.g. /bin/sh with /bin linked to /usr/bin), so the parent
+// directory is resolved when the path does not exist as given.
+func lookupPackagePath(tree *filetree.FileTree, p string) *filetree.FileNode {
+	if node, err := tree.GetNode(p); err == nil {
+		return node
+	}
+	parent, _ := tree.ResolvePath(path.Dir(p))
+	if parent == nil {
+		return nil
+	}
+	if node, err := tree.GetNode(path.Join(parent.Path(), path.Base(p))); err == nil {
+		return node
+	}
+	return nil
+}
+
+// parseStanzas splits a file of "Key: value" (dpkg) or "K:value" (apk) stanzas separated by blank lines, calling the
+// given function for each line (with an empty key at the end of each stanza).
+func parseStanzas(data []byte, separator string, visitor func(key, value string)) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			visitor("", "")
+			continue
+		}
+		// continuation lines (dpkg) are not of interest
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		fields := strings.SplitN(line, separator, 2)
+		if len(fields) != 2 {
+			continue
+		}
+		visitor(fields[0], strings.TrimSpace(fields[1]))
+	}
+	visitor("", "")
+	return scanner.Err()
+}
+
+// parseDpkgStatus reads the installed packages from a dpkg status file (files are listed in separate list files).
+func parseDpkgStatus(data []byte) ([]packageEntry, error) {
+	var entries []packageEntry
+	var entry packageEntry
+	var installed bool
+	err := parseStanzas(data, ":", func(key, value string) {
+		switch key {
+		case "Package":
+			entry.name = value
+		case "Version":
+			entry.version = value
+		case "Architecture":
+			entry.arch = value
+		case "Status":
+			// e.g. "install ok installed" (as opposed to "deinstall ok config-files")
+			fields := strings.Fields(value)
+			installed = len(fields) == 3 && fields[2] == "installed"
+		case "":
+			if entry.name != "" && installed {
+				entries = append(entries, entry)
+			}
+			entry, installed = packageEntry{}, false
+		}
+	})
+	return entries, err
+}
+
+// parseDpkgFileList reads the paths of a dpkg "info/<package>.list" file.
+func parseDpkgFileList(data []byte) []string {
+	var files []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "/." {
+			continue
+		}
+		files = append(files, path.Clean(line))
+	}
+	return files
+}
+
+// parseApkInstalled reads the installed packages (and their files) from an apk installed database.
+func parseApkInstalled(data []byte) ([]packageEntry, error) {
+	var entries []packageEntry
+	var entry packageEntry
+	var dir string
+	err := parseStanzas(data, ":", func(key, value string) {
+		switch key {
+		case "P":
+			entry.name = value
+		case "V":
+			entry.version = value
+		case "A":
+			entry.arch = value
+		case "F":
+			dir = value
+		case "R":
+			entry.files = append(entry.files, path.Join("/", dir, value))
+		case "":
+			if entry.name != "" {
+				entries = append(entries, entry)
+			}
+			entry, dir = packageEntry{}, ""
+		}
+	})
+	return entries, err
+}
@@ -0,0 +1,234 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// testRpmHeader builds an rpm header blob with the given string (and string array) tags and a dir index tag.
+func testRpmHeader(strs map[int][]string, dirIndexes []int) []byte {
+	var index, store bytes.Buffer
+	count := 0
+	add := func(tag, kind, count int, value []byte) {
+		for _, field := range []int{tag, kind, store.Len(), count} {
+			_ = binary.Write(&index, binary.BigEndian, uint32(field))
+		}
+		store.Write(value)
+	}
+	for _, tag := range []int{rpmTagName, rpmTagVersion, rpmTagRelease, rpmTagBasenames, rpmTagDirNames} {
+		values, exists := strs[tag]
+		if !exists {
+			continue
+		}
+		var value bytes.Buffer
+		for _, s := range values {
+			value.WriteString(s)
+			value.WriteByte(0)
+		}
+		kind := rpmTypeString
+		if tag == rpmTagBasenames || tag == rpmTagDirNames {
+			kind = rpmTypeStringArray
+		}
+		add(tag, kind, len(values), value.Bytes())
+		count++
+	}
+	if dirIndexes != nil {
+		var value bytes.Buffer
+		for _, dirIndex := range dirIndexes {
+			_ = binary.Write(&value, binary.BigEndian, uint32(dirIndex))
+		}
+		add(rpmTagDirIndexes, rpmTypeInt32, len(dirIndexes), value.Bytes())
+		count++
+	}
+
+	var blob bytes.Buffer
+	_ = binary.Write(&blob, binary.BigEndian, uint32(count))
+	_ = binary.Write(&blob, binary.BigEndian, uint32(store.Len()))
+	blob.Write(index.Bytes())
+	blob.Write(store.Bytes())
+	return blob.Bytes()
+}
+
+// testRpmDatabase builds a (little endian) berkeley db hash database holding the given header blobs, each stored either
+// on the hash page or within a chain of overflow pages.
+func testRpmDatabase(onPage bool, blobs ...[]byte) []byte {
+	const pageSize = 512
+	var pages [][]byte
+	newPage := func(pageType byte) []byte {
+		page := make([]byte, pageSize)
+		page[25] = pageType
+		pages = append(pages, page)
+		return page
+	}
+
+	meta := newPage(8)
+	hash := newPage(bdbHashPage)
+	binary.LittleEndian.PutUint32(meta[12:], bdbHashMagic)
+	binary.LittleEndian.PutUint32(meta[20:], pageSize)
+
+	itemOffset := pageSize
+	for idx, blob := range blobs {
+		// the key (stored on the page)
+		itemOffset -= 8
+		hash[itemOffset] = 1
+		binary.LittleEndian.PutUint16(hash[bdbPageHeaderSize+idx*4:], uint16(itemOffset))
+
+		if onPage {
+			itemOffset -= 1 + len(blob)
+			hash[itemOffset] = bdbKeyDataItem
+			copy(hash[itemOffset+1:], blob)
+			binary.LittleEndian.PutUint16(hash[bdbPageHeaderSize+idx*4+2:], uint16(itemOffset))
+			continue
+		}
+
+		// the value (stored off page)
+		itemOffset -= 12
+		hash[itemOffset] = bdbOffPageItem
+		binary.LittleEndian.PutUint32(hash[itemOffset+4:], uint32(len(pages)))
+		binary.LittleEndian.PutUint32(hash[itemOffset+8:], uint32(len(blob)))
+		binary.LittleEndian.PutUint16(hash[bdbPageHeaderSize+idx*4+2:], uint16(itemOffset))
+
+		for remaining := blob; len(remaining) > 0; {
+			page := newPage(bdbOverflowPage)
+			used := copy(page[bdbPageHeaderSize:], remaining)
+			remaining = remaining[used:]
+			if len(remaining) > 0 {
+				binary.LittleEndian.PutUint32(page[16:], uint32(len(pages)))
+			} else {
+				binary.LittleEndian.PutUint16(page[22:], uint16(used))
+			}
+		}
+	}
+	binary.LittleEndian.PutUint16(hash[20:], uint16(len(blobs)*2))
+	binary.LittleEndian.PutUint32(meta[32:], uint32(len(pages)-1))
+
+	return bytes.Join(pages, nil)
+}
+
+func TestAnalyzePackages(t *testing.T) {
+	dpkgStatus := func(packages ...string) string {
+		var status string
+		for _, pkg := range packages {
+			status += "Package: " + pkg + "\nStatus: install ok installed\nArchitecture: amd64\nVersion: 1.0\nDescription: a package\n multi-line\n\n"
+		}
+		return status + "Package: purged\nStatus: deinstall ok config-files\nVersion: 0.1\n"
+	}
+
+	table := map[string]struct {
+		content     testContent
+		expected    []Package
+		unowned     []string
+		unsupported []string
+	}{
+		"dpkg": {
+			content: testContent{
+				{
+					file("var/lib/dpkg/status", dpkgStatus("base")),
+					file("var/lib/dpkg/info/base.list", "/.\n/etc\n/etc/base.conf\n/bin/base\n"),
+					{header: tar.Header{Typeflag: tar.TypeSymlink, Name: "usr/bin", Linkname: "../bin"}},
+					file("bin/base", "base-binary"),
+					file("etc/base.conf", "conf"),
+					file("etc/hostname", "host"),
+				},
+				{
+					file("var/lib/dpkg/status", dpkgStatus("base", "curl")),
+					file("var/lib/dpkg/info/curl:amd64.list", "/usr/bin/curl\n/usr/share/doc/curl/missing\n"),
+					file("bin/curl", "curl-binary-data"),
+					file("app/main", "app"),
+				},
+			},
+			expected: []Package{
+				{Manager: PackageManagerDpkg, Name: "curl", Version: "1.0", LayerIndex: 1, Size: 16, Files: []string{"/bin/curl"}},
+				{Manager: PackageManagerDpkg, Name: "base", Version: "1.0", LayerIndex: 0, Size: 15, Files: []string{"/bin/base", "/etc/base.conf"}},
+			},
+			unowned: []string{"/app/main", "/etc/hostname", "/usr/bin"},
+		},
+		"apk": {
+			content: testContent{
+				{
+					file("lib/apk/db/installed", "P:musl\nV:1.2.3-r0\nA:x86_64\nF:lib\nR:ld-musl.so.1\n\nP:busybox\nV:1.36.1-r2\nF:bin\nR:busybox\n"),
+					file("lib/ld-musl.so.1", "musl"),
+					file("bin/busybox", "busybox"),
+				},
+				{
+					// busybox is reinstalled (the package remains installed throughout)
+					file("lib/apk/db/installed", "P:musl\nV:1.2.3-r0\nF:lib\nR:ld-musl.so.1\n\nP:busybox\nV:1.36.1-r3\nF:bin\nR:busybox\n\nP:tzdata\nV:2024a-r0\nF:usr/share/zoneinfo\nR:UTC\n"),
+					file("bin/busybox", "busybox-r3"),
+					file("usr/share/zoneinfo/UTC", "utc"),
+				},
+			},
+			expected: []Package{
+				{Manager: PackageManagerApk, Name: "busybox", Version: "1.36.1-r3", LayerIndex: 0, Size: 10, Files: []string{"/bin/busybox"}},
+				{Manager: PackageManagerApk, Name: "musl", Version: "1.2.3-r0", LayerIndex: 0, Size: 4, Files: []string{"/lib/ld-musl.so.1"}},
+				{Manager: PackageManagerApk, Name: "tzdata", Version: "2024a-r0", LayerIndex: 1, Size: 3, Files: []string{"/usr/share/zoneinfo/UTC"}},
+			},
+			unowned: nil,
+		},
+		"rpm": {
+			content: testContent{
+				{
+					file("var/lib/rpm/Packages", string(testRpmDatabase(false,
+						testRpmHeader(map[int][]string{
+							rpmTagName:      {"bash"},
+							rpmTagVersion:   {"5.1.8"},
+							rpmTagRelease:   {"6.el9"},
+							rpmTagBasenames: {"bash", "bashrc"},
+							rpmTagDirNames:  {"/usr/bin", "/etc"},
+						}, []int{0, 1}),
+					))),
+					file("usr/bin/bash", "bash-binary"),
+					file("etc/bashrc", "rc"),
+				},
+			},
+			expected: []Package{
+				{Manager: PackageManagerRpm, Name: "bash", Version: "5.1.8-6.el9", LayerIndex: 0, Size: 13, Files: []string{"/etc/bashrc", "/usr/bin/bash"}},
+			},
+			unowned: nil,
+		},
+		"rpm sqlite": {
+			content: testContent{
+				{
+					file("usr/lib/sysimage/rpm/rpmdb.sqlite", "SQLite format 3"),
+					file("usr/bin/bash", "bash-binary"),
+				},
+			},
+			expected:    nil,
+			unowned:     nil,
+			unsupported: []string{"/usr/lib/sysimage/rpm/rpmdb.sqlite"},
+		},
+	}
+
+	for name, test := range table {
+		trees := testTrees(t, test.content)
+		report, err := AnalyzePackages(trees, stackAll(t, trees), test.content)
+		if err != nil {
+			t.Fatalf("%s: unable to analyze packages: %+v", name, err)
+		}
+		if report == nil {
+			t.Fatalf("%s: expected a package report", name)
+		}
+		if len(report.Packages) != len(test.expected) {
+			t.Fatalf("%s: expected %d packages, got %d", name, len(test.expected), len(report.Packages))
+		}
+		for idx, pkg := range report.Packages {
+			if !reflect.DeepEqual(*pkg, test.expected[idx]) {
+				t.Errorf("%s: package %d: expected %+v, got %+v", name, idx, test.expected[idx], *pkg)
+			}
+		}
+		if !reflect.DeepEqual(report.UnownedFiles, test.unowned) {
+			t.Errorf("%s: expected unowned files %v, got %v", name, test.unowned, report.UnownedFiles)
+		}
+		if !reflect.DeepEqual(report.UnsupportedDatabases, test.unsupported) {
+			t.Errorf("%s: expected unsupported databases %v, got %v", name, test.unsupported, report.UnsupportedDatabases)
+		}
+	}
+
+	trees := testTrees(t, testContent{{file("etc/hostname", "host")}})
+	report, err := AnalyzePackages(trees, stackAll(t, trees), nil)
+	if err != nil || report != nil {
+		t.Errorf("expected no report without a package database, got %+v (%v)", report, err)
+	}
+}
@@ -4,15 +4,23 @@ import (
 	"fmt"
 	"github.com/wagoodman/dive/dive/image"
 	"github.com/wagoodman/dive/dive/image/docker"
+	"io"
 	"io/ioutil"
 )
 
-type resolver struct{}
+type resolver struct {
+	layers *image.LayerCache
+}
 
 func NewResolverFromEngine() *resolver {
 	return &resolver{}
 }
 
+// ShareLayers reuses the parsed layers of previously fetched images (see image.LayerSharingResolver).
+func (r *resolver) ShareLayers(layers *image.LayerCache) {
+	r.layers = layers
+}
+
 func (r *resolver) Build(args []string) (*image.Image, error) {
 	id, err := buildImageFromCli(args)
 	if err != nil {
@@ -38,9 +46,22 @@ func (r *resolver) resolveFromDockerArchive(id string) (*image.Image, error) {
 		return nil, err
 	}
 
-	img, err := docker.NewImageArchive(ioutil.NopCloser(reader))
+	archive, err := docker.NewImageArchiveWithLayerCache(ioutil.NopCloser(reader), r.layers)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := archive.ToImage()
 	if err != nil {
 		return nil, err
 	}
-	return img.ToImage()
+	// note: the image contents are not kept on disk, they are saved from podman again when needed
+	img.Content = archive.ContentReader(func() (io.ReadCloser, error) {
+		err, reader := streamPodmanCmd("image", "save", id)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(reader), nil
+	})
+	return img, nil
 }
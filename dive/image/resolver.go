@@ -4,3 +4,10 @@ type Resolver interface {
 	Fetch(id string) (*Image, error)
 	Build(options []string) (*Image, error)
 }
+
+// LayerSharingResolver is a Resolver that can take the layers shared with previously fetched images from a cache,
+// rather than parsing them again.
+type LayerSharingResolver interface {
+	Resolver
+	ShareLayers(layers *LayerCache)
+}
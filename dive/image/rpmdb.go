@@ -0,0 +1,244 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"path"
+)
+
+// the subset of the Berkeley DB hash format used by the rpm "Packages" database (see db_page.h)
+const (
+	bdbHashMagic        = 0x061561
+	bdbPageHeaderSize   = 26
+	bdbHashUnsortedPage = 2
+	bdbOverflowPage     = 7
+	bdbHashPage         = 13
+	bdbKeyDataItem      = 1
+	bdbOffPageItem      = 3
+)
+
+// the rpm header tags (and tag types) used to describe an installed package (see rpmtag.h)
+const (
+	rpmTagName         = 1000
+	rpmTagVersion      = 1001
+	rpmTagRelease      = 1002
+	rpmTagEpoch        = 1003
+	rpmTagArch         = 1022
+	rpmTagOldFilenames = 1027
+	rpmTagDirIndexes   = 1116
+	rpmTagBasenames    = 1117
+	rpmTagDirNames     = 1118
+
+	rpmTypeInt32       = 4
+	rpmTypeString      = 6
+	rpmTypeStringArray = 8
+	rpmTypeI18nString  = 9
+)
+
+// parseRpmDatabase reads every installed package from a Berkeley DB rpm database (/var/lib/rpm/Packages). Each value
+// within the hash database is an rpm header blob describing a single package.
+func parseRpmDatabase(data []byte) ([]packageEntry, error) {
+	if len(data) < 512 {
+		return nil, fmt.Errorf("rpm database is too small")
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(data[12:]) != bdbHashMagic {
+		order = binary.BigEndian
+		if order.Uint32(data[12:]) != bdbHashMagic {
+			return nil, fmt.Errorf("rpm database is not a berkeley db hash database")
+		}
+	}
+
+	pageSize := int(order.Uint32(data[20:]))
+	lastPage := int(order.Uint32(data[32:]))
+	// note: the page count is checked by division, a corrupt page size and count could overflow their product
+	if pageSize < 512 || lastPage >= len(data)/pageSize {
+		return nil, fmt.Errorf("rpm database has an invalid page size (%d) or page count (%d)", pageSize, lastPage+1)
+	}
+	page := func(pageNo int) []byte {
+		return data[pageNo*pageSize : (pageNo+1)*pageSize]
+	}
+
+	var entries []packageEntry
+	for pageNo := 1; pageNo <= lastPage; pageNo++ {
+		current := page(pageNo)
+		pageType := current[25]
+		if pageType != bdbHashPage && pageType != bdbHashUnsortedPage {
+			continue
+		}
+		// items alternate between keys and values, only values are of interest. The item offsets follow the page
+		// header while the items are stored from the end of the page, so an item ends where the previous one starts.
+		itemCount := int(order.Uint16(current[20:]))
+		if bdbPageHeaderSize+itemCount*2 > pageSize {
+			return nil, fmt.Errorf("rpm database page %d has an invalid item count (%d)", pageNo, itemCount)
+		}
+		itemOffset := func(itemIdx int) int {
+			return int(order.Uint16(current[bdbPageHeaderSize+itemIdx*2:]))
+		}
+		for itemIdx := 1; itemIdx < itemCount; itemIdx += 2 {
+			offset, end := itemOffset(itemIdx), itemOffset(itemIdx-1)
+			if offset < bdbPageHeaderSize || offset >= end || end > pageSize {
+				return nil, fmt.Errorf("rpm database page %d has an invalid item offset (%d)", pageNo, offset)
+			}
+
+			var blob []byte
+			switch current[offset] {
+			case bdbKeyDataItem:
+				// small values are stored on the page itself (following the item type)
+				blob = current[offset+1 : end]
+			case bdbOffPageItem:
+				if offset+12 > end {
+					return nil, fmt.Errorf("rpm database page %d has an invalid item offset (%d)", pageNo, offset)
+				}
+				var err error
+				blob, err = readOverflowPages(page, lastPage, int(order.Uint32(current[offset+4:])), order)
+				if err != nil {
+					return nil, err
+				}
+			default:
+				// duplicate sets are not used by rpm
+				continue
+			}
+			entry, err := parseRpmHeader(blob)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// readOverflowPages returns the value stored within the chain of overflow pages starting at the given page.
+func readOverflowPages(page func(int) []byte, lastPage, pageNo int, order binary.ByteOrder) ([]byte, error) {
+	var value []byte
+	for visited := 0; pageNo != 0; visited++ {
+		if pageNo < 0 || pageNo > lastPage || visited > lastPage {
+			return nil, fmt.Errorf("rpm database has an invalid overflow page (%d)", pageNo)
+		}
+		current := page(pageNo)
+		if current[25] != bdbOverflowPage {
+			return nil, fmt.Errorf("rpm database page %d is not an overflow page", pageNo)
+		}
+		next := int(order.Uint32(current[16:]))
+		if next == 0 {
+			// the last page notes how much of the page is used
+			used := int(order.Uint16(current[22:]))
+			if bdbPageHeaderSize+used > len(current) {
+				return nil, fmt.Errorf("rpm database page %d has an invalid length", pageNo)
+			}
+			value = append(value, current[bdbPageHeaderSize:bdbPageHeaderSize+used]...)
+		} else {
+			value = append(value, current[bdbPageHeaderSize:]...)
+		}
+		pageNo = next
+	}
+	return value, nil
+}
+
+// rpmHeaderEntry is a single tag of an rpm header (the value is within the data store of the header).
+type rpmHeaderEntry struct {
+	tag, kind, offset, count int
+}
+
+// parseRpmHeader describes the package of the given rpm header blob (an index of tags followed by a data store, all big
+// endian).
+func parseRpmHeader(blob []byte) (packageEntry, error) {
+	var entry packageEntry
+	if len(blob) < 8 {
+		return entry, fmt.Errorf("rpm header is too small")
+	}
+	indexCount := int(binary.BigEndian.Uint32(blob[0:]))
+	storeSize := int(binary.BigEndian.Uint32(blob[4:]))
+	storeStart := 8 + indexCount*16
+	if indexCount < 0 || storeSize < 0 || storeStart+storeSize > len(blob) {
+		return entry, fmt.Errorf("rpm header has an invalid size")
+	}
+	store := blob[storeStart : storeStart+storeSize]
+
+	tags := make(map[int]rpmHeaderEntry)
+	for idx := 0; idx < indexCount; idx++ {
+		raw := blob[8+idx*16:]
+		tag := rpmHeaderEntry{
+			tag:    int(binary.BigEndian.Uint32(raw[0:])),
+			kind:   int(binary.BigEndian.Uint32(raw[4:])),
+			offset: int(binary.BigEndian.Uint32(raw[8:])),
+			count:  int(binary.BigEndian.Uint32(raw[12:])),
+		}
+		if tag.offset < 0 || tag.offset > len(store) || tag.count < 0 {
+			return entry, fmt.Errorf("rpm header tag %d has an invalid offset", tag.tag)
+		}
+		tags[tag.tag] = tag
+	}
+
+	stringsOf := func(tagID int) []string {
+		tag, exists := tags[tagID]
+		if !exists || (tag.kind != rpmTypeString && tag.kind != rpmTypeStringArray && tag.kind != rpmTypeI18nString) {
+			return nil
+		}
+		// note: every value takes at least its terminator, so a corrupt count cannot exceed the remaining store
+		remaining := store[tag.offset:]
+		capacity := tag.count
+		if capacity > len(remaining) {
+			capacity = len(remaining)
+		}
+		values := make([]string, 0, capacity)
+		for idx := 0; idx < tag.count; idx++ {
+			end := bytes.IndexByte(remaining, 0)
+			if end < 0 {
+				break
+			}
+			values = append(values, string(remaining[:end]))
+			remaining = remaining[end+1:]
+		}
+		return values
+	}
+	stringOf := func(tagID int) string {
+		values := stringsOf(tagID)
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+	intsOf := func(tagID int) []int {
+		tag, exists := tags[tagID]
+		if !exists || tag.kind != rpmTypeInt32 || tag.count > (len(store)-tag.offset)/4 {
+			return nil
+		}
+		values := make([]int, tag.count)
+		for idx := range values {
+			values[idx] = int(int32(binary.BigEndian.Uint32(store[tag.offset+idx*4:])))
+		}
+		return values
+	}
+
+	entry.name = stringOf(rpmTagName)
+	if entry.name == "" {
+		return entry, fmt.Errorf("rpm header has no package name")
+	}
+	entry.arch = stringOf(rpmTagArch)
+	entry.version = stringOf(rpmTagVersion)
+	if release := stringOf(rpmTagRelease); release != "" {
+		entry.version += "-" + release
+	}
+	if epochs := intsOf(rpmTagEpoch); len(epochs) > 0 && epochs[0] != 0 {
+		entry.version = fmt.Sprintf("%d:%s", epochs[0], entry.version)
+	}
+
+	// files are either stored as a directory index per base name, or (for old packages) as complete paths
+	baseNames, dirNames, dirIndexes := stringsOf(rpmTagBasenames), stringsOf(rpmTagDirNames), intsOf(rpmTagDirIndexes)
+	if len(baseNames) > 0 && len(baseNames) == len(dirIndexes) {
+		for idx, baseName := range baseNames {
+			if dirIndexes[idx] < 0 || dirIndexes[idx] >= len(dirNames) {
+				return entry, fmt.Errorf("rpm header for '%s' has an invalid directory index", entry.name)
+			}
+			entry.files = append(entry.files, path.Join(dirNames[dirIndexes[idx]], baseName))
+		}
+	} else {
+		entry.files = stringsOf(rpmTagOldFilenames)
+	}
+
+	return entry, nil
+}
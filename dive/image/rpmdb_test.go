@@ -0,0 +1,153 @@
+package image
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func testRpmHeaders() [][]byte {
+	return [][]byte{
+		testRpmHeader(map[int][]string{
+			rpmTagName:      {"bash"},
+			rpmTagVersion:   {"5.1.8"},
+			rpmTagRelease:   {"6.el9"},
+			rpmTagBasenames: {"bash", "bashrc"},
+			rpmTagDirNames:  {"/usr/bin", "/etc"},
+		}, []int{0, 1}),
+		testRpmHeader(map[int][]string{
+			rpmTagName:    {"setup"},
+			rpmTagVersion: {"2.13.7"},
+		}, nil),
+	}
+}
+
+func TestParseRpmDatabase(t *testing.T) {
+	expected := []packageEntry{
+		{name: "bash", version: "5.1.8-6.el9", files: []string{"/usr/bin/bash", "/etc/bashrc"}},
+		{name: "setup", version: "2.13.7"},
+	}
+
+	table := map[string]bool{
+		"overflow pages": false,
+		"on page":        true,
+	}
+
+	for name, onPage := range table {
+		entries, err := parseRpmDatabase(testRpmDatabase(onPage, testRpmHeaders()...))
+		if err != nil {
+			t.Fatalf("%s: unable to parse database: %+v", name, err)
+		}
+		if !reflect.DeepEqual(entries, expected) {
+			t.Errorf("%s: expected %+v, got %+v", name, expected, entries)
+		}
+	}
+}
+
+func TestParseRpmDatabase_Corrupt(t *testing.T) {
+	const pageSize = 512
+
+	table := map[string]func(data []byte) []byte{
+		"truncated": func(data []byte) []byte {
+			return data[:pageSize+100]
+		},
+		"overflowing page count": func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[20:], 1<<31)
+			binary.LittleEndian.PutUint32(data[32:], 1<<31)
+			return data
+		},
+		"item count beyond the page": func(data []byte) []byte {
+			binary.LittleEndian.PutUint16(data[pageSize+20:], 0xffff)
+			return data
+		},
+		"item offset beyond the page": func(data []byte) []byte {
+			binary.LittleEndian.PutUint16(data[pageSize+bdbPageHeaderSize+2:], 0xffff)
+			return data
+		},
+		"overflow page beyond the database": func(data []byte) []byte {
+			offset := int(binary.LittleEndian.Uint16(data[pageSize+bdbPageHeaderSize+2:]))
+			binary.LittleEndian.PutUint32(data[pageSize+offset+4:], 0xffffffff)
+			return data
+		},
+		"overflow page cycle": func(data []byte) []byte {
+			// the first value starts at page 2, point its last overflow page back at it
+			for pageNo := 2; pageNo*pageSize < len(data); pageNo++ {
+				page := data[pageNo*pageSize:]
+				if binary.LittleEndian.Uint32(page[16:]) == 0 {
+					binary.LittleEndian.PutUint32(page[16:], 2)
+					break
+				}
+			}
+			return data
+		},
+	}
+
+	for name, corrupt := range table {
+		data := corrupt(testRpmDatabase(false, testRpmHeaders()...))
+		_, err := parseRpmDatabase(data)
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseRpmHeader_Corrupt(t *testing.T) {
+	header := testRpmHeaders()[0]
+
+	table := map[string]func(blob []byte) []byte{
+		"truncated": func(blob []byte) []byte {
+			return blob[:len(blob)-1]
+		},
+		"index count beyond the header": func(blob []byte) []byte {
+			binary.BigEndian.PutUint32(blob[0:], 0xffffffff)
+			return blob
+		},
+		"tag offset beyond the store": func(blob []byte) []byte {
+			binary.BigEndian.PutUint32(blob[8+8:], 0xffffffff)
+			return blob
+		},
+	}
+
+	for name, corrupt := range table {
+		blob := corrupt(append([]byte(nil), header...))
+		_, err := parseRpmHeader(blob)
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// a corrupt count is bounded by the store rather than allocated as is
+	blob := append([]byte(nil), header...)
+	for idx := 0; idx < int(binary.BigEndian.Uint32(blob[0:])); idx++ {
+		binary.BigEndian.PutUint32(blob[8+idx*16+12:], 0x7fffffff)
+	}
+	entry, err := parseRpmHeader(blob)
+	if err != nil {
+		t.Fatalf("unable to parse header: %+v", err)
+	}
+	if entry.name != "bash" {
+		t.Errorf("expected package 'bash', got %q", entry.name)
+	}
+}
+
+// TestParseRpmDatabase_Mutations ensures that no single corrupt byte (at a few values) panics the parser, whatever the
+// outcome of the parse.
+func TestParseRpmDatabase_Mutations(t *testing.T) {
+	for _, onPage := range []bool{false, true} {
+		original := testRpmDatabase(onPage, testRpmHeaders()...)
+		for idx := range original {
+			for _, value := range []byte{0x00, 0x01, 0x7f, 0xff} {
+				data := append([]byte(nil), original...)
+				data[idx] = value
+				func() {
+					defer func() {
+						if r := recover(); r != nil {
+							t.Fatalf("byte %d set to %#x (on page: %v): panic: %v", idx, value, onPage, r)
+						}
+					}()
+					_, _ = parseRpmDatabase(data)
+				}()
+			}
+		}
+	}
+}
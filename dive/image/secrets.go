@@ -0,0 +1,265 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/dive/filetree"
+)
+
+// DefaultSecretContentSize is the largest file (in bytes) read when checking file contents for secrets.
+const DefaultSecretContentSize = 1024 * 1024
+
+// SecretPattern describes a file that is likely to contain a secret, by path and/or by content.
+type SecretPattern struct {
+	Name string
+	// Path is a path.Match glob matched as described by filetree.MatchPath (e.g. "id_rsa" or ".aws/credentials")
+	Path string
+	// Content is a regular expression matched against the file contents (only checked when content scanning is enabled)
+	Content  string
+	Severity filetree.Severity
+}
+
+// BuiltinSecretPatterns are the well-known secret files (and secret formats) that are always checked.
+var BuiltinSecretPatterns = []SecretPattern{
+	{Name: "ssh private key", Path: "id_rsa", Severity: filetree.SeverityHigh},
+	{Name: "ssh private key", Path: "id_dsa", Severity: filetree.SeverityHigh},
+	{Name: "ssh private key", Path: "id_ecdsa", Severity: filetree.SeverityHigh},
+	{Name: "ssh private key", Path: "id_ed25519", Severity: filetree.SeverityHigh},
+	{Name: "aws credentials", Path: ".aws/credentials", Severity: filetree.SeverityHigh},
+	{Name: "netrc credentials", Path: ".netrc", Severity: filetree.SeverityHigh},
+	{Name: "git credentials", Path: ".git-credentials", Severity: filetree.SeverityHigh},
+	{Name: "npm config", Path: ".npmrc", Severity: filetree.SeverityMedium},
+	{Name: "pypi config", Path: ".pypirc", Severity: filetree.SeverityMedium},
+	{Name: "docker config", Path: ".docker/config.json", Severity: filetree.SeverityMedium},
+	{Name: "kubernetes config", Path: ".kube/config", Severity: filetree.SeverityMedium},
+	{Name: "dotenv file", Path: ".env", Severity: filetree.SeverityMedium},
+	{Name: "dotenv file", Path: ".env.*", Severity: filetree.SeverityMedium},
+	// note: "*.pem" and "*.key" files are commonly certificates (e.g. CA bundles), so these are only of low severity,
+	// the "private key" content pattern reports the files that do hold a private key
+	{Name: "key file", Path: "*.pem", Severity: filetree.SeverityLow},
+	{Name: "key file", Path: "*.key", Severity: filetree.SeverityLow},
+	{Name: "key file", Path: "*.p12", Severity: filetree.SeverityLow},
+	{Name: "key file", Path: "*.pfx", Severity: filetree.SeverityLow},
+	{Name: "private key", Content: `-----BEGIN ((RSA|DSA|EC|OPENSSH|ENCRYPTED) )?PRIVATE KEY-----`, Severity: filetree.SeverityHigh},
+	{Name: "aws access key", Content: `\b(AKIA|ASIA)[0-9A-Z]{16}\b`, Severity: filetree.SeverityHigh},
+	{Name: "github token", Content: `\bgh[pousr]_[A-Za-z0-9]{36}\b`, Severity: filetree.SeverityHigh},
+	{Name: "slack token", Content: `\bxox[abprs]-[A-Za-z0-9-]{10,}`, Severity: filetree.SeverityHigh},
+	{Name: "npm token", Content: `_authToken=[^\s$]\S*`, Severity: filetree.SeverityHigh},
+}
+
+// SecretOptions adjusts what is reported by ScanSecrets.
+type SecretOptions struct {
+	// Patterns are checked in addition to the BuiltinSecretPatterns
+	Patterns []SecretPattern
+	// ScanContent enables checking file contents (which requires reading every layer)
+	ScanContent bool
+	// MaxContentSize is the largest file (in bytes) checked by content, larger files are skipped
+	MaxContentSize int64
+}
+
+// secretPattern is a SecretPattern with a compiled content expression.
+type secretPattern struct {
+	SecretPattern
+	content *regexp.Regexp
+}
+
+// matchesPath indicates if the given path matches the path glob of the pattern (see SecretPattern.Path).
+func (pattern *secretPattern) matchesPath(p string) bool {
+	return pattern.Path == "" || filetree.MatchPath(pattern.Path, p)
+}
+
+// secretOccurrence is a single path matching a single pattern, along with the node for the path within each layer
+// that provides it.
+type secretOccurrence struct {
+	pattern *secretPattern
+	path    string
+	nodes   map[int]*filetree.FileNode
+}
+
+// ScanSecrets reports files that are likely to contain secrets. Every layer is checked, not only the final image, since
+// a secret that is later removed still ships within the layer that added it (the given final tree is only used to
+// note the secrets removed from the final image). Content is only read when enabled.
+func ScanSecrets(trees []*filetree.FileTree, final *filetree.FileTree, content ContentReader, options SecretOptions) (filetree.Findings, error) {
+	var patterns []*secretPattern
+	for _, pattern := range append(append([]SecretPattern{}, BuiltinSecretPatterns...), options.Patterns...) {
+		if pattern.Path == "" && pattern.Content == "" {
+			return nil, fmt.Errorf("secret pattern '%s' must have a path or content", pattern.Name)
+		}
+		if pattern.Path != "" {
+			if _, err := path.Match(pattern.Path, ""); err != nil {
+				return nil, fmt.Errorf("invalid path for secret pattern '%s': %v", pattern.Name, err)
+			}
+		}
+		compiled := &secretPattern{SecretPattern: pattern}
+		if pattern.Content != "" {
+			expression, err := regexp.Compile(pattern.Content)
+			if err != nil {
+				return nil, fmt.Errorf("invalid content for secret pattern '%s': %v", pattern.Name, err)
+			}
+			compiled.content = expression
+		}
+		patterns = append(patterns, compiled)
+	}
+
+	scanContent := options.ScanContent
+	if scanContent && content == nil {
+		logrus.Warn("the image source does not support reading file contents, secrets are only detected by path")
+		scanContent = false
+	}
+	maxContentSize := options.MaxContentSize
+	if maxContentSize <= 0 {
+		maxContentSize = DefaultSecretContentSize
+	}
+
+	occurrences := make(map[string]*secretOccurrence)
+	record := func(pattern *secretPattern, node *filetree.FileNode, layerIdx int) {
+		key := node.Path() + "\x00" + pattern.Name
+		occurrence, exists := occurrences[key]
+		if !exists {
+			occurrence = &secretOccurrence{
+				pattern: pattern,
+				path:    node.Path(),
+				nodes:   make(map[int]*filetree.FileNode),
+			}
+			occurrences[key] = occurrence
+		}
+		occurrence.nodes[layerIdx] = node
+	}
+
+	// contentCandidates are the paths (by layer) to check by content, along with the patterns to check them against
+	contentCandidates := make(map[int]map[string][]*secretPattern)
+	nodes := make(map[int]map[string]*filetree.FileNode)
+
+	for layerIdx, tree := range trees {
+		err := tree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+			info := node.Data.FileInfo
+			if node.IsImplied() || node.IsWhiteout() {
+				return nil
+			}
+			if info.TypeFlag != tar.TypeReg && info.TypeFlag != tar.TypeRegA && info.TypeFlag != tar.TypeLink {
+				return nil
+			}
+			p := node.Path()
+			for _, pattern := range patterns {
+				if !pattern.matchesPath(p) {
+					continue
+				}
+				if pattern.content == nil {
+					record(pattern, node, layerIdx)
+					continue
+				}
+				// hard links are checked by the contents of their target
+				if scanContent && info.TypeFlag != tar.TypeLink && info.Size <= maxContentSize {
+					if _, exists := contentCandidates[layerIdx]; !exists {
+						contentCandidates[layerIdx] = make(map[string][]*secretPattern)
+						nodes[layerIdx] = make(map[string]*filetree.FileNode)
+					}
+					contentCandidates[layerIdx][p] = append(contentCandidates[layerIdx][p], pattern)
+					nodes[layerIdx][p] = node
+				}
+			}
+			return nil
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(contentCandidates) > 0 {
+		layerIdxs := make([]int, 0, len(contentCandidates))
+		for layerIdx := range contentCandidates {
+			layerIdxs = append(layerIdxs, layerIdx)
+		}
+		sort.Ints(layerIdxs)
+
+		err := content.ReadLayers(layerIdxs, func(layerIdx int, header *tar.Header, reader io.Reader) error {
+			p := path.Clean("/" + header.Name)
+			candidates, exists := contentCandidates[layerIdx][p]
+			if !exists || (header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA) {
+				return nil
+			}
+			contents, err := ioutil.ReadAll(io.LimitReader(reader, maxContentSize))
+			if err != nil {
+				return err
+			}
+			// the expressions only describe text formats
+			if bytes.IndexByte(contents[:minInt(len(contents), 8000)], 0) >= 0 {
+				return nil
+			}
+			for _, pattern := range candidates {
+				if pattern.content.Match(contents) {
+					record(pattern, nodes[layerIdx][p], layerIdx)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(occurrences) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(occurrences))
+	for key := range occurrences {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	findings := make(filetree.Findings, 0, len(occurrences))
+	for _, key := range keys {
+		occurrence := occurrences[key]
+
+		layerIdxs := make([]int, 0, len(occurrence.nodes))
+		for layerIdx := range occurrence.nodes {
+			layerIdxs = append(layerIdxs, layerIdx)
+		}
+		sort.Ints(layerIdxs)
+
+		layers := make([]string, len(layerIdxs))
+		for idx, layerIdx := range layerIdxs {
+			layers[idx] = fmt.Sprintf("%d", layerIdx)
+		}
+		detail := fmt.Sprintf("%s (layer %s", occurrence.pattern.Name, strings.Join(layers, ", "))
+		if final != nil {
+			if _, err := final.GetNode(occurrence.path); err != nil {
+				detail += ", removed from the final image"
+			}
+		}
+		detail += ")"
+
+		// the metadata is taken from the layer that first added the secret
+		info := occurrence.nodes[layerIdxs[0]].Data.FileInfo
+		findings = append(findings, filetree.Finding{
+			Kind:       filetree.FindingSecret,
+			Severity:   occurrence.pattern.Severity,
+			Path:       occurrence.path,
+			LayerIndex: layerIdxs[0],
+			Mode:       info.Mode,
+			Uid:        info.Uid,
+			Gid:        info.Gid,
+			Detail:     detail,
+		})
+	}
+	findings.Sort()
+
+	return findings, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,28 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/wagoodman/dive/dive/image"
+)
+
+type resolver struct{}
+
+func NewResolver() *resolver {
+	return &resolver{}
+}
+
+func (r *resolver) Fetch(path string) (*image.Image, error) {
+	reader, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return Read(reader)
+}
+
+func (r *resolver) Build(args []string) (*image.Image, error) {
+	return nil, fmt.Errorf("build option not supported for snapshot resolver")
+}
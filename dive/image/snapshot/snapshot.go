@@ -0,0 +1,145 @@
+package snapshot
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
+)
+
+// Version is the current version of the snapshot format. Snapshots from newer versions of dive are rejected, since the
+// fields they rely on cannot be known.
+const Version = 1
+
+// snapshot is the (gzip compressed) JSON document written to a snapshot file.
+type snapshot struct {
+	Version int             `json:"version"`
+	Layers  []layer         `json:"layers"`
+	History []historyEntry  `json:"history"`
+	Config  json.RawMessage `json:"config,omitempty"`
+}
+
+// layer is an image layer along with every entry of its tree.
+type layer struct {
+	Id       string               `json:"id"`
+	Index    int                  `json:"index"`
+	Command  string               `json:"command"`
+	Size     uint64               `json:"sizeBytes"`
+	Names    []string             `json:"names"`
+	Digest   string               `json:"digest"`
+	TreeName string               `json:"treeName"`
+	Files    []filetree.FileEntry `json:"files"`
+}
+
+// historyEntry is a single step of the image history (including steps that did not produce a layer).
+type historyEntry struct {
+	Created    string `json:"created,omitempty"`
+	Author     string `json:"author,omitempty"`
+	CreatedBy  string `json:"created_by,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+	EmptyLayer bool   `json:"empty_layer,omitempty"`
+}
+
+// Write saves the layers, history, config, and every layer tree of the given image, so the image can be analyzed later
+// without the original image (see Read). Note: file contents are not saved.
+func Write(img *image.Image, writer io.Writer) error {
+	doc := snapshot{
+		Version: Version,
+		Layers:  make([]layer, len(img.Layers)),
+		History: make([]historyEntry, 0),
+	}
+
+	if len(img.Layers) != len(img.Trees) {
+		return fmt.Errorf("image has %d layers but %d trees", len(img.Layers), len(img.Trees))
+	}
+	for idx, imgLayer := range img.Layers {
+		doc.Layers[idx] = layer{
+			Id:       imgLayer.Id,
+			Index:    imgLayer.Index,
+			Command:  imgLayer.Command,
+			Size:     imgLayer.Size,
+			Names:    imgLayer.Names,
+			Digest:   imgLayer.Digest,
+			TreeName: img.Trees[idx].Name,
+			Files:    img.Trees[idx].Entries(),
+		}
+	}
+
+	if len(img.Config) > 0 {
+		doc.Config = json.RawMessage(img.Config)
+		var config struct {
+			History []historyEntry `json:"history"`
+		}
+		err := json.Unmarshal(img.Config, &config)
+		if err != nil {
+			return fmt.Errorf("unable to read image config: %v", err)
+		}
+		if config.History != nil {
+			doc.History = config.History
+		}
+	}
+
+	compressed := gzip.NewWriter(writer)
+	err := json.NewEncoder(compressed).Encode(&doc)
+	if err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+// Read loads an image from a snapshot (see Write). The image has no content reader, so anything that reads file
+// contents (e.g. extracting files) is unavailable.
+func Read(reader io.Reader) (*image.Image, error) {
+	decompressed, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("not a dive snapshot: %v", err)
+	}
+	defer decompressed.Close()
+
+	var doc snapshot
+	err = json.NewDecoder(decompressed).Decode(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read snapshot: %v", err)
+	}
+	if doc.Version < 1 || doc.Version > Version {
+		return nil, fmt.Errorf("unsupported snapshot version: %d (the latest supported version is %d)", doc.Version, Version)
+	}
+	if len(doc.Layers) == 0 {
+		return nil, fmt.Errorf("snapshot has no layers")
+	}
+
+	img := &image.Image{
+		Trees:  make([]*filetree.FileTree, len(doc.Layers)),
+		Layers: make([]*image.Layer, len(doc.Layers)),
+	}
+	if len(doc.Config) > 0 {
+		img.Config = []byte(doc.Config)
+		img.ContainerConfig, err = image.ParseContainerConfig(img.Config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for idx, snapshotLayer := range doc.Layers {
+		tree, err := filetree.NewFileTreeFromEntries(snapshotLayer.TreeName, snapshotLayer.Files)
+		if err != nil {
+			return nil, fmt.Errorf("unable to rebuild layer %d: %v", idx, err)
+		}
+		// the layer size is the size of the original layer tar contents (which may hold entries that were replaced)
+		tree.FileSize = snapshotLayer.Size
+
+		img.Trees[idx] = tree
+		img.Layers[idx] = &image.Layer{
+			Id:      snapshotLayer.Id,
+			Index:   snapshotLayer.Index,
+			Command: snapshotLayer.Command,
+			Size:    snapshotLayer.Size,
+			Tree:    tree,
+			Names:   snapshotLayer.Names,
+			Digest:  snapshotLayer.Digest,
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,93 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wagoodman/dive/dive/image/docker"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	archive, err := docker.TestLoadArchive("../../../.data/test-docker-image.tar")
+	if err != nil {
+		t.Fatalf("unable to fetch archive: %v", err)
+	}
+	img, err := archive.ToImage()
+	if err != nil {
+		t.Fatalf("unable to convert to image: %v", err)
+	}
+
+	var buffer bytes.Buffer
+	err = Write(img, &buffer)
+	if err != nil {
+		t.Fatalf("unable to write snapshot: %v", err)
+	}
+
+	loaded, err := Read(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Fatalf("unable to read snapshot: %v", err)
+	}
+
+	if len(loaded.Layers) != len(img.Layers) || len(loaded.Trees) != len(img.Trees) {
+		t.Fatalf("expected %d layers, got %d (%d trees)", len(img.Layers), len(loaded.Layers), len(loaded.Trees))
+	}
+	if !bytes.Equal(loaded.Config, img.Config) {
+		t.Errorf("expected the image config to be kept")
+	}
+	for idx, layer := range img.Layers {
+		actual := *loaded.Layers[idx]
+		expected := *layer
+		actual.Tree, expected.Tree = nil, nil
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("layer %d: expected %+v, got %+v", idx, expected, actual)
+		}
+		if img.Trees[idx].String(true) != loaded.Trees[idx].String(true) {
+			t.Errorf("layer %d: the tree differs from the original", idx)
+		}
+	}
+
+	expected, err := img.Analyze()
+	if err != nil {
+		t.Fatalf("unable to analyze image: %v", err)
+	}
+	actual, err := loaded.Analyze()
+	if err != nil {
+		t.Fatalf("unable to analyze snapshot: %v", err)
+	}
+	if actual.Efficiency != expected.Efficiency || actual.SizeBytes != expected.SizeBytes || actual.WastedBytes != expected.WastedBytes {
+		t.Errorf("expected the same analysis as the original image: %v/%d/%d, got %v/%d/%d",
+			expected.Efficiency, expected.SizeBytes, expected.WastedBytes, actual.Efficiency, actual.SizeBytes, actual.WastedBytes)
+	}
+	if len(actual.Inefficiencies) != len(expected.Inefficiencies) || len(actual.Findings) != len(expected.Findings) {
+		t.Errorf("expected the same inefficiencies and findings as the original image")
+	}
+}
+
+func TestReadUnsupported(t *testing.T) {
+	compress := func(content string) *bytes.Buffer {
+		var buffer bytes.Buffer
+		writer := gzip.NewWriter(&buffer)
+		_, _ = writer.Write([]byte(content))
+		_ = writer.Close()
+		return &buffer
+	}
+
+	table := map[string]struct {
+		reader   *bytes.Buffer
+		expected string
+	}{
+		"not-gzip":      {reader: bytes.NewBufferString(`{"version": 1}`), expected: "not a dive snapshot"},
+		"newer-version": {reader: compress(`{"version": 2, "layers": [{}]}`), expected: "unsupported snapshot version: 2"},
+		"no-layers":     {reader: compress(`{"version": 1, "layers": []}`), expected: "snapshot has no layers"},
+	}
+
+	for name, test := range table {
+		_, err := Read(test.reader)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", name, test.expected, err)
+		}
+	}
+}
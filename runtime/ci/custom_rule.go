@@ -0,0 +1,178 @@
+package ci
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/dive/image"
+	"github.com/wagoodman/dive/runtime/ci/expr"
+	"github.com/wagoodman/dive/runtime/export"
+)
+
+// customRulesKey is the CI config key of the custom rules.
+const customRulesKey = "customRules"
+
+// CustomCiRule is a rule declared within the CI config as an expression over the analysis (see newExpressionModel),
+// which holds when the expression is true:
+//
+//	customRules:
+//	  - name: noDocs
+//	    expression: 'count(files, f.path matches "^/usr/share/doc/") == 0'
+//	    message: documentation should not be installed
+//	    severity: warn
+type CustomCiRule struct {
+	name       string
+	source     string
+	message    string
+	severity   RuleSeverity
+	expression *expr.Expression
+	models     *expressionModels
+	err        error
+}
+
+func (rule *CustomCiRule) Key() string {
+	return rule.name
+}
+
+func (rule *CustomCiRule) Configuration() string {
+	return rule.source
+}
+
+func (rule *CustomCiRule) Severity() RuleSeverity {
+	return rule.severity
+}
+
+// Description explains the rule within reports, which is the configured message (or the expression when not given).
+func (rule *CustomCiRule) Description() string {
+	if rule.message != "" {
+		return rule.message
+	}
+	return fmt.Sprintf("custom rule: %s", rule.source)
+}
+
+func (rule *CustomCiRule) Validate() error {
+	return rule.err
+}
+
+// Evaluate fails the rule (or warns, with the "warn" severity) when the expression is false, or cannot be evaluated.
+func (rule *CustomCiRule) Evaluate(result *image.AnalysisResult, _ *export.Comparison) (RuleStatus, string) {
+	holds, err := rule.expression.EvaluateBool(rule.models.get(result))
+	if err != nil {
+		return RuleFailed, fmt.Sprintf("unable to evaluate expression: %v", err)
+	}
+	if holds {
+		return RulePassed, ""
+	}
+
+	message := rule.message
+	if message == "" {
+		message = fmt.Sprintf("expression is false: %s", rule.source)
+	}
+	if rule.severity == RuleSeverityWarn {
+		return RuleWarning, message
+	}
+	return RuleFailed, message
+}
+
+// loadCustomRules reads the custom rules from the CI config, a rule that is not configured correctly (e.g. its
+// expression does not compile, or its name is taken) is misconfigured. The names of the given rules are taken.
+func loadCustomRules(config *viper.Viper, taken []CiRule) []CiRule {
+	value := config.Get(customRulesKey)
+	if value == nil {
+		return nil
+	}
+	items, isList := value.([]interface{})
+	if !isList {
+		return []CiRule{&CustomCiRule{
+			name:     customRulesKey,
+			severity: RuleSeverityError,
+			err:      fmt.Errorf("%s must be a list of rules (each with a name and an expression)", customRulesKey),
+		}}
+	}
+
+	names := make(map[string]bool)
+	for _, rule := range taken {
+		names[rule.Key()] = true
+	}
+
+	models := &expressionModels{}
+	rules := make([]CiRule, 0, len(items))
+	for idx, item := range items {
+		rule := parseCustomRule(idx, item)
+		rule.models = models
+		if rule.err == nil && names[rule.name] {
+			rule.err = fmt.Errorf("custom rule name '%s' is already taken by another rule", rule.name)
+		}
+		if names[rule.name] {
+			// note: results are recorded by name, so the rule is renamed to report the error
+			rule.name = fmt.Sprintf("%s[%d]", customRulesKey, idx)
+		}
+		names[rule.name] = true
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// parseCustomRule reads the custom rule at the given index of the list of custom rules.
+func parseCustomRule(idx int, value interface{}) *CustomCiRule {
+	rule := &CustomCiRule{severity: RuleSeverityError}
+	defer func() {
+		if rule.name == "" {
+			rule.name = fmt.Sprintf("%s[%d]", customRulesKey, idx)
+		}
+	}()
+
+	fields, isMap := configFields(value)
+	if !isMap {
+		rule.err = fmt.Errorf("custom rule %d must have a name and an expression", idx)
+		return rule
+	}
+
+	options := make([]string, 0, len(fields))
+	for option := range fields {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+	for _, option := range options {
+		field := fmt.Sprintf("%v", fields[option])
+		switch strings.ToLower(option) {
+		case "name":
+			rule.name = strings.TrimSpace(field)
+		case "expression":
+			rule.source = field
+		case "message":
+			rule.message = field
+		case "severity":
+			var err error
+			rule.severity, err = parseSeverity("custom rule", fields[option])
+			if err != nil && rule.err == nil {
+				rule.err = err
+			}
+		default:
+			if rule.err == nil {
+				rule.err = fmt.Errorf("unknown custom rule option: '%s' (expected name, expression, message, or severity)", option)
+			}
+		}
+	}
+
+	if rule.err != nil {
+		return rule
+	}
+	if rule.name == "" {
+		rule.err = fmt.Errorf("custom rule %d requires a name", idx)
+		return rule
+	}
+	if strings.TrimSpace(rule.source) == "" {
+		rule.err = fmt.Errorf("custom rule '%s' requires an expression", rule.name)
+		return rule
+	}
+	expression, err := expr.Compile(rule.source)
+	if err != nil {
+		rule.err = fmt.Errorf("invalid expression: %v", err)
+		return rule
+	}
+	rule.expression = expression
+	return rule
+}
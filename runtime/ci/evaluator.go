@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"github.com/dustin/go-humanize"
 	"github.com/wagoodman/dive/dive/image"
+	"github.com/wagoodman/dive/runtime/export"
 	"github.com/wagoodman/dive/utils"
-	"sort"
 	"strconv"
 	"strings"
 
@@ -20,7 +20,17 @@ type CiEvaluator struct {
 	Tally            ResultTally
 	Pass             bool
 	Misconfigured    bool
+	FailOnWarn       bool
 	InefficientFiles []ReferenceFile
+	// Ignore are the path globs of the inefficient files that no ignorable rule counts (see ignoreKey)
+	Ignore []string
+	// IgnoredFiles are the inefficient files that match the Ignore globs (and so are not within the
+	// InefficientFiles), or that some rules ignore (see ReferenceFile.IgnoredBy)
+	IgnoredFiles []ReferenceFile
+	// Baseline is a previous export of the image (e.g. built from main) that the baseline rules compare against, or nil
+	Baseline *export.Export
+	// Comparison is how the image changed since the baseline (set once evaluated with a baseline)
+	Comparison *export.Comparison
 }
 
 type ResultTally struct {
@@ -33,14 +43,16 @@ type ResultTally struct {
 
 func NewCiEvaluator(config *viper.Viper) *CiEvaluator {
 	return &CiEvaluator{
-		Rules:   loadCiRules(config),
-		Results: make(map[string]RuleResult),
-		Pass:    true,
+		Rules:      loadCiRules(config),
+		Results:    make(map[string]RuleResult),
+		Pass:       true,
+		FailOnWarn: config.GetBool("fail-on-warn"),
+		Ignore:     config.GetStringSlice(ignoreKey),
 	}
 }
 
 func (ci *CiEvaluator) isRuleEnabled(rule CiRule) bool {
-	return rule.Configuration() != "disabled"
+	return rule.Severity() != RuleSeverityOff
 }
 
 func (ci *CiEvaluator) Evaluate(analysis *image.AnalysisResult) bool {
@@ -80,11 +92,23 @@ func (ci *CiEvaluator) Evaluate(analysis *image.AnalysisResult) bool {
 	for idx := 0; idx < len(analysis.Inefficiencies); idx++ {
 		fileData := analysis.Inefficiencies[len(analysis.Inefficiencies)-1-idx]
 
-		ci.InefficientFiles = append(ci.InefficientFiles, ReferenceFile{
-			References: len(fileData.Nodes),
-			SizeBytes:  uint64(fileData.CumulativeSize),
-			Path:       fileData.Path,
-		})
+		file := ReferenceFile{
+			References:   len(fileData.Nodes),
+			SizeBytes:    uint64(fileData.CumulativeSize),
+			Path:         fileData.Path,
+			LayerIndexes: inefficiencyLayers(analysis, fileData),
+		}
+		file.IgnoredBy, file.IgnoredGlobally = ci.ignoredBy(fileData.Path)
+		if file.IgnoredBy != "" {
+			ci.IgnoredFiles = append(ci.IgnoredFiles, file)
+		}
+		if !file.IgnoredGlobally {
+			ci.InefficientFiles = append(ci.InefficientFiles, file)
+		}
+	}
+
+	if ci.Baseline != nil {
+		ci.Comparison = export.NewExport(analysis).Compare(ci.Baseline)
 	}
 
 	// evaluate results against the configured CI rules
@@ -97,13 +121,13 @@ func (ci *CiEvaluator) Evaluate(analysis *image.AnalysisResult) bool {
 			continue
 		}
 
-		status, message := rule.Evaluate(analysis)
+		status, message := rule.Evaluate(analysis, ci.Comparison)
 
 		if value, exists := ci.Results[rule.Key()]; exists && value.status != RuleConfigured && value.status != RuleMisconfigured {
 			panic(fmt.Errorf("CI rule result recorded twice: %s", rule.Key()))
 		}
 
-		if status == RuleFailed {
+		if status == RuleFailed || (status == RuleWarning && ci.FailOnWarn) {
 			ci.Pass = false
 		}
 
@@ -148,21 +172,42 @@ func (ci *CiEvaluator) Report() string {
 		}
 	}
 
-	fmt.Fprintln(&sb, utils.TitleFormat("Results:"))
+	if len(ci.IgnoredFiles) > 0 {
+		fmt.Fprintln(&sb, utils.TitleFormat("Ignored Inefficient Files:"))
+		fmt.Fprintf(&sb, template, "Count", "Wasted Space", "File Path")
+		for _, file := range ci.IgnoredFiles {
+			fmt.Fprintf(&sb, template, strconv.Itoa(file.References), humanize.Bytes(file.SizeBytes), fmt.Sprintf("%s (ignored by %s)", file.Path, file.IgnoredBy))
+		}
+	}
 
-	status := "PASS"
+	if ci.Comparison != nil {
+		comparison := ci.Comparison
+		fmt.Fprintln(&sb, utils.TitleFormat("Compared To Baseline:"))
+		fmt.Fprintf(&sb, "  imageSize: %s (%s)\n", humanize.Bytes(comparison.SizeBytes.Value), comparison.SizeBytes)
+		fmt.Fprintf(&sb, "  wastedBytes: %s (%s)\n", humanize.Bytes(comparison.InefficientBytes.Value), comparison.InefficientBytes)
+		fmt.Fprintf(&sb, "  efficiency: %2.4f %% (%s)\n", comparison.EfficiencyScore*100, comparison.EfficiencyString())
+		fmt.Fprintf(&sb, "  layers: %d (%s)\n", comparison.LayerCount, comparison.LayerCountString())
 
-	rules := make([]string, 0, len(ci.Results))
-	for name := range ci.Results {
-		rules = append(rules, name)
+		fmt.Fprintln(&sb, utils.TitleFormat("New Inefficient Files:"))
+		fmt.Fprintf(&sb, template, "Count", "Wasted Space", "File Path")
+		if len(comparison.NewInefficientFiles) == 0 {
+			fmt.Fprintln(&sb, "None")
+		} else {
+			for _, file := range comparison.NewInefficientFiles {
+				fmt.Fprintf(&sb, template, strconv.Itoa(file.References), humanize.Bytes(file.SizeBytes), file.Path)
+			}
+		}
 	}
-	sort.Strings(rules)
 
-	if ci.Tally.Fail > 0 {
+	fmt.Fprintln(&sb, utils.TitleFormat("Results:"))
+
+	status := "PASS"
+
+	if !ci.Pass {
 		status = "FAIL"
 	}
 
-	for _, rule := range rules {
+	for _, rule := range ci.sortedResults() {
 		result := ci.Results[rule]
 		name := strings.TrimPrefix(rule, "rules.")
 		if result.message != "" {
@@ -177,13 +222,56 @@ func (ci *CiEvaluator) Report() string {
 
 	} else {
 		summary := fmt.Sprintf("Result:%s [Total:%d] [Passed:%d] [Failed:%d] [Warn:%d] [Skipped:%d]", status, ci.Tally.Total, ci.Tally.Pass, ci.Tally.Fail, ci.Tally.Warn, ci.Tally.Skip)
-		if ci.Pass {
-			fmt.Fprintln(&sb, aurora.Green(summary))
-		} else if ci.Pass && ci.Tally.Warn > 0 {
+		if ci.Pass && ci.Tally.Warn > 0 {
 			fmt.Fprintln(&sb, aurora.Blue(summary))
+		} else if ci.Pass {
+			fmt.Fprintln(&sb, aurora.Green(summary))
 		} else {
 			fmt.Fprintln(&sb, aurora.Red(summary))
 		}
 	}
 	return sb.String()
 }
+
+// status returns the overall status of the (evaluated) image: failed, passed (or warned, when not failing on
+// warnings), or misconfigured.
+func (ci *CiEvaluator) status() RuleStatus {
+	switch {
+	case ci.Misconfigured:
+		return RuleMisconfigured
+	case !ci.Pass:
+		return RuleFailed
+	case ci.Tally.Warn > 0:
+		return RuleWarning
+	default:
+		return RulePassed
+	}
+}
+
+// CombinedReport returns the result of every evaluated image of a CI run, along with the overall result (which fails
+// when any image fails).
+func CombinedReport(images []ReportImage) string {
+	var sb strings.Builder
+	fmt.Fprintln(&sb, utils.TitleFormat("Images:"))
+
+	pass, failed := true, 0
+	for _, img := range images {
+		fmt.Fprintf(&sb, "  %s: %s\n", img.Evaluator.status().String(), img.Source.Image)
+		if !img.Evaluator.Pass {
+			pass = false
+			failed++
+		}
+	}
+
+	status := "PASS"
+	if !pass {
+		status = "FAIL"
+	}
+	summary := fmt.Sprintf("Result:%s [Images:%d] [Passed:%d] [Failed:%d]", status, len(images), len(images)-failed, failed)
+	if pass {
+		fmt.Fprintln(&sb, aurora.Green(summary))
+	} else {
+		fmt.Fprintln(&sb, aurora.Red(summary))
+	}
+	return sb.String()
+}
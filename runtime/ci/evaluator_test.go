@@ -1,7 +1,12 @@
 package ci
 
 import (
+	"bytes"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
 	"github.com/wagoodman/dive/dive/image/docker"
+	"github.com/wagoodman/dive/runtime/export"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -28,6 +33,10 @@ func Test_Evaluator(t *testing.T) {
 
 	for name, test := range table {
 		ciConfig := viper.New()
+		// the other rules are covered individually (e.g. Test_EvaluatorRuleValues)
+		for _, rule := range RegisteredRules() {
+			ciConfig.SetDefault("rules."+rule.Key, "disabled")
+		}
 		ciConfig.SetDefault("rules.lowestEfficiency", test.efficiency)
 		ciConfig.SetDefault("rules.highestWastedBytes", test.wastedBytes)
 		ciConfig.SetDefault("rules.highestUserWastedPercent", test.wastedPercent)
@@ -41,13 +50,20 @@ func Test_Evaluator(t *testing.T) {
 			t.Errorf("Test_Evaluator: expected pass=%v, got %v", test.expectedPass, pass)
 		}
 
-		if len(test.expectedResult) != len(evaluator.Results) {
+		if len(RegisteredRules()) != len(evaluator.Results) {
 			t.Logf("Test: %s", name)
-			t.Errorf("Test_Evaluator: expected %v results, got %v", len(test.expectedResult), len(evaluator.Results))
+			t.Errorf("Test_Evaluator: expected %v results, got %v", len(RegisteredRules()), len(evaluator.Results))
 		}
 
 		for rule, actualResult := range evaluator.Results {
-			expectedStatus := test.expectedResult[strings.TrimPrefix(rule, "rules.")]
+			expectedStatus, exists := test.expectedResult[strings.TrimPrefix(rule, "rules.")]
+			if !exists {
+				// note: the disabled rules remain unevaluated (configured) when another rule is misconfigured
+				if actualResult.status != RuleDisabled && actualResult.status != RuleConfigured {
+					t.Errorf("   %v: expected the rule to be disabled, got %v: %v", rule, actualResult.status, actualResult)
+				}
+				continue
+			}
 			if expectedStatus != actualResult.status {
 				t.Errorf("   %v: expected %v rule failures, got %v: %v", rule, expectedStatus, actualResult.status, actualResult)
 			}
@@ -56,3 +72,556 @@ func Test_Evaluator(t *testing.T) {
 	}
 
 }
+
+func Test_EvaluatorRuleValues(t *testing.T) {
+
+	result := docker.TestAnalysisFromArchive(t, "../../.data/test-docker-image.tar")
+
+	table := map[string]struct {
+		rule           string
+		value          string
+		expectedStatus RuleStatus
+	}{
+		"removableBytes":             {"highestRemovableBytes", "1MB", RulePassed},
+		"removableBytesHigh":         {"highestRemovableBytes", "1XB", RuleMisconfigured},
+		"removableBytesLow":          {"highestRemovableBytes", "-1B", RuleMisconfigured},
+		"danglingSymlinks":           {"noDanglingSymlinks", "true", RulePassed},
+		"danglingSymlinksInvalid":    {"noDanglingSymlinks", "yes", RuleMisconfigured},
+		"setuid":                     {"forbidSetuid", "true", RulePassed},
+		"setuidInvalid":              {"forbidSetuid", "no", RuleMisconfigured},
+		"worldWritable":              {"forbidWorldWritable", "true", RulePassed},
+		"worldWritableInvalid":       {"forbidWorldWritable", "2", RuleMisconfigured},
+		"secrets":                    {"noSecrets", "true", RulePassed},
+		"secretsInvalid":             {"noSecrets", "always", RuleMisconfigured},
+		"pathCollisions":             {"forbidPathCollisions", "true", RulePassed},
+		"pathCollisionsInvalid":      {"forbidPathCollisions", "sometimes", RuleMisconfigured},
+		"imageSizeInvalid":           {"highestImageSize", "-1B", RuleMisconfigured},
+		"layerCountHigh":             {"highestLayerCount", "1.5", RuleMisconfigured},
+		"layerCountLow":              {"highestLayerCount", "-1", RuleMisconfigured},
+		"layerSizeInvalid":           {"highestLayerSize", "1XB", RuleMisconfigured},
+		"singleFileSizeInvalid":      {"highestSingleFileSize", "1BB", RuleMisconfigured},
+		"layerEfficiencyHigh":        {"lowestLayerEfficiency", "1.1", RuleMisconfigured},
+		"layerEfficiencyLow":         {"lowestLayerEfficiency", "-0.1", RuleMisconfigured},
+		"sizeIncreaseInvalid":        {"maxSizeIncrease", "5x%", RuleMisconfigured},
+		"wastedIncreaseInvalid":      {"maxWastedBytesIncrease", "-1B", RuleMisconfigured},
+		"newInefficientFilesInvalid": {"noNewInefficientFiles", "-1", RuleMisconfigured},
+		"nonRootUserInvalid":         {"requireNonRootUser", "yes", RuleMisconfigured},
+		"healthcheckInvalid":         {"requireHealthcheck", "no", RuleMisconfigured},
+		"labelsInvalid":              {"requiredLabels", "=maintainer", RuleMisconfigured},
+		"portsHigh":                  {"forbiddenExposedPorts", "70000", RuleMisconfigured},
+		"portsLow":                   {"forbiddenExposedPorts", "-1", RuleMisconfigured},
+		"envKeysInvalid":             {"forbiddenEnvKeys", "*_TOKEN[", RuleMisconfigured},
+	}
+
+	for name, test := range table {
+		ciConfig := viper.New()
+		for _, rule := range RegisteredRules() {
+			ciConfig.SetDefault("rules."+rule.Key, "disabled")
+		}
+		ciConfig.SetDefault("rules."+test.rule, test.value)
+
+		evaluator := NewCiEvaluator(ciConfig)
+		pass := evaluator.Evaluate(result)
+
+		actualResult := evaluator.Results[test.rule]
+		if test.expectedStatus != actualResult.status {
+			t.Errorf("%s: expected %v, got %v: %v", name, test.expectedStatus, actualResult.status, actualResult)
+		}
+		if pass != (test.expectedStatus == RulePassed) {
+			t.Errorf("%s: expected pass=%v, got %v", name, test.expectedStatus == RulePassed, pass)
+		}
+	}
+
+}
+
+func Test_EvaluatorFindings(t *testing.T) {
+
+	result := docker.TestAnalysisFromArchive(t, "../../.data/test-docker-image.tar")
+	result.Findings = filetree.Findings{
+		{Kind: filetree.FindingSetuid, Severity: filetree.SeverityHigh, Path: "/bin/su"},
+		{Kind: filetree.FindingWorldWritable, Severity: filetree.SeverityHigh, Path: "/app/cache/index"},
+		{Kind: filetree.FindingSetgid, Severity: filetree.SeverityMedium, Path: "/usr/bin/wall"},
+		{Kind: filetree.FindingUnexpectedOwner, Severity: filetree.SeverityLow, Path: "/home/app"},
+		{Kind: filetree.FindingSecret, Severity: filetree.SeverityHigh, Path: "/root/.npmrc"},
+		{Kind: filetree.FindingPathCollision, Severity: filetree.SeverityLow, Path: "/app/Makefile"},
+		{Kind: filetree.FindingPathCollision, Severity: filetree.SeverityLow, Path: "/app/makefile"},
+	}
+
+	table := map[string]struct {
+		setuidAllowlist        []string
+		worldWritableAllowlist []string
+		secretsAllowlist       []string
+		collisionAllowlist     []string
+		expectedResult         map[string]RuleStatus
+	}{
+		"noAllowlist":      {nil, nil, nil, nil, map[string]RuleStatus{"forbidSetuid": RuleFailed, "forbidWorldWritable": RuleFailed, "noSecrets": RuleFailed, "forbidPathCollisions": RuleFailed}},
+		"partialAllowlist": {[]string{"/bin/su"}, []string{"/app/cache/*.tmp"}, []string{"/home"}, []string{"Makefile"}, map[string]RuleStatus{"forbidSetuid": RuleFailed, "forbidWorldWritable": RuleFailed, "noSecrets": RuleFailed, "forbidPathCollisions": RuleFailed}},
+		"allAllowed":       {[]string{"/bin/su", "/usr/bin/*"}, []string{"/app/cache"}, []string{"/root/.npmrc"}, []string{"/app/[Mm]akefile"}, map[string]RuleStatus{"forbidSetuid": RulePassed, "forbidWorldWritable": RulePassed, "noSecrets": RulePassed, "forbidPathCollisions": RulePassed}},
+	}
+
+	for name, test := range table {
+		ciConfig := viper.New()
+		for _, rule := range RegisteredRules() {
+			ciConfig.SetDefault("rules."+rule.Key, "disabled")
+		}
+		ciConfig.SetDefault("rules.forbidSetuid", "true")
+		ciConfig.SetDefault("rules.forbidWorldWritable", "true")
+		ciConfig.SetDefault("rules.noSecrets", "true")
+		ciConfig.SetDefault("rules.forbidPathCollisions", "true")
+		ciConfig.SetDefault("rules.setuidAllowlist", test.setuidAllowlist)
+		ciConfig.SetDefault("rules.worldWritableAllowlist", test.worldWritableAllowlist)
+		ciConfig.SetDefault("rules.secretsAllowlist", test.secretsAllowlist)
+		ciConfig.SetDefault("rules.pathCollisionAllowlist", test.collisionAllowlist)
+
+		evaluator := NewCiEvaluator(ciConfig)
+		evaluator.Evaluate(result)
+
+		for rule, expectedStatus := range test.expectedResult {
+			actualResult := evaluator.Results[rule]
+			if expectedStatus != actualResult.status {
+				t.Errorf("%s: %v: expected %v, got %v: %v", name, rule, expectedStatus, actualResult.status, actualResult)
+			}
+		}
+	}
+
+}
+
+func Test_EvaluatorRemovable(t *testing.T) {
+
+	result := docker.TestAnalysisFromArchive(t, "../../.data/test-docker-image.tar")
+	result.Removable = filetree.RemovableSlice{
+		{Path: "/root/.cache/pip", Category: filetree.RemovableLanguageCache, Size: 2000, FileCount: 4},
+		{Path: "/var/lib/apt/lists", Category: filetree.RemovablePackageCache, Size: 30000, FileCount: 12},
+	}
+	result.RemovableBytes = result.Removable.TotalSize()
+
+	table := map[string]struct {
+		removableBytes string
+		expectedStatus RuleStatus
+		expectedDetail string
+	}{
+		"overThreshold":  {"10kB", RuleFailed, "too many likely removable bytes (removable-bytes=32000 > threshold=10000): /var/lib/apt/lists (package-cache, 30 kB), /root/.cache/pip (language-cache, 2.0 kB)"},
+		"underThreshold": {"32kB", RulePassed, ""},
+	}
+
+	for name, test := range table {
+		ciConfig := viper.New()
+		for _, rule := range RegisteredRules() {
+			ciConfig.SetDefault("rules."+rule.Key, "disabled")
+		}
+		ciConfig.SetDefault("rules.highestRemovableBytes", test.removableBytes)
+
+		evaluator := NewCiEvaluator(ciConfig)
+		evaluator.Evaluate(result)
+
+		actualResult := evaluator.Results["highestRemovableBytes"]
+		if test.expectedStatus != actualResult.status || test.expectedDetail != actualResult.message {
+			t.Errorf("%s: expected %v (%q), got %v (%q)", name, test.expectedStatus, test.expectedDetail, actualResult.status, actualResult.message)
+		}
+	}
+
+}
+
+func Test_EvaluatorImageRules(t *testing.T) {
+
+	result := docker.TestAnalysisFromArchive(t, "../../.data/test-docker-image.tar")
+
+	table := map[string]struct {
+		rule           string
+		value          string
+		expectedStatus RuleStatus
+		expectedDetail string
+	}{
+		"imageSizeOver":        {"highestImageSize", "1MB", RuleFailed, "image is too large (image-size=1220598 > threshold=1000000)"},
+		"imageSizeUnder":       {"highestImageSize", "1.3MB", RulePassed, ""},
+		"layerCountOver":       {"highestLayerCount", "13", RuleFailed, "too many layers (layer-count=14 > threshold=13)"},
+		"layerCountAtLimit":    {"highestLayerCount", "14", RulePassed, ""},
+		"layerSizeOver":        {"highestLayerSize", "6kB", RuleFailed, "found 11 layers that are too large (threshold=6000): layer 0 28cfe03618aa2e9 (1.2 MB), layer 1 1871059774abe69 (6.4 kB), layer 3 80cd2ca1ffc8996 (6.4 kB), layer 4 c99e2f8d3f62826 (6.4 kB), layer 5 5eca617bdc3bc06 (6.4 kB), and 6 more"},
+		"layerSizeUnder":       {"highestLayerSize", "1.2MB", RulePassed, ""},
+		"singleFileSizeOver":   {"highestSingleFileSize", "6kB", RuleFailed, "found 7 files that are too large (threshold=6000): /bin/[ (1.1 MB), /bin/getconf (78 kB), /root/.data/saved.again2.txt (6.4 kB), /root/.saved.txt (6.4 kB), /root/saved.txt (6.4 kB), and 2 more"},
+		"singleFileSizeUnder":  {"highestSingleFileSize", "1.2MB", RulePassed, ""},
+		"layerEfficiencyUnder": {"lowestLayerEfficiency", "0.5", RuleFailed, "found 5 layers with too low an efficiency (threshold=0.5): layer 3 80cd2ca1ffc8996 (efficiency=0), layer 4 c99e2f8d3f62826 (efficiency=0), layer 5 5eca617bdc3bc06 (efficiency=0), layer 6 f07c3eb88757239 (efficiency=0), layer 7 461885fc2258915 (efficiency=0)"},
+		"layerEfficiencyOver":  {"lowestLayerEfficiency", "0", RulePassed, ""},
+	}
+
+	for name, test := range table {
+		ciConfig := viper.New()
+		for _, rule := range RegisteredRules() {
+			ciConfig.SetDefault("rules."+rule.Key, "disabled")
+		}
+		ciConfig.SetDefault("rules."+test.rule, test.value)
+
+		evaluator := NewCiEvaluator(ciConfig)
+		pass := evaluator.Evaluate(result)
+
+		actualResult := evaluator.Results[test.rule]
+		if test.expectedStatus != actualResult.status || test.expectedDetail != actualResult.message {
+			t.Errorf("%s: expected %v (%q), got %v (%q)", name, test.expectedStatus, test.expectedDetail, actualResult.status, actualResult.message)
+		}
+		if pass != (test.expectedStatus == RulePassed) {
+			t.Errorf("%s: expected pass=%v, got %v", name, test.expectedStatus == RulePassed, pass)
+		}
+	}
+
+}
+
+func Test_RuleRegistry(t *testing.T) {
+
+	definitions := RegisteredRules()
+	for idx := 1; idx < len(definitions); idx++ {
+		if definitions[idx-1].Key >= definitions[idx].Key {
+			t.Errorf("expected rules ordered by key, got %s before %s", definitions[idx-1].Key, definitions[idx].Key)
+		}
+	}
+
+	rules := loadCiRules(viper.New())
+	if len(rules) != len(definitions) {
+		t.Fatalf("expected %d rules, got %d", len(definitions), len(rules))
+	}
+	for idx, rule := range rules {
+		if rule.Key() != definitions[idx].Key {
+			t.Errorf("rule %d: expected %s, got %s", idx, definitions[idx].Key, rule.Key())
+		}
+		if err := definitions[idx].Schema.Validate(rule.Key(), definitions[idx].Default); definitions[idx].Default != "disabled" && err != nil {
+			t.Errorf("%s: invalid default value: %v", rule.Key(), err)
+		}
+	}
+
+	table := map[string]RuleDefinition{
+		"duplicate":  definitions[0],
+		"incomplete": {Key: "highestSomething", Schema: BytesSchema},
+	}
+	for name, definition := range table {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected registering the rule to panic", name)
+				}
+			}()
+			RegisterRule(definition)
+		}()
+	}
+
+}
+
+func Test_EvaluatorSeverity(t *testing.T) {
+
+	result := docker.TestAnalysisFromArchive(t, "../../.data/test-docker-image.tar")
+
+	// note: the image efficiency is ~0.984, with 32025 bytes wasted
+	table := map[string]struct {
+		config         string
+		failOnWarn     bool
+		expectedStatus RuleStatus
+		expectedDetail string
+		expectedPass   bool
+	}{
+		"warnCrossed":           {"lowestEfficiency:\n  warn: 0.99\n  fail: 0.5", false, RuleWarning, "image efficiency is too low (efficiency=0.9844212134184309 < threshold=0.99)", true},
+		"warnCrossedFailOnWarn": {"lowestEfficiency:\n  warn: 0.99\n  fail: 0.5", true, RuleWarning, "image efficiency is too low (efficiency=0.9844212134184309 < threshold=0.99)", false},
+		"failCrossed":           {"lowestEfficiency:\n  severity: error\n  warn: 0.999\n  fail: 0.99", false, RuleFailed, "image efficiency is too low (efficiency=0.9844212134184309 < threshold=0.99)", false},
+		"warnOnly":              {"lowestEfficiency:\n  warn: 0.9", false, RulePassed, "", true},
+		"warnSeverity":          {"lowestEfficiency:\n  severity: warn\n  fail: 0.99", false, RuleWarning, "image efficiency is too low (efficiency=0.9844212134184309 < threshold=0.99)", true},
+		"singleValue":           {"lowestEfficiency: 0.99", false, RuleFailed, "image efficiency is too low (efficiency=0.9844212134184309 < threshold=0.99)", false},
+		"offSeverity":           {"lowestEfficiency:\n  severity: off\n  fail: 0.99", false, RuleDisabled, "rule disabled", true},
+		"unknownSeverity":       {"lowestEfficiency:\n  severity: fatal\n  fail: 0.99", false, RuleMisconfigured, "invalid lowestEfficiency severity ('fatal'): expected error, warn, or off", false},
+		"unknownOption":         {"lowestEfficiency:\n  warning: 0.99", false, RuleMisconfigured, "unknown lowestEfficiency option: 'warning' (expected severity, warn, fail, or ignore)", false},
+		"noThreshold":           {"lowestEfficiency:\n  severity: warn", false, RuleMisconfigured, "lowestEfficiency requires a 'warn' or 'fail' threshold", false},
+		"invalidWarn":           {"lowestEfficiency:\n  warn: 1.5\n  fail: 0.9", false, RuleMisconfigured, "warn threshold: lowestEfficiency config value is outside allowed range (0-1), given '1.5'", false},
+		"bytesWarn":             {"highestWastedBytes:\n  warn: 10kB\n  fail: 50kB", false, RuleWarning, "too many bytes wasted (wasted-bytes=32025 > threshold=10000)", true},
+	}
+
+	for name, test := range table {
+		ciConfig := viper.New()
+		ciConfig.SetConfigType("yaml")
+		if err := ciConfig.ReadConfig(bytes.NewBufferString("rules:\n  " + strings.ReplaceAll(test.config, "\n", "\n  "))); err != nil {
+			t.Fatalf("%s: unable to read config: %v", name, err)
+		}
+		rule := strings.SplitN(test.config, ":", 2)[0]
+		for _, definition := range RegisteredRules() {
+			if definition.Key != rule {
+				ciConfig.SetDefault("rules."+definition.Key, "disabled")
+			}
+		}
+		ciConfig.SetDefault("fail-on-warn", test.failOnWarn)
+
+		evaluator := NewCiEvaluator(ciConfig)
+		pass := evaluator.Evaluate(result)
+
+		actualResult := evaluator.Results[rule]
+		if test.expectedStatus != actualResult.status || test.expectedDetail != actualResult.message {
+			t.Errorf("%s: expected %v (%q), got %v (%q)", name, test.expectedStatus, test.expectedDetail, actualResult.status, actualResult.message)
+		}
+		if test.expectedPass != pass {
+			t.Errorf("%s: expected pass=%v, got %v", name, test.expectedPass, pass)
+		}
+		if test.expectedStatus == RuleWarning && evaluator.Tally.Warn != 1 {
+			t.Errorf("%s: expected 1 warning, got %d", name, evaluator.Tally.Warn)
+		}
+	}
+
+}
+
+func Test_EvaluatorBaseline(t *testing.T) {
+
+	result := docker.TestAnalysisFromArchive(t, "../../.data/test-docker-image.tar")
+
+	// the baseline is 10% smaller, wastes 1 kB less, and has one fewer inefficient file
+	baseline := export.NewExport(result)
+	baseline.Image.SizeBytes = baseline.Image.SizeBytes * 10 / 11
+	baseline.Image.InefficientBytes -= 1000
+	baseline.Image.InefficientFiles = baseline.Image.InefficientFiles[1:]
+
+	table := map[string]struct {
+		baseline       *export.Export
+		sizeIncrease   interface{}
+		wastedIncrease interface{}
+		noNewFiles     string
+		expectedPass   bool
+		expectedResult map[string]RuleResult
+	}{
+		"noBaseline": {nil, "5%", "1MB", "true", true, map[string]RuleResult{
+			"maxSizeIncrease":        {status: RuleDisabled, message: "no baseline given (see --baseline)"},
+			"maxWastedBytesIncrease": {status: RuleDisabled, message: "no baseline given (see --baseline)"},
+			"noNewInefficientFiles":  {status: RuleDisabled, message: "no baseline given (see --baseline)"},
+		}},
+		"unchanged": {export.NewExport(result), "0%", "0B", "true", true, map[string]RuleResult{
+			"maxSizeIncrease":        {status: RulePassed},
+			"maxWastedBytesIncrease": {status: RulePassed},
+			"noNewInefficientFiles":  {status: RulePassed},
+		}},
+		"grown": {baseline, "5%", "500B", "true", false, map[string]RuleResult{
+			"maxSizeIncrease":        {status: RuleFailed, message: "image size grew too much since the baseline (image-size=1220598, baseline=1109634, increase=110964 (10.00%) > threshold=5%)"},
+			"maxWastedBytesIncrease": {status: RuleFailed, message: "wasted bytes grew too much since the baseline (wasted-bytes=32025, baseline=31025, increase=1000 (3.22%) > threshold=500B)"},
+			"noNewInefficientFiles":  {status: RuleFailed, message: "found 1 new inefficient files since the baseline: /root/saved.txt (13 kB)"},
+		}},
+		"grownWithinLimits": {baseline, map[string]interface{}{"warn": "5%", "fail": "20%"}, "10%", "false", true, map[string]RuleResult{
+			"maxSizeIncrease":        {status: RuleWarning, message: "image size grew too much since the baseline (image-size=1220598, baseline=1109634, increase=110964 (10.00%) > threshold=5%)"},
+			"maxWastedBytesIncrease": {status: RulePassed},
+			"noNewInefficientFiles":  {status: RuleDisabled},
+		}},
+	}
+
+	for name, test := range table {
+		ciConfig := viper.New()
+		for _, rule := range RegisteredRules() {
+			ciConfig.SetDefault("rules."+rule.Key, "disabled")
+		}
+		ciConfig.SetDefault("rules.maxSizeIncrease", test.sizeIncrease)
+		ciConfig.SetDefault("rules.maxWastedBytesIncrease", test.wastedIncrease)
+		ciConfig.SetDefault("rules.noNewInefficientFiles", test.noNewFiles)
+
+		evaluator := NewCiEvaluator(ciConfig)
+		evaluator.Baseline = test.baseline
+		pass := evaluator.Evaluate(result)
+
+		if test.expectedPass != pass {
+			t.Errorf("%s: expected pass=%v, got %v", name, test.expectedPass, pass)
+		}
+		for rule, expectedResult := range test.expectedResult {
+			if actualResult := evaluator.Results[rule]; expectedResult != actualResult {
+				t.Errorf("%s: %s: expected %v (%q), got %v (%q)", name, rule, expectedResult.status, expectedResult.message, actualResult.status, actualResult.message)
+			}
+		}
+	}
+
+	// the differences are shown within the report
+	evaluator := NewCiEvaluator(viper.New())
+	evaluator.Rules = nil
+	evaluator.Baseline = baseline
+	evaluator.Evaluate(result)
+	report := evaluator.Report()
+	for _, expected := range []string{"  imageSize: 1.2 MB (+111 kB)\n", "  wastedBytes: 32 kB (+1.0 kB)\n", "  layers: 14 (no change)\n", "    2         13 kB  /root/saved.txt\n"} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected report containing %q, got:\n%s", expected, report)
+		}
+	}
+
+}
+
+func Test_EvaluatorCustomRules(t *testing.T) {
+
+	result := docker.TestAnalysisFromArchive(t, "../../.data/test-docker-image.tar")
+
+	table := map[string]struct {
+		config         string
+		rule           string
+		expectedStatus RuleStatus
+		expectedDetail string
+		expectedPass   bool
+	}{
+		"lastLayer":         {"name: lastLayer\nexpression: 'layers[-1].sizeBytes < 50MB'", "lastLayer", RulePassed, "", true},
+		"noDocs":            {"name: noDocs\nexpression: 'count(files, f.path matches \"^/root/\") == 0'\nmessage: remove the files of /root", "noDocs", RuleFailed, "remove the files of /root", false},
+		"defaultMessage":    {"name: small\nexpression: image.sizeBytes < 1MB", "small", RuleFailed, "expression is false: image.sizeBytes < 1MB", false},
+		"warnSeverity":      {"name: small\nexpression: image.sizeBytes < 1MB\nseverity: warn", "small", RuleWarning, "expression is false: image.sizeBytes < 1MB", true},
+		"offSeverity":       {"name: small\nexpression: image.sizeBytes < 1MB\nseverity: off", "small", RuleDisabled, "rule disabled", true},
+		"config":            {"name: user\nexpression: config.User == \"\" && config.Cmd[0] == \"sh\" && image.os == \"linux\"", "user", RulePassed, "", true},
+		"inefficiencies":    {"name: wasted\nexpression: sum(inefficiencies, i.sizeBytes) == image.wastedBytes && any(inefficiencies, len(i.layers) > 1)", "wasted", RulePassed, "", true},
+		"fileLayers":        {"name: layers\nexpression: all(files, f.layer >= 0 && f.layer < image.layerCount)", "layers", RulePassed, "", true},
+		"evaluationError":   {"name: index\nexpression: layers[99].sizeBytes > 0", "index", RuleFailed, "unable to evaluate expression: index 99 is out of range (length 14) (at position 6)", false},
+		"notBool":           {"name: size\nexpression: image.sizeBytes", "size", RuleFailed, "unable to evaluate expression: expression must be true or false, got a number 1220598", false},
+		"compileError":      {"name: size\nexpression: image.sizeBytes <", "size", RuleMisconfigured, "invalid expression: unexpected end of expression at position 17", false},
+		"noExpression":      {"name: size", "size", RuleMisconfigured, "custom rule 'size' requires an expression", false},
+		"noName":            {"expression: 'true'", "customRules[0]", RuleMisconfigured, "custom rule 0 requires a name", false},
+		"takenName":         {"name: lowestEfficiency\nexpression: 'true'", "customRules[0]", RuleMisconfigured, "custom rule name 'lowestEfficiency' is already taken by another rule", false},
+		"unknownOption":     {"name: size\nexpression: 'true'\nfail: 1", "size", RuleMisconfigured, "unknown custom rule option: 'fail' (expected name, expression, message, or severity)", false},
+		"unknownSeverity":   {"name: size\nexpression: 'true'\nseverity: fatal", "size", RuleMisconfigured, "invalid custom rule severity ('fatal'): expected error, warn, or off", false},
+		"notRuleDefinition": {"true", "customRules[0]", RuleMisconfigured, "custom rule 0 must have a name and an expression", false},
+	}
+
+	for name, test := range table {
+		ciConfig := viper.New()
+		ciConfig.SetConfigType("yaml")
+		config := "customRules:\n  - " + strings.ReplaceAll(test.config, "\n", "\n    ")
+		if err := ciConfig.ReadConfig(bytes.NewBufferString(config)); err != nil {
+			t.Fatalf("%s: unable to read config: %v", name, err)
+		}
+		for _, definition := range RegisteredRules() {
+			ciConfig.SetDefault("rules."+definition.Key, "disabled")
+		}
+
+		evaluator := NewCiEvaluator(ciConfig)
+		pass := evaluator.Evaluate(result)
+
+		actualResult, exists := evaluator.Results[test.rule]
+		if !exists {
+			t.Errorf("%s: expected a result for %s, got %v", name, test.rule, evaluator.Results)
+			continue
+		}
+		if test.expectedStatus != actualResult.status || test.expectedDetail != actualResult.message {
+			t.Errorf("%s: expected %v (%q), got %v (%q)", name, test.expectedStatus, test.expectedDetail, actualResult.status, actualResult.message)
+		}
+		if test.expectedPass != pass {
+			t.Errorf("%s: expected pass=%v, got %v", name, test.expectedPass, pass)
+		}
+	}
+
+	// custom rules cannot share a name, and must be given as a list
+	ciConfig := viper.New()
+	ciConfig.Set("customRules", []interface{}{
+		map[string]interface{}{"name": "same", "expression": "true"},
+		map[string]interface{}{"name": "same", "expression": "false"},
+	})
+	rules := loadCustomRules(ciConfig, nil)
+	if len(rules) != 2 || rules[0].Validate() != nil || rules[1].Key() != "customRules[1]" || rules[1].Validate() == nil {
+		t.Errorf("expected the second rule to be misconfigured, got %+v", rules)
+	}
+	ciConfig.Set("customRules", "layers[0].sizeBytes > 0")
+	rules = loadCustomRules(ciConfig, nil)
+	if len(rules) != 1 || rules[0].Validate() == nil || rules[0].Validate().Error() != "customRules must be a list of rules (each with a name and an expression)" {
+		t.Errorf("expected a misconfigured list, got %+v", rules)
+	}
+
+}
+
+func Test_EvaluatorIgnore(t *testing.T) {
+
+	result := docker.TestAnalysisFromArchive(t, "../../.data/test-docker-image.tar")
+
+	// note: 32025 bytes are wasted, 12810 of them by /root/saved.txt
+	table := map[string]struct {
+		config               string
+		rule                 string
+		expectedStatus       RuleStatus
+		expectedDetail       string
+		expectedIgnored      map[string]string
+		expectedInefficients int
+	}{
+		"none":          {"rules:\n  highestWastedBytes: 20kB", "highestWastedBytes", RuleFailed, "too many bytes wasted (wasted-bytes=32025 > threshold=20000)", map[string]string{}, 3},
+		"global":        {"ignore:\n  - /root/saved.txt\nrules:\n  highestWastedBytes: 20kB", "highestWastedBytes", RulePassed, "", map[string]string{"/root/saved.txt": "ignore (highestWastedBytes)"}, 2},
+		"globalPercent": {"ignore: [/root/saved.txt]\nrules:\n  highestUserWastedPercent: 0.1", "highestUserWastedPercent", RuleFailed, "too many bytes wasted, relative to the user bytes added (%-user-wasted-bytes=0.29009466008424295 > threshold=0.1)", map[string]string{"/root/saved.txt": "ignore (highestUserWastedPercent)"}, 2},
+		"rule":          {"rules:\n  highestWastedBytes:\n    fail: 10kB\n    ignore: [/root/example]", "highestWastedBytes", RuleFailed, "too many bytes wasted (wasted-bytes=12810 > threshold=10000)", map[string]string{"/root/example/somefile1.txt": "rules.highestWastedBytes.ignore", "/root/example/somefile3.txt": "rules.highestWastedBytes.ignore"}, 3},
+		"glob":          {"rules:\n  highestWastedBytes:\n    fail: 20kB\n    ignore: ['/root/*.txt']", "highestWastedBytes", RulePassed, "", map[string]string{"/root/saved.txt": "rules.highestWastedBytes.ignore"}, 3},
+		"notIgnorable":  {"rules:\n  lowestEfficiency:\n    fail: 0.9\n    ignore: [/root]", "lowestEfficiency", RuleMisconfigured, "lowestEfficiency does not support the 'ignore' option", map[string]string{}, 0},
+	}
+
+	for name, test := range table {
+		ciConfig := viper.New()
+		ciConfig.SetConfigType("yaml")
+		if err := ciConfig.ReadConfig(bytes.NewBufferString(test.config)); err != nil {
+			t.Fatalf("%s: unable to read config: %v", name, err)
+		}
+		for _, definition := range RegisteredRules() {
+			if definition.Key != test.rule {
+				ciConfig.SetDefault("rules."+definition.Key, "disabled")
+			}
+		}
+
+		evaluator := NewCiEvaluator(ciConfig)
+		evaluator.Evaluate(result)
+
+		actualResult := evaluator.Results[test.rule]
+		if test.expectedStatus != actualResult.status || test.expectedDetail != actualResult.message {
+			t.Errorf("%s: expected %v (%q), got %v (%q)", name, test.expectedStatus, test.expectedDetail, actualResult.status, actualResult.message)
+		}
+		ignored := make(map[string]string)
+		for _, file := range evaluator.IgnoredFiles {
+			ignored[file.Path] = file.IgnoredBy
+		}
+		if !reflect.DeepEqual(test.expectedIgnored, ignored) {
+			t.Errorf("%s: expected ignored files %v, got %v", name, test.expectedIgnored, ignored)
+		}
+		if len(evaluator.InefficientFiles) != test.expectedInefficients {
+			t.Errorf("%s: expected %d inefficient files, got %d", name, test.expectedInefficients, len(evaluator.InefficientFiles))
+		}
+	}
+
+}
+
+func Test_EvaluatorPolicyRules(t *testing.T) {
+
+	result := docker.TestAnalysisFromArchive(t, "../../.data/test-docker-image.tar")
+
+	configured := *result
+	configured.ContainerConfig = &image.ContainerConfig{
+		User:         "app:staff",
+		Env:          []string{"PATH=/bin", "GITHUB_TOKEN=secret", "app_token=secret"},
+		Labels:       map[string]string{"maintainer": "dive", "version": "1.2.3"},
+		ExposedPorts: map[string]struct{}{"22/tcp": {}, "53/udp": {}, "8080/tcp": {}},
+		Healthcheck:  &image.HealthConfig{Test: []string{"CMD", "true"}},
+	}
+	disabledHealthcheck := configured
+	disabledHealthcheck.ContainerConfig = &image.ContainerConfig{User: "0", Healthcheck: &image.HealthConfig{Test: []string{"NONE"}}}
+	unknownConfig := *result
+	unknownConfig.ContainerConfig = nil
+
+	table := map[string]struct {
+		analysis       *image.AnalysisResult
+		rule           string
+		value          interface{}
+		expectedStatus RuleStatus
+		expectedDetail string
+	}{
+		"rootByDefault":      {result, "requireNonRootUser", true, RuleFailed, "image runs as root (no USER given)"},
+		"rootByUid":          {&disabledHealthcheck, "requireNonRootUser", true, RuleFailed, "image runs as root (user=0)"},
+		"nonRootUser":        {&configured, "requireNonRootUser", true, RulePassed, ""},
+		"noHealthcheck":      {result, "requireHealthcheck", true, RuleFailed, "image has no health check"},
+		"disableHealthcheck": {&disabledHealthcheck, "requireHealthcheck", true, RuleFailed, "image disables the health check (HEALTHCHECK NONE)"},
+		"healthcheck":        {&configured, "requireHealthcheck", true, RulePassed, ""},
+		"labels":             {&configured, "requiredLabels", []string{"maintainer", `version=^\d+\.\d+\.\d+$`}, RulePassed, ""},
+		"labelsMissing":      {&configured, "requiredLabels", []string{"maintainer", "version=^2", "licenses"}, RuleFailed, "missing 2 required labels: version (value '1.2.3' does not match '^2'), licenses"},
+		"labelsInvalid":      {&configured, "requiredLabels", []string{"version=("}, RuleMisconfigured, "invalid config value ('version=('): error parsing regexp: missing closing ): `(`"},
+		"portsForbidden":     {&configured, "forbiddenExposedPorts", []string{"22", "50-60/udp", "8000-8079/tcp"}, RuleFailed, "found 2 forbidden exposed ports: 22/tcp, 53/udp"},
+		"portsAllowed":       {&configured, "forbiddenExposedPorts", []string{"53/tcp", "6000-6063"}, RulePassed, ""},
+		"portsInvalid":       {&configured, "forbiddenExposedPorts", []string{"22/icmp"}, RuleMisconfigured, "invalid config value ('22/icmp'): unknown protocol 'icmp' (expected tcp, udp, or sctp)"},
+		"envKeysForbidden":   {&configured, "forbiddenEnvKeys", []string{"*_TOKEN"}, RuleFailed, "found 2 forbidden environment variables: GITHUB_TOKEN, app_token"},
+		"envKeysAllowed":     {&configured, "forbiddenEnvKeys", []string{"*_PASSWORD"}, RulePassed, ""},
+		"configUnknown":      {&unknownConfig, "requiredLabels", []string{"maintainer"}, RuleDisabled, "image config unknown"},
+	}
+
+	for name, test := range table {
+		ciConfig := viper.New()
+		for _, rule := range RegisteredRules() {
+			ciConfig.SetDefault("rules."+rule.Key, "disabled")
+		}
+		ciConfig.Set("rules."+test.rule, test.value)
+
+		evaluator := NewCiEvaluator(ciConfig)
+		evaluator.Evaluate(test.analysis)
+
+		actualResult := evaluator.Results[test.rule]
+		if test.expectedStatus != actualResult.status || test.expectedDetail != actualResult.message {
+			t.Errorf("%s: expected %v (%q), got %v (%q)", name, test.expectedStatus, test.expectedDetail, actualResult.status, actualResult.message)
+		}
+	}
+
+}
@@ -0,0 +1,415 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxSteps bounds the work of a single evaluation (each evaluated subexpression is a step), so that an expression over
+// every file of a large image (e.g. comparing every file with every other file) fails rather than running for hours.
+const MaxSteps = 10000000
+
+// stepLimit is the limit enforced (see MaxSteps).
+var stepLimit = MaxSteps
+
+// Expression is a compiled expression, which can be evaluated any number of times.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses the given source as an expression.
+func Compile(source string) (*Expression, error) {
+	root, err := parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+func (expression *Expression) String() string {
+	return expression.source
+}
+
+// Evaluate returns the value of the expression, given the variables it may refer to. Variables are values as decoded
+// from JSON: nil, bool, float64, string, []interface{}, and map[string]interface{} (any other value is an error when
+// used).
+func (expression *Expression) Evaluate(variables map[string]interface{}) (interface{}, error) {
+	ctx := &context{variables: variables}
+	return expression.root.eval(ctx)
+}
+
+// EvaluateBool returns the value of an expression that must be true or false.
+func (expression *Expression) EvaluateBool(variables map[string]interface{}) (bool, error) {
+	value, err := expression.Evaluate(variables)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression must be true or false, got %s %s", typeName(value), format(value))
+	}
+	return result, nil
+}
+
+// context is the state of a single evaluation.
+type context struct {
+	variables map[string]interface{}
+	// bindings are the names given to the current element(s) within predicates, innermost last
+	bindings []map[string]interface{}
+	steps    int
+}
+
+func (ctx *context) step(n node) error {
+	ctx.steps++
+	if ctx.steps > stepLimit {
+		return fmt.Errorf("expression is too expensive to evaluate (stopped at position %d after %d steps)", n.position(), stepLimit)
+	}
+	return nil
+}
+
+func (ctx *context) lookup(name string) (interface{}, bool) {
+	for idx := len(ctx.bindings) - 1; idx >= 0; idx-- {
+		if value, exists := ctx.bindings[idx][name]; exists {
+			return value, true
+		}
+	}
+	value, exists := ctx.variables[name]
+	return value, exists
+}
+
+// errorAt describes an evaluation error at the given node.
+func errorAt(n node, format string, args ...interface{}) error {
+	return fmt.Errorf("%s (at position %d)", fmt.Sprintf(format, args...), n.position())
+}
+
+func (n *literalNode) eval(ctx *context) (interface{}, error) {
+	return n.value, ctx.step(n)
+}
+
+func (n *identNode) eval(ctx *context) (interface{}, error) {
+	if err := ctx.step(n); err != nil {
+		return nil, err
+	}
+	value, exists := ctx.lookup(n.name)
+	if !exists {
+		return nil, errorAt(n, "unknown name '%s'", n.name)
+	}
+	return value, nil
+}
+
+func (n *memberNode) eval(ctx *context) (interface{}, error) {
+	if err := ctx.step(n); err != nil {
+		return nil, err
+	}
+	target, err := n.target.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch typed := target.(type) {
+	case map[string]interface{}:
+		// note: missing fields are null, so that optional fields (e.g. of the image config) can be checked
+		return typed[n.name], nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, errorAt(n, "cannot get field '%s' of %s", n.name, typeName(target))
+	}
+}
+
+func (n *indexNode) eval(ctx *context) (interface{}, error) {
+	if err := ctx.step(n); err != nil {
+		return nil, err
+	}
+	target, err := n.target.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typed := target.(type) {
+	case []interface{}:
+		number, ok := index.(float64)
+		if !ok || number != math.Trunc(number) {
+			return nil, errorAt(n, "list index must be a whole number, got %s", format(index))
+		}
+		idx := int(number)
+		if idx < 0 {
+			// negative indexes count from the end (e.g. -1 is the last element)
+			idx += len(typed)
+		}
+		if idx < 0 || idx >= len(typed) {
+			return nil, errorAt(n, "index %s is out of range (length %d)", format(index), len(typed))
+		}
+		return typed[idx], nil
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return nil, errorAt(n, "object key must be a string, got %s", format(index))
+		}
+		return typed[key], nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, errorAt(n, "cannot index %s", typeName(target))
+	}
+}
+
+func (n *listNode) eval(ctx *context) (interface{}, error) {
+	if err := ctx.step(n); err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(n.items))
+	for idx, item := range n.items {
+		value, err := item.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values[idx] = value
+	}
+	return values, nil
+}
+
+func (n *unaryNode) eval(ctx *context) (interface{}, error) {
+	if err := ctx.step(n); err != nil {
+		return nil, err
+	}
+	operand, err := n.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		value, ok := operand.(bool)
+		if !ok {
+			return nil, errorAt(n, "cannot negate %s (expected true or false)", typeName(operand))
+		}
+		return !value, nil
+	default:
+		value, ok := operand.(float64)
+		if !ok {
+			return nil, errorAt(n, "cannot negate %s (expected a number)", typeName(operand))
+		}
+		return -value, nil
+	}
+}
+
+func (n *binaryNode) eval(ctx *context) (interface{}, error) {
+	if err := ctx.step(n); err != nil {
+		return nil, err
+	}
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the logical operators only evaluate the right side when it decides the result
+	if n.op == "&&" || n.op == "||" {
+		leftBool, ok := left.(bool)
+		if !ok {
+			return nil, errorAt(n, "'%s' expects true or false, got %s", n.op, typeName(left))
+		}
+		if (n.op == "&&" && !leftBool) || (n.op == "||" && leftBool) {
+			return leftBool, nil
+		}
+		right, err := n.right.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rightBool, ok := right.(bool)
+		if !ok {
+			return nil, errorAt(n, "'%s' expects true or false, got %s", n.op, typeName(right))
+		}
+		return rightBool, nil
+	}
+
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "<", "<=", ">", ">=":
+		return n.compare(left, right)
+	case "+", "-", "*", "/", "%":
+		return n.arithmetic(left, right)
+	case "matches":
+		return n.matches(left, right)
+	case "contains":
+		return n.contains(left, right)
+	case "in":
+		return n.contains(right, left)
+	}
+	return nil, errorAt(n, "unknown operator '%s'", n.op)
+}
+
+func (n *binaryNode) compare(left, right interface{}) (interface{}, error) {
+	var order int
+	switch leftValue := left.(type) {
+	case float64:
+		rightValue, ok := right.(float64)
+		if !ok {
+			return nil, errorAt(n, "cannot compare %s with %s", typeName(left), typeName(right))
+		}
+		switch {
+		case leftValue < rightValue:
+			order = -1
+		case leftValue > rightValue:
+			order = 1
+		}
+	case string:
+		rightValue, ok := right.(string)
+		if !ok {
+			return nil, errorAt(n, "cannot compare %s with %s", typeName(left), typeName(right))
+		}
+		order = strings.Compare(leftValue, rightValue)
+	default:
+		return nil, errorAt(n, "cannot compare %s with %s", typeName(left), typeName(right))
+	}
+
+	switch n.op {
+	case "<":
+		return order < 0, nil
+	case "<=":
+		return order <= 0, nil
+	case ">":
+		return order > 0, nil
+	default:
+		return order >= 0, nil
+	}
+}
+
+func (n *binaryNode) arithmetic(left, right interface{}) (interface{}, error) {
+	if n.op == "+" {
+		if leftValue, ok := left.(string); ok {
+			if rightValue, ok := right.(string); ok {
+				return leftValue + rightValue, nil
+			}
+		}
+	}
+
+	leftValue, leftOk := left.(float64)
+	rightValue, rightOk := right.(float64)
+	if !leftOk || !rightOk {
+		return nil, errorAt(n, "cannot apply '%s' to %s and %s", n.op, typeName(left), typeName(right))
+	}
+	switch n.op {
+	case "+":
+		return leftValue + rightValue, nil
+	case "-":
+		return leftValue - rightValue, nil
+	case "*":
+		return leftValue * rightValue, nil
+	}
+	if rightValue == 0 {
+		return nil, errorAt(n, "division by zero")
+	}
+	if n.op == "/" {
+		return leftValue / rightValue, nil
+	}
+	return math.Mod(leftValue, rightValue), nil
+}
+
+func (n *binaryNode) matches(left, right interface{}) (interface{}, error) {
+	value, ok := left.(string)
+	if !ok {
+		return nil, errorAt(n, "'matches' expects a string, got %s", typeName(left))
+	}
+	pattern := n.pattern
+	if pattern == nil {
+		source, ok := right.(string)
+		if !ok {
+			return nil, errorAt(n, "'matches' expects a pattern string, got %s", typeName(right))
+		}
+		var err error
+		pattern, err = regexp.Compile(source)
+		if err != nil {
+			return nil, errorAt(n, "invalid pattern: %v", err)
+		}
+	}
+	return pattern.MatchString(value), nil
+}
+
+// contains returns true when the container (a string, list, or object) holds the element (a substring, list element,
+// or object key).
+func (n *binaryNode) contains(container, element interface{}) (interface{}, error) {
+	switch typed := container.(type) {
+	case string:
+		substring, ok := element.(string)
+		if !ok {
+			return nil, errorAt(n, "cannot search a string for %s", typeName(element))
+		}
+		return strings.Contains(typed, substring), nil
+	case []interface{}:
+		for _, item := range typed {
+			if equal(item, element) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		key, ok := element.(string)
+		if !ok {
+			return nil, errorAt(n, "object key must be a string, got %s", typeName(element))
+		}
+		_, exists := typed[key]
+		return exists, nil
+	case nil:
+		return false, nil
+	default:
+		return nil, errorAt(n, "cannot search %s", typeName(container))
+	}
+}
+
+func equal(left, right interface{}) bool {
+	return reflect.DeepEqual(left, right)
+}
+
+// typeName describes the type of a value within error messages.
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	default:
+		return fmt.Sprintf("an unsupported value (%T)", value)
+	}
+}
+
+// format describes a value within error messages.
+func format(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case string:
+		return fmt.Sprintf("%q", typed)
+	case []interface{}:
+		return fmt.Sprintf("(list of %d)", len(typed))
+	case map[string]interface{}:
+		return "(object)"
+	default:
+		return fmt.Sprintf("%v", typed)
+	}
+}
@@ -0,0 +1,159 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func testVariables() map[string]interface{} {
+	return map[string]interface{}{
+		"layers": []interface{}{
+			map[string]interface{}{"index": float64(0), "sizeBytes": float64(5000000), "command": "#(nop) ADD file:abc in /"},
+			map[string]interface{}{"index": float64(1), "sizeBytes": float64(60000000), "command": "apt-get install -y curl"},
+		},
+		"files": []interface{}{
+			map[string]interface{}{"path": "/usr/share/doc/curl/README", "sizeBytes": float64(1200)},
+			map[string]interface{}{"path": "/usr/bin/curl", "sizeBytes": float64(250000)},
+			map[string]interface{}{"path": "/etc/hosts", "sizeBytes": float64(100)},
+		},
+		"config": map[string]interface{}{
+			"User":   "app",
+			"Labels": map[string]interface{}{"maintainer": "team@example.com"},
+			"Env":    []interface{}{"PATH=/usr/bin", "LANG=C.UTF-8"},
+		},
+	}
+}
+
+func Test_Evaluate(t *testing.T) {
+	table := map[string]struct {
+		source   string
+		expected interface{}
+	}{
+		"lastLayer":        {`layers[-1].sizeBytes < 50MB`, false},
+		"firstLayer":       {`layers[0].sizeBytes <= 5MB`, true},
+		"countMatches":     {`count(files, f.path matches "^/usr/share/doc") == 0`, false},
+		"countAll":         {`count(files)`, float64(3)},
+		"it":               {`count(files, it.sizeBytes > 1kB)`, float64(2)},
+		"any":              {`any(layers, l.command contains "apt-get")`, true},
+		"all":              {`all(files, startsWith(f.path, "/"))`, true},
+		"filterAndSum":     {`sum(filter(files, f.sizeBytes < 10KiB), f.sizeBytes)`, float64(1300)},
+		"max":              {`max(layers, l.sizeBytes) / 1MB`, float64(60)},
+		"minEmpty":         {`min(filter(files, false))`, float64(0)},
+		"map":              {`map(layers, l.index)`, []interface{}{float64(0), float64(1)}},
+		"nested":           {`any(layers, any(files, f.sizeBytes > l.sizeBytes))`, false},
+		"config":           {`config.User != "root" && config.User != ""`, true},
+		"missingField":     {`config.Healthcheck == null`, true},
+		"missingNested":    {`config.Healthcheck.Test == null`, true},
+		"labelIn":          {`"maintainer" in config.Labels`, true},
+		"labelIndex":       {`config.Labels["maintainer"] matches "@example\\.com$"`, true},
+		"envContains":      {`config.Env contains "LANG=C.UTF-8"`, true},
+		"listLiteral":      {`config.User in ["app", "nobody"]`, true},
+		"keywords":         {`not (config.User == "root") and (false or true)`, true},
+		"precedence":       {`1 + 2 * 3 - 4 / 2 % 3`, float64(5)},
+		"negation":         {`-layers[0].index - 1`, float64(-1)},
+		"strings":          {`lower("AB") + upper('c') + "\t"`, "abC\t"},
+		"len":              {`len(config.Env) + len("héllo") + len(config.Labels) + len(null)`, float64(8)},
+		"endsWith":         {`endsWith(files[1].path, "/curl")`, true},
+		"shortCircuit":     {`false && layers[99].sizeBytes > 0`, false},
+		"underscoreNumber": {`1_000 == 1000`, true},
+		"stringCompare":    {`"a" < "b"`, true},
+	}
+
+	for name, test := range table {
+		expression, err := Compile(test.source)
+		if err != nil {
+			t.Errorf("%s: unable to compile %q: %v", name, test.source, err)
+			continue
+		}
+		actual, err := expression.Evaluate(testVariables())
+		if err != nil {
+			t.Errorf("%s: unable to evaluate %q: %v", name, test.source, err)
+			continue
+		}
+		if !equal(actual, test.expected) {
+			t.Errorf("%s: expected %v, got %v", name, test.expected, actual)
+		}
+	}
+}
+
+func Test_CompileErrors(t *testing.T) {
+	table := map[string]struct {
+		source   string
+		expected string
+	}{
+		"empty":          {``, "unexpected end of expression at position 0"},
+		"trailing":       {`1 2`, "unexpected '2' at position 2"},
+		"unclosed":       {`count(files`, "expected ')' at position 11, found end of expression"},
+		"unknownFunc":    {`size(files)`, "unknown function 'size' at position 0"},
+		"arity":          {`len(files, 1)`, "len() at position 0 takes 1 arguments, given 2"},
+		"badSize":        {`10XB`, "invalid size '10XB' at position 0"},
+		"badString":      {`"abc`, "unterminated string at position 0"},
+		"badChar":        {`a # b`, "unexpected character '#' at position 2"},
+		"badPattern":     {`"a" matches "("`, "invalid pattern at position 12"},
+		"patternType":    {`"a" matches 1`, "expected a pattern string after 'matches' at position 12"},
+		"field":          {`config.`, "expected a field name at position 7, found end of expression"},
+		"keywordAsValue": {`in`, "unexpected 'in' at position 0"},
+		"tooDeep":        {strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100), "expression is nested too deeply"},
+	}
+
+	for name, test := range table {
+		_, err := Compile(test.source)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", name, test.expected, err)
+		}
+	}
+}
+
+func Test_EvaluateErrors(t *testing.T) {
+	defer func(limit int) { stepLimit = limit }(stepLimit)
+	stepLimit = 1000
+
+	table := map[string]struct {
+		source   string
+		expected string
+	}{
+		"unknownName":   {`image.sizeBytes > 0`, "unknown name 'image' (at position 0)"},
+		"outOfRange":    {`layers[2]`, "index 2 is out of range (length 2)"},
+		"fractionIndex": {`layers[0.5]`, "list index must be a whole number, got 0.5"},
+		"compare":       {`config.User > 1`, "cannot compare a string with a number (at position 12)"},
+		"compareNull":   {`config.Healthcheck > 1`, "cannot compare null with a number"},
+		"arithmetic":    {`config.User * 2`, "cannot apply '*' to a string and a number"},
+		"division":      {`1 / 0`, "division by zero"},
+		"logical":       {`1 && true`, "'&&' expects true or false, got a number"},
+		"notList":       {`count(config.User, true)`, "count() expects a list, got a string"},
+		"notBool":       {`any(files, f.path)`, "any() expects true or false for each element, got a string"},
+		"field":         {`config.User.name`, "cannot get field 'name' of a string"},
+		"expensive":     {`any(files, any(files, any(files, any(files, any(files, any(files, false))))))`, "expression is too expensive to evaluate (stopped at position 66 after 1000 steps)"},
+	}
+
+	for name, test := range table {
+		expression, err := Compile(test.source)
+		if err != nil {
+			t.Errorf("%s: unable to compile %q: %v", name, test.source, err)
+			continue
+		}
+		_, err = expression.Evaluate(testVariables())
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", name, test.expected, err)
+		}
+	}
+}
+
+func Test_EvaluateBool(t *testing.T) {
+	expression, err := Compile(`len(files)`)
+	if err != nil {
+		t.Fatalf("unable to compile: %v", err)
+	}
+	_, err = expression.EvaluateBool(testVariables())
+	if err == nil || err.Error() != "expression must be true or false, got a number 3" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	expression, err = Compile(`config.User == "app"`)
+	if err != nil {
+		t.Fatalf("unable to compile: %v", err)
+	}
+	if result, err := expression.EvaluateBool(testVariables()); err != nil || !result {
+		t.Errorf("expected true, got %v (%v)", result, err)
+	}
+}
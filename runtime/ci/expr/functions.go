@@ -0,0 +1,244 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// function is a built-in function. Functions over a list may take an expression as their second argument, which is
+// evaluated for each element of the list (see elementNames).
+type function struct {
+	minArgs int
+	maxArgs int
+	// perElement evaluates the second argument once for each element of the list given as the first argument
+	perElement bool
+	call       func(n *callNode, args []interface{}) (interface{}, error)
+	apply      func(n *callNode, list []interface{}, results []interface{}) (interface{}, error)
+}
+
+func (fn function) arity() string {
+	if fn.minArgs == fn.maxArgs {
+		return fmt.Sprintf("%d arguments", fn.minArgs)
+	}
+	return fmt.Sprintf("%d to %d arguments", fn.minArgs, fn.maxArgs)
+}
+
+var functions = map[string]function{
+	"len": {minArgs: 1, maxArgs: 1, call: func(n *callNode, args []interface{}) (interface{}, error) {
+		switch typed := args[0].(type) {
+		case string:
+			return float64(utf8.RuneCountInString(typed)), nil
+		case []interface{}:
+			return float64(len(typed)), nil
+		case map[string]interface{}:
+			return float64(len(typed)), nil
+		case nil:
+			return float64(0), nil
+		}
+		return nil, errorAt(n, "len() expects a string, list, or object, got %s", typeName(args[0]))
+	}},
+	"lower":      {minArgs: 1, maxArgs: 1, call: stringFunction(strings.ToLower)},
+	"upper":      {minArgs: 1, maxArgs: 1, call: stringFunction(strings.ToUpper)},
+	"startsWith": {minArgs: 2, maxArgs: 2, call: stringPredicate(strings.HasPrefix)},
+	"endsWith":   {minArgs: 2, maxArgs: 2, call: stringPredicate(strings.HasSuffix)},
+
+	"count": {minArgs: 1, maxArgs: 2, perElement: true, apply: func(n *callNode, list, results []interface{}) (interface{}, error) {
+		if results == nil {
+			return float64(len(list)), nil
+		}
+		var count float64
+		for _, result := range results {
+			matched, err := boolResult(n, result)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				count++
+			}
+		}
+		return count, nil
+	}},
+	"any": {minArgs: 2, maxArgs: 2, perElement: true, apply: func(n *callNode, list, results []interface{}) (interface{}, error) {
+		for _, result := range results {
+			if matched, err := boolResult(n, result); err != nil || matched {
+				return matched, err
+			}
+		}
+		return false, nil
+	}},
+	"all": {minArgs: 2, maxArgs: 2, perElement: true, apply: func(n *callNode, list, results []interface{}) (interface{}, error) {
+		for _, result := range results {
+			if matched, err := boolResult(n, result); err != nil || !matched {
+				return matched, err
+			}
+		}
+		return true, nil
+	}},
+	"filter": {minArgs: 2, maxArgs: 2, perElement: true, apply: func(n *callNode, list, results []interface{}) (interface{}, error) {
+		filtered := make([]interface{}, 0)
+		for idx, result := range results {
+			matched, err := boolResult(n, result)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				filtered = append(filtered, list[idx])
+			}
+		}
+		return filtered, nil
+	}},
+	"map": {minArgs: 2, maxArgs: 2, perElement: true, apply: func(n *callNode, list, results []interface{}) (interface{}, error) {
+		return results, nil
+	}},
+	"sum": {minArgs: 1, maxArgs: 2, perElement: true, apply: numbersFunction(func(numbers []float64) float64 {
+		var total float64
+		for _, number := range numbers {
+			total += number
+		}
+		return total
+	})},
+	"max": {minArgs: 1, maxArgs: 2, perElement: true, apply: numbersFunction(func(numbers []float64) float64 {
+		var highest float64
+		for idx, number := range numbers {
+			if idx == 0 || number > highest {
+				highest = number
+			}
+		}
+		return highest
+	})},
+	"min": {minArgs: 1, maxArgs: 2, perElement: true, apply: numbersFunction(func(numbers []float64) float64 {
+		var lowest float64
+		for idx, number := range numbers {
+			if idx == 0 || number < lowest {
+				lowest = number
+			}
+		}
+		return lowest
+	})},
+}
+
+func (n *callNode) eval(ctx *context) (interface{}, error) {
+	if err := ctx.step(n); err != nil {
+		return nil, err
+	}
+	fn := functions[n.name]
+
+	if !fn.perElement {
+		args := make([]interface{}, len(n.args))
+		for idx, arg := range n.args {
+			value, err := arg.eval(ctx)
+			if err != nil {
+				return nil, err
+			}
+			args[idx] = value
+		}
+		return fn.call(n, args)
+	}
+
+	value, err := n.args[0].eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var list []interface{}
+	switch typed := value.(type) {
+	case []interface{}:
+		list = typed
+	case nil:
+		// e.g. an optional list of the image config
+	default:
+		return nil, errorAt(n, "%s() expects a list, got %s", n.name, typeName(value))
+	}
+	if len(n.args) == 1 {
+		return fn.apply(n, list, nil)
+	}
+
+	names := elementNames(n.args[0])
+	binding := make(map[string]interface{}, len(names))
+	ctx.bindings = append(ctx.bindings, binding)
+	defer func() { ctx.bindings = ctx.bindings[:len(ctx.bindings)-1] }()
+
+	results := make([]interface{}, len(list))
+	for idx, element := range list {
+		for _, name := range names {
+			binding[name] = element
+		}
+		result, err := n.args[1].eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		results[idx] = result
+	}
+	return fn.apply(n, list, results)
+}
+
+// elementNames returns the names of the current element within an expression evaluated for each element of a list:
+// "it", along with the first letter of the name of the list (e.g. "f" for files, including files filtered by filter()).
+func elementNames(list node) []string {
+	names := []string{"it"}
+	var listName string
+	switch typed := list.(type) {
+	case *identNode:
+		listName = typed.name
+	case *memberNode:
+		listName = typed.name
+	case *callNode:
+		if typed.name == "filter" {
+			return elementNames(typed.args[0])
+		}
+	}
+	if first, _ := utf8.DecodeRuneInString(listName); first != utf8.RuneError {
+		names = append(names, string(unicode.ToLower(first)))
+	}
+	return names
+}
+
+func boolResult(n *callNode, result interface{}) (bool, error) {
+	matched, ok := result.(bool)
+	if !ok {
+		return false, errorAt(n, "%s() expects true or false for each element, got %s", n.name, typeName(result))
+	}
+	return matched, nil
+}
+
+// numbersFunction returns a function of the numbers of a list (or of the values given for each element), where an
+// empty list is zero.
+func numbersFunction(reduce func([]float64) float64) func(n *callNode, list, results []interface{}) (interface{}, error) {
+	return func(n *callNode, list, results []interface{}) (interface{}, error) {
+		values := list
+		if results != nil {
+			values = results
+		}
+		numbers := make([]float64, len(values))
+		for idx, value := range values {
+			number, ok := value.(float64)
+			if !ok {
+				return nil, errorAt(n, "%s() expects numbers, got %s", n.name, typeName(value))
+			}
+			numbers[idx] = number
+		}
+		return reduce(numbers), nil
+	}
+}
+
+func stringFunction(transform func(string) string) func(*callNode, []interface{}) (interface{}, error) {
+	return func(n *callNode, args []interface{}) (interface{}, error) {
+		value, ok := args[0].(string)
+		if !ok {
+			return nil, errorAt(n, "%s() expects a string, got %s", n.name, typeName(args[0]))
+		}
+		return transform(value), nil
+	}
+}
+
+func stringPredicate(predicate func(string, string) bool) func(*callNode, []interface{}) (interface{}, error) {
+	return func(n *callNode, args []interface{}) (interface{}, error) {
+		value, valueOk := args[0].(string)
+		affix, affixOk := args[1].(string)
+		if !valueOk || !affixOk {
+			return nil, errorAt(n, "%s() expects two strings, got %s and %s", n.name, typeName(args[0]), typeName(args[1]))
+		}
+		return predicate(value, affix), nil
+	}
+}
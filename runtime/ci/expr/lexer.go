@@ -0,0 +1,137 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dustin/go-humanize"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	// text is the token as written (the operator, or the name of an identifier)
+	text   string
+	number float64
+	str    string
+	pos    int
+}
+
+func (tok token) String() string {
+	if tok.kind == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("'%s'", tok.text)
+}
+
+// operators are every operator, the longest first (so that "<=" is not read as "<").
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", "[", "]", ".", ","}
+
+// tokenize splits the source into tokens, ending with an EOF token.
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	pos := 0
+
+	for pos < len(runes) {
+		char := runes[pos]
+		switch {
+		case unicode.IsSpace(char):
+			pos++
+
+		case unicode.IsDigit(char):
+			start := pos
+			for pos < len(runes) && (unicode.IsDigit(runes[pos]) || runes[pos] == '_') {
+				pos++
+			}
+			if pos+1 < len(runes) && runes[pos] == '.' && unicode.IsDigit(runes[pos+1]) {
+				pos++
+				for pos < len(runes) && unicode.IsDigit(runes[pos]) {
+					pos++
+				}
+			}
+			digits := strings.ReplaceAll(string(runes[start:pos]), "_", "")
+
+			// a size unit may directly follow the number (e.g. 50MB)
+			unitStart := pos
+			for pos < len(runes) && unicode.IsLetter(runes[pos]) {
+				pos++
+			}
+			text := string(runes[start:pos])
+			var value float64
+			if unitStart < pos {
+				bytes, err := humanize.ParseBytes(digits + string(runes[unitStart:pos]))
+				if err != nil {
+					return nil, fmt.Errorf("invalid size '%s' at position %d", text, start)
+				}
+				value = float64(bytes)
+			} else {
+				var err error
+				value, err = strconv.ParseFloat(digits, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid number '%s' at position %d", text, start)
+				}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, number: value, pos: start})
+
+		case char == '"' || char == '\'':
+			start := pos
+			pos++
+			var value strings.Builder
+			for ; pos < len(runes) && runes[pos] != char; pos++ {
+				if runes[pos] == '\\' && pos+1 < len(runes) {
+					pos++
+					switch runes[pos] {
+					case 'n':
+						value.WriteRune('\n')
+					case 't':
+						value.WriteRune('\t')
+					default:
+						value.WriteRune(runes[pos])
+					}
+					continue
+				}
+				value.WriteRune(runes[pos])
+			}
+			if pos == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			pos++
+			tokens = append(tokens, token{kind: tokenString, text: string(runes[start:pos]), str: value.String(), pos: start})
+
+		case unicode.IsLetter(char) || char == '_':
+			start := pos
+			for pos < len(runes) && (unicode.IsLetter(runes[pos]) || unicode.IsDigit(runes[pos]) || runes[pos] == '_') {
+				pos++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:pos]), pos: start})
+
+		default:
+			matched := false
+			for _, operator := range operators {
+				if strings.HasPrefix(string(runes[pos:]), operator) {
+					tokens = append(tokens, token{kind: tokenOperator, text: operator, pos: pos})
+					pos += len([]rune(operator))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character '%c' at position %d", char, pos)
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
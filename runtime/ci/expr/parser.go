@@ -0,0 +1,358 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// maxDepth is the deepest expressions may nest (e.g. within parentheses).
+const maxDepth = 64
+
+// node is a parsed (sub)expression.
+type node interface {
+	eval(ctx *context) (interface{}, error)
+	position() int
+}
+
+type literalNode struct {
+	pos   int
+	value interface{}
+}
+
+type identNode struct {
+	pos  int
+	name string
+}
+
+type memberNode struct {
+	pos    int
+	target node
+	name   string
+}
+
+type indexNode struct {
+	pos    int
+	target node
+	index  node
+}
+
+type listNode struct {
+	pos   int
+	items []node
+}
+
+type callNode struct {
+	pos  int
+	name string
+	args []node
+}
+
+type unaryNode struct {
+	pos     int
+	op      string
+	operand node
+}
+
+type binaryNode struct {
+	pos   int
+	op    string
+	left  node
+	right node
+	// pattern is the compiled regular expression of a "matches" with a literal pattern
+	pattern *regexp.Regexp
+}
+
+func (n *literalNode) position() int { return n.pos }
+func (n *identNode) position() int   { return n.pos }
+func (n *memberNode) position() int  { return n.pos }
+func (n *indexNode) position() int   { return n.pos }
+func (n *listNode) position() int    { return n.pos }
+func (n *callNode) position() int    { return n.pos }
+func (n *unaryNode) position() int   { return n.pos }
+func (n *binaryNode) position() int  { return n.pos }
+
+// keywordOperators are the operators written as words, along with the symbol each is the same as (if any).
+var keywordOperators = map[string]string{
+	"and":      "&&",
+	"or":       "||",
+	"not":      "!",
+	"matches":  "matches",
+	"contains": "contains",
+	"in":       "in",
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+func parse(source string) (node, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+	}
+	return root, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// operator returns the operator of the next token (with keywords given as their symbol), or "" when it is not one.
+func (p *parser) operator() string {
+	tok := p.peek()
+	switch tok.kind {
+	case tokenOperator:
+		return tok.text
+	case tokenIdent:
+		return keywordOperators[tok.text]
+	}
+	return ""
+}
+
+func (p *parser) expect(operator string) error {
+	if tok := p.next(); tok.kind != tokenOperator || tok.text != operator {
+		return fmt.Errorf("expected '%s' at position %d, found %s", operator, tok.pos, tok)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return nil, fmt.Errorf("expression is nested too deeply at position %d", p.peek().pos)
+	}
+
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.operator() == "||" {
+		tok := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: tok.pos, op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.operator() == "&&" {
+		tok := p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: tok.pos, op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.operator(); op {
+	case "==", "!=", "<", "<=", ">", ">=", "matches", "contains", "in":
+		tok := p.next()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		binary := &binaryNode{pos: tok.pos, op: op, left: left, right: right}
+		if literal, ok := right.(*literalNode); ok && op == "matches" {
+			pattern, isString := literal.value.(string)
+			if !isString {
+				return nil, fmt.Errorf("expected a pattern string after 'matches' at position %d", literal.pos)
+			}
+			binary.pattern, err = regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern at position %d: %v", literal.pos, err)
+			}
+		}
+		return binary, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.operator(); op == "+" || op == "-"; op = p.operator() {
+		tok := p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: tok.pos, op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.operator(); op == "*" || op == "/" || op == "%"; op = p.operator() {
+		tok := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: tok.pos, op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if op := p.operator(); op == "!" || op == "-" {
+		tok := p.next()
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > maxDepth {
+			return nil, fmt.Errorf("expression is nested too deeply at position %d", tok.pos)
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{pos: tok.pos, op: op, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	target, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.operator() {
+		case ".":
+			p.next()
+			name := p.next()
+			if name.kind != tokenIdent {
+				return nil, fmt.Errorf("expected a field name at position %d, found %s", name.pos, name)
+			}
+			target = &memberNode{pos: name.pos, target: target, name: name.text}
+		case "[":
+			tok := p.next()
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			target = &indexNode{pos: tok.pos, target: target, index: index}
+		default:
+			return target, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		return &literalNode{pos: tok.pos, value: tok.number}, nil
+	case tokenString:
+		return &literalNode{pos: tok.pos, value: tok.str}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{pos: tok.pos, value: true}, nil
+		case "false":
+			return &literalNode{pos: tok.pos, value: false}, nil
+		case "null":
+			return &literalNode{pos: tok.pos, value: nil}, nil
+		}
+		if _, isKeyword := keywordOperators[tok.text]; isKeyword {
+			return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+		}
+		if p.operator() == "(" {
+			return p.parseCall(tok)
+		}
+		return &identNode{pos: tok.pos, name: tok.text}, nil
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			list := &listNode{pos: tok.pos}
+			items, err := p.parseList("]")
+			list.items = items
+			return list, err
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	definition, exists := functions[name.text]
+	if !exists {
+		return nil, fmt.Errorf("unknown function '%s' at position %d", name.text, name.pos)
+	}
+	p.next()
+	args, err := p.parseList(")")
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < definition.minArgs || len(args) > definition.maxArgs {
+		return nil, fmt.Errorf("%s() at position %d takes %s, given %d", name.text, name.pos, definition.arity(), len(args))
+	}
+	return &callNode{pos: name.pos, name: name.text, args: args}, nil
+}
+
+// parseList reads comma separated expressions up to (and including) the given closing operator.
+func (p *parser) parseList(closing string) ([]node, error) {
+	var items []node
+	if p.operator() == closing {
+		p.next()
+		return items, nil
+	}
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.operator() != "," {
+			break
+		}
+		p.next()
+	}
+	return items, p.expect(closing)
+}
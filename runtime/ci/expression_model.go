@@ -0,0 +1,142 @@
+package ci
+
+import (
+	"archive/tar"
+	"encoding/json"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
+)
+
+// newExpressionModel describes the analysis as the variables that custom rule expressions refer to (see the README):
+//
+//	image           sizes and efficiency of the whole image, along with its os and architecture
+//	layers          each layer, in order (index, id, digest, command, sizeBytes)
+//	files           each path of the final image (path, name, sizeBytes, mode, uid, gid, isDir, isLink, linkTarget, layer)
+//	inefficiencies  each path duplicated or removed across layers (path, count, sizeBytes, layers)
+//	config          the container config of the image (e.g. User, Env, Labels, ExposedPorts, Healthcheck), null if unknown
+//
+// Values are as decoded from JSON (numbers are float64), as expected by the expr package.
+func newExpressionModel(analysis *image.AnalysisResult) map[string]interface{} {
+	var rawConfig map[string]interface{}
+	if len(analysis.Config) > 0 {
+		if err := json.Unmarshal(analysis.Config, &rawConfig); err != nil {
+			// note: rules over the config treat an unreadable config as unknown (null)
+			logrus.Warnf("unable to read the image config for custom rules: %v", err)
+		}
+	}
+
+	layers := make([]interface{}, len(analysis.Layers))
+	for idx, layer := range analysis.Layers {
+		layers[idx] = map[string]interface{}{
+			"index":     float64(layer.Index),
+			"id":        layer.Id,
+			"digest":    layer.Digest,
+			"command":   layer.Command,
+			"sizeBytes": float64(layer.Size),
+		}
+	}
+
+	inefficiencies := make([]interface{}, len(analysis.Inefficiencies))
+	for idx, data := range analysis.Inefficiencies {
+		layerIndexes := inefficiencyLayers(analysis, data)
+		indexes := make([]interface{}, len(layerIndexes))
+		for layerIdx, layer := range layerIndexes {
+			indexes[layerIdx] = float64(layer)
+		}
+		inefficiencies[idx] = map[string]interface{}{
+			"path":      data.Path,
+			"count":     float64(len(data.Nodes)),
+			"sizeBytes": float64(data.CumulativeSize),
+			"layers":    indexes,
+		}
+	}
+
+	return map[string]interface{}{
+		"image": map[string]interface{}{
+			"sizeBytes":         float64(analysis.SizeBytes),
+			"userSizeBytes":     float64(analysis.UserSizeByes),
+			"wastedBytes":       float64(analysis.WastedBytes),
+			"wastedUserPercent": analysis.WastedUserPercent,
+			"efficiency":        analysis.Efficiency,
+			"removableBytes":    float64(analysis.RemovableBytes),
+			"layerCount":        float64(len(analysis.Layers)),
+			"os":                rawConfig["os"],
+			"architecture":      rawConfig["architecture"],
+		},
+		"layers":         layers,
+		"files":          expressionFiles(analysis),
+		"inefficiencies": inefficiencies,
+		"config":         rawConfig["config"],
+	}
+}
+
+// expressionFiles lists the paths of the final image, each with the index of the last layer to add (or change) it.
+func expressionFiles(analysis *image.AnalysisResult) []interface{} {
+	files := make([]interface{}, 0)
+	if len(analysis.RefTrees) == 0 {
+		return files
+	}
+
+	addedBy := make(map[string]int)
+	for layerIdx, tree := range analysis.RefTrees {
+		err := tree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+			if !node.IsImplied() && !node.IsWhiteout() {
+				addedBy[node.Path()] = layerIdx
+			}
+			return nil
+		}, nil)
+		if err != nil {
+			logrus.Warnf("unable to list layer %d files for custom rules: %v", layerIdx, err)
+		}
+	}
+
+	tree, err := finalTree(analysis)
+	if err != nil {
+		logrus.Warnf("unable to stack image layers for custom rules: %v", err)
+		return files
+	}
+	err = tree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+		if node.IsWhiteout() {
+			return nil
+		}
+		info := node.Data.FileInfo
+		p := node.Path()
+		layer, exists := addedBy[p]
+		if !exists {
+			layer = -1
+		}
+		files = append(files, map[string]interface{}{
+			"path":       p,
+			"name":       node.Name,
+			"sizeBytes":  float64(info.Size),
+			"mode":       info.Mode.String(),
+			"uid":        float64(info.Uid),
+			"gid":        float64(info.Gid),
+			"isDir":      info.IsDir,
+			"isLink":     info.TypeFlag == tar.TypeSymlink,
+			"linkTarget": info.Linkname,
+			"layer":      float64(layer),
+		})
+		return nil
+	}, nil)
+	if err != nil {
+		logrus.Warnf("unable to list image files for custom rules: %v", err)
+	}
+	return files
+}
+
+// expressionModels builds the model of an analysis once for every custom rule evaluated against it.
+type expressionModels struct {
+	analysis  *image.AnalysisResult
+	variables map[string]interface{}
+}
+
+func (models *expressionModels) get(analysis *image.AnalysisResult) map[string]interface{} {
+	if models.analysis != analysis || models.variables == nil {
+		models.analysis = analysis
+		models.variables = newExpressionModel(analysis)
+	}
+	return models.variables
+}
@@ -0,0 +1,65 @@
+package ci
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
+)
+
+// ignoreKey is the CI config key of the path globs of the inefficient files that no (ignorable) rule counts, such as
+// files that are deliberately changed by several layers (e.g. /etc/passwd by useradd):
+//
+//	ignore:
+//	  - /etc/passwd
+//	  - /var/cache/apt
+const ignoreKey = "ignore"
+
+// withoutIgnored returns the analysis without the inefficient files matching the given globs (see
+// filetree.PathAllowed), with the wasted bytes recalculated. The given analysis is returned when nothing is ignored.
+func withoutIgnored(analysis *image.AnalysisResult, ignore []string) *image.AnalysisResult {
+	if len(ignore) == 0 {
+		return analysis
+	}
+
+	filtered := *analysis
+	filtered.Inefficiencies = make(filetree.EfficiencySlice, 0, len(analysis.Inefficiencies))
+	filtered.WastedBytes = 0
+	for _, data := range analysis.Inefficiencies {
+		if filetree.PathAllowed(data.Path, ignore) {
+			continue
+		}
+		filtered.Inefficiencies = append(filtered.Inefficiencies, data)
+		filtered.WastedBytes += uint64(data.CumulativeSize)
+	}
+	filtered.WastedUserPercent = float64(filtered.WastedBytes) / float64(filtered.UserSizeByes)
+	return &filtered
+}
+
+// ignoredBy describes the options ignoring the given inefficient path, empty when not ignored. A path matching the
+// global ignore globs is described along with the (enabled) rules that do not count it, e.g. "ignore
+// (highestWastedBytes)", since the other rules (e.g. lowestEfficiency) still do. Otherwise the path is described by
+// the rule options that ignore it (e.g. "rules.highestWastedBytes.ignore"). The global flag indicates if the path
+// matches the global ignore globs.
+func (ci *CiEvaluator) ignoredBy(p string) (description string, global bool) {
+	global = filetree.PathAllowed(p, ci.Ignore)
+	var rules, options []string
+	for _, rule := range ci.Rules {
+		generic, isGeneric := rule.(*GenericCiRule)
+		if !isGeneric || !ci.isRuleEnabled(rule) || !ruleRegistry[rule.Key()].Ignorable {
+			continue
+		}
+		rules = append(rules, rule.Key())
+		if filetree.PathAllowed(p, generic.config.Ignore) {
+			options = append(options, fmt.Sprintf("rules.%s.%s", rule.Key(), ignoreKey))
+		}
+	}
+	if global {
+		if len(rules) == 0 {
+			return ignoreKey, true
+		}
+		return fmt.Sprintf("%s (%s)", ignoreKey, strings.Join(rules, ", ")), true
+	}
+	return strings.Join(options, ", "), false
+}
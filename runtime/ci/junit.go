@@ -0,0 +1,125 @@
+package ci
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+	SystemOut *junitOutput    `xml:"system-out,omitempty"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut *junitOutput  `xml:"system-out,omitempty"`
+}
+
+type junitOutput struct {
+	Text string `xml:",cdata"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+}
+
+// writeJUnitReport writes a testsuite for every image (named after the image), with every rule as a testcase. The
+// inefficient files (and then the ignored ones) are listed within the testsuite output.
+func writeJUnitReport(images []ReportImage, writer io.Writer) error {
+	report := junitTestSuites{Name: "dive"}
+	for _, img := range images {
+		suite := newJUnitTestSuite(img.Evaluator, img.Source)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
+
+// newJUnitTestSuite returns the testsuite of the given (evaluated) image.
+func newJUnitTestSuite(ci *CiEvaluator, source ReportSource) junitTestSuite {
+	suite := junitTestSuite{Name: source.Image}
+	for _, rule := range ci.sortedResults() {
+		result := ci.Results[rule]
+		testCase := junitTestCase{ClassName: "dive", Name: rule}
+
+		switch result.status {
+		case RuleFailed:
+			testCase.Failure = &junitMessage{Message: result.message, Type: "failure"}
+			suite.Failures++
+		case RuleWarning:
+			if ci.FailOnWarn {
+				testCase.Failure = &junitMessage{Message: result.message, Type: "warning"}
+				suite.Failures++
+			} else {
+				testCase.SystemOut = &junitOutput{Text: "warning: " + result.message}
+			}
+		case RuleMisconfigured:
+			testCase.Error = &junitMessage{Message: result.message, Type: "misconfigured"}
+			suite.Errors++
+		case RuleDisabled:
+			testCase.Skipped = &junitMessage{Message: result.message}
+			suite.Skipped++
+		case RuleConfigured:
+			// the rules are not evaluated when any rule is misconfigured
+			testCase.Skipped = &junitMessage{Message: "not evaluated (CI misconfigured)"}
+			suite.Skipped++
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	suite.Tests = len(suite.TestCases)
+
+	if len(ci.InefficientFiles) > 0 || len(ci.IgnoredFiles) > 0 {
+		var sb strings.Builder
+		template := "%5s  %12s  %-s\n"
+		fmt.Fprintf(&sb, template, "Count", "Wasted Space", "File Path")
+		for _, file := range ci.InefficientFiles {
+			fmt.Fprintf(&sb, template, strconv.Itoa(file.References), humanize.Bytes(file.SizeBytes), file.Path)
+		}
+		if len(ci.IgnoredFiles) > 0 {
+			fmt.Fprintln(&sb, "\nIgnored:")
+			for _, file := range ci.IgnoredFiles {
+				fmt.Fprintf(&sb, template, strconv.Itoa(file.References), humanize.Bytes(file.SizeBytes), fmt.Sprintf("%s (ignored by %s)", file.Path, file.IgnoredBy))
+			}
+		}
+		suite.SystemOut = &junitOutput{Text: sb.String()}
+	}
+	return suite
+}
@@ -0,0 +1,85 @@
+package ci
+
+import (
+	"io"
+	"strings"
+
+	"github.com/wagoodman/dive/runtime/export"
+)
+
+var markdownStatus = map[RuleStatus]string{
+	RulePassed:        "PASS",
+	RuleFailed:        "**FAIL**",
+	RuleWarning:       "WARN",
+	RuleDisabled:      "SKIP",
+	RuleMisconfigured: "**MISCONFIGURED**",
+	RuleConfigured:    "SKIP",
+}
+
+// writeMarkdownReport writes a summary of every image analysis (see export.Export.Markdown) along with every rule
+// result, suitable for a pull request comment. Several images are preceded by an overview of their results (see
+// export.MarkdownOverview).
+func writeMarkdownReport(images []ReportImage, writer io.Writer) error {
+	var doc strings.Builder
+	exports := make([]*export.Export, len(images))
+	overview := make([]export.MarkdownImage, len(images))
+	for idx, img := range images {
+		exports[idx] = export.NewExport(img.Source.Analysis)
+		overview[idx] = export.MarkdownImage{
+			Export: exports[idx],
+			Title:  img.Source.Image,
+			Result: markdownResult(img.Evaluator),
+			Pass:   img.Evaluator.Pass,
+		}
+	}
+
+	if len(images) > 1 {
+		doc.WriteString(export.MarkdownOverview(overview))
+	}
+	for idx, img := range images {
+		doc.WriteString(exports[idx].Markdown(img.Evaluator.MarkdownOptions(img.Source)))
+	}
+
+	_, err := io.WriteString(writer, doc.String())
+	return err
+}
+
+func markdownResult(ci *CiEvaluator) string {
+	if !ci.Pass {
+		return "**FAIL**"
+	}
+	return "PASS"
+}
+
+// MarkdownOptions returns the rule results (and the files they ignore) of the given image, as shown within its summary
+// (see export.Export.Markdown).
+func (ci *CiEvaluator) MarkdownOptions(source ReportSource) export.MarkdownOptions {
+	options := export.MarkdownOptions{
+		Title:         source.Image,
+		Result:        markdownResult(ci),
+		Baseline:      source.Baseline,
+		BaselineLabel: source.BaselineLabel,
+	}
+
+	for _, rule := range ci.sortedResults() {
+		result := ci.Results[rule]
+		row := export.MarkdownRule{Name: rule, Status: markdownStatus[result.status], Message: result.message}
+		switch {
+		case result.status == RuleWarning && ci.FailOnWarn:
+			row.Status = "**WARN**"
+		case result.status == RuleConfigured:
+			// the rules are not evaluated when any rule is misconfigured
+			row.Message = "not evaluated (CI misconfigured)"
+		}
+		options.Rules = append(options.Rules, row)
+	}
+	for _, file := range ci.IgnoredFiles {
+		options.IgnoredFiles = append(options.IgnoredFiles, export.MarkdownIgnoredFile{
+			Path:       file.Path,
+			References: file.References,
+			SizeBytes:  file.SizeBytes,
+			IgnoredBy:  file.IgnoredBy,
+		})
+	}
+	return options
+}
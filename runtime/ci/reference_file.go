@@ -4,4 +4,10 @@ type ReferenceFile struct {
 	References int    `json:"count"`
 	SizeBytes  uint64 `json:"sizeBytes"`
 	Path       string `json:"file"`
+	// LayerIndexes are the layers that added (or removed) the file
+	LayerIndexes []int `json:"-"`
+	// IgnoredBy are the options ignoring the file (see CiEvaluator.ignoredBy), empty when not ignored
+	IgnoredBy string `json:"-"`
+	// IgnoredGlobally indicates if the file matches the global ignore globs (no ignorable rule counts it)
+	IgnoredGlobally bool `json:"-"`
 }
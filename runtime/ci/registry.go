@@ -0,0 +1,176 @@
+package ci
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/dive/image"
+	"github.com/wagoodman/dive/runtime/export"
+)
+
+// RuleDefinition declares a CI rule: the key it is configured with (under "rules."), the value it expects, and how an
+// image is evaluated against the configured value.
+type RuleDefinition struct {
+	Key string
+	// Default is the value used when the rule is not configured (e.g. "disabled")
+	Default string
+	// Description explains the rule within the CLI help
+	Description string
+	Schema      RuleSchema
+	// Evaluator checks the analysis against the (validated) config value, the full CI config is given for any
+	// additional options of the rule (e.g. allowlists)
+	Evaluator func(analysis *image.AnalysisResult, value string, config *viper.Viper) (RuleStatus, string)
+	// BaselineEvaluator checks how the image changed since the baseline image instead (given with --baseline), rules
+	// set either an Evaluator or a BaselineEvaluator. The rule is skipped when no baseline is given.
+	BaselineEvaluator func(comparison *export.Comparison, value string, config *viper.Viper) (RuleStatus, string)
+	// Ignorable rules are evaluated without the inefficient files matching the global or rule "ignore" globs (the
+	// analysis given to the Evaluator has the ignored files removed from the inefficiencies and wasted bytes)
+	Ignorable bool
+}
+
+// RuleSchema describes the config value of a rule.
+type RuleSchema struct {
+	validate func(key, value string) error
+}
+
+// Validate returns an error when the given value does not fit the schema.
+func (schema RuleSchema) Validate(key, value string) error {
+	return schema.validate(key, value)
+}
+
+var (
+	// BoolSchema are "true" or "false" values, where false disables the rule
+	BoolSchema = RuleSchema{validate: func(_, value string) error {
+		_, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid config value ('%v'): %v", value, err)
+		}
+		return nil
+	}}
+	// BytesSchema are sizes such as "20MB"
+	BytesSchema = RuleSchema{validate: func(_, value string) error {
+		_, err := humanize.ParseBytes(value)
+		if err != nil {
+			return fmt.Errorf("invalid config value ('%v'): %v", value, err)
+		}
+		return nil
+	}}
+	// CountSchema are non-negative integers
+	CountSchema = RuleSchema{validate: func(_, value string) error {
+		_, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid config value ('%v'): %v", value, err)
+		}
+		return nil
+	}}
+	// IncreaseSchema are sizes such as "1MB", or percentages of the baseline such as "5%"
+	IncreaseSchema = RuleSchema{validate: func(_, value string) error {
+		_, err := parseIncreaseLimit(value)
+		if err != nil {
+			return fmt.Errorf("invalid config value ('%v'): %v", value, err)
+		}
+		return nil
+	}}
+	// RatioSchema are numbers between 0 and 1
+	RatioSchema = RuleSchema{validate: func(key, value string) error {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid config value ('%v'): %v", value, err)
+		}
+		if ratio < 0 || ratio > 1 {
+			return fmt.Errorf("%s config value is outside allowed range (0-1), given '%s'", key, value)
+		}
+		return nil
+	}}
+)
+
+// ListSchema are lists of values (such as label names), each checked by the given function. A single value is a
+// list of one.
+func ListSchema(validateItem func(item string) error) RuleSchema {
+	return RuleSchema{validate: func(_, value string) error {
+		items := listItems(value)
+		if len(items) == 0 {
+			return fmt.Errorf("invalid config value ('%v'): expected a list of values", value)
+		}
+		for _, item := range items {
+			if err := validateItem(item); err != nil {
+				return fmt.Errorf("invalid config value ('%v'): %v", item, err)
+			}
+		}
+		return nil
+	}}
+}
+
+// listItems returns the (non-empty) items of a rule value given as a list (see ListSchema).
+func listItems(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, listSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+var ruleRegistry = make(map[string]RuleDefinition)
+
+// RegisterRule makes the given rule available to every CI evaluation. Registering a key twice panics.
+func RegisterRule(definition RuleDefinition) {
+	if definition.Key == "" || (definition.Evaluator == nil) == (definition.BaselineEvaluator == nil) || definition.Schema.validate == nil {
+		panic(fmt.Errorf("incomplete CI rule definition: %+v", definition))
+	}
+	if _, exists := ruleRegistry[definition.Key]; exists {
+		panic(fmt.Errorf("CI rule registered twice: %s", definition.Key))
+	}
+	ruleRegistry[definition.Key] = definition
+}
+
+// RegisteredRules returns every registered rule, ordered by key.
+func RegisteredRules() []RuleDefinition {
+	definitions := make([]RuleDefinition, 0, len(ruleRegistry))
+	for _, definition := range ruleRegistry {
+		definitions = append(definitions, definition)
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Key < definitions[j].Key
+	})
+	return definitions
+}
+
+func loadCiRules(config *viper.Viper) []CiRule {
+	var rules = make([]CiRule, 0, len(ruleRegistry))
+	globalIgnore := config.GetStringSlice(ignoreKey)
+	for _, definition := range RegisteredRules() {
+		definition := definition
+		key := fmt.Sprintf("rules.%s", definition.Key)
+		ruleConfig := parseRuleConfig(definition.Key, config.Get(key), config.GetString(key))
+		if len(ruleConfig.Ignore) > 0 && !definition.Ignorable && ruleConfig.err == nil {
+			ruleConfig.err = fmt.Errorf("%s does not support the 'ignore' option", definition.Key)
+		}
+		ignore := append(append([]string{}, globalIgnore...), ruleConfig.Ignore...)
+		rules = append(rules, newGenericCiRule(
+			definition.Key,
+			ruleConfig,
+			func(value string) error {
+				return definition.Schema.Validate(definition.Key, value)
+			},
+			func(analysis *image.AnalysisResult, comparison *export.Comparison, value string) (RuleStatus, string) {
+				if definition.BaselineEvaluator == nil {
+					if definition.Ignorable {
+						analysis = withoutIgnored(analysis, ignore)
+					}
+					return definition.Evaluator(analysis, value, config)
+				}
+				if comparison == nil {
+					return RuleDisabled, "no baseline given (see --baseline)"
+				}
+				return definition.BaselineEvaluator(comparison, value, config)
+			},
+		))
+	}
+	return append(rules, loadCustomRules(config, rules)...)
+}
@@ -0,0 +1,106 @@
+package ci
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/wagoodman/dive/dive/image"
+	"github.com/wagoodman/dive/runtime/export"
+)
+
+// reportWriter writes the results of the evaluated images in a machine readable format, with a section per image.
+type reportWriter func(images []ReportImage, writer io.Writer) error
+
+var reportWriters = map[string]reportWriter{
+	"junit":    writeJUnitReport,
+	"markdown": writeMarkdownReport,
+	"sarif":    writeSarifReport,
+}
+
+// ReportSource is the evaluated image, as described within the reports.
+type ReportSource struct {
+	Image    string
+	Analysis *image.AnalysisResult
+	// Dockerfile is the path of the Dockerfile the image was built from (empty when not known), used to point results
+	// at the instruction that created each layer
+	Dockerfile string
+	// Baseline is a previous export of the image (e.g. built from main) that summaries are compared against, or nil
+	Baseline *export.Export
+	// BaselineLabel names the baseline within summaries (e.g. "main")
+	BaselineLabel string
+}
+
+// ReportImage is an evaluated image within the reports of a CI run (see WriteReports).
+type ReportImage struct {
+	Evaluator *CiEvaluator
+	Source    ReportSource
+}
+
+// ReportFormats are the formats the CI results can be written in (see ReportTarget).
+func ReportFormats() []string {
+	formats := make([]string, 0, len(reportWriters))
+	for format := range reportWriters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// ReportTarget is a file the CI results are written to, in the given format.
+type ReportTarget struct {
+	Format string
+	Path   string
+}
+
+// ParseReportTargets reads "format=path" values (e.g. "junit=report.xml").
+func ParseReportTargets(values []string) ([]ReportTarget, error) {
+	targets := make([]ReportTarget, 0, len(values))
+	for _, value := range values {
+		fields := strings.SplitN(value, "=", 2)
+		if len(fields) != 2 || fields[1] == "" {
+			return nil, fmt.Errorf("invalid report '%s' (expected format=path)", value)
+		}
+		format := strings.ToLower(strings.TrimSpace(fields[0]))
+		if _, exists := reportWriters[format]; !exists {
+			return nil, fmt.Errorf("unknown report format '%s' (expected one of: %s)", fields[0], strings.Join(ReportFormats(), ", "))
+		}
+		targets = append(targets, ReportTarget{Format: format, Path: fields[1]})
+	}
+	return targets, nil
+}
+
+// WriteReport writes the results of the given (evaluated) image to the target file.
+func (ci *CiEvaluator) WriteReport(target ReportTarget, source ReportSource) error {
+	return WriteReports(target, []ReportImage{{Evaluator: ci, Source: source}})
+}
+
+// WriteReports writes the combined results of the given (evaluated) images to the target file.
+func WriteReports(target ReportTarget, images []ReportImage) error {
+	writer, exists := reportWriters[target.Format]
+	if !exists {
+		return fmt.Errorf("unknown report format '%s'", target.Format)
+	}
+
+	file, err := os.Create(target.Path)
+	if err != nil {
+		return err
+	}
+	if err := writer(images, file); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// sortedResults returns the rule keys of the results in order.
+func (ci *CiEvaluator) sortedResults() []string {
+	rules := make([]string, 0, len(ci.Results))
+	for name := range ci.Results {
+		rules = append(rules, name)
+	}
+	sort.Strings(rules)
+	return rules
+}
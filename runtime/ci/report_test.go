@@ -0,0 +1,313 @@
+package ci
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/dive/image/docker"
+)
+
+func Test_ParseReportTargets(t *testing.T) {
+
+	targets, err := ParseReportTargets([]string{"junit=report.xml", "JUnit=out/dive=results.xml"})
+	if err != nil {
+		t.Fatalf("unable to parse targets: %v", err)
+	}
+	expected := []ReportTarget{{Format: "junit", Path: "report.xml"}, {Format: "junit", Path: "out/dive=results.xml"}}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("expected %+v, got %+v", expected, targets)
+	}
+
+	for _, value := range []string{"junit", "junit=", "yaml=report.yaml", "=report.sarif"} {
+		if _, err := ParseReportTargets([]string{value}); err == nil {
+			t.Errorf("%s: expected an error", value)
+		}
+	}
+
+}
+
+func Test_JUnitReport(t *testing.T) {
+
+	evaluator := &CiEvaluator{
+		Results: map[string]RuleResult{
+			"lowestEfficiency":   {status: RulePassed},
+			"highestWastedBytes": {status: RuleFailed, message: "too many bytes wasted (wasted-bytes=32025 > threshold=1000)"},
+			"highestImageSize":   {status: RuleWarning, message: "image is too large (image-size=1220598 > threshold=1000000)"},
+			"noSecrets":          {status: RuleDisabled, message: "rule disabled"},
+		},
+		InefficientFiles: []ReferenceFile{{References: 2, SizeBytes: 12810, Path: "/root/saved.txt"}},
+	}
+
+	table := map[string]struct {
+		failOnWarn bool
+		expected   string
+	}{
+		"warnings": {false, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="dive" tests="4" failures="1" errors="0" skipped="1">
+  <testsuite name="dive-example:latest" tests="4" failures="1" errors="0" skipped="1">
+    <testcase classname="dive" name="highestImageSize">
+      <system-out><![CDATA[warning: image is too large (image-size=1220598 > threshold=1000000)]]></system-out>
+    </testcase>
+    <testcase classname="dive" name="highestWastedBytes">
+      <failure message="too many bytes wasted (wasted-bytes=32025 &gt; threshold=1000)" type="failure"></failure>
+    </testcase>
+    <testcase classname="dive" name="lowestEfficiency"></testcase>
+    <testcase classname="dive" name="noSecrets">
+      <skipped message="rule disabled"></skipped>
+    </testcase>
+    <system-out><![CDATA[Count  Wasted Space  File Path
+    2         13 kB  /root/saved.txt
+]]></system-out>
+  </testsuite>
+</testsuites>
+`},
+		"failOnWarn": {true, `<testcase classname="dive" name="highestImageSize">
+      <failure message="image is too large (image-size=1220598 &gt; threshold=1000000)" type="warning"></failure>
+    </testcase>`},
+	}
+
+	for name, test := range table {
+		evaluator.FailOnWarn = test.failOnWarn
+
+		var buf bytes.Buffer
+		if err := writeJUnitReport([]ReportImage{{Evaluator: evaluator, Source: ReportSource{Image: "dive-example:latest"}}}, &buf); err != nil {
+			t.Fatalf("%s: unable to write report: %v", name, err)
+		}
+		if !strings.Contains(buf.String(), test.expected) {
+			t.Errorf("%s: expected report containing:\n%s\ngot:\n%s", name, test.expected, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	evaluator.Results = map[string]RuleResult{
+		"lowestEfficiency":   {status: RuleMisconfigured, message: "invalid config value ('x')"},
+		"highestWastedBytes": {status: RuleConfigured, message: "test"},
+	}
+	if err := writeJUnitReport([]ReportImage{{Evaluator: evaluator, Source: ReportSource{Image: "dive-example:latest"}}}, &buf); err != nil {
+		t.Fatalf("unable to write report: %v", err)
+	}
+	for _, expected := range []string{`errors="1" skipped="1"`, `<error message="invalid config value (&#39;x&#39;)" type="misconfigured"></error>`, `<skipped message="not evaluated (CI misconfigured)"></skipped>`} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected misconfigured report containing %s, got:\n%s", expected, buf.String())
+		}
+	}
+
+}
+
+func Test_SarifReport(t *testing.T) {
+
+	result := docker.TestAnalysisFromArchive(t, "../../.data/test-docker-image.tar")
+
+	ciConfig := viper.New()
+	for _, rule := range RegisteredRules() {
+		ciConfig.SetDefault("rules."+rule.Key, "disabled")
+	}
+	ciConfig.SetDefault("rules.highestWastedBytes", "1kB")
+	ciConfig.SetDefault("rules.highestImageSize", map[string]interface{}{"severity": "warn", "fail": "1MB"})
+	ciConfig.SetDefault("ignore", []string{"/root/example"})
+
+	evaluator := NewCiEvaluator(ciConfig)
+	evaluator.Evaluate(result)
+
+	table := map[string]struct {
+		dockerfile string
+		// expectedRegions are the Dockerfile lines of the locations of the /root/saved.txt result (latest layer first)
+		expectedRegions []int
+	}{
+		"withDockerfile":    {"../../.data/Dockerfile.test-image", []int{14, 8}},
+		"withoutDockerfile": {"", []int{0, 0}},
+	}
+
+	for name, test := range table {
+		var buf bytes.Buffer
+		if err := writeSarifReport([]ReportImage{{Evaluator: evaluator, Source: ReportSource{Image: "dive-example:latest", Analysis: result, Dockerfile: test.dockerfile}}}, &buf); err != nil {
+			t.Fatalf("%s: unable to write report: %v", name, err)
+		}
+
+		var log sarifLog
+		if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+			t.Fatalf("%s: unable to read report: %v", name, err)
+		}
+		if log.Version != "2.1.0" || len(log.Runs) != 1 {
+			t.Fatalf("%s: unexpected report: %+v", name, log)
+		}
+		run := log.Runs[0]
+
+		for _, result := range run.Results {
+			if run.Tool.Driver.Rules[result.RuleIndex].ID != result.RuleID {
+				t.Errorf("%s: result of %s has the index of %s", name, result.RuleID, run.Tool.Driver.Rules[result.RuleIndex].ID)
+			}
+		}
+
+		levels := make(map[string]string)
+		var savedFile *sarifResult
+		suppressed := 0
+		for idx, result := range run.Results {
+			if result.RuleID == sarifInefficientFileRule && strings.HasSuffix(result.Message.Text, " /root/saved.txt") {
+				savedFile = &run.Results[idx]
+			}
+			if len(result.Suppressions) > 0 {
+				if !strings.Contains(result.Message.Text, " /root/example/") {
+					t.Errorf("%s: unexpected suppressed result: %+v", name, result)
+				}
+				if justification := result.Suppressions[0].Justification; justification != "ignored by the CI config (ignore (highestWastedBytes))" {
+					t.Errorf("%s: unexpected suppression justification: %q", name, justification)
+				}
+				suppressed++
+			}
+			levels[result.RuleID] = result.Level
+		}
+		if suppressed != 2 {
+			t.Errorf("%s: expected the 2 ignored files as suppressed results, got %d", name, suppressed)
+		}
+		expectedLevels := map[string]string{"highestWastedBytes": "error", "highestImageSize": "warning", sarifInefficientFileRule: "warning", "unexpected-owner": "note"}
+		if !reflect.DeepEqual(levels, expectedLevels) {
+			t.Errorf("%s: expected result levels %v, got %v", name, expectedLevels, levels)
+		}
+
+		if savedFile == nil {
+			t.Fatalf("%s: expected an inefficient file result for /root/saved.txt", name)
+		}
+		locations := append(savedFile.Locations, savedFile.RelatedLocations...)
+		regions := make([]int, len(locations))
+		for idx, location := range locations {
+			if location.LogicalLocations[0].FullyQualifiedName != "/root/saved.txt" {
+				t.Errorf("%s: unexpected logical location: %+v", name, location.LogicalLocations)
+			}
+			if (location.PhysicalLocation != nil) != (test.dockerfile != "") {
+				t.Errorf("%s: unexpected physical location: %+v", name, location.PhysicalLocation)
+			}
+			if location.PhysicalLocation != nil && location.PhysicalLocation.Region != nil {
+				regions[idx] = location.PhysicalLocation.Region.StartLine
+			}
+		}
+		if !reflect.DeepEqual(regions, test.expectedRegions) {
+			t.Errorf("%s: expected regions %v, got %v", name, test.expectedRegions, regions)
+		}
+	}
+
+}
+
+func Test_MarkdownReport(t *testing.T) {
+
+	result := docker.TestAnalysisFromArchive(t, "../../.data/test-docker-image.tar")
+
+	evaluator := &CiEvaluator{
+		Pass: false,
+		Results: map[string]RuleResult{
+			"lowestEfficiency":   {status: RulePassed, message: "test"},
+			"highestWastedBytes": {status: RuleFailed, message: "too many bytes wasted (wasted-bytes=32025 > threshold=1000)"},
+			"highestImageSize":   {status: RuleWarning, message: "image is too large (image-size=1220598 > threshold=1000000)"},
+			"noSecrets":          {status: RuleDisabled, message: "rule disabled"},
+		},
+		IgnoredFiles: []ReferenceFile{{References: 2, SizeBytes: 12810, Path: "/root/saved.txt", IgnoredBy: "ignore (highestUserWastedPercent, highestWastedBytes)", IgnoredGlobally: true}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeMarkdownReport([]ReportImage{{Evaluator: evaluator, Source: ReportSource{Image: "dive-example:latest", Analysis: result}}}, &buf); err != nil {
+		t.Fatalf("unable to write report: %v", err)
+	}
+
+	for _, expected := range []string{
+		"## dive: `dive-example:latest`\n\n**Result:** **FAIL**\n",
+		"| Image size | 1.2 MB |\n",
+		"| 13 | 6.4 kB | `chmod +x /root/saved.txt` |\n",
+		"| 2 | 13 kB | `/root/saved.txt` | ignore (highestUserWastedPercent, highestWastedBytes) |\n",
+		`### Rules
+
+| Rule | Status | Message |
+|---|---|---|
+| highestImageSize | WARN | image is too large (image-size=1220598 > threshold=1000000) |
+| highestWastedBytes | **FAIL** | too many bytes wasted (wasted-bytes=32025 > threshold=1000) |
+| lowestEfficiency | PASS | test |
+| noSecrets | SKIP | rule disabled |
+`,
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected report containing:\n%s\ngot:\n%s", expected, buf.String())
+		}
+	}
+
+}
+
+func Test_CombinedReports(t *testing.T) {
+
+	result := docker.TestAnalysisFromArchive(t, "../../.data/test-docker-image.tar")
+
+	var images []ReportImage
+	for _, img := range []struct {
+		name  string
+		rule  string
+		value string
+	}{
+		{"passing:latest", "lowestEfficiency", "0.9"},
+		{"failing:latest", "highestWastedBytes", "1kB"},
+	} {
+		ciConfig := viper.New()
+		for _, rule := range RegisteredRules() {
+			ciConfig.SetDefault("rules."+rule.Key, "disabled")
+		}
+		ciConfig.SetDefault("rules."+img.rule, img.value)
+
+		evaluator := NewCiEvaluator(ciConfig)
+		evaluator.Evaluate(result)
+		images = append(images, ReportImage{Evaluator: evaluator, Source: ReportSource{Image: img.name, Analysis: result}})
+	}
+
+	var junit bytes.Buffer
+	if err := writeJUnitReport(images, &junit); err != nil {
+		t.Fatalf("unable to write junit report: %v", err)
+	}
+	for _, expected := range []string{
+		`<testsuites name="dive" tests="44" failures="1" errors="0" skipped="42">`,
+		`<testsuite name="passing:latest" tests="22" failures="0" errors="0" skipped="21">`,
+		`<testsuite name="failing:latest" tests="22" failures="1" errors="0" skipped="21">`,
+	} {
+		if !strings.Contains(junit.String(), expected) {
+			t.Errorf("expected junit report containing %s, got:\n%s", expected, junit.String())
+		}
+	}
+
+	var sarif bytes.Buffer
+	if err := writeSarifReport(images, &sarif); err != nil {
+		t.Fatalf("unable to write sarif report: %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(sarif.Bytes(), &log); err != nil {
+		t.Fatalf("unable to read sarif report: %v", err)
+	}
+	var ids []string
+	for _, run := range log.Runs {
+		if run.AutomationDetails == nil {
+			t.Fatalf("expected every run to have automation details")
+		}
+		ids = append(ids, run.AutomationDetails.ID)
+	}
+	if expected := []string{"dive/passing:latest/", "dive/failing:latest/"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected runs %v, got %v", expected, ids)
+	}
+
+	var markdown bytes.Buffer
+	if err := writeMarkdownReport(images, &markdown); err != nil {
+		t.Fatalf("unable to write markdown report: %v", err)
+	}
+	for _, expected := range []string{
+		"# dive: 2 images\n\n**Result:** **FAIL** (1 of 2 images failed)\n",
+		"| `passing:latest` | PASS | 1.2 MB | 98.44 % | 32 kB |\n| `failing:latest` | **FAIL** | 1.2 MB | 98.44 % | 32 kB |\n",
+		"## dive: `passing:latest`\n\n**Result:** PASS\n",
+		"## dive: `failing:latest`\n\n**Result:** **FAIL**\n",
+	} {
+		if !strings.Contains(markdown.String(), expected) {
+			t.Errorf("expected markdown report containing:\n%s\ngot:\n%s", expected, markdown.String())
+		}
+	}
+
+	if combined := CombinedReport(images); !strings.Contains(combined, "Result:FAIL [Images:2] [Passed:1] [Failed:1]") {
+		t.Errorf("unexpected combined report: %s", combined)
+	}
+
+}
@@ -2,12 +2,11 @@ package ci
 
 import (
 	"fmt"
+	"github.com/wagoodman/dive/dive/filetree"
 	"github.com/wagoodman/dive/dive/image"
-	"strconv"
+	"github.com/wagoodman/dive/runtime/export"
+	"strings"
 
-	"github.com/spf13/viper"
-
-	"github.com/dustin/go-humanize"
 	"github.com/logrusorgru/aurora"
 )
 
@@ -21,18 +20,23 @@ const (
 	RuleConfigured
 )
 
+// maxReportedPaths is the most paths listed within a rule failure message.
+const maxReportedPaths = 5
+
 type CiRule interface {
 	Key() string
 	Configuration() string
+	Severity() RuleSeverity
 	Validate() error
-	Evaluate(result *image.AnalysisResult) (RuleStatus, string)
+	// Evaluate checks the image against the rule, the comparison is with the baseline image (nil when not given)
+	Evaluate(result *image.AnalysisResult, comparison *export.Comparison) (RuleStatus, string)
 }
 
 type GenericCiRule struct {
 	key             string
-	configValue     string
+	config          RuleConfig
 	configValidator func(string) error
-	evaluator       func(*image.AnalysisResult, string) (RuleStatus, string)
+	evaluator       func(*image.AnalysisResult, *export.Comparison, string) (RuleStatus, string)
 }
 
 type RuleStatus int
@@ -42,10 +46,10 @@ type RuleResult struct {
 	message string
 }
 
-func newGenericCiRule(key string, configValue string, validator func(string) error, evaluator func(*image.AnalysisResult, string) (RuleStatus, string)) *GenericCiRule {
+func newGenericCiRule(key string, config RuleConfig, validator func(string) error, evaluator func(*image.AnalysisResult, *export.Comparison, string) (RuleStatus, string)) *GenericCiRule {
 	return &GenericCiRule{
 		key:             key,
-		configValue:     configValue,
+		config:          config,
 		configValidator: validator,
 		evaluator:       evaluator,
 	}
@@ -56,15 +60,65 @@ func (rule *GenericCiRule) Key() string {
 }
 
 func (rule *GenericCiRule) Configuration() string {
-	return rule.configValue
+	return rule.config.String()
+}
+
+func (rule *GenericCiRule) Severity() RuleSeverity {
+	return rule.config.Severity
 }
 
 func (rule *GenericCiRule) Validate() error {
-	return rule.configValidator(rule.configValue)
+	if rule.config.err != nil {
+		return rule.config.err
+	}
+	// note: a single value is always the fail threshold
+	if rule.config.Fail != "" || rule.config.Warn == "" {
+		if err := rule.configValidator(rule.config.Fail); err != nil {
+			return err
+		}
+	}
+	if rule.config.Warn != "" {
+		if err := rule.configValidator(rule.config.Warn); err != nil {
+			return fmt.Errorf("warn threshold: %v", err)
+		}
+	}
+	return nil
 }
 
-func (rule *GenericCiRule) Evaluate(result *image.AnalysisResult) (RuleStatus, string) {
-	return rule.evaluator(result, rule.configValue)
+// Evaluate checks the fail threshold first, then the warn threshold. Crossing the fail threshold fails the rule, unless
+// the rule severity is "warn". A rule that cannot be evaluated (e.g. without a baseline) is skipped.
+func (rule *GenericCiRule) Evaluate(result *image.AnalysisResult, comparison *export.Comparison) (RuleStatus, string) {
+	var failStatus RuleStatus = RuleFailed
+	if rule.config.Severity == RuleSeverityWarn {
+		failStatus = RuleWarning
+	}
+
+	var status RuleStatus = RuleDisabled
+	var skipMessage string
+	for _, threshold := range []struct {
+		value  string
+		status RuleStatus
+	}{
+		{rule.config.Fail, failStatus},
+		{rule.config.Warn, RuleWarning},
+	} {
+		if threshold.value == "" {
+			continue
+		}
+		thresholdStatus, message := rule.evaluator(result, comparison, threshold.value)
+		switch thresholdStatus {
+		case RuleFailed:
+			return threshold.status, message
+		case RulePassed:
+			status = RulePassed
+		case RuleDisabled:
+			skipMessage = message
+		}
+	}
+	if status == RuleDisabled {
+		return status, skipMessage
+	}
+	return status, ""
 }
 
 func (status RuleStatus) String() string {
@@ -86,83 +140,45 @@ func (status RuleStatus) String() string {
 	}
 }
 
-func loadCiRules(config *viper.Viper) []CiRule {
-	var rules = make([]CiRule, 0)
-	var ruleKey = "lowestEfficiency"
-	rules = append(rules, newGenericCiRule(
-		ruleKey,
-		config.GetString(fmt.Sprintf("rules.%s", ruleKey)),
-		func(value string) error {
-			lowestEfficiency, err := strconv.ParseFloat(value, 64)
-			if err != nil {
-				return fmt.Errorf("invalid config value ('%v'): %v", value, err)
-			}
-			if lowestEfficiency < 0 || lowestEfficiency > 1 {
-				return fmt.Errorf("lowestEfficiency config value is outside allowed range (0-1), given '%s'", value)
-			}
-			return nil
-		},
-		func(analysis *image.AnalysisResult, value string) (RuleStatus, string) {
-			lowestEfficiency, err := strconv.ParseFloat(value, 64)
-			if err != nil {
-				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
-			}
-			if lowestEfficiency > analysis.Efficiency {
-				return RuleFailed, fmt.Sprintf("image efficiency is too low (efficiency=%v < threshold=%v)", analysis.Efficiency, lowestEfficiency)
-			}
-			return RulePassed, ""
-		},
-	))
-
-	ruleKey = "highestWastedBytes"
-	rules = append(rules, newGenericCiRule(
-		ruleKey,
-		config.GetString(fmt.Sprintf("rules.%s", ruleKey)),
-		func(value string) error {
-			_, err := humanize.ParseBytes(value)
-			if err != nil {
-				return fmt.Errorf("invalid config value ('%v'): %v", value, err)
-			}
-			return nil
-		},
-		func(analysis *image.AnalysisResult, value string) (RuleStatus, string) {
-			highestWastedBytes, err := humanize.ParseBytes(value)
-			if err != nil {
-				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
-			}
-			if analysis.WastedBytes > highestWastedBytes {
-				return RuleFailed, fmt.Sprintf("too many bytes wasted (wasted-bytes=%v > threshold=%v)", analysis.WastedBytes, highestWastedBytes)
-			}
-			return RulePassed, ""
-		},
-	))
-
-	ruleKey = "highestUserWastedPercent"
-	rules = append(rules, newGenericCiRule(
-		ruleKey,
-		config.GetString(fmt.Sprintf("rules.%s", ruleKey)),
-		func(value string) error {
-			highestUserWastedPercent, err := strconv.ParseFloat(value, 64)
-			if err != nil {
-				return fmt.Errorf("invalid config value ('%v'): %v", value, err)
-			}
-			if highestUserWastedPercent < 0 || highestUserWastedPercent > 1 {
-				return fmt.Errorf("highestUserWastedPercent config value is outside allowed range (0-1), given '%s'", value)
-			}
-			return nil
-		},
-		func(analysis *image.AnalysisResult, value string) (RuleStatus, string) {
-			highestUserWastedPercent, err := strconv.ParseFloat(value, 64)
-			if err != nil {
-				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
-			}
-			if highestUserWastedPercent < analysis.WastedUserPercent {
-				return RuleFailed, fmt.Sprintf("too many bytes wasted, relative to the user bytes added (%%-user-wasted-bytes=%v > threshold=%v)", analysis.WastedUserPercent, highestUserWastedPercent)
-			}
+// summarizePaths joins the given paths, eliding any beyond the first few.
+func summarizePaths(paths []string) string {
+	if len(paths) > maxReportedPaths {
+		paths = append(paths[:maxReportedPaths:maxReportedPaths], fmt.Sprintf("and %d more", len(paths)-maxReportedPaths))
+	}
+	return strings.Join(paths, ", ")
+}
 
-			return RulePassed, ""
-		},
-	))
+// layerName describes the layer at the given index within rule failure messages.
+func layerName(analysis *image.AnalysisResult, idx int) string {
+	if idx < len(analysis.Layers) && analysis.Layers[idx].ShortId() != "" {
+		return fmt.Sprintf("layer %d %s", idx, analysis.Layers[idx].ShortId())
+	}
+	return fmt.Sprintf("layer %d", idx)
+}
 
-	return rules
+// finalTree returns the filesystem of the final image, as stacked by the analysis (the trees are only stacked here
+// for analysis results built by other means, once for all rules).
+func finalTree(analysis *image.AnalysisResult) (*filetree.FileTree, error) {
+	if analysis.FinalTree == nil {
+		tree, _, err := filetree.StackTreeRange(analysis.RefTrees, 0, len(analysis.RefTrees)-1)
+		if err != nil {
+			return nil, err
+		}
+		analysis.FinalTree = tree
+	}
+	return analysis.FinalTree, nil
+}
+
+// inefficiencyLayers returns the indexes of the layers that added (or removed) the given inefficient path.
+func inefficiencyLayers(analysis *image.AnalysisResult, data *filetree.EfficiencyData) []int {
+	var layerIndexes []int
+	for _, node := range data.Nodes {
+		for layerIdx, tree := range analysis.RefTrees {
+			if node.Tree == tree {
+				layerIndexes = append(layerIndexes, layerIdx)
+				break
+			}
+		}
+	}
+	return layerIndexes
 }
@@ -0,0 +1,173 @@
+package ci
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RuleSeverity describes how a rule that does not hold is reported.
+type RuleSeverity string
+
+const (
+	// RuleSeverityError fails the CI validation when the fail threshold is crossed (warning when the warn threshold is)
+	RuleSeverityError RuleSeverity = "error"
+	// RuleSeverityWarn warns when either threshold is crossed, without failing the CI validation
+	RuleSeverityWarn RuleSeverity = "warn"
+	// RuleSeverityOff disables the rule
+	RuleSeverityOff RuleSeverity = "off"
+)
+
+// RuleConfig is the configuration of a single rule, given either as a single (fail) value:
+//
+//	lowestEfficiency: 0.9
+//
+// or with a severity and separate warn and fail thresholds:
+//
+//	lowestEfficiency:
+//	  severity: error
+//	  warn: 0.95
+//	  fail: 0.9
+//
+// Rules over the inefficient files may also ignore paths (see RuleDefinition.Ignorable):
+//
+//	highestWastedBytes:
+//	  fail: 20MB
+//	  ignore:
+//	    - /etc/passwd
+type RuleConfig struct {
+	Severity RuleSeverity
+	// Fail is the value the rule fails with (empty when only warning)
+	Fail string
+	// Warn is the value the rule warns with (empty when only failing)
+	Warn string
+	// Ignore are the path globs of the inefficient files the rule does not count (see filetree.PathAllowed)
+	Ignore []string
+	err    error
+}
+
+// listSeparator joins the items of a list value into a single rule value (see ListSchema).
+const listSeparator = "\n"
+
+// parseRuleConfig reads the config of the given rule from its raw (single value, list, or map) config value.
+func parseRuleConfig(key string, value interface{}, scalar string) RuleConfig {
+	fields, isMap := configFields(value)
+	if !isMap {
+		if isConfigList(value) {
+			scalar = configValue(value)
+		}
+		if scalar == "disabled" {
+			return RuleConfig{Severity: RuleSeverityOff, Fail: scalar}
+		}
+		return RuleConfig{Severity: RuleSeverityError, Fail: scalar}
+	}
+
+	config := RuleConfig{Severity: RuleSeverityError}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := configValue(fields[name])
+		switch strings.ToLower(name) {
+		case "severity":
+			config.Severity, config.err = parseSeverity(key, fields[name])
+			if config.err != nil {
+				return config
+			}
+		case "fail":
+			config.Fail = field
+		case "warn":
+			config.Warn = field
+		case "ignore":
+			config.Ignore = configList(fields[name])
+		default:
+			config.err = fmt.Errorf("unknown %s option: '%s' (expected severity, warn, fail, or ignore)", key, name)
+			return config
+		}
+	}
+	if config.Severity != RuleSeverityOff && config.Fail == "" && config.Warn == "" {
+		config.err = fmt.Errorf("%s requires a 'warn' or 'fail' threshold", key)
+	}
+	return config
+}
+
+// configFields returns the fields of a map config value (as read from yaml or set directly), false when the value is
+// not a map.
+func configFields(value interface{}) (map[string]interface{}, bool) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		return typed, true
+	case map[interface{}]interface{}:
+		fields := make(map[string]interface{}, len(typed))
+		for name, field := range typed {
+			fields[fmt.Sprintf("%v", name)] = field
+		}
+		return fields, true
+	}
+	return nil, false
+}
+
+// configValue returns the given config value as a rule value, where the items of a list are joined (see ListSchema).
+func configValue(value interface{}) string {
+	if isConfigList(value) {
+		return strings.Join(configList(value), listSeparator)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// isConfigList indicates if the given config value is a list (as read from yaml or set directly).
+func isConfigList(value interface{}) bool {
+	switch value.(type) {
+	case []interface{}, []string:
+		return true
+	}
+	return false
+}
+
+// configList returns the values of a list config value (a single value is a list of one).
+func configList(value interface{}) []string {
+	switch typed := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		values := make([]string, len(typed))
+		for idx, item := range typed {
+			values[idx] = fmt.Sprintf("%v", item)
+		}
+		return values
+	case []string:
+		return typed
+	}
+	return []string{fmt.Sprintf("%v", value)}
+}
+
+// parseSeverity reads the severity option of the given rule.
+func parseSeverity(key string, value interface{}) (RuleSeverity, error) {
+	if off, isBool := value.(bool); isBool && !off {
+		// an unquoted "off" is read from yaml as false
+		return RuleSeverityOff, nil
+	}
+	severity := RuleSeverity(strings.ToLower(fmt.Sprintf("%v", value)))
+	switch severity {
+	case RuleSeverityError, RuleSeverityWarn, RuleSeverityOff:
+		return severity, nil
+	}
+	return severity, fmt.Errorf("invalid %s severity ('%v'): expected error, warn, or off", key, value)
+}
+
+func (config RuleConfig) String() string {
+	if config.Warn == "" && config.Severity != RuleSeverityWarn {
+		return config.Fail
+	}
+	var fields []string
+	fields = append(fields, "severity="+string(config.Severity))
+	if config.Warn != "" {
+		fields = append(fields, "warn="+config.Warn)
+	}
+	if config.Fail != "" {
+		fields = append(fields, "fail="+config.Fail)
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,111 @@
+package ci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/runtime/export"
+)
+
+// increaseLimit is the most a size may grow since the baseline, either in bytes or relative to the baseline size.
+type increaseLimit struct {
+	bytes    uint64
+	ratio    float64
+	relative bool
+	value    string
+}
+
+// parseIncreaseLimit reads sizes such as "1MB", or percentages of the baseline such as "5%".
+func parseIncreaseLimit(value string) (increaseLimit, error) {
+	limit := increaseLimit{value: value}
+	if strings.HasSuffix(value, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "%")), 64)
+		if err != nil {
+			return limit, err
+		}
+		if percent < 0 {
+			return limit, fmt.Errorf("negative percentage")
+		}
+		limit.ratio = percent / 100
+		limit.relative = true
+		return limit, nil
+	}
+
+	bytes, err := humanize.ParseBytes(value)
+	limit.bytes = bytes
+	return limit, err
+}
+
+// exceeded returns true when the size grew beyond the limit.
+func (limit increaseLimit) exceeded(change export.SizeChange) bool {
+	if limit.relative {
+		return change.IncreaseRatio() > limit.ratio
+	}
+	return change.Increase() > limit.bytes
+}
+
+// newIncreaseRule returns a rule that fails when the given size of the image grows too much since the baseline.
+func newIncreaseRule(key, description, subject string, size func(*export.Comparison) export.SizeChange) RuleDefinition {
+	return RuleDefinition{
+		Key:         key,
+		Default:     "disabled",
+		Description: description,
+		Schema:      IncreaseSchema,
+		BaselineEvaluator: func(comparison *export.Comparison, value string, _ *viper.Viper) (RuleStatus, string) {
+			limit, err := parseIncreaseLimit(value)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+			change := size(comparison)
+			if limit.exceeded(change) {
+				return RuleFailed, fmt.Sprintf("%s grew too much since the baseline (%s=%v, baseline=%v, increase=%v (%.2f%%) > threshold=%v)",
+					subject, strings.ReplaceAll(subject, " ", "-"), change.Value, change.Baseline, change.Increase(), change.IncreaseRatio()*100, limit.value)
+			}
+			return RulePassed, ""
+		},
+	}
+}
+
+func init() {
+	RegisterRule(newIncreaseRule(
+		"maxSizeIncrease",
+		"highest allowable growth of the image size since the --baseline image, as a size (e.g. 10MB) or a percentage of the baseline size (e.g. 5%), otherwise CI validation will fail.",
+		"image size",
+		func(comparison *export.Comparison) export.SizeChange { return comparison.SizeBytes },
+	))
+
+	RegisterRule(newIncreaseRule(
+		"maxWastedBytesIncrease",
+		"highest allowable growth of the bytes wasted since the --baseline image, as a size (e.g. 1MB) or a percentage of the baseline bytes wasted (e.g. 5%), otherwise CI validation will fail.",
+		"wasted bytes",
+		func(comparison *export.Comparison) export.SizeChange { return comparison.InefficientBytes },
+	))
+
+	RegisterRule(RuleDefinition{
+		Key:         "noNewInefficientFiles",
+		Default:     "disabled",
+		Description: "fail CI validation if any file is inefficient (duplicated or removed across layers) that is not inefficient within the --baseline image (true/false).",
+		Schema:      BoolSchema,
+		BaselineEvaluator: func(comparison *export.Comparison, value string, _ *viper.Viper) (RuleStatus, string) {
+			noNewInefficientFiles, err := strconv.ParseBool(value)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+			if !noNewInefficientFiles {
+				return RuleDisabled, ""
+			}
+
+			if len(comparison.NewInefficientFiles) > 0 {
+				paths := make([]string, len(comparison.NewInefficientFiles))
+				for idx, file := range comparison.NewInefficientFiles {
+					paths[idx] = fmt.Sprintf("%s (%s)", file.Path, humanize.Bytes(file.SizeBytes))
+				}
+				return RuleFailed, fmt.Sprintf("found %d new inefficient files since the baseline: %s", len(paths), summarizePaths(paths))
+			}
+			return RulePassed, ""
+		},
+	})
+}
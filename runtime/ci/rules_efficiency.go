@@ -0,0 +1,91 @@
+package ci
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
+)
+
+func init() {
+	RegisterRule(RuleDefinition{
+		Key:         "lowestEfficiency",
+		Default:     "0.9",
+		Description: "lowest allowable image efficiency (as a ratio between 0-1), otherwise CI validation will fail.",
+		Schema:      RatioSchema,
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			lowestEfficiency, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+			if lowestEfficiency > analysis.Efficiency {
+				return RuleFailed, fmt.Sprintf("image efficiency is too low (efficiency=%v < threshold=%v)", analysis.Efficiency, lowestEfficiency)
+			}
+			return RulePassed, ""
+		},
+	})
+
+	RegisterRule(RuleDefinition{
+		Key:         "highestWastedBytes",
+		Default:     "disabled",
+		Description: "highest allowable bytes wasted, otherwise CI validation will fail.",
+		Schema:      BytesSchema,
+		Ignorable:   true,
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			highestWastedBytes, err := humanize.ParseBytes(value)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+			if analysis.WastedBytes > highestWastedBytes {
+				return RuleFailed, fmt.Sprintf("too many bytes wasted (wasted-bytes=%v > threshold=%v)", analysis.WastedBytes, highestWastedBytes)
+			}
+			return RulePassed, ""
+		},
+	})
+
+	RegisterRule(RuleDefinition{
+		Key:         "highestUserWastedPercent",
+		Default:     "0.1",
+		Description: "highest allowable percentage of bytes wasted (as a ratio between 0-1), otherwise CI validation will fail.",
+		Schema:      RatioSchema,
+		Ignorable:   true,
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			highestUserWastedPercent, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+			if highestUserWastedPercent < analysis.WastedUserPercent {
+				return RuleFailed, fmt.Sprintf("too many bytes wasted, relative to the user bytes added (%%-user-wasted-bytes=%v > threshold=%v)", analysis.WastedUserPercent, highestUserWastedPercent)
+			}
+
+			return RulePassed, ""
+		},
+	})
+
+	RegisterRule(RuleDefinition{
+		Key:         "lowestLayerEfficiency",
+		Default:     "disabled",
+		Description: "lowest allowable efficiency of any layer (the ratio of the bytes added by the layer that are not replaced or removed by a later layer, between 0-1), otherwise CI validation will fail.",
+		Schema:      RatioSchema,
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			lowestLayerEfficiency, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+
+			var layers []string
+			for idx, efficiency := range filetree.LayerEfficiency(analysis.RefTrees) {
+				if efficiency < lowestLayerEfficiency {
+					layers = append(layers, fmt.Sprintf("%s (efficiency=%.4g)", layerName(analysis, idx), efficiency))
+				}
+			}
+			if len(layers) > 0 {
+				return RuleFailed, fmt.Sprintf("found %d layers with too low an efficiency (threshold=%v): %s", len(layers), lowestLayerEfficiency, summarizePaths(layers))
+			}
+			return RulePassed, ""
+		},
+	})
+}
@@ -0,0 +1,105 @@
+package ci
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
+)
+
+func init() {
+	RegisterRule(RuleDefinition{
+		Key:         "noDanglingSymlinks",
+		Default:     "disabled",
+		Description: "fail CI validation if any symlink in the final image does not resolve (true/false).",
+		Schema:      BoolSchema,
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			noDanglingSymlinks, err := strconv.ParseBool(value)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+			if !noDanglingSymlinks {
+				return RuleDisabled, ""
+			}
+
+			// links are resolved against the final image filesystem
+			tree, err := finalTree(analysis)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("unable to stack image layers: %v", err)
+			}
+			broken := tree.ResolveLinks()
+			if len(broken) > 0 {
+				links := make([]string, len(broken))
+				for idx, resolution := range broken {
+					links[idx] = fmt.Sprintf("%s → %s (%s)", resolution.Path, resolution.Target, resolution.Status)
+				}
+				return RuleFailed, fmt.Sprintf("found %d dangling or cyclic symlinks: %s", len(broken), summarizePaths(links))
+			}
+			return RulePassed, ""
+		},
+	})
+
+	RegisterRule(newFindingsRule(
+		"noSecrets",
+		"secretsAllowlist",
+		"fail CI validation if any layer has a file that is likely to contain a secret (true/false).",
+		"files likely to contain secrets",
+		filetree.FindingSecret,
+	))
+
+	RegisterRule(newFindingsRule(
+		"forbidSetuid",
+		"setuidAllowlist",
+		"fail CI validation if the final image has any setuid/setgid files (true/false).",
+		"setuid/setgid files",
+		filetree.FindingSetuid, filetree.FindingSetgid,
+	))
+
+	RegisterRule(newFindingsRule(
+		"forbidWorldWritable",
+		"worldWritableAllowlist",
+		"fail CI validation if the final image has any world-writable paths outside of the temporary directories (true/false).",
+		"world-writable paths",
+		filetree.FindingWorldWritable,
+	))
+
+	RegisterRule(newFindingsRule(
+		"forbidPathCollisions",
+		"pathCollisionAllowlist",
+		"fail CI validation if the final image has paths that differ only by case or Unicode normalization, which collide on macOS and Windows (true/false).",
+		"paths colliding by case or Unicode normalization",
+		filetree.FindingPathCollision,
+	))
+}
+
+// newFindingsRule defines a rule that (when set to true) fails if the image has any audit findings of the given kinds
+// outside of the paths allowed by the given allowlist config key.
+func newFindingsRule(key, allowlistKey, description, subject string, kinds ...filetree.FindingKind) RuleDefinition {
+	return RuleDefinition{
+		Key:         key,
+		Default:     "disabled",
+		Description: description,
+		Schema:      BoolSchema,
+		Evaluator: func(analysis *image.AnalysisResult, value string, config *viper.Viper) (RuleStatus, string) {
+			forbid, err := strconv.ParseBool(value)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+			if !forbid {
+				return RuleDisabled, ""
+			}
+
+			findings := analysis.Findings.Filter(config.GetStringSlice(fmt.Sprintf("rules.%s", allowlistKey)), kinds...)
+			if len(findings) > 0 {
+				paths := make([]string, len(findings))
+				for idx, finding := range findings {
+					paths[idx] = finding.Path
+				}
+				return RuleFailed, fmt.Sprintf("found %d %s: %s", len(findings), subject, summarizePaths(paths))
+			}
+			return RulePassed, ""
+		},
+	}
+}
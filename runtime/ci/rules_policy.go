@@ -0,0 +1,290 @@
+package ci
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
+)
+
+// unknownConfigMessage is the reason the image config rules are skipped for images without a (readable) config.
+const unknownConfigMessage = "image config unknown"
+
+func init() {
+	RegisterRule(RuleDefinition{
+		Key:         "requireNonRootUser",
+		Default:     "disabled",
+		Description: "fail CI validation if the image runs as root, either by default or with a root USER (true/false).",
+		Schema:      BoolSchema,
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			require, err := strconv.ParseBool(value)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+			if !require {
+				return RuleDisabled, ""
+			}
+			config := analysis.ContainerConfig
+			if config == nil {
+				return RuleDisabled, unknownConfigMessage
+			}
+
+			user := config.UserName()
+			if user == "" {
+				return RuleFailed, "image runs as root (no USER given)"
+			}
+			if isRootUser(user, analysis.FinalOwners()) {
+				return RuleFailed, fmt.Sprintf("image runs as root (user=%s)", config.User)
+			}
+			return RulePassed, ""
+		},
+	})
+
+	RegisterRule(RuleDefinition{
+		Key:         "requireHealthcheck",
+		Default:     "disabled",
+		Description: "fail CI validation if the image does not give a HEALTHCHECK (true/false).",
+		Schema:      BoolSchema,
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			require, err := strconv.ParseBool(value)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+			if !require {
+				return RuleDisabled, ""
+			}
+			config := analysis.ContainerConfig
+			if config == nil {
+				return RuleDisabled, unknownConfigMessage
+			}
+
+			if config.Healthcheck != nil && !config.HasHealthcheck() {
+				return RuleFailed, "image disables the health check (HEALTHCHECK NONE)"
+			}
+			if !config.HasHealthcheck() {
+				return RuleFailed, "image has no health check"
+			}
+			return RulePassed, ""
+		},
+	})
+
+	RegisterRule(RuleDefinition{
+		Key:         "requiredLabels",
+		Default:     "disabled",
+		Description: "labels the image must have, given as 'name' or as 'name=regex' to also check the value, otherwise CI validation will fail.",
+		Schema: ListSchema(func(item string) error {
+			_, _, err := parseLabelRequirement(item)
+			return err
+		}),
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			config := analysis.ContainerConfig
+			if config == nil {
+				return RuleDisabled, unknownConfigMessage
+			}
+
+			var missing []string
+			for _, item := range listItems(value) {
+				name, pattern, err := parseLabelRequirement(item)
+				if err != nil {
+					return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", item, err)
+				}
+				labelValue, exists := config.Labels[name]
+				switch {
+				case !exists:
+					missing = append(missing, name)
+				case pattern != nil && !pattern.MatchString(labelValue):
+					missing = append(missing, fmt.Sprintf("%s (value '%s' does not match '%s')", name, labelValue, pattern))
+				}
+			}
+			if len(missing) > 0 {
+				return RuleFailed, fmt.Sprintf("missing %d required labels: %s", len(missing), summarizePaths(missing))
+			}
+			return RulePassed, ""
+		},
+	})
+
+	RegisterRule(RuleDefinition{
+		Key:         "forbiddenExposedPorts",
+		Default:     "disabled",
+		Description: "ports the image must not expose, given as 'port', 'port/protocol', or a range such as '6000-6063/tcp', otherwise CI validation will fail.",
+		Schema: ListSchema(func(item string) error {
+			_, err := parsePortRange(item)
+			return err
+		}),
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			config := analysis.ContainerConfig
+			if config == nil {
+				return RuleDisabled, unknownConfigMessage
+			}
+
+			var ranges []portRange
+			for _, item := range listItems(value) {
+				forbidden, err := parsePortRange(item)
+				if err != nil {
+					return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", item, err)
+				}
+				ranges = append(ranges, forbidden)
+			}
+
+			var exposed []string
+			for port := range config.ExposedPorts {
+				exposed = append(exposed, port)
+			}
+			sort.Strings(exposed)
+
+			var forbidden []string
+			for _, port := range exposed {
+				number, protocol, err := parseExposedPort(port)
+				if err != nil {
+					continue
+				}
+				for _, forbiddenRange := range ranges {
+					if forbiddenRange.contains(number, protocol) {
+						forbidden = append(forbidden, port)
+						break
+					}
+				}
+			}
+			if len(forbidden) > 0 {
+				return RuleFailed, fmt.Sprintf("found %d forbidden exposed ports: %s", len(forbidden), summarizePaths(forbidden))
+			}
+			return RulePassed, ""
+		},
+	})
+
+	RegisterRule(RuleDefinition{
+		Key:         "forbiddenEnvKeys",
+		Default:     "disabled",
+		Description: "environment variables the image must not set, given as case-insensitive globs such as '*_TOKEN', otherwise CI validation will fail.",
+		Schema: ListSchema(func(item string) error {
+			_, err := path.Match(item, "")
+			return err
+		}),
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			config := analysis.ContainerConfig
+			if config == nil {
+				return RuleDisabled, unknownConfigMessage
+			}
+
+			patterns := listItems(value)
+			var forbidden []string
+			for _, key := range config.EnvKeys() {
+				for _, pattern := range patterns {
+					// note: only the names are reported, as the values may be secrets
+					if matched, _ := path.Match(strings.ToUpper(pattern), strings.ToUpper(key)); matched {
+						forbidden = append(forbidden, key)
+						break
+					}
+				}
+			}
+			if len(forbidden) > 0 {
+				return RuleFailed, fmt.Sprintf("found %d forbidden environment variables: %s", len(forbidden), summarizePaths(forbidden))
+			}
+			return RulePassed, ""
+		},
+	})
+}
+
+// isRootUser indicates if the given user (a name or uid) is root, names are resolved with the passwd entries of the
+// image when known.
+func isRootUser(user string, owners *filetree.Owners) bool {
+	if uid, err := strconv.Atoi(user); err == nil {
+		return uid == 0
+	}
+	if user == "root" {
+		return true
+	}
+	if owners != nil {
+		for uid, name := range owners.Users {
+			if name == user {
+				return uid == 0
+			}
+		}
+	}
+	return false
+}
+
+// parseLabelRequirement reads a required label, given as "name" or "name=regex".
+func parseLabelRequirement(item string) (string, *regexp.Regexp, error) {
+	fields := strings.SplitN(item, "=", 2)
+	name := strings.TrimSpace(fields[0])
+	if name == "" {
+		return "", nil, fmt.Errorf("expected a label name")
+	}
+	if len(fields) == 1 {
+		return name, nil, nil
+	}
+	pattern, err := regexp.Compile(fields[1])
+	if err != nil {
+		return "", nil, err
+	}
+	return name, pattern, nil
+}
+
+// portRange is a range of ports, of any protocol when the protocol is empty.
+type portRange struct {
+	low      int
+	high     int
+	protocol string
+}
+
+func (ports portRange) contains(port int, protocol string) bool {
+	return port >= ports.low && port <= ports.high && (ports.protocol == "" || ports.protocol == protocol)
+}
+
+// parsePortRange reads a port or range of ports, optionally with a protocol (e.g. "22", "53/udp", or "6000-6063/tcp").
+func parsePortRange(item string) (portRange, error) {
+	fields := strings.SplitN(item, "/", 2)
+	var ports portRange
+	if len(fields) == 2 {
+		ports.protocol = strings.ToLower(fields[1])
+		if ports.protocol != "tcp" && ports.protocol != "udp" && ports.protocol != "sctp" {
+			return ports, fmt.Errorf("unknown protocol '%s' (expected tcp, udp, or sctp)", fields[1])
+		}
+	}
+
+	bounds := strings.SplitN(fields[0], "-", 2)
+	var err error
+	ports.low, err = parsePort(bounds[0])
+	if err != nil {
+		return ports, err
+	}
+	ports.high = ports.low
+	if len(bounds) == 2 {
+		ports.high, err = parsePort(bounds[1])
+		if err != nil {
+			return ports, err
+		}
+		if ports.high < ports.low {
+			return ports, fmt.Errorf("port range ends before it starts")
+		}
+	}
+	return ports, nil
+}
+
+// parseExposedPort reads an exposed port of the image config (e.g. "80/tcp", where the protocol defaults to tcp).
+func parseExposedPort(port string) (int, string, error) {
+	fields := strings.SplitN(port, "/", 2)
+	number, err := parsePort(fields[0])
+	if err != nil {
+		return 0, "", err
+	}
+	if len(fields) == 1 {
+		return number, "tcp", nil
+	}
+	return number, strings.ToLower(fields[1]), nil
+}
+
+func parsePort(value string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || port < 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port '%s'", value)
+	}
+	return port, nil
+}
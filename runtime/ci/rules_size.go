@@ -0,0 +1,139 @@
+package ci
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
+)
+
+func init() {
+	RegisterRule(RuleDefinition{
+		Key:         "highestImageSize",
+		Default:     "disabled",
+		Description: "highest allowable size of the image, otherwise CI validation will fail.",
+		Schema:      BytesSchema,
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			highestImageSize, err := humanize.ParseBytes(value)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+			if analysis.SizeBytes > highestImageSize {
+				return RuleFailed, fmt.Sprintf("image is too large (image-size=%v > threshold=%v)", analysis.SizeBytes, highestImageSize)
+			}
+			return RulePassed, ""
+		},
+	})
+
+	RegisterRule(RuleDefinition{
+		Key:         "highestLayerCount",
+		Default:     "disabled",
+		Description: "highest allowable number of layers, otherwise CI validation will fail.",
+		Schema:      CountSchema,
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			highestLayerCount, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+			if layerCount := uint64(len(analysis.Layers)); layerCount > highestLayerCount {
+				return RuleFailed, fmt.Sprintf("too many layers (layer-count=%v > threshold=%v)", layerCount, highestLayerCount)
+			}
+			return RulePassed, ""
+		},
+	})
+
+	RegisterRule(RuleDefinition{
+		Key:         "highestLayerSize",
+		Default:     "disabled",
+		Description: "highest allowable size of any layer, otherwise CI validation will fail.",
+		Schema:      BytesSchema,
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			highestLayerSize, err := humanize.ParseBytes(value)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+
+			var layers []string
+			for idx, layer := range analysis.Layers {
+				if layer.Size > highestLayerSize {
+					layers = append(layers, fmt.Sprintf("%s (%s)", layerName(analysis, idx), humanize.Bytes(layer.Size)))
+				}
+			}
+			if len(layers) > 0 {
+				return RuleFailed, fmt.Sprintf("found %d layers that are too large (threshold=%v): %s", len(layers), highestLayerSize, summarizePaths(layers))
+			}
+			return RulePassed, ""
+		},
+	})
+
+	RegisterRule(RuleDefinition{
+		Key:         "highestSingleFileSize",
+		Default:     "disabled",
+		Description: "highest allowable size of any file within the final image, otherwise CI validation will fail.",
+		Schema:      BytesSchema,
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			highestSingleFileSize, err := humanize.ParseBytes(value)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+
+			tree, err := finalTree(analysis)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("unable to stack image layers: %v", err)
+			}
+			var large []*filetree.FileNode
+			err = tree.VisitDepthChildFirst(func(node *filetree.FileNode) error {
+				if !node.Data.FileInfo.IsDir && node.Data.FileInfo.Size > 0 && uint64(node.Data.FileInfo.Size) > highestSingleFileSize {
+					large = append(large, node)
+				}
+				return nil
+			}, nil)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("unable to visit the final image: %v", err)
+			}
+
+			if len(large) > 0 {
+				// the largest files are the most actionable
+				sort.SliceStable(large, func(i, j int) bool {
+					return large[i].Data.FileInfo.Size > large[j].Data.FileInfo.Size
+				})
+				paths := make([]string, len(large))
+				for idx, node := range large {
+					paths[idx] = fmt.Sprintf("%s (%s)", node.Path(), humanize.Bytes(uint64(node.Data.FileInfo.Size)))
+				}
+				return RuleFailed, fmt.Sprintf("found %d files that are too large (threshold=%v): %s", len(large), highestSingleFileSize, summarizePaths(paths))
+			}
+			return RulePassed, ""
+		},
+	})
+
+	RegisterRule(RuleDefinition{
+		Key:         "highestRemovableBytes",
+		Default:     "disabled",
+		Description: "highest allowable bytes of likely removable paths (e.g. package caches), otherwise CI validation will fail.",
+		Schema:      BytesSchema,
+		Evaluator: func(analysis *image.AnalysisResult, value string, _ *viper.Viper) (RuleStatus, string) {
+			highestRemovableBytes, err := humanize.ParseBytes(value)
+			if err != nil {
+				return RuleFailed, fmt.Sprintf("invalid config value ('%v'): %v", value, err)
+			}
+			if analysis.RemovableBytes > highestRemovableBytes {
+				// the largest removable paths are the most actionable
+				removable := append(filetree.RemovableSlice{}, analysis.Removable...)
+				sort.SliceStable(removable, func(i, j int) bool {
+					return removable[i].Size > removable[j].Size
+				})
+				paths := make([]string, len(removable))
+				for idx, removablePath := range removable {
+					paths[idx] = fmt.Sprintf("%s (%s, %s)", removablePath.Path, removablePath.Category, humanize.Bytes(uint64(removablePath.Size)))
+				}
+				return RuleFailed, fmt.Sprintf("too many likely removable bytes (removable-bytes=%v > threshold=%v): %s", analysis.RemovableBytes, highestRemovableBytes, summarizePaths(paths))
+			}
+			return RulePassed, ""
+		},
+	})
+}
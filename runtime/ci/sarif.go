@@ -0,0 +1,304 @@
+package ci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// sarifInefficientFileRule is the rule of the files duplicated or removed across layers
+	sarifInefficientFileRule = "inefficient-file"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool              sarifTool               `json:"tool"`
+	AutomationDetails *sarifAutomationDetails `json:"automationDetails,omitempty"`
+	Results           []sarifResult           `json:"results"`
+}
+
+// sarifAutomationDetails tells the runs of a report apart (e.g. the category of the results within GitHub code scanning).
+type sarifAutomationDetails struct {
+	ID string `json:"id"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifResult struct {
+	RuleID           string             `json:"ruleId"`
+	RuleIndex        int                `json:"ruleIndex"`
+	Level            string             `json:"level"`
+	Message          sarifMessage       `json:"message"`
+	Locations        []sarifLocation    `json:"locations,omitempty"`
+	RelatedLocations []sarifLocation    `json:"relatedLocations,omitempty"`
+	Suppressions     []sarifSuppression `json:"suppressions,omitempty"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	ID               int                    `json:"id,omitempty"`
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+	Message          *sarifMessage          `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifBuilder collects the rule descriptors and results of a single run.
+type sarifBuilder struct {
+	run       sarifRun
+	ruleIndex map[string]int
+	// instructions are the Dockerfile instructions that created each layer (nil when not known)
+	instructions []*image.DockerfileInstruction
+	dockerfile   string
+}
+
+func (builder *sarifBuilder) addRule(id, description, level string) {
+	if _, exists := builder.ruleIndex[id]; exists {
+		return
+	}
+	builder.ruleIndex[id] = len(builder.run.Tool.Driver.Rules)
+	builder.run.Tool.Driver.Rules = append(builder.run.Tool.Driver.Rules, sarifRule{
+		ID:                   id,
+		ShortDescription:     sarifMessage{Text: description},
+		DefaultConfiguration: sarifConfiguration{Level: level},
+	})
+}
+
+func (builder *sarifBuilder) addResult(id, level, message string, locations ...sarifLocation) {
+	result := sarifResult{
+		RuleID:    id,
+		RuleIndex: builder.ruleIndex[id],
+		Level:     level,
+		Message:   sarifMessage{Text: message},
+	}
+	// note: a location without the image path or the Dockerfile would point nowhere
+	var known []sarifLocation
+	for _, location := range locations {
+		if location.PhysicalLocation != nil || len(location.LogicalLocations) > 0 {
+			known = append(known, location)
+		}
+	}
+	locations = known
+	if len(locations) > 0 {
+		result.Locations = locations[:1]
+		result.RelatedLocations = locations[1:]
+		for idx := range result.RelatedLocations {
+			result.RelatedLocations[idx].ID = idx + 1
+		}
+	}
+	builder.run.Results = append(builder.run.Results, result)
+}
+
+// location points at the image path, along with the Dockerfile instruction that created the given layer (when known).
+func (builder *sarifBuilder) location(p string, layerIdx int) sarifLocation {
+	location := sarifLocation{}
+	if p != "" {
+		location.LogicalLocations = []sarifLogicalLocation{{Name: filepath.Base(p), FullyQualifiedName: p, Kind: "resource"}}
+	}
+	if builder.dockerfile != "" {
+		location.PhysicalLocation = &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: builder.dockerfile}}
+		if layerIdx >= 0 && layerIdx < len(builder.instructions) && builder.instructions[layerIdx] != nil {
+			instruction := builder.instructions[layerIdx]
+			location.PhysicalLocation.Region = &sarifRegion{StartLine: instruction.StartLine, EndLine: instruction.EndLine}
+			location.Message = &sarifMessage{Text: fmt.Sprintf("layer %d: %s", layerIdx, instruction.Command)}
+		}
+	}
+	return location
+}
+
+// writeSarifReport writes the failed (and warning) rules along with the inefficient files and the audit findings as
+// SARIF 2.1.0 results, as a run for every image. Results point at the image path, and at the Dockerfile line that
+// created the layer when the Dockerfile is known.
+func writeSarifReport(images []ReportImage, writer io.Writer) error {
+	runs := make([]sarifRun, 0, len(images))
+	for _, img := range images {
+		run := newSarifRun(img.Evaluator, img.Source)
+		if len(images) > 1 {
+			// note: a run without details would be taken as a later run of the same analysis, replacing the others
+			run.AutomationDetails = &sarifAutomationDetails{ID: fmt.Sprintf("dive/%s/", img.Source.Image)}
+		}
+		runs = append(runs, run)
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: runs})
+}
+
+// newSarifRun returns the run of the given (evaluated) image.
+func newSarifRun(ci *CiEvaluator, source ReportSource) sarifRun {
+	builder := sarifBuilder{
+		run: sarifRun{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "dive", InformationURI: "https://github.com/wagoodman/dive"}},
+			Results: make([]sarifResult, 0),
+		},
+		ruleIndex: make(map[string]int),
+	}
+
+	if source.Dockerfile != "" {
+		builder.dockerfile = filepath.ToSlash(source.Dockerfile)
+		if source.Analysis != nil {
+			instructions, err := readDockerfile(source.Dockerfile)
+			if err != nil {
+				// note: the results are still reported against the Dockerfile, without the lines
+				logrus.Warnf("unable to read the Dockerfile (%s): %v", source.Dockerfile, err)
+			} else {
+				builder.instructions = image.MatchDockerfile(source.Analysis.Layers, instructions)
+			}
+		}
+	}
+
+	// CI rules
+	descriptions := make(map[string]string)
+	for _, definition := range RegisteredRules() {
+		descriptions[definition.Key] = definition.Description
+	}
+	for _, rule := range ci.Rules {
+		if custom, isCustom := rule.(*CustomCiRule); isCustom {
+			descriptions[custom.Key()] = custom.Description()
+		}
+	}
+	for _, rule := range ci.sortedResults() {
+		builder.addRule(rule, descriptions[rule], "error")
+
+		result := ci.Results[rule]
+		switch result.status {
+		case RuleFailed, RuleMisconfigured:
+			builder.addResult(rule, "error", result.message, builder.location("", -1))
+		case RuleWarning:
+			level := "warning"
+			if ci.FailOnWarn {
+				level = "error"
+			}
+			builder.addResult(rule, level, result.message, builder.location("", -1))
+		}
+	}
+
+	// inefficient files
+	builder.addRule(sarifInefficientFileRule, "file duplicated or removed across layers, wasting space", "warning")
+	files := ci.InefficientFiles
+	for _, file := range ci.IgnoredFiles {
+		if file.IgnoredGlobally {
+			files = append(files[:len(files):len(files)], file)
+		}
+	}
+	for _, file := range files {
+		// the latest layer is the one that wasted the space of the layers before it
+		locations := make([]sarifLocation, 0, len(file.LayerIndexes))
+		for idx := len(file.LayerIndexes) - 1; idx >= 0; idx-- {
+			locations = append(locations, builder.location(file.Path, file.LayerIndexes[idx]))
+		}
+		if len(locations) == 0 {
+			locations = append(locations, builder.location(file.Path, -1))
+		}
+		builder.addResult(sarifInefficientFileRule, "warning", fmt.Sprintf("%s wasted by %d copies of %s", humanize.Bytes(file.SizeBytes), file.References, file.Path), locations...)
+		if file.IgnoredGlobally {
+			// note: ignored files are still reported, as suppressed results
+			last := &builder.run.Results[len(builder.run.Results)-1]
+			last.Suppressions = []sarifSuppression{{Kind: "external", Justification: fmt.Sprintf("ignored by the CI config (%s)", file.IgnoredBy)}}
+		}
+	}
+
+	// audit findings
+	if source.Analysis != nil {
+		kinds := make([]string, 0)
+		for _, finding := range source.Analysis.Findings {
+			kinds = append(kinds, string(finding.Kind))
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			builder.addRule(kind, filetree.FindingKind(kind).Description(), "warning")
+		}
+		for _, finding := range source.Analysis.Findings {
+			message := fmt.Sprintf("%s: %s", finding.Kind.Description(), finding.Path)
+			if finding.Detail != "" {
+				message += fmt.Sprintf(" (%s)", finding.Detail)
+			}
+			builder.addResult(string(finding.Kind), sarifLevel(finding.Severity), message, builder.location(finding.Path, finding.LayerIndex))
+		}
+	}
+
+	return builder.run
+}
+
+// sarifLevel returns the SARIF level of the given finding severity.
+func sarifLevel(severity filetree.Severity) string {
+	switch severity {
+	case filetree.SeverityHigh:
+		return "error"
+	case filetree.SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func readDockerfile(path string) ([]image.DockerfileInstruction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return image.ParseDockerfile(file)
+}
@@ -0,0 +1,103 @@
+package export
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/dustin/go-humanize"
+)
+
+// SizeChange is a size of the image, along with the same size within the baseline.
+type SizeChange struct {
+	Value    uint64
+	Baseline uint64
+}
+
+// Increase returns how many bytes larger the value is than the baseline (zero when it is not larger).
+func (change SizeChange) Increase() uint64 {
+	if change.Value > change.Baseline {
+		return change.Value - change.Baseline
+	}
+	return 0
+}
+
+// IncreaseRatio returns the increase relative to the baseline (e.g. 0.05 is 5% larger).
+func (change SizeChange) IncreaseRatio() float64 {
+	increase := change.Increase()
+	if increase == 0 {
+		return 0
+	}
+	if change.Baseline == 0 {
+		return math.Inf(1)
+	}
+	return float64(increase) / float64(change.Baseline)
+}
+
+// String returns the change from the baseline (e.g. "+12 MB").
+func (change SizeChange) String() string {
+	switch {
+	case change.Value > change.Baseline:
+		return "+" + humanize.Bytes(change.Value-change.Baseline)
+	case change.Value < change.Baseline:
+		return "-" + humanize.Bytes(change.Baseline-change.Value)
+	default:
+		return "no change"
+	}
+}
+
+// Comparison is how an image changed since a baseline export (e.g. of the image built from main).
+type Comparison struct {
+	SizeBytes        SizeChange
+	InefficientBytes SizeChange
+	RemovableBytes   SizeChange
+	EfficiencyScore  float64
+	// EfficiencyChange is the efficiency score less the efficiency score of the baseline
+	EfficiencyChange float64
+	LayerCount       int
+	// LayerCountChange is the layer count less the layer count of the baseline
+	LayerCountChange int
+	// NewInefficientFiles are the inefficient files whose paths are not inefficient within the baseline (largest first)
+	NewInefficientFiles []fileReference
+}
+
+// Compare returns how the image changed since the given baseline.
+func (exp *Export) Compare(baseline *Export) *Comparison {
+	comparison := Comparison{
+		SizeBytes:        SizeChange{Value: exp.Image.SizeBytes, Baseline: baseline.Image.SizeBytes},
+		InefficientBytes: SizeChange{Value: exp.Image.InefficientBytes, Baseline: baseline.Image.InefficientBytes},
+		RemovableBytes:   SizeChange{Value: exp.Image.RemovableBytes, Baseline: baseline.Image.RemovableBytes},
+		EfficiencyScore:  exp.Image.EfficiencyScore,
+		EfficiencyChange: exp.Image.EfficiencyScore - baseline.Image.EfficiencyScore,
+		LayerCount:       len(exp.Layer),
+		LayerCountChange: len(exp.Layer) - len(baseline.Layer),
+	}
+
+	inefficient := make(map[string]bool, len(baseline.Image.InefficientFiles))
+	for _, file := range baseline.Image.InefficientFiles {
+		inefficient[file.Path] = true
+	}
+	for _, file := range exp.Image.InefficientFiles {
+		if !inefficient[file.Path] {
+			comparison.NewInefficientFiles = append(comparison.NewInefficientFiles, file)
+		}
+	}
+
+	return &comparison
+}
+
+// EfficiencyString returns the change in efficiency from the baseline, in percentage points (e.g. "-1.50 %").
+func (comparison *Comparison) EfficiencyString() string {
+	formatted := fmt.Sprintf("%+.2f %%", comparison.EfficiencyChange*100)
+	if formatted == "+0.00 %" || formatted == "-0.00 %" {
+		return "no change"
+	}
+	return formatted
+}
+
+// LayerCountString returns the change in layer count from the baseline (e.g. "+1").
+func (comparison *Comparison) LayerCountString() string {
+	if comparison.LayerCountChange == 0 {
+		return "no change"
+	}
+	return fmt.Sprintf("%+d", comparison.LayerCountChange)
+}
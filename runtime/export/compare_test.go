@@ -0,0 +1,79 @@
+package export
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func Test_SizeChange(t *testing.T) {
+	table := map[string]struct {
+		change           SizeChange
+		expectedIncrease uint64
+		expectedRatio    float64
+		expectedString   string
+	}{
+		"increase":      {SizeChange{Value: 13000000, Baseline: 1000000}, 12000000, 12, "+12 MB"},
+		"decrease":      {SizeChange{Value: 1000000, Baseline: 1500000}, 0, 0, "-500 kB"},
+		"same":          {SizeChange{Value: 1000, Baseline: 1000}, 0, 0, "no change"},
+		"emptyBaseline": {SizeChange{Value: 1000, Baseline: 0}, 1000, math.Inf(1), "+1.0 kB"},
+	}
+
+	for name, test := range table {
+		if actual := test.change.Increase(); actual != test.expectedIncrease {
+			t.Errorf("%s: expected increase %d, got %d", name, test.expectedIncrease, actual)
+		}
+		if actual := test.change.IncreaseRatio(); actual != test.expectedRatio {
+			t.Errorf("%s: expected ratio %v, got %v", name, test.expectedRatio, actual)
+		}
+		if actual := test.change.String(); actual != test.expectedString {
+			t.Errorf("%s: expected %q, got %q", name, test.expectedString, actual)
+		}
+	}
+}
+
+func Test_Compare(t *testing.T) {
+	baseline := &Export{
+		Layer: make([]layer, 3),
+		Image: image{
+			SizeBytes:        1000000,
+			InefficientBytes: 2000,
+			EfficiencyScore:  0.98,
+			InefficientFiles: []fileReference{{References: 2, SizeBytes: 2000, Path: "/root/saved.txt"}},
+		},
+	}
+	current := &Export{
+		Layer: make([]layer, 2),
+		Image: image{
+			SizeBytes:        1050000,
+			InefficientBytes: 5000,
+			EfficiencyScore:  0.965,
+			InefficientFiles: []fileReference{
+				{References: 2, SizeBytes: 3000, Path: "/tmp/cache.tar"},
+				{References: 2, SizeBytes: 2000, Path: "/root/saved.txt"},
+			},
+		},
+	}
+
+	comparison := current.Compare(baseline)
+	if comparison.SizeBytes.String() != "+50 kB" || comparison.SizeBytes.IncreaseRatio() != 0.05 {
+		t.Errorf("unexpected size change: %+v", comparison.SizeBytes)
+	}
+	if comparison.InefficientBytes.Increase() != 3000 {
+		t.Errorf("unexpected wasted bytes change: %+v", comparison.InefficientBytes)
+	}
+	if actual := comparison.EfficiencyString(); actual != "-1.50 %" {
+		t.Errorf("expected efficiency change '-1.50 %%', got %q", actual)
+	}
+	if actual := comparison.LayerCountString(); actual != "-1" {
+		t.Errorf("expected layer count change '-1', got %q", actual)
+	}
+	expectedFiles := []fileReference{{References: 2, SizeBytes: 3000, Path: "/tmp/cache.tar"}}
+	if !reflect.DeepEqual(comparison.NewInefficientFiles, expectedFiles) {
+		t.Errorf("expected new inefficient files %+v, got %+v", expectedFiles, comparison.NewInefficientFiles)
+	}
+
+	if actual := baseline.Compare(baseline).EfficiencyString(); actual != "no change" {
+		t.Errorf("expected no efficiency change, got %q", actual)
+	}
+}
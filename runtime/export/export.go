@@ -2,22 +2,32 @@ package export
 
 import (
 	"encoding/json"
+	"io"
+
 	diveImage "github.com/wagoodman/dive/dive/image"
 )
 
-type export struct {
+// Export is the analysis of an image, as written with --json (and read back as a baseline to compare against).
+type Export struct {
 	Layer []layer `json:"layer"`
 	Image image   `json:"image"`
 }
 
-func NewExport(analysis *diveImage.AnalysisResult) *export {
-	data := export{
+func NewExport(analysis *diveImage.AnalysisResult) *Export {
+	data := Export{
 		Layer: make([]layer, len(analysis.Layers)),
 		Image: image{
-			InefficientFiles: make([]fileReference, len(analysis.Inefficiencies)),
-			SizeBytes:        analysis.SizeBytes,
-			EfficiencyScore:  analysis.Efficiency,
-			InefficientBytes: analysis.WastedBytes,
+			InefficientFiles:     make([]fileReference, len(analysis.Inefficiencies)),
+			SizeBytes:            analysis.SizeBytes,
+			EfficiencyScore:      analysis.Efficiency,
+			InefficientBytes:     analysis.WastedBytes,
+			RemovableBytes:       analysis.RemovableBytes,
+			RemovablePaths:       make([]removablePath, len(analysis.Removable)),
+			Findings:             make([]finding, len(analysis.Findings)),
+			Packages:             make([]pkg, 0),
+			UnownedFiles:         make([]string, 0),
+			UnsupportedDatabases: make([]string, 0),
+			Problems:             make([]problem, len(analysis.PathErrors)),
 		},
 	}
 
@@ -43,9 +53,77 @@ func NewExport(analysis *diveImage.AnalysisResult) *export {
 		}
 	}
 
+	// add likely removable paths (by path)
+	for idx, curRemovable := range analysis.Removable {
+		data.Image.RemovablePaths[idx] = removablePath{
+			Category:  curRemovable.Category.String(),
+			Path:      curRemovable.Path,
+			SizeBytes: uint64(curRemovable.Size),
+			FileCount: curRemovable.FileCount,
+		}
+	}
+
+	// add findings (most severe first)
+	for idx, curFinding := range analysis.Findings {
+		data.Image.Findings[idx] = finding{
+			Severity:   curFinding.Severity.String(),
+			Kind:       string(curFinding.Kind),
+			Path:       curFinding.Path,
+			LayerIndex: curFinding.LayerIndex,
+			Mode:       curFinding.Mode.String(),
+			Uid:        curFinding.Uid,
+			Gid:        curFinding.Gid,
+			User:       curFinding.User,
+			Group:      curFinding.Group,
+			Detail:     curFinding.Detail,
+		}
+	}
+
+	// add packages (largest first), the files no package owns, and the package databases that cannot be read
+	if analysis.Packages != nil {
+		for _, curPackage := range analysis.Packages.Packages {
+			data.Image.Packages = append(data.Image.Packages, pkg{
+				Manager:    string(curPackage.Manager),
+				Name:       curPackage.Name,
+				Version:    curPackage.Version,
+				LayerIndex: curPackage.LayerIndex,
+				SizeBytes:  curPackage.Size,
+				FileCount:  len(curPackage.Files),
+			})
+		}
+		data.Image.UnownedBytes = analysis.Packages.UnownedSize
+		data.Image.UnownedFiles = append(data.Image.UnownedFiles, analysis.Packages.UnownedFiles...)
+		data.Image.UnsupportedDatabases = append(data.Image.UnsupportedDatabases, analysis.Packages.UnsupportedDatabases...)
+	}
+
+	// add the layer entries that could not be stacked as given (by layer)
+	for idx, curPathErr := range analysis.PathErrors {
+		var detail string
+		if curPathErr.Err != nil {
+			detail = curPathErr.Err.Error()
+		}
+		data.Image.Problems[idx] = problem{
+			LayerIndex: curPathErr.LayerIndex,
+			Path:       curPathErr.Path,
+			Kind:       string(curPathErr.Kind),
+			Action:     curPathErr.Action.String(),
+			Policy:     string(curPathErr.Policy),
+			Detail:     detail,
+		}
+	}
+
 	return &data
 }
 
-func (exp *export) Marshal() ([]byte, error) {
+func (exp *Export) Marshal() ([]byte, error) {
 	return json.MarshalIndent(&exp, "", "  ")
 }
+
+// Read decodes an export previously written with Marshal.
+func Read(reader io.Reader) (*Export, error) {
+	var exp Export
+	if err := json.NewDecoder(reader).Decode(&exp); err != nil {
+		return nil, err
+	}
+	return &exp, nil
+}
@@ -136,7 +136,52 @@ func Test_Export(t *testing.T) {
         "sizeBytes": 6405,
         "file": "/root/example/somefile3.txt"
       }
-    ]
+    ],
+    "removableBytes": 0,
+    "removablePaths": [],
+    "findings": [
+      {
+        "severity": "low",
+        "kind": "unexpected-owner",
+        "path": "/home",
+        "layer": 0,
+        "mode": "drwxr-xr-x",
+        "uid": 65534,
+        "gid": 65534,
+        "user": "nobody",
+        "group": "nogroup",
+        "detail": "owned by uid 65534"
+      },
+      {
+        "severity": "low",
+        "kind": "unexpected-owner",
+        "path": "/usr/sbin",
+        "layer": 0,
+        "mode": "drwxr-xr-x",
+        "uid": 1,
+        "gid": 1,
+        "user": "daemon",
+        "group": "daemon",
+        "detail": "owned by uid 1"
+      },
+      {
+        "severity": "low",
+        "kind": "unexpected-owner",
+        "path": "/var/spool/mail",
+        "layer": 0,
+        "mode": "drwxr-xr-x",
+        "uid": 8,
+        "gid": 8,
+        "user": "mail",
+        "group": "mail",
+        "detail": "owned by uid 8"
+      }
+    ],
+    "packages": [],
+    "unownedBytes": 0,
+    "unownedFiles": [],
+    "unsupportedPackageDatabases": [],
+    "problems": []
   }
 }`
 	actualResult := string(payload)
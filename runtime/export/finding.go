@@ -0,0 +1,14 @@
+package export
+
+type finding struct {
+	Severity   string `json:"severity"`
+	Kind       string `json:"kind"`
+	Path       string `json:"path"`
+	LayerIndex int    `json:"layer"`
+	Mode       string `json:"mode"`
+	Uid        int    `json:"uid"`
+	Gid        int    `json:"gid"`
+	User       string `json:"user,omitempty"`
+	Group      string `json:"group,omitempty"`
+	Detail     string `json:"detail"`
+}
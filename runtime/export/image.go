@@ -1,8 +1,16 @@
 package export
 
 type image struct {
-	SizeBytes        uint64          `json:"sizeBytes"`
-	InefficientBytes uint64          `json:"inefficientBytes"`
-	EfficiencyScore  float64         `json:"efficiencyScore"`
-	InefficientFiles []fileReference `json:"fileReference"`
+	SizeBytes            uint64          `json:"sizeBytes"`
+	InefficientBytes     uint64          `json:"inefficientBytes"`
+	EfficiencyScore      float64         `json:"efficiencyScore"`
+	InefficientFiles     []fileReference `json:"fileReference"`
+	RemovableBytes       uint64          `json:"removableBytes"`
+	RemovablePaths       []removablePath `json:"removablePaths"`
+	Findings             []finding       `json:"findings"`
+	Packages             []pkg           `json:"packages"`
+	UnownedBytes         uint64          `json:"unownedBytes"`
+	UnownedFiles         []string        `json:"unownedFiles"`
+	UnsupportedDatabases []string        `json:"unsupportedPackageDatabases"`
+	Problems             []problem       `json:"problems"`
 }
@@ -0,0 +1,256 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+const (
+	// maxMarkdownFiles is the most inefficient files listed within the Markdown summary
+	maxMarkdownFiles = 10
+	// maxMarkdownCommand is the longest layer command shown within the Markdown summary (longer commands are cut short)
+	maxMarkdownCommand = 100
+)
+
+// MarkdownRule is the result of a CI rule, as listed within the Markdown summary.
+type MarkdownRule struct {
+	Name    string
+	Status  string
+	Message string
+}
+
+// MarkdownIgnoredFile is an inefficient file ignored by the CI rules, as listed within the Markdown summary.
+type MarkdownIgnoredFile struct {
+	Path       string
+	References int
+	SizeBytes  uint64
+	// IgnoredBy describes the CI config options ignoring the file
+	IgnoredBy string
+}
+
+// MarkdownOptions adjust what the Markdown summary of an export holds.
+type MarkdownOptions struct {
+	// Title is shown as the heading of the summary (e.g. the image name)
+	Title string
+	// Result is the overall CI result (omitted when empty)
+	Result string
+	// Rules are the CI rule results (the rules table is omitted when there are none)
+	Rules []MarkdownRule
+	// IgnoredFiles are the inefficient files ignored by the CI rules (omitted when there are none)
+	IgnoredFiles []MarkdownIgnoredFile
+	// Baseline is a previous export the image is compared against (e.g. of the image built from main), or nil
+	Baseline *Export
+	// BaselineLabel names the baseline within the summary (e.g. "+12 MB vs main")
+	BaselineLabel string
+}
+
+// Markdown renders the export as a Markdown summary, suitable for a pull request comment: the image size and
+// efficiency, every layer with its command, the top inefficient files, and any CI rule results. When a baseline is given
+// the image totals are shown along with the change since the baseline.
+func (exp *Export) Markdown(options MarkdownOptions) string {
+	var doc strings.Builder
+
+	title := options.Title
+	if title == "" {
+		title = "image"
+	}
+	fmt.Fprintf(&doc, "## dive: %s\n\n", markdownCode(title))
+	if options.Result != "" {
+		fmt.Fprintf(&doc, "**Result:** %s\n\n", options.Result)
+	}
+
+	var comparison *Comparison
+	if options.Baseline != nil {
+		comparison = exp.Compare(options.Baseline)
+	}
+
+	exp.writeMarkdownSummary(&doc, comparison, options.baselineLabel())
+	exp.writeMarkdownLayers(&doc)
+	exp.writeMarkdownFiles(&doc)
+	if comparison != nil {
+		fmt.Fprintf(&doc, "### New inefficient files (vs %s)\n\n", markdownText(options.baselineLabel()))
+		if len(comparison.NewInefficientFiles) == 0 {
+			doc.WriteString("No new inefficient files.\n\n")
+		} else {
+			writeMarkdownFileTable(&doc, comparison.NewInefficientFiles)
+		}
+	}
+	if len(options.IgnoredFiles) > 0 {
+		writeMarkdownIgnoredFiles(&doc, options.IgnoredFiles)
+	}
+	if len(options.Rules) > 0 {
+		doc.WriteString("### Rules\n\n")
+		doc.WriteString("| Rule | Status | Message |\n")
+		doc.WriteString("|---|---|---|\n")
+		for _, rule := range options.Rules {
+			fmt.Fprintf(&doc, "| %s | %s | %s |\n", rule.Name, rule.Status, markdownText(rule.Message))
+		}
+		doc.WriteString("\n")
+	}
+
+	return doc.String()
+}
+
+// MarkdownImage is an image within the Markdown overview of several images.
+type MarkdownImage struct {
+	Export *Export
+	// Title names the image (e.g. the image name)
+	Title string
+	// Result is the CI result of the image (e.g. "PASS")
+	Result string
+	// Pass indicates if the image passed, as counted within the overall result
+	Pass bool
+}
+
+// MarkdownOverview renders the heading of a Markdown summary of several images (see Markdown for the summary of each):
+// the overall result along with a table of the images, their results, and their size and efficiency.
+func MarkdownOverview(images []MarkdownImage) string {
+	var doc strings.Builder
+
+	failed := 0
+	for _, img := range images {
+		if !img.Pass {
+			failed++
+		}
+	}
+
+	fmt.Fprintf(&doc, "# dive: %d images\n\n", len(images))
+	if failed > 0 {
+		fmt.Fprintf(&doc, "**Result:** **FAIL** (%d of %d images failed)\n\n", failed, len(images))
+	} else {
+		doc.WriteString("**Result:** PASS\n\n")
+	}
+
+	doc.WriteString("| Image | Result | Image size | Efficiency | Wasted bytes |\n")
+	doc.WriteString("|---|---|---:|---:|---:|\n")
+	for _, img := range images {
+		fmt.Fprintf(&doc, "| %s | %s | %s | %.2f %% | %s |\n", markdownCode(img.Title), img.Result, humanize.Bytes(img.Export.Image.SizeBytes), img.Export.Image.EfficiencyScore*100, humanize.Bytes(img.Export.Image.InefficientBytes))
+	}
+	doc.WriteString("\n")
+
+	return doc.String()
+}
+
+// baselineLabel returns the name of the baseline within the summary.
+func (options MarkdownOptions) baselineLabel() string {
+	if options.BaselineLabel == "" {
+		return "baseline"
+	}
+	return options.BaselineLabel
+}
+
+func (exp *Export) writeMarkdownSummary(doc *strings.Builder, comparison *Comparison, baselineLabel string) {
+	type summaryRow struct {
+		name  string
+		value string
+		delta string
+	}
+
+	rows := []summaryRow{
+		{name: "Image size", value: humanize.Bytes(exp.Image.SizeBytes)},
+		{name: "Efficiency", value: fmt.Sprintf("%.2f %%", exp.Image.EfficiencyScore*100)},
+		{name: "Wasted bytes", value: humanize.Bytes(exp.Image.InefficientBytes)},
+		{name: "Removable bytes", value: humanize.Bytes(exp.Image.RemovableBytes)},
+		{name: "Layers", value: fmt.Sprintf("%d", len(exp.Layer))},
+	}
+	if comparison != nil {
+		rows[0].delta = comparison.SizeBytes.String()
+		rows[1].delta = comparison.EfficiencyString()
+		rows[2].delta = comparison.InefficientBytes.String()
+		rows[3].delta = comparison.RemovableBytes.String()
+		rows[4].delta = comparison.LayerCountString()
+	}
+
+	if comparison == nil {
+		doc.WriteString("| | Value |\n")
+		doc.WriteString("|---|---:|\n")
+		for _, row := range rows {
+			fmt.Fprintf(doc, "| %s | %s |\n", row.name, row.value)
+		}
+	} else {
+		fmt.Fprintf(doc, "| | Value | vs %s |\n", markdownText(baselineLabel))
+		doc.WriteString("|---|---:|---:|\n")
+		for _, row := range rows {
+			fmt.Fprintf(doc, "| %s | %s | %s |\n", row.name, row.value, row.delta)
+		}
+	}
+	doc.WriteString("\n")
+}
+
+func (exp *Export) writeMarkdownLayers(doc *strings.Builder) {
+	doc.WriteString("### Layers\n\n")
+	doc.WriteString("| Index | Size | Command |\n")
+	doc.WriteString("|---:|---:|---|\n")
+	for _, curLayer := range exp.Layer {
+		fmt.Fprintf(doc, "| %d | %s | %s |\n", curLayer.Index, humanize.Bytes(curLayer.SizeBytes), markdownCode(markdownCommand(curLayer.Command)))
+	}
+	doc.WriteString("\n")
+}
+
+func (exp *Export) writeMarkdownFiles(doc *strings.Builder) {
+	doc.WriteString("### Top inefficient files\n\n")
+	if len(exp.Image.InefficientFiles) == 0 {
+		doc.WriteString("No inefficient files.\n\n")
+		return
+	}
+
+	// note: the files are exported largest first
+	writeMarkdownFileTable(doc, exp.Image.InefficientFiles)
+}
+
+func writeMarkdownFileTable(doc *strings.Builder, files []fileReference) {
+	doc.WriteString("| Count | Wasted space | File path |\n")
+	doc.WriteString("|---:|---:|---|\n")
+	for idx, file := range files {
+		if idx == maxMarkdownFiles {
+			break
+		}
+		fmt.Fprintf(doc, "| %d | %s | %s |\n", file.References, humanize.Bytes(file.SizeBytes), markdownCode(file.Path))
+	}
+	if remaining := len(files) - maxMarkdownFiles; remaining > 0 {
+		fmt.Fprintf(doc, "\n...and %d more\n", remaining)
+	}
+	doc.WriteString("\n")
+}
+
+func writeMarkdownIgnoredFiles(doc *strings.Builder, files []MarkdownIgnoredFile) {
+	doc.WriteString("### Ignored inefficient files\n\n")
+	doc.WriteString("| Count | Wasted space | File path | Ignored by |\n")
+	doc.WriteString("|---:|---:|---|---|\n")
+	for idx, file := range files {
+		if idx == maxMarkdownFiles {
+			break
+		}
+		fmt.Fprintf(doc, "| %d | %s | %s | %s |\n", file.References, humanize.Bytes(file.SizeBytes), markdownCode(file.Path), markdownText(file.IgnoredBy))
+	}
+	if remaining := len(files) - maxMarkdownFiles; remaining > 0 {
+		fmt.Fprintf(doc, "\n...and %d more\n", remaining)
+	}
+	doc.WriteString("\n")
+}
+
+// markdownCommand returns the layer command on a single line, cut short when too long to read within a table.
+func markdownCommand(command string) string {
+	command = strings.Join(strings.Fields(command), " ")
+	if runes := []rune(command); len(runes) > maxMarkdownCommand {
+		command = string(runes[:maxMarkdownCommand-1]) + "…"
+	}
+	return command
+}
+
+// markdownCode returns the value as a code span that can be placed within a table cell.
+func markdownCode(value string) string {
+	if value == "" {
+		return ""
+	}
+	value = strings.ReplaceAll(value, "`", "'")
+	return "`" + strings.ReplaceAll(value, "|", "\\|") + "`"
+}
+
+// markdownText returns the value on a single line that can be placed within a table cell.
+func markdownText(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	return strings.ReplaceAll(value, "|", "\\|")
+}
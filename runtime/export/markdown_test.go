@@ -0,0 +1,104 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/wagoodman/dive/dive/image/docker"
+)
+
+func Test_Markdown(t *testing.T) {
+	result := docker.TestAnalysisFromArchive(t, "../../.data/test-docker-image.tar")
+	exp := NewExport(result)
+
+	// a baseline read back from an export, as given with --baseline
+	payload, err := exp.Marshal()
+	if err != nil {
+		t.Fatalf("unable to export analysis: %v", err)
+	}
+	baseline, err := Read(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("unable to read export: %v", err)
+	}
+	baseline.Image.SizeBytes += 12000000
+	baseline.Image.EfficiencyScore -= 0.015
+	baseline.Layer = baseline.Layer[:len(baseline.Layer)-1]
+	baseline.Image.InefficientFiles = baseline.Image.InefficientFiles[1:]
+
+	table := map[string]struct {
+		options  MarkdownOptions
+		expected []string
+		absent   []string
+	}{
+		"summary": {
+			options: MarkdownOptions{Title: "dive-example:latest"},
+			expected: []string{
+				"## dive: `dive-example:latest`\n",
+				"| Image size | 1.2 MB |\n| Efficiency | 98.44 % |\n| Wasted bytes | 32 kB |\n| Removable bytes | 0 B |\n| Layers | 14 |\n",
+				"| 0 | 1.2 MB | `#(nop) ADD file:ce026b62356eec3ad1214f92be2c9dc063fe205bd5e600be3492c4dfb17148bd in /` |\n",
+				"| 13 | 6.4 kB | `chmod +x /root/saved.txt` |\n",
+				"| Count | Wasted space | File path |\n|---:|---:|---|\n| 2 | 13 kB | `/root/saved.txt` |\n",
+			},
+			absent: []string{"vs ", "**Result:**", "### Rules"},
+		},
+		"baseline": {
+			options: MarkdownOptions{Title: "dive-example:latest", Baseline: baseline, BaselineLabel: "main"},
+			expected: []string{
+				"| | Value | vs main |\n",
+				"| Image size | 1.2 MB | -12 MB |\n",
+				"| Efficiency | 98.44 % | +1.50 % |\n",
+				"| Wasted bytes | 32 kB | no change |\n",
+				"| Layers | 14 | +1 |\n",
+				"### New inefficient files (vs main)\n\n| Count | Wasted space | File path |\n|---:|---:|---|\n| 2 | 13 kB | `/root/saved.txt` |\n\n",
+			},
+		},
+		"rules": {
+			options: MarkdownOptions{
+				Title:  "dive-example:latest",
+				Result: "**FAIL**",
+				Rules: []MarkdownRule{
+					{Name: "highestWastedBytes", Status: "**FAIL**", Message: "too many bytes wasted\n(wasted-bytes=32025 > threshold=1000)"},
+					{Name: "lowestEfficiency", Status: "PASS"},
+				},
+			},
+			expected: []string{
+				"**Result:** **FAIL**\n",
+				"| highestWastedBytes | **FAIL** | too many bytes wasted (wasted-bytes=32025 > threshold=1000) |\n",
+				"| lowestEfficiency | PASS |  |\n",
+			},
+		},
+	}
+
+	for name, test := range table {
+		actual := exp.Markdown(test.options)
+		for _, expected := range test.expected {
+			if !strings.Contains(actual, expected) {
+				t.Errorf("%s: expected summary containing:\n%s\ngot:\n%s", name, expected, actual)
+			}
+		}
+		for _, absent := range test.absent {
+			if strings.Contains(actual, absent) {
+				t.Errorf("%s: expected summary without %q, got:\n%s", name, absent, actual)
+			}
+		}
+	}
+}
+
+func Test_MarkdownCells(t *testing.T) {
+	table := map[string]struct {
+		actual   string
+		expected string
+	}{
+		"command":      {markdownCode(markdownCommand("/bin/sh -c  apt-get update \\\n\t&& apt-get install -y curl | tee `log`")), "`/bin/sh -c apt-get update \\ && apt-get install -y curl \\| tee 'log'`"},
+		"longCommand":  {markdownCommand(strings.Repeat("x", 120)), strings.Repeat("x", maxMarkdownCommand-1) + "…"},
+		"emptyCommand": {markdownCode(markdownCommand("")), ""},
+		"text":         {markdownText("a | b\nc"), "a \\| b c"},
+	}
+
+	for name, test := range table {
+		if test.actual != test.expected {
+			t.Errorf("%s: expected %q, got %q", name, test.expected, test.actual)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package export
+
+type pkg struct {
+	Manager    string `json:"manager"`
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	LayerIndex int    `json:"layer"`
+	SizeBytes  uint64 `json:"sizeBytes"`
+	FileCount  int    `json:"fileCount"`
+}
@@ -0,0 +1,10 @@
+package export
+
+type problem struct {
+	LayerIndex int    `json:"layer"`
+	Path       string `json:"path"`
+	Kind       string `json:"kind"`
+	Action     string `json:"action"`
+	Policy     string `json:"policy"`
+	Detail     string `json:"detail"`
+}
@@ -0,0 +1,8 @@
+package export
+
+type removablePath struct {
+	Category  string `json:"category"`
+	Path      string `json:"path"`
+	SizeBytes uint64 `json:"sizeBytes"`
+	FileCount int    `json:"fileCount"`
+}
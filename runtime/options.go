@@ -3,14 +3,53 @@ package runtime
 import (
 	"github.com/spf13/viper"
 	"github.com/wagoodman/dive/dive"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
+	"github.com/wagoodman/dive/runtime/ci"
 )
 
+// CiImage is one of the images evaluated by a single CI run (see Options.Images).
+type CiImage struct {
+	Source dive.ImageSource
+	Image  string
+	// CiConfig are the rules the image is held to, the shared rules (Options.CiConfig) when nil
+	CiConfig *viper.Viper
+	// BaselineFile is a previous export of the image the baseline rules and summaries compare against (see
+	// Options.BaselineFile), none when empty
+	BaselineFile string
+}
+
 type Options struct {
 	Ci           bool
 	Image        string
 	Source       dive.ImageSource
 	IgnoreErrors bool
 	ExportFile   string
+	// SnapshotFile is where the image is saved for later analysis (see the snapshot image source)
+	SnapshotFile string
 	CiConfig     *viper.Viper
-	BuildArgs    []string
+	// Images are evaluated in CI instead of the Image, with a combined result (layers shared by the images are parsed
+	// once)
+	Images    []CiImage
+	BuildArgs []string
+	// CiReports are the files the CI results are written to (in addition to the report shown)
+	CiReports []ci.ReportTarget
+	// MarkdownFile is where a Markdown summary of the analysis is written (skipping the UI, like an export)
+	MarkdownFile string
+	// BaselineFile is a previous export (see ExportFile) the Markdown summaries are compared against (a single image
+	// only, each of the Images names its own)
+	BaselineFile string
+	// BaselineLabel names the baseline within the Markdown summaries (e.g. "main"), of every image
+	BaselineLabel string
+	// Dockerfile is the Dockerfile the image was built from (when known), which report results point at (a single image
+	// only, it is not given along with Images)
+	Dockerfile string
+	// TreeCacheSize bounds the number of built file trees held in memory (zero uses the default)
+	TreeCacheSize int
+	// Secrets adjusts how layers are scanned for secrets (the built-in patterns are always checked)
+	Secrets image.SecretOptions
+	// RemovableRules find the likely removable paths (e.g. caches) within the final image
+	RemovableRules filetree.RemovableRules
+	// PathPolicies decide how layer entries that cannot be stacked are recovered from (e.g. skipped)
+	PathPolicies filetree.PathPolicies
 }
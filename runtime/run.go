@@ -8,6 +8,7 @@ import (
 	"github.com/wagoodman/dive/dive"
 	"github.com/wagoodman/dive/dive/filetree"
 	"github.com/wagoodman/dive/dive/image"
+	"github.com/wagoodman/dive/dive/image/snapshot"
 	"github.com/wagoodman/dive/runtime/ci"
 	"github.com/wagoodman/dive/runtime/export"
 	"github.com/wagoodman/dive/runtime/ui"
@@ -21,9 +22,17 @@ func run(enableUi bool, options Options, imageResolver image.Resolver, events ev
 	var err error
 	defer close(events)
 
-	doExport := options.ExportFile != ""
+	doExport := options.ExportFile != "" || options.MarkdownFile != ""
+	// a --json export skips the CI evaluation, while a Markdown summary holds the rule results (and fails along with CI)
+	doCi := options.Ci && (!doExport || options.MarkdownFile != "")
+	doSnapshot := options.SnapshotFile != ""
 	doBuild := len(options.BuildArgs) > 0
 
+	baseline, ok := readBaseline(options.BaselineFile, filesystem, events)
+	if !ok {
+		return
+	}
+
 	if doBuild {
 		events.message(utils.TitleFormat("Building image..."))
 		img, err = imageResolver.Build(options.BuildArgs)
@@ -41,44 +50,109 @@ func run(enableUi bool, options Options, imageResolver image.Resolver, events ev
 		}
 	}
 
-	events.message(utils.TitleFormat("Analyzing image..."))
-	analysis, err := img.Analyze()
-	if err != nil {
-		events.exitWithErrorMessage("cannot analyze image", err)
-		return
-	}
-
-	if doExport {
-		events.message(utils.TitleFormat(fmt.Sprintf("Exporting image to '%s'...", options.ExportFile)))
-		bytes, err := export.NewExport(analysis).Marshal()
+	if doSnapshot {
+		events.message(utils.TitleFormat(fmt.Sprintf("Writing snapshot to '%s'...", options.SnapshotFile)))
+		file, err := filesystem.OpenFile(options.SnapshotFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 		if err != nil {
-			events.exitWithErrorMessage("cannot marshal export payload", err)
+			events.exitWithErrorMessage("cannot open snapshot file", err)
 			return
 		}
+		defer file.Close()
 
-		file, err := filesystem.OpenFile(options.ExportFile, os.O_RDWR|os.O_CREATE, 0644)
+		err = snapshot.Write(img, file)
 		if err != nil {
-			events.exitWithErrorMessage("cannot open export file", err)
+			events.exitWithErrorMessage("cannot write snapshot", err)
 			return
 		}
-		defer file.Close()
 
-		_, err = file.Write(bytes)
-		if err != nil {
-			events.exitWithErrorMessage("cannot write to export file", err)
+		// like an export, a snapshot skips the UI (but can still be evaluated in CI)
+		if !doExport && !doCi {
+			return
 		}
+	}
+
+	analysis, ok := analyzeImage(img, options, events)
+	if !ok {
 		return
 	}
 
-	if options.Ci {
-		events.message(fmt.Sprintf("  efficiency: %2.4f %%", analysis.Efficiency*100))
-		events.message(fmt.Sprintf("  wastedBytes: %d bytes (%s)", analysis.WastedBytes, humanize.Bytes(analysis.WastedBytes)))
-		events.message(fmt.Sprintf("  userWastedPercent: %2.4f %%", analysis.WastedUserPercent*100))
+	var evaluator *ci.CiEvaluator
+	var source ci.ReportSource
+	pass := true
+	if doCi {
+		showCiMetrics(analysis, events)
 
-		evaluator := ci.NewCiEvaluator(options.CiConfig)
-		pass := evaluator.Evaluate(analysis)
+		evaluator = ci.NewCiEvaluator(options.CiConfig)
+		evaluator.Baseline = baseline
+		pass = evaluator.Evaluate(analysis)
 		events.message(evaluator.Report())
 
+		source = ci.ReportSource{
+			Image:         options.Image,
+			Analysis:      analysis,
+			Dockerfile:    options.Dockerfile,
+			Baseline:      baseline,
+			BaselineLabel: options.BaselineLabel,
+		}
+		for _, report := range options.CiReports {
+			if err := evaluator.WriteReport(report, source); err != nil {
+				events.exitWithErrorMessage(fmt.Sprintf("cannot write %s report", report.Format), err)
+				return
+			}
+		}
+	}
+
+	if doExport {
+		exp := export.NewExport(analysis)
+
+		if options.ExportFile != "" {
+			events.message(utils.TitleFormat(fmt.Sprintf("Exporting image to '%s'...", options.ExportFile)))
+			bytes, err := exp.Marshal()
+			if err != nil {
+				events.exitWithErrorMessage("cannot marshal export payload", err)
+				return
+			}
+
+			file, err := filesystem.OpenFile(options.ExportFile, os.O_RDWR|os.O_CREATE, 0644)
+			if err != nil {
+				events.exitWithErrorMessage("cannot open export file", err)
+				return
+			}
+			defer file.Close()
+
+			_, err = file.Write(bytes)
+			if err != nil {
+				events.exitWithErrorMessage("cannot write to export file", err)
+				return
+			}
+		}
+
+		if options.MarkdownFile != "" {
+			events.message(utils.TitleFormat(fmt.Sprintf("Writing Markdown summary to '%s'...", options.MarkdownFile)))
+			file, err := filesystem.OpenFile(options.MarkdownFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+			if err != nil {
+				events.exitWithErrorMessage("cannot open markdown file", err)
+				return
+			}
+			defer file.Close()
+
+			markdownOptions := export.MarkdownOptions{
+				Title:         options.Image,
+				Baseline:      baseline,
+				BaselineLabel: options.BaselineLabel,
+			}
+			if evaluator != nil {
+				markdownOptions = evaluator.MarkdownOptions(source)
+			}
+			_, err = file.WriteString(exp.Markdown(markdownOptions))
+			if err != nil {
+				events.exitWithErrorMessage("cannot write to markdown file", err)
+				return
+			}
+		}
+	}
+
+	if doCi || doExport {
 		if !pass {
 			events.exitWithError(nil)
 		}
@@ -88,6 +162,12 @@ func run(enableUi bool, options Options, imageResolver image.Resolver, events ev
 	} else {
 		events.message(utils.TitleFormat("Building cache..."))
 		treeStack := filetree.NewComparer(analysis.RefTrees)
+		defer treeStack.Close()
+		if options.TreeCacheSize > 0 {
+			treeStack.SetCacheSize(options.TreeCacheSize)
+		}
+		treeStack.SetRemovableRules(analysis.RemovableRules)
+		treeStack.SetOwners(analysis.Owners)
 		errors := treeStack.BuildCache()
 		if errors != nil {
 			for _, err := range errors {
@@ -116,20 +196,185 @@ func run(enableUi bool, options Options, imageResolver image.Resolver, events ev
 	}
 }
 
+// readBaseline reads the previous export given as a baseline (see Options.BaselineFile), nil when no path is given. Any
+// failure is sent as an event, in which case false is returned (and the run should stop).
+func readBaseline(path string, filesystem afero.Fs, events eventChannel) (*export.Export, bool) {
+	if path == "" {
+		return nil, true
+	}
+	file, err := filesystem.Open(path)
+	if err != nil {
+		events.exitWithErrorMessage("cannot open baseline file", err)
+		return nil, false
+	}
+	defer file.Close()
+
+	baseline, err := export.Read(file)
+	if err != nil {
+		events.exitWithErrorMessage(fmt.Sprintf("cannot read baseline file '%s' (expected a --json export)", path), err)
+		return nil, false
+	}
+	return baseline, true
+}
+
+// analyzeImage analyzes the fetched image, along with its secrets and removable paths. Any failure is sent as an
+// event, in which case false is returned (and the run should stop).
+func analyzeImage(img *image.Image, options Options, events eventChannel) (*image.AnalysisResult, bool) {
+	events.message(utils.TitleFormat("Analyzing image..."))
+	img.PathPolicies = options.PathPolicies
+	analysis, err := img.Analyze()
+	if err != nil {
+		events.exitWithErrorMessage("cannot analyze image", err)
+		return nil, false
+	}
+
+	// entries recovered from by skipping (or synthesizing a parent) are only noted, see the problems pane and export
+	for _, pathErr := range analysis.PathErrors {
+		logrus.Debugf("path error (%s): %s", pathErr.Policy, pathErr)
+	}
+	if failed := analysis.PathErrors.Failed(); len(failed) > 0 {
+		for _, pathErr := range failed {
+			events.message("  " + pathErr.String())
+		}
+		if !options.IgnoreErrors {
+			events.exitWithError(fmt.Errorf("file tree has path errors (use '--ignore-errors' to attempt to continue, or set a 'path-errors' policy)"))
+			return nil, false
+		}
+	}
+
+	if options.Secrets.ScanContent {
+		events.message(utils.TitleFormat("Scanning layer contents for secrets..."))
+	}
+	secrets, err := image.ScanSecrets(analysis.RefTrees, analysis.FinalTree, analysis.Content, options.Secrets)
+	if err != nil {
+		events.exitWithErrorMessage("cannot scan for secrets", err)
+		return nil, false
+	}
+	analysis.Findings = append(analysis.Findings, secrets...)
+	analysis.Findings.SetOwners(analysis.FinalOwners())
+	analysis.Findings.Sort()
+
+	analysis.RemovableRules = options.RemovableRules
+	if analysis.FinalTree != nil && len(options.RemovableRules) > 0 {
+		analysis.Removable = analysis.FinalTree.MarkRemovable(options.RemovableRules)
+	}
+	analysis.RemovableBytes = analysis.Removable.TotalSize()
+	return analysis, true
+}
+
+func showCiMetrics(analysis *image.AnalysisResult, events eventChannel) {
+	events.message(fmt.Sprintf("  efficiency: %2.4f %%", analysis.Efficiency*100))
+	events.message(fmt.Sprintf("  wastedBytes: %d bytes (%s)", analysis.WastedBytes, humanize.Bytes(analysis.WastedBytes)))
+	events.message(fmt.Sprintf("  userWastedPercent: %2.4f %%", analysis.WastedUserPercent*100))
+	events.message(fmt.Sprintf("  removableBytes: %d bytes (%s)", analysis.RemovableBytes, humanize.Bytes(analysis.RemovableBytes)))
+}
+
+// resolverGetter returns the resolver of the given image source (see dive.GetImageResolver).
+type resolverGetter func(source dive.ImageSource) (image.Resolver, error)
+
+// runImages evaluates every image of options.Images in CI, showing the results of each image followed by the combined
+// result, and writes the combined reports. Layers shared by the images are parsed once (see image.LayerCache).
+func runImages(options Options, resolve resolverGetter, events eventChannel, filesystem afero.Fs) {
+	defer close(events)
+
+	layers := image.NewLayerCache()
+	resolvers := make(map[dive.ImageSource]image.Resolver)
+	reports := make([]ci.ReportImage, 0, len(options.Images))
+	pass := true
+	for idx, ciImage := range options.Images {
+		events.message(utils.TitleFormat(fmt.Sprintf("Evaluating image %d of %d...", idx+1, len(options.Images))))
+
+		imageResolver, exists := resolvers[ciImage.Source]
+		if !exists {
+			var err error
+			imageResolver, err = resolve(ciImage.Source)
+			if err != nil {
+				events.exitWithErrorMessage("cannot determine image provider", err)
+				return
+			}
+			if sharing, isSharing := imageResolver.(image.LayerSharingResolver); isSharing {
+				sharing.ShareLayers(layers)
+			}
+			resolvers[ciImage.Source] = imageResolver
+		}
+
+		baseline, ok := readBaseline(ciImage.BaselineFile, filesystem, events)
+		if !ok {
+			return
+		}
+
+		events.message(utils.TitleFormat("Image Source: ") + ciImage.Source.String() + "://" + ciImage.Image)
+		events.message(utils.TitleFormat("Fetching image...") + " (this can take a while for large images)")
+		img, err := imageResolver.Fetch(ciImage.Image)
+		if err != nil {
+			events.exitWithErrorMessage(fmt.Sprintf("cannot fetch image '%s'", ciImage.Image), err)
+			return
+		}
+
+		analysis, ok := analyzeImage(img, options, events)
+		if !ok {
+			return
+		}
+		showCiMetrics(analysis, events)
+
+		config := ciImage.CiConfig
+		if config == nil {
+			config = options.CiConfig
+		}
+		evaluator := ci.NewCiEvaluator(config)
+		evaluator.Baseline = baseline
+		if !evaluator.Evaluate(analysis) {
+			pass = false
+		}
+		events.message(evaluator.Report())
+
+		reports = append(reports, ci.ReportImage{
+			Evaluator: evaluator,
+			// note: the Dockerfile (see Options.Dockerfile) describes a single image, so is not given with several
+			Source: ci.ReportSource{
+				Image:         ciImage.Image,
+				Analysis:      analysis,
+				Baseline:      baseline,
+				BaselineLabel: options.BaselineLabel,
+			},
+		})
+	}
+
+	if hits := layers.Hits(); hits > 0 {
+		events.message(fmt.Sprintf("  reused %d parsed layers shared between images", hits))
+	}
+	events.message(ci.CombinedReport(reports))
+
+	for _, report := range options.CiReports {
+		if err := ci.WriteReports(report, reports); err != nil {
+			events.exitWithErrorMessage(fmt.Sprintf("cannot write %s report", report.Format), err)
+			return
+		}
+	}
+
+	if !pass {
+		events.exitWithError(nil)
+	}
+}
+
 func Run(options Options) {
 	var exitCode int
 	var events = make(eventChannel)
 
-	imageResolver, err := dive.GetImageResolver(options.Source)
-	if err != nil {
-		message := "cannot determine image provider"
-		logrus.Error(message)
-		logrus.Error(err)
-		fmt.Fprintf(os.Stderr, "%s: %+v\n", message, err)
-		os.Exit(1)
-	}
+	if len(options.Images) > 0 {
+		go runImages(options, dive.GetImageResolver, events, afero.NewOsFs())
+	} else {
+		imageResolver, err := dive.GetImageResolver(options.Source)
+		if err != nil {
+			message := "cannot determine image provider"
+			logrus.Error(message)
+			logrus.Error(err)
+			fmt.Fprintf(os.Stderr, "%s: %+v\n", message, err)
+			os.Exit(1)
+		}
 
-	go run(true, options, imageResolver, events, afero.NewOsFs())
+		go run(true, options, imageResolver, events, afero.NewOsFs())
+	}
 
 	for event := range events {
 		if event.stdout != "" {
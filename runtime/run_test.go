@@ -8,7 +8,10 @@ import (
 	"github.com/wagoodman/dive/dive"
 	"github.com/wagoodman/dive/dive/image"
 	"github.com/wagoodman/dive/dive/image/docker"
+	"github.com/wagoodman/dive/runtime/ci"
+	"github.com/wagoodman/dive/runtime/export"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -85,6 +88,25 @@ func configureCi() *viper.Viper {
 	ciConfig.SetDefault("rules.lowestEfficiency", "0.9")
 	ciConfig.SetDefault("rules.highestWastedBytes", "1000")
 	ciConfig.SetDefault("rules.highestUserWastedPercent", "0.1")
+	ciConfig.SetDefault("rules.noDanglingSymlinks", "true")
+	ciConfig.SetDefault("rules.forbidSetuid", "true")
+	ciConfig.SetDefault("rules.forbidWorldWritable", "true")
+	ciConfig.SetDefault("rules.forbidPathCollisions", "true")
+	ciConfig.SetDefault("rules.noSecrets", "true")
+	ciConfig.SetDefault("rules.highestRemovableBytes", "1MB")
+	ciConfig.SetDefault("rules.highestImageSize", "10MB")
+	ciConfig.SetDefault("rules.highestLayerCount", "20")
+	ciConfig.SetDefault("rules.highestLayerSize", "5MB")
+	ciConfig.SetDefault("rules.highestSingleFileSize", "1MB")
+	ciConfig.SetDefault("rules.lowestLayerEfficiency", "0.1")
+	ciConfig.SetDefault("rules.maxSizeIncrease", "5%")
+	ciConfig.SetDefault("rules.maxWastedBytesIncrease", "1MB")
+	ciConfig.SetDefault("rules.noNewInefficientFiles", "true")
+	ciConfig.SetDefault("rules.requireNonRootUser", "true")
+	ciConfig.SetDefault("rules.requireHealthcheck", "true")
+	ciConfig.SetDefault("rules.requiredLabels", []interface{}{"maintainer"})
+	ciConfig.SetDefault("rules.forbiddenExposedPorts", []interface{}{"22", "6000-6063/tcp"})
+	ciConfig.SetDefault("rules.forbiddenEnvKeys", "*_TOKEN")
 	return ciConfig
 }
 
@@ -192,7 +214,8 @@ func TestRun(t *testing.T) {
 				{stdout: "  efficiency: 98.4421 %", stderr: "", errorOnExit: false, errMessage: ""},
 				{stdout: "  wastedBytes: 32025 bytes (32 kB)", stderr: "", errorOnExit: false, errMessage: ""},
 				{stdout: "  userWastedPercent: 48.3491 %", stderr: "", errorOnExit: false, errMessage: ""},
-				{stdout: "Inefficient Files:\nCount  Wasted Space  File Path\n    2         13 kB  /root/saved.txt\n    2         13 kB  /root/example/somefile1.txt\n    2        6.4 kB  /root/example/somefile3.txt\nResults:\n  FAIL: highestUserWastedPercent: too many bytes wasted, relative to the user bytes added (%-user-wasted-bytes=0.4834911001404049 > threshold=0.1)\n  FAIL: highestWastedBytes: too many bytes wasted (wasted-bytes=32025 > threshold=1000)\n  PASS: lowestEfficiency\nResult:FAIL [Total:3] [Passed:1] [Failed:2] [Warn:0] [Skipped:0]\n", stderr: "", errorOnExit: false, errMessage: ""},
+				{stdout: "  removableBytes: 0 bytes (0 B)", stderr: "", errorOnExit: false, errMessage: ""},
+				{stdout: "Inefficient Files:\nCount  Wasted Space  File Path\n    2         13 kB  /root/saved.txt\n    2         13 kB  /root/example/somefile1.txt\n    2        6.4 kB  /root/example/somefile3.txt\nResults:\n  PASS: forbidPathCollisions\n  PASS: forbidSetuid\n  PASS: forbidWorldWritable\n  PASS: forbiddenEnvKeys\n  PASS: forbiddenExposedPorts\n  PASS: highestImageSize\n  PASS: highestLayerCount\n  PASS: highestLayerSize\n  PASS: highestRemovableBytes\n  FAIL: highestSingleFileSize: found 1 files that are too large (threshold=1000000): /bin/[ (1.1 MB)\n  FAIL: highestUserWastedPercent: too many bytes wasted, relative to the user bytes added (%-user-wasted-bytes=0.4834911001404049 > threshold=0.1)\n  FAIL: highestWastedBytes: too many bytes wasted (wasted-bytes=32025 > threshold=1000)\n  PASS: lowestEfficiency\n  FAIL: lowestLayerEfficiency: found 5 layers with too low an efficiency (threshold=0.1): layer 3 80cd2ca1ffc8996 (efficiency=0), layer 4 c99e2f8d3f62826 (efficiency=0), layer 5 5eca617bdc3bc06 (efficiency=0), layer 6 f07c3eb88757239 (efficiency=0), layer 7 461885fc2258915 (efficiency=0)\n  SKIP: maxSizeIncrease: no baseline given (see --baseline)\n  SKIP: maxWastedBytesIncrease: no baseline given (see --baseline)\n  PASS: noDanglingSymlinks\n  SKIP: noNewInefficientFiles: no baseline given (see --baseline)\n  PASS: noSecrets\n  FAIL: requireHealthcheck: image has no health check\n  FAIL: requireNonRootUser: image runs as root (no USER given)\n  FAIL: requiredLabels: missing 1 required labels: maintainer\nResult:FAIL [Total:22] [Passed:12] [Failed:7] [Warn:0] [Skipped:3]\n", stderr: "", errorOnExit: false, errMessage: ""},
 				{stdout: "", stderr: "", errorOnExit: true, errMessage: ""},
 			},
 		},
@@ -212,7 +235,8 @@ func TestRun(t *testing.T) {
 				{stdout: "  efficiency: 98.4421 %", stderr: "", errorOnExit: false, errMessage: ""},
 				{stdout: "  wastedBytes: 32025 bytes (32 kB)", stderr: "", errorOnExit: false, errMessage: ""},
 				{stdout: "  userWastedPercent: 48.3491 %", stderr: "", errorOnExit: false, errMessage: ""},
-				{stdout: "Inefficient Files:\nCount  Wasted Space  File Path\nNone\nResults:\n  MISCONFIGURED: highestUserWastedPercent: invalid config value (''): strconv.ParseFloat: parsing \"\": invalid syntax\n  MISCONFIGURED: highestWastedBytes: invalid config value (''): strconv.ParseFloat: parsing \"\": invalid syntax\n  MISCONFIGURED: lowestEfficiency: invalid config value (''): strconv.ParseFloat: parsing \"\": invalid syntax\nCI Misconfigured\n", stderr: "", errorOnExit: false, errMessage: ""},
+				{stdout: "  removableBytes: 0 bytes (0 B)", stderr: "", errorOnExit: false, errMessage: ""},
+				{stdout: "Inefficient Files:\nCount  Wasted Space  File Path\nNone\nResults:\n  MISCONFIGURED: forbidPathCollisions: invalid config value (''): strconv.ParseBool: parsing \"\": invalid syntax\n  MISCONFIGURED: forbidSetuid: invalid config value (''): strconv.ParseBool: parsing \"\": invalid syntax\n  MISCONFIGURED: forbidWorldWritable: invalid config value (''): strconv.ParseBool: parsing \"\": invalid syntax\n  MISCONFIGURED: forbiddenEnvKeys: invalid config value (''): expected a list of values\n  MISCONFIGURED: forbiddenExposedPorts: invalid config value (''): expected a list of values\n  MISCONFIGURED: highestImageSize: invalid config value (''): strconv.ParseFloat: parsing \"\": invalid syntax\n  MISCONFIGURED: highestLayerCount: invalid config value (''): strconv.ParseUint: parsing \"\": invalid syntax\n  MISCONFIGURED: highestLayerSize: invalid config value (''): strconv.ParseFloat: parsing \"\": invalid syntax\n  MISCONFIGURED: highestRemovableBytes: invalid config value (''): strconv.ParseFloat: parsing \"\": invalid syntax\n  MISCONFIGURED: highestSingleFileSize: invalid config value (''): strconv.ParseFloat: parsing \"\": invalid syntax\n  MISCONFIGURED: highestUserWastedPercent: invalid config value (''): strconv.ParseFloat: parsing \"\": invalid syntax\n  MISCONFIGURED: highestWastedBytes: invalid config value (''): strconv.ParseFloat: parsing \"\": invalid syntax\n  MISCONFIGURED: lowestEfficiency: invalid config value (''): strconv.ParseFloat: parsing \"\": invalid syntax\n  MISCONFIGURED: lowestLayerEfficiency: invalid config value (''): strconv.ParseFloat: parsing \"\": invalid syntax\n  MISCONFIGURED: maxSizeIncrease: invalid config value (''): strconv.ParseFloat: parsing \"\": invalid syntax\n  MISCONFIGURED: maxWastedBytesIncrease: invalid config value (''): strconv.ParseFloat: parsing \"\": invalid syntax\n  MISCONFIGURED: noDanglingSymlinks: invalid config value (''): strconv.ParseBool: parsing \"\": invalid syntax\n  MISCONFIGURED: noNewInefficientFiles: invalid config value (''): strconv.ParseBool: parsing \"\": invalid syntax\n  MISCONFIGURED: noSecrets: invalid config value (''): strconv.ParseBool: parsing \"\": invalid syntax\n  MISCONFIGURED: requireHealthcheck: invalid config value (''): strconv.ParseBool: parsing \"\": invalid syntax\n  MISCONFIGURED: requireNonRootUser: invalid config value (''): strconv.ParseBool: parsing \"\": invalid syntax\n  MISCONFIGURED: requiredLabels: invalid config value (''): expected a list of values\nCI Misconfigured\n", stderr: "", errorOnExit: false, errMessage: ""},
 				{stdout: "", stderr: "", errorOnExit: true, errMessage: ""},
 			},
 		},
@@ -232,6 +256,51 @@ func TestRun(t *testing.T) {
 				{stdout: "Exporting image to 'some-file.json'...", stderr: "", errorOnExit: false, errMessage: ""},
 			},
 		},
+		"markdown-go-case": {
+			resolver: &defaultResolver{},
+			options: Options{
+				Ci:           false,
+				Image:        "dive-example",
+				Source:       dive.SourceDockerEngine,
+				MarkdownFile: "some-file.md",
+				CiConfig:     configureCi(),
+			},
+			events: []testEvent{
+				{stdout: "Image Source: docker://dive-example", stderr: "", errorOnExit: false, errMessage: ""},
+				{stdout: "Fetching image... (this can take a while for large images)", stderr: "", errorOnExit: false, errMessage: ""},
+				{stdout: "Analyzing image...", stderr: "", errorOnExit: false, errMessage: ""},
+				{stdout: "Writing Markdown summary to 'some-file.md'...", stderr: "", errorOnExit: false, errMessage: ""},
+			},
+		},
+		"missing-baseline": {
+			resolver: &defaultResolver{},
+			options: Options{
+				Ci:           false,
+				Image:        "dive-example",
+				Source:       dive.SourceDockerEngine,
+				MarkdownFile: "some-file.md",
+				BaselineFile: "missing.json",
+				CiConfig:     configureCi(),
+			},
+			events: []testEvent{
+				{stdout: "", stderr: "cannot open baseline file", errorOnExit: true, errMessage: "open missing.json: file does not exist"},
+			},
+		},
+		"snapshot-go-case": {
+			resolver: &defaultResolver{},
+			options: Options{
+				Ci:           false,
+				Image:        "dive-example",
+				Source:       dive.SourceDockerEngine,
+				SnapshotFile: "some-file.dive",
+				CiConfig:     configureCi(),
+			},
+			events: []testEvent{
+				{stdout: "Image Source: docker://dive-example", stderr: "", errorOnExit: false, errMessage: ""},
+				{stdout: "Fetching image... (this can take a while for large images)", stderr: "", errorOnExit: false, errMessage: ""},
+				{stdout: "Writing snapshot to 'some-file.dive'...", stderr: "", errorOnExit: false, errMessage: ""},
+			},
+		},
 	}
 
 	for name, test := range table {
@@ -283,6 +352,192 @@ func TestRun(t *testing.T) {
 					t.Errorf("%s.%s: expected export file but did not find one", t.Name(), name)
 				}
 			}
+
+			if test.options.MarkdownFile != "" && !expectedEvent.errorOnExit {
+				if _, err := filesystem.Stat(test.options.MarkdownFile); os.IsNotExist(err) {
+					t.Errorf("%s.%s: expected markdown file but did not find one", t.Name(), name)
+				}
+			}
+
+			if test.options.SnapshotFile != "" {
+				if _, err := filesystem.Stat(test.options.SnapshotFile); os.IsNotExist(err) {
+					t.Errorf("%s.%s: expected snapshot file but did not find one", t.Name(), name)
+				}
+			}
+		}
+	}
+}
+
+func TestRunCiMarkdown(t *testing.T) {
+	var ec = make(eventChannel)
+	var events = make([]testEvent, 0)
+	var filesystem = afero.NewMemMapFs()
+
+	go run(false, Options{
+		Ci:           true,
+		Image:        "dive-example",
+		Source:       dive.SourceDockerEngine,
+		MarkdownFile: "some-file.md",
+		CiConfig:     configureCi(),
+	}, &defaultResolver{}, ec, filesystem)
+
+	for event := range ec {
+		events = append(events, newTestEvent(event))
+	}
+
+	// the rules are evaluated before the summary is written, the run fails along with CI
+	expected := []testEvent{
+		{stdout: "Writing Markdown summary to 'some-file.md'...", stderr: "", errorOnExit: false, errMessage: ""},
+		{stdout: "", stderr: "", errorOnExit: true, errMessage: ""},
+	}
+	if len(events) < len(expected) {
+		t.Fatalf("expected at least %d events, got %d", len(expected), len(events))
+	}
+	actualEvents := events[len(events)-len(expected):]
+	for idx, expectedEvent := range expected {
+		actualEvent := actualEvents[idx]
+		actualEvent.stdout = vtclean.Clean(actualEvent.stdout, false)
+		if expectedEvent != actualEvent {
+			t.Errorf("expected event %+v, got %+v", expectedEvent, actualEvent)
 		}
 	}
+
+	contents, err := afero.ReadFile(filesystem, "some-file.md")
+	if err != nil {
+		t.Fatalf("unable to read markdown file: %v", err)
+	}
+	for _, expectedContent := range []string{"**Result:** **FAIL**", "### Rules", "| highestWastedBytes | **FAIL** |"} {
+		if !strings.Contains(string(contents), expectedContent) {
+			t.Errorf("expected the markdown summary to contain %q:\n%s", expectedContent, contents)
+		}
+	}
+}
+
+func TestRunImages(t *testing.T) {
+	passingConfig := viper.New()
+	for _, rule := range ci.RegisteredRules() {
+		passingConfig.SetDefault("rules."+rule.Key, "disabled")
+	}
+	passingConfig.SetDefault("rules.lowestEfficiency", "0.9")
+
+	images := []CiImage{
+		{Source: dive.SourceDockerEngine, Image: "dive-example", CiConfig: passingConfig},
+		{Source: dive.SourceDockerArchive, Image: "dive-example.tar"},
+	}
+
+	table := map[string]struct {
+		resolver image.Resolver
+		// events are the last events of the run
+		events []testEvent
+		// reports are the number of image reports shown
+		reports int
+	}{
+		"ci-images-case": {
+			resolver: &defaultResolver{},
+			events: []testEvent{
+				{stdout: "Images:\n  PASS: dive-example\n  FAIL: dive-example.tar\nResult:FAIL [Images:2] [Passed:1] [Failed:1]\n", stderr: "", errorOnExit: false, errMessage: ""},
+				{stdout: "", stderr: "", errorOnExit: true, errMessage: ""},
+			},
+			reports: 2,
+		},
+		"failed-fetch": {
+			resolver: &failedFetchResolver{},
+			events: []testEvent{
+				{stdout: "Fetching image... (this can take a while for large images)", stderr: "", errorOnExit: false, errMessage: ""},
+				{stdout: "", stderr: "cannot fetch image 'dive-example'", errorOnExit: true, errMessage: "some fetch failure"},
+			},
+			reports: 0,
+		},
+	}
+
+	for name, test := range table {
+		var ec = make(eventChannel)
+		var events = make([]testEvent, 0)
+
+		resolver := test.resolver
+		go runImages(Options{Ci: true, CiConfig: configureCi(), Images: images}, func(dive.ImageSource) (image.Resolver, error) {
+			return resolver, nil
+		}, ec, afero.NewMemMapFs())
+
+		reports := 0
+		for event := range ec {
+			events = append(events, newTestEvent(event))
+			if strings.HasPrefix(vtclean.Clean(event.stdout, false), "Inefficient Files:") {
+				reports++
+			}
+		}
+
+		if reports != test.reports {
+			t.Errorf("%s.%s: expected %d image reports, got %d", t.Name(), name, test.reports, reports)
+		}
+		if len(events) < len(test.events) {
+			t.Fatalf("%s.%s: expected at least %d events, got %d", t.Name(), name, len(test.events), len(events))
+		}
+		if vtclean.Clean(events[0].stdout, false) != "Evaluating image 1 of 2..." {
+			t.Errorf("%s.%s: unexpected first event: %+v", t.Name(), name, events[0])
+		}
+
+		actualEvents := events[len(events)-len(test.events):]
+		for idx, expectedEvent := range test.events {
+			actualEvent := actualEvents[idx]
+			actualEvent.stdout = vtclean.Clean(actualEvent.stdout, false)
+			actualEvent.stderr = vtclean.Clean(actualEvent.stderr, false)
+			if expectedEvent != actualEvent {
+				t.Errorf("%s.%s: expected event %+v, got %+v", t.Name(), name, expectedEvent, actualEvent)
+			}
+		}
+	}
+}
+
+func TestRunImagesBaseline(t *testing.T) {
+	img, err := (&defaultResolver{}).Fetch("dive-example")
+	if err != nil {
+		t.Fatalf("unable to fetch image: %v", err)
+	}
+	analysis, err := img.Analyze()
+	if err != nil {
+		t.Fatalf("unable to analyze image: %v", err)
+	}
+	baseline, err := export.NewExport(analysis).Marshal()
+	if err != nil {
+		t.Fatalf("unable to marshal baseline: %v", err)
+	}
+	filesystem := afero.NewMemMapFs()
+	if err := afero.WriteFile(filesystem, "main.json", baseline, 0644); err != nil {
+		t.Fatalf("unable to write baseline: %v", err)
+	}
+
+	config := viper.New()
+	for _, rule := range ci.RegisteredRules() {
+		config.SetDefault("rules."+rule.Key, "disabled")
+	}
+	config.SetDefault("rules.maxSizeIncrease", "0%")
+
+	// each image is compared with its own baseline, the run stops at the first baseline that cannot be read
+	images := []CiImage{
+		{Source: dive.SourceDockerEngine, Image: "dive-example", CiConfig: config, BaselineFile: "main.json"},
+		{Source: dive.SourceDockerEngine, Image: "dive-example:pr", CiConfig: config, BaselineFile: "missing.json"},
+	}
+
+	var ec = make(eventChannel)
+	var events = make([]testEvent, 0)
+	go runImages(Options{Ci: true, CiConfig: configureCi(), Images: images}, func(dive.ImageSource) (image.Resolver, error) {
+		return &defaultResolver{}, nil
+	}, ec, filesystem)
+
+	compared := false
+	for event := range ec {
+		events = append(events, newTestEvent(event))
+		if strings.Contains(vtclean.Clean(event.stdout, false), "PASS: maxSizeIncrease") {
+			compared = true
+		}
+	}
+
+	if !compared {
+		t.Errorf("expected the first image to be compared with its baseline")
+	}
+	expectedEvent := testEvent{stdout: "", stderr: "cannot open baseline file", errorOnExit: true, errMessage: "open missing.json: file does not exist"}
+	if actualEvent := events[len(events)-1]; actualEvent != expectedEvent {
+		t.Errorf("expected event %+v, got %+v", expectedEvent, actualEvent)
+	}
 }
@@ -26,7 +26,7 @@ var (
 	appSingleton *app
 )
 
-func newApp(gui *gocui.Gui, analysis *image.AnalysisResult, cache filetree.Comparer) (*app, error) {
+func newApp(gui *gocui.Gui, analysis *image.AnalysisResult, cache *filetree.Comparer) (*app, error) {
 	var err error
 	once.Do(func() {
 		var controller *Controller
@@ -41,6 +41,10 @@ func newApp(gui *gocui.Gui, analysis *image.AnalysisResult, cache filetree.Compa
 		lm := layout.NewManager()
 		lm.Add(controller.views.Status, layout.LocationFooter)
 		lm.Add(controller.views.Filter, layout.LocationFooter)
+		lm.Add(controller.views.History, layout.LocationFooter)
+		lm.Add(controller.views.Findings, layout.LocationFooter)
+		lm.Add(controller.views.Packages, layout.LocationFooter)
+		lm.Add(controller.views.Problems, layout.LocationFooter)
 		lm.Add(compound.NewLayerDetailsCompoundLayout(controller.views.Layer, controller.views.Details), layout.LocationColumn)
 		lm.Add(controller.views.Tree, layout.LocationColumn)
 
@@ -81,6 +85,24 @@ func newApp(gui *gocui.Gui, analysis *image.AnalysisResult, cache filetree.Compa
 				IsSelected: controller.views.Filter.IsVisible,
 				Display:    "Filter",
 			},
+			{
+				ConfigKeys: []string{"keybinding.toggle-findings"},
+				OnAction:   controller.ToggleFindingsView,
+				IsSelected: controller.views.Findings.IsVisible,
+				Display:    "Findings",
+			},
+			{
+				ConfigKeys: []string{"keybinding.toggle-packages"},
+				OnAction:   controller.TogglePackagesView,
+				IsSelected: controller.views.Packages.IsVisible,
+				Display:    "Packages",
+			},
+			{
+				ConfigKeys: []string{"keybinding.toggle-problems"},
+				OnAction:   controller.ToggleProblemsView,
+				IsSelected: controller.views.Problems.IsVisible,
+				Display:    "Problems",
+			},
 		}
 
 		globalHelpKeys, err = key.GenerateBindings(gui, "", infos)
@@ -90,6 +112,33 @@ func newApp(gui *gocui.Gui, analysis *image.AnalysisResult, cache filetree.Compa
 
 		controller.views.Status.AddHelpKeys(globalHelpKeys...)
 
+		// these actions are for the selected file tree node, so the bindings are scoped to the file tree view
+		var treeInfos = []key.BindingInfo{
+			{
+				ConfigKeys: []string{"keybinding.toggle-history"},
+				OnAction:   controller.ToggleHistoryView,
+				IsSelected: controller.views.History.IsVisible,
+				Display:    "Timeline",
+			},
+			{
+				ConfigKeys: []string{"keybinding.goto-link-target"},
+				OnAction:   controller.GotoLinkTarget,
+				Display:    "Go to link",
+			},
+			{
+				ConfigKeys: []string{"keybinding.extract-file"},
+				OnAction:   controller.ExtractSelectedNode,
+				Display:    "Extract",
+			},
+		}
+
+		treeHelpKeys, err := key.GenerateBindings(gui, controller.views.Tree.Name(), treeInfos)
+		if err != nil {
+			return
+		}
+
+		controller.views.Tree.AddHelpKeys(treeHelpKeys...)
+
 		// perform the first update and render now that all resources have been loaded
 		err = controller.UpdateAndRender()
 		if err != nil {
@@ -127,7 +176,7 @@ func (a *app) quit() error {
 }
 
 // Run is the UI entrypoint.
-func Run(analysis *image.AnalysisResult, treeStack filetree.Comparer) error {
+func Run(analysis *image.AnalysisResult, treeStack *filetree.Comparer) error {
 	var err error
 
 	g, err := gocui.NewGui(gocui.OutputNormal)
@@ -1,8 +1,10 @@
 package ui
 
 import (
+	"fmt"
 	"github.com/jroimartin/gocui"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"github.com/wagoodman/dive/dive/filetree"
 	"github.com/wagoodman/dive/dive/image"
 	"github.com/wagoodman/dive/runtime/ui/view"
@@ -11,19 +13,25 @@ import (
 )
 
 type Controller struct {
-	gui   *gocui.Gui
-	views *view.Views
+	gui      *gocui.Gui
+	views    *view.Views
+	refTrees []*filetree.FileTree
+	content  image.ContentReader
+	owners   []*filetree.Owners
 }
 
-func NewCollection(g *gocui.Gui, analysis *image.AnalysisResult, cache filetree.Comparer) (*Controller, error) {
+func NewCollection(g *gocui.Gui, analysis *image.AnalysisResult, cache *filetree.Comparer) (*Controller, error) {
 	views, err := view.NewViews(g, analysis, cache)
 	if err != nil {
 		return nil, err
 	}
 
 	controller := &Controller{
-		gui:   g,
-		views: views,
+		gui:      g,
+		views:    views,
+		refTrees: analysis.RefTrees,
+		content:  analysis.Content,
+		owners:   analysis.Owners,
 	}
 
 	// layer view cursor down event should trigger an update in the file tree
@@ -81,6 +89,8 @@ func (c *Controller) onFilterEdit(filter string) error {
 }
 
 func (c *Controller) onLayerChange(selection viewmodel.LayerSelection) error {
+	c.views.Status.SetNotice("")
+
 	// update the details
 	c.views.Details.SetCurrentLayer(selection.Layer)
 
@@ -97,7 +107,19 @@ func (c *Controller) onLayerChange(selection viewmodel.LayerSelection) error {
 	}
 
 	// update details and filetree panes
-	return c.UpdateAndRender()
+	problems := c.views.Problems.Count()
+	err = c.Update()
+	if err != nil {
+		logrus.Debug("failed update: ", err)
+		return err
+	}
+
+	// building the selected tree may have run into path errors not found while stacking the layers up front
+	if found := c.views.Problems.Count() - problems; found > 0 {
+		c.views.Status.SetNotice(fmt.Sprintf("Found %d more path problems (see the problems pane)", found))
+	}
+
+	return c.Render()
 }
 
 func (c *Controller) UpdateAndRender() error {
@@ -143,6 +165,8 @@ func (c *Controller) Render() error {
 
 // ToggleView switches between the file view and the layer view and re-renders the screen.
 func (c *Controller) ToggleView() (err error) {
+	c.views.Status.SetNotice("")
+
 	v := c.gui.CurrentView()
 	if v == nil || v.Name() == c.views.Layer.Name() {
 		_, err = c.gui.SetCurrentView(c.views.Tree.Name())
@@ -183,3 +207,123 @@ func (c *Controller) ToggleFilterView() error {
 
 	return c.UpdateAndRender()
 }
+
+// ToggleHistoryView shows/hides the timeline of every layer's change to the selected file tree node.
+func (c *Controller) ToggleHistoryView() error {
+	if !c.views.History.IsVisible() {
+		node := c.views.Tree.SelectedNode()
+		if node == nil {
+			return nil
+		}
+
+		history, err := filetree.HistoryOf(c.refTrees, node.Path())
+		if err != nil {
+			logrus.Error("unable to get path history: ", err)
+			return err
+		}
+		history.SetOwners(c.owners)
+		c.views.History.SetHistory(history)
+	}
+
+	c.views.History.ToggleVisible()
+
+	return c.UpdateAndRender()
+}
+
+// GotoLinkTarget moves the file tree cursor to the target of the selected symlink (or hard link), noting on the status
+// bar when the target cannot be shown.
+func (c *Controller) GotoLinkTarget() error {
+	node := c.views.Tree.SelectedNode()
+	if node == nil {
+		return nil
+	}
+
+	resolution, selected := c.views.Tree.GotoLinkTarget(node)
+	switch {
+	case selected:
+		c.views.Status.SetNotice(fmt.Sprintf("%s → %s", resolution.Path, resolution.Target))
+	case resolution.Status == filetree.LinkDangling:
+		c.views.Status.SetNotice(fmt.Sprintf("Link target %s does not exist", resolution.Target))
+	case resolution.Status == filetree.LinkCyclic:
+		c.views.Status.SetNotice(fmt.Sprintf("Link %s is cyclic (revisits %s)", resolution.Path, resolution.Target))
+	case resolution.Status == filetree.LinkResolved:
+		c.views.Status.SetNotice(fmt.Sprintf("Link target %s is hidden", resolution.Target))
+	default:
+		// not a link
+		return nil
+	}
+
+	return c.Render()
+}
+
+// ToggleFindingsView shows/hides the permission and ownership findings for the final image filesystem.
+func (c *Controller) ToggleFindingsView() error {
+	c.views.Findings.ToggleVisible()
+
+	return c.UpdateAndRender()
+}
+
+// ToggleProblemsView shows/hides the layer entries that could not be stacked as given.
+func (c *Controller) ToggleProblemsView() error {
+	c.views.Problems.ToggleVisible()
+
+	return c.UpdateAndRender()
+}
+
+// TogglePackagesView shows/hides the OS packages installed within the image. The pane takes focus while shown, focus
+// returns to the layer pane when hidden.
+func (c *Controller) TogglePackagesView() error {
+	c.views.Packages.ToggleVisible()
+
+	if c.views.Packages.IsVisible() {
+		c.views.Status.SetCurrentView(c.views.Packages)
+	} else if v := c.gui.CurrentView(); v == nil || v.Name() == c.views.Packages.Name() {
+		_, err := c.gui.SetCurrentView(c.views.Layer.Name())
+		if err != nil {
+			logrus.Error("unable to toggle packages view: ", err)
+			return err
+		}
+		c.views.Status.SetCurrentView(c.views.Layer)
+	}
+
+	return c.UpdateAndRender()
+}
+
+// ExtractSelectedNode writes the selected file tree node (file or directory) to disk in the background. Either compare
+// mode shows the image as it exists at the selected layer, so every entry is taken from the topmost layer at or below
+// the selected layer that provides it (not only from the selected layer, which may not provide the node at all).
+func (c *Controller) ExtractSelectedNode() error {
+	node := c.views.Tree.SelectedNode()
+	if node == nil {
+		return nil
+	}
+
+	target := node.Path()
+	layerIdx := c.views.Layer.CurrentLayer().Index
+	destination := viper.GetString("filetree.extract-dir")
+
+	go func() {
+		var notice string
+		result, err := image.Extract(c.content, c.refTrees, layerIdx, true, target, destination)
+		switch {
+		case err != nil:
+			logrus.Errorf("unable to extract %s: %+v", target, err)
+			notice = fmt.Sprintf("Unable to extract %s: %v", target, err)
+		case len(result.Skipped) > 0:
+			for _, skipped := range result.Skipped {
+				logrus.Warnf("extract %s skipped: %s", target, skipped)
+			}
+			notice = fmt.Sprintf("Extracted %s to %s (skipped %d entries)", target, result.Destination, len(result.Skipped))
+		default:
+			notice = fmt.Sprintf("Extracted %s to %s", target, result.Destination)
+		}
+
+		c.gui.Update(func(g *gocui.Gui) error {
+			c.views.Status.SetNotice(notice)
+			return c.views.Status.Render()
+		})
+	}()
+
+	c.views.Status.SetNotice(fmt.Sprintf("Extracting %s...", target))
+	return c.views.Status.Render()
+}
@@ -157,7 +157,8 @@ func (lm *Manager) layoutFooters(g *gocui.Gui, area Area, footerHeights []int) e
 			for oIdx := 0; oIdx <= idx; oIdx++ {
 				bottomPadding += footerHeights[oIdx]
 			}
-			topY = area.maxY - bottomPadding - height
+			// note: the bottom padding already includes this footer's height
+			topY = area.maxY - bottomPadding - 1
 			// +1 for border
 			bottomY = topY + height + 1
 
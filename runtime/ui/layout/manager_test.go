@@ -324,6 +324,38 @@ func Test_layout(t *testing.T) {
 					}, LocationColumn),
 			},
 		},
+		"1 header + 2 footers (1 multi-row) + 1 column": {
+			elements: []*testElement{
+				newTestElement(t, 1,
+					Area{
+						minX: -1,
+						minY: -1,
+						maxX: 120,
+						maxY: 0,
+					}, LocationHeader),
+				newTestElement(t, 1,
+					Area{
+						minX: -1,
+						minY: 78,
+						maxX: 120,
+						maxY: 80,
+					}, LocationFooter),
+				newTestElement(t, 3,
+					Area{
+						minX: -1,
+						minY: 75,
+						maxX: 120,
+						maxY: 79,
+					}, LocationFooter),
+				newTestElement(t, -1,
+					Area{
+						minX: -1,
+						minY: 0,
+						maxX: 120,
+						maxY: 76,
+					}, LocationColumn),
+			},
+		},
 		"1 header + 1 footer + 2 equal columns + 1 sized column": {
 			elements: []*testElement{
 				newTestElement(t, 1,
@@ -24,12 +24,13 @@ type Details struct {
 	efficiency     float64
 	inefficiencies filetree.EfficiencySlice
 	imageSize      uint64
+	removableBytes uint64
 
 	currentLayer *image.Layer
 }
 
 // newDetailsView creates a new view object attached the the global [gocui] screen object.
-func newDetailsView(gui *gocui.Gui, efficiency float64, inefficiencies filetree.EfficiencySlice, imageSize uint64) (controller *Details) {
+func newDetailsView(gui *gocui.Gui, efficiency float64, inefficiencies filetree.EfficiencySlice, imageSize, removableBytes uint64) (controller *Details) {
 	controller = new(Details)
 
 	// populate main fields
@@ -38,6 +39,7 @@ func newDetailsView(gui *gocui.Gui, efficiency float64, inefficiencies filetree.
 	controller.efficiency = efficiency
 	controller.inefficiencies = inefficiencies
 	controller.imageSize = imageSize
+	controller.removableBytes = removableBytes
 
 	return controller
 }
@@ -120,7 +122,8 @@ func (v *Details) SetCurrentLayer(layer *image.Layer) {
 // 1. the current selected layer's command string
 // 2. the image efficiency score
 // 3. the estimated wasted image space
-// 4. a list of inefficient file allocations
+// 4. the likely removable image space (caches and build leftovers)
+// 5. a list of inefficient file allocations
 func (v *Details) Render() error {
 	logrus.Tracef("view.Render() %s", v.Name())
 
@@ -151,6 +154,7 @@ func (v *Details) Render() error {
 	imageSizeStr := fmt.Sprintf("%s %s", format.Header("Total Image size:"), humanize.Bytes(v.imageSize))
 	effStr := fmt.Sprintf("%s %d %%", format.Header("Image efficiency score:"), int(100.0*v.efficiency))
 	wastedSpaceStr := fmt.Sprintf("%s %s", format.Header("Potential wasted space:"), humanize.Bytes(uint64(wastedSpace)))
+	removableSpaceStr := fmt.Sprintf("%s %s", format.Header("Likely removable space:"), humanize.Bytes(v.removableBytes))
 
 	v.gui.Update(func(g *gocui.Gui) error {
 		// update header
@@ -181,6 +185,7 @@ func (v *Details) Render() error {
 		lines = append(lines, "\n"+imageHeaderStr)
 		lines = append(lines, imageSizeStr)
 		lines = append(lines, wastedSpaceStr)
+		lines = append(lines, removableSpaceStr)
 		lines = append(lines, effStr+"\n")
 		lines = append(lines, inefficiencyReport)
 
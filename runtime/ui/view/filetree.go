@@ -1,6 +1,7 @@
 package view
 
 import (
+	"archive/tar"
 	"fmt"
 	"github.com/jroimartin/gocui"
 	"github.com/sirupsen/logrus"
@@ -10,9 +11,14 @@ import (
 	"github.com/wagoodman/dive/runtime/ui/key"
 	"github.com/wagoodman/dive/runtime/ui/viewmodel"
 	"github.com/wagoodman/dive/utils"
+	"path"
 	"regexp"
+	"time"
 )
 
+// loadingFrames are rendered in sequence while a requested layer tree is still being built.
+var loadingFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
 type ViewOptionChangeListener func() error
 
 // FileTree holds the UI objects and data models for populating the right pane. Specifically the pane that
@@ -29,10 +35,15 @@ type FileTree struct {
 	listeners           []ViewOptionChangeListener
 	helpKeys            []*key.Binding
 	requestedWidthRatio float64
+
+	// loading state for trees that are not yet built (only accessed from the UI thread)
+	loading      bool
+	loadRequest  int
+	loadingFrame int
 }
 
 // newFileTreeView creates a new view object attached the the global [gocui] screen object.
-func newFileTreeView(gui *gocui.Gui, tree *filetree.FileTree, refTrees []*filetree.FileTree, cache filetree.Comparer) (controller *FileTree, err error) {
+func newFileTreeView(gui *gocui.Gui, tree *filetree.FileTree, refTrees []*filetree.FileTree, cache *filetree.Comparer) (controller *FileTree, err error) {
 	controller = new(FileTree)
 	controller.listeners = make([]ViewOptionChangeListener, 0)
 
@@ -66,6 +77,11 @@ func (v *FileTree) SetFilterRegex(filterRegex *regexp.Regexp) {
 	v.filterRegex = filterRegex
 }
 
+// AddHelpKeys adds bindings registered outside of this view (but scoped to it) to the key help.
+func (v *FileTree) AddHelpKeys(keys ...*key.Binding) {
+	v.helpKeys = append(v.helpKeys, keys...)
+}
+
 func (v *FileTree) Name() string {
 	return v.name
 }
@@ -120,6 +136,12 @@ func (v *FileTree) Setup(view *gocui.View, header *gocui.View) error {
 			IsSelected: func() bool { return !v.vm.HiddenDiffTypes[filetree.Unmodified] },
 			Display:    "Unmodified",
 		},
+		{
+			ConfigKeys: []string{"keybinding.toggle-collisions"},
+			OnAction:   v.toggleOnlyCollisions,
+			IsSelected: func() bool { return v.vm.OnlyCollisions },
+			Display:    "Collisions",
+		},
 		{
 			ConfigKeys: []string{"keybinding.toggle-filetree-attributes"},
 			OnAction:   v.toggleAttributes,
@@ -181,8 +203,18 @@ func (v *FileTree) resetCursor() {
 	v.vm.ResetCursor()
 }
 
-// SetTreeByLayer populates the view model by stacking the indicated image layer file trees.
+// SetTreeByLayer populates the view model by stacking the indicated image layer file trees. If the tree has not been
+// built yet, a spinner is shown while the tree is built in the background.
 func (v *FileTree) SetTree(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop int) error {
+	// any outstanding request is now stale
+	v.loadRequest++
+	v.loading = false
+
+	if v.view != nil && !v.vm.IsTreeReady(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop) {
+		v.loadTree(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop)
+		return v.Render()
+	}
+
 	err := v.vm.SetTreeByLayer(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop)
 	if err != nil {
 		return err
@@ -192,6 +224,84 @@ func (v *FileTree) SetTree(bottomTreeStart, bottomTreeStop, topTreeStart, topTre
 	return v.Render()
 }
 
+// loadTree builds the indicated tree in the background, animating the loading spinner until the tree is ready. Only
+// the most recent request is applied to the view model.
+func (v *FileTree) loadTree(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop int) {
+	request := v.loadRequest
+	v.loading = true
+	v.loadingFrame = 0
+
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				v.gui.Update(func(g *gocui.Gui) error {
+					if request != v.loadRequest || !v.loading {
+						return nil
+					}
+					v.loadingFrame = (v.loadingFrame + 1) % len(loadingFrames)
+					return v.Render()
+				})
+			}
+		}
+	}()
+
+	go func() {
+		err := v.vm.PrepareTree(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop)
+		close(done)
+
+		v.gui.Update(func(g *gocui.Gui) error {
+			if request != v.loadRequest {
+				// the user has since selected another layer
+				return nil
+			}
+			v.loading = false
+			if err != nil {
+				logrus.Errorf("unable to build layer tree: %+v", err)
+				return err
+			}
+
+			err = v.vm.SetTreeByLayer(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop)
+			if err != nil {
+				return err
+			}
+			_ = v.Update()
+			return v.Render()
+		})
+	}()
+}
+
+// GotoLinkTarget moves the cursor to the target of the given symlink (following any chain of links) or hard link. The
+// resolution is returned along with an indication if the target could be selected.
+func (v *FileTree) GotoLinkTarget(node *filetree.FileNode) (filetree.LinkResolution, bool) {
+	var resolution filetree.LinkResolution
+	switch node.Data.FileInfo.TypeFlag {
+	case tar.TypeSymlink:
+		_, resolution = v.vm.ModelTree.ResolveLink(node)
+	case tar.TypeLink:
+		// hard link targets are always relative to the root, and are never followed any further
+		target := path.Clean("/" + node.Data.FileInfo.Linkname)
+		resolution = filetree.LinkResolution{Path: node.Path(), Target: target, Status: filetree.LinkResolved}
+		if targetNode, err := v.vm.ModelTree.GetNode(target); err != nil || targetNode.Data.DiffType == filetree.Removed {
+			resolution.Status = filetree.LinkDangling
+		}
+	default:
+		return resolution, false
+	}
+
+	if resolution.Status != filetree.LinkResolved || !v.vm.SelectPath(resolution.Target, v.filterRegex) {
+		return resolution, false
+	}
+	_ = v.Update()
+	return resolution, true
+}
+
 // CursorDown moves the cursor down and renders the view.
 // Note: we cannot use the gocui buffer since any state change requires writing the entire tree to the buffer.
 // Instead we are keeping an upper and lower bounds of the tree string to render and only flushing
@@ -252,10 +362,13 @@ func (v *FileTree) PageUp() error {
 	return v.Render()
 }
 
-// getAbsPositionNode determines the selected screen cursor's location in the file tree, returning the selected FileNode.
-// func (controller *FileTree) getAbsPositionNode() (node *filetree.FileNode) {
-// 	return controller.vm.getAbsPositionNode(filterRegex())
-// }
+// SelectedNode returns the FileNode under the cursor (or nil while the tree is loading).
+func (v *FileTree) SelectedNode() *filetree.FileNode {
+	if v.loading {
+		return nil
+	}
+	return v.vm.SelectedNode(v.filterRegex)
+}
 
 // ToggleCollapse will collapse/expand the selected FileNode.
 func (v *FileTree) toggleCollapse() error {
@@ -311,6 +424,23 @@ func (v *FileTree) toggleAttributes() error {
 	return v.notifyOnViewOptionChangeListeners()
 }
 
+// toggleOnlyCollisions will show only the nodes colliding with a sibling (or show every node) in the filetree pane.
+func (v *FileTree) toggleOnlyCollisions() error {
+	v.vm.ToggleOnlyCollisions()
+
+	err := v.Update()
+	if err != nil {
+		return err
+	}
+	err = v.Render()
+	if err != nil {
+		return err
+	}
+
+	// we need to render the changes to the status pane as well (not just this contoller/view)
+	return v.notifyOnViewOptionChangeListeners()
+}
+
 // ToggleShowDiffType will show/hide the selected DiffType in the filetree pane.
 func (v *FileTree) toggleShowDiffType(diffType filetree.DiffType) error {
 	v.vm.ToggleShowDiffType(diffType)
@@ -370,6 +500,10 @@ func (v *FileTree) Render() error {
 
 		// update the contents
 		v.view.Clear()
+		if v.loading {
+			_, err := fmt.Fprintf(v.view, " %s Loading layer contents...\n", loadingFrames[v.loadingFrame])
+			return err
+		}
 		err := v.vm.Render()
 		if err != nil {
 			return err
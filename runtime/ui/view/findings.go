@@ -0,0 +1,173 @@
+package view
+
+import (
+	"fmt"
+
+	"github.com/jroimartin/gocui"
+	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/runtime/ui/format"
+	"github.com/wagoodman/dive/utils"
+)
+
+// maxFindingsHeight is the most screen rows the findings pane will take (including the header).
+const maxFindingsHeight = 12
+
+// Findings holds the UI objects and data models for populating the findings pane above the status bar. Specifically
+// the pane that shows the concerns raised by auditing the final image filesystem.
+type Findings struct {
+	name   string
+	gui    *gocui.Gui
+	view   *gocui.View
+	header *gocui.View
+	hidden bool
+
+	findings filetree.Findings
+}
+
+// newFindingsView creates a new view object attached the the global [gocui] screen object.
+func newFindingsView(gui *gocui.Gui, findings filetree.Findings) (controller *Findings) {
+	controller = new(Findings)
+
+	// populate main fields
+	controller.name = "findings"
+	controller.gui = gui
+	controller.findings = findings
+	controller.hidden = true
+
+	return controller
+}
+
+func (v *Findings) Name() string {
+	return v.name
+}
+
+// Setup initializes the UI concerns within the context of a global [gocui] view object.
+func (v *Findings) Setup(view *gocui.View, header *gocui.View) error {
+	logrus.Tracef("view.Setup() %s", v.Name())
+
+	// set controller options
+	v.view = view
+	v.view.Editable = false
+	v.view.Wrap = false
+	v.view.Frame = false
+
+	v.header = header
+	v.header.Editable = false
+	v.header.Wrap = false
+	v.header.Frame = false
+
+	return v.Render()
+}
+
+// ToggleVisible shows/hides the findings pane.
+func (v *Findings) ToggleVisible() {
+	v.hidden = !v.hidden
+}
+
+// IsVisible indicates if the findings pane is currently shown.
+func (v *Findings) IsVisible() bool {
+	if v == nil {
+		return false
+	}
+	return !v.hidden
+}
+
+// Update refreshes the state objects for future rendering (currently does nothing).
+func (v *Findings) Update() error {
+	return nil
+}
+
+// Render flushes the state objects to the screen. Currently this is every finding (most severe first), truncated to
+// the height of the pane.
+func (v *Findings) Render() error {
+	logrus.Tracef("view.Render() %s", v.Name())
+
+	if v.view == nil || v.header == nil {
+		return nil
+	}
+
+	v.gui.Update(func(g *gocui.Gui) error {
+		v.header.Clear()
+		width, _ := g.Size()
+		headerStr := format.RenderHeader(fmt.Sprintf("Findings (%d)", len(v.findings)), width, false)
+		headerStr += fmt.Sprintf(filetree.FindingFormat+"  %s", "Severity", "Kind", "Permission", "UID:GID", "Layer", "Path", "Detail")
+		_, err := fmt.Fprintln(v.header, headerStr)
+		if err != nil {
+			return err
+		}
+
+		v.view.Clear()
+		if len(v.findings) == 0 {
+			_, err = fmt.Fprintln(v.view, " No findings")
+			return err
+		}
+
+		_, height := v.view.Size()
+		for idx, finding := range v.findings {
+			if idx == height-1 && len(v.findings) > height {
+				_, err = fmt.Fprintf(v.view, " ...and %d more (see the --json export)\n", len(v.findings)-idx)
+				return err
+			}
+			_, err = fmt.Fprintf(v.view, "%s  %s\n", finding.String(), finding.Detail)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// KeyHelp indicates all the possible actions a user can take while the current pane is selected.
+func (v *Findings) KeyHelp() string {
+	return ""
+}
+
+// OnLayoutChange is called whenever the screen dimensions are changed
+func (v *Findings) OnLayoutChange() error {
+	err := v.Update()
+	if err != nil {
+		return err
+	}
+	return v.Render()
+}
+
+func (v *Findings) Layout(g *gocui.Gui, minX, minY, maxX, maxY int) error {
+	logrus.Tracef("view.Layout(minX: %d, minY: %d, maxX: %d, maxY: %d) %s", minX, minY, maxX, maxY, v.Name())
+
+	// like the timeline pane, the findings span several rows and would otherwise overlap the panes above it
+	if v.hidden {
+		if v.view != nil {
+			_ = g.DeleteView(v.Name())
+			_ = g.DeleteView(v.Name() + "header")
+			v.view, v.header = nil, nil
+		}
+		return nil
+	}
+
+	// the header is the title row followed by the column row
+	headerSize := 2
+	header, headerErr := g.SetView(v.Name()+"header", minX, minY, maxX, minY+headerSize+1)
+	view, viewErr := g.SetView(v.Name(), minX, minY+headerSize, maxX, maxY)
+	if utils.IsNewView(viewErr, headerErr) {
+		err := v.Setup(view, header)
+		if err != nil {
+			logrus.Error("unable to setup findings controller", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Findings) RequestedSize(available int) *int {
+	// two header rows plus a row per finding (or a single row noting there are no findings)
+	height := 2 + len(v.findings)
+	if len(v.findings) == 0 {
+		height++
+	}
+	if height > maxFindingsHeight {
+		height = maxFindingsHeight
+	}
+	return &height
+}
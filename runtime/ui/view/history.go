@@ -0,0 +1,177 @@
+package view
+
+import (
+	"fmt"
+
+	"github.com/jroimartin/gocui"
+	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/dive/image"
+	"github.com/wagoodman/dive/runtime/ui/format"
+	"github.com/wagoodman/dive/utils"
+)
+
+// maxHistoryHeight is the most screen rows the timeline pane will take (including the header).
+const maxHistoryHeight = 12
+
+// History holds the UI objects and data models for populating the timeline pane above the status bar. Specifically
+// the pane that shows every layer's change to the selected file tree node.
+type History struct {
+	name   string
+	gui    *gocui.Gui
+	view   *gocui.View
+	header *gocui.View
+	hidden bool
+
+	layers  []*image.Layer
+	history filetree.PathHistory
+}
+
+// newHistoryView creates a new view object attached the the global [gocui] screen object.
+func newHistoryView(gui *gocui.Gui, layers []*image.Layer) (controller *History) {
+	controller = new(History)
+
+	// populate main fields
+	controller.name = "history"
+	controller.gui = gui
+	controller.layers = layers
+	controller.hidden = true
+
+	return controller
+}
+
+func (v *History) Name() string {
+	return v.name
+}
+
+// SetHistory sets the timeline to show the next time the view is rendered.
+func (v *History) SetHistory(history filetree.PathHistory) {
+	v.history = history
+}
+
+// Setup initializes the UI concerns within the context of a global [gocui] view object.
+func (v *History) Setup(view *gocui.View, header *gocui.View) error {
+	logrus.Tracef("view.Setup() %s", v.Name())
+
+	// set controller options
+	v.view = view
+	v.view.Editable = false
+	v.view.Wrap = false
+	v.view.Frame = false
+
+	v.header = header
+	v.header.Editable = false
+	v.header.Wrap = false
+	v.header.Frame = false
+
+	return v.Render()
+}
+
+// ToggleVisible shows/hides the timeline pane.
+func (v *History) ToggleVisible() {
+	v.hidden = !v.hidden
+}
+
+// IsVisible indicates if the timeline pane is currently shown.
+func (v *History) IsVisible() bool {
+	if v == nil {
+		return false
+	}
+	return !v.hidden
+}
+
+// Update refreshes the state objects for future rendering (currently does nothing).
+func (v *History) Update() error {
+	return nil
+}
+
+// Render flushes the state objects to the screen. Currently this is the timeline of the selected path.
+func (v *History) Render() error {
+	logrus.Tracef("view.Render() %s", v.Name())
+
+	if v.view == nil || v.header == nil {
+		return nil
+	}
+
+	v.gui.Update(func(g *gocui.Gui) error {
+		v.header.Clear()
+		width, _ := g.Size()
+		headerStr := format.RenderHeader(fmt.Sprintf("Timeline: %s", v.history.Path), width, false)
+		headerStr += fmt.Sprintf(filetree.HistoryFormat+"  %s", "Layer", "Action", "Size", "Permission", "UID:GID", "Hash", "Command")
+		_, err := fmt.Fprintln(v.header, headerStr)
+		if err != nil {
+			return err
+		}
+
+		v.view.Clear()
+		if len(v.history.Entries) == 0 {
+			_, err = fmt.Fprintln(v.view, " No layer has changed this path")
+			return err
+		}
+		for _, entry := range v.history.Entries {
+			var command string
+			if entry.LayerIndex < len(v.layers) {
+				command = v.layers[entry.LayerIndex].Command
+			}
+			_, err = fmt.Fprintf(v.view, "%s  %s\n", entry.String(), command)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// KeyHelp indicates all the possible actions a user can take while the current pane is selected.
+func (v *History) KeyHelp() string {
+	return ""
+}
+
+// OnLayoutChange is called whenever the screen dimensions are changed
+func (v *History) OnLayoutChange() error {
+	err := v.Update()
+	if err != nil {
+		return err
+	}
+	return v.Render()
+}
+
+func (v *History) Layout(g *gocui.Gui, minX, minY, maxX, maxY int) error {
+	logrus.Tracef("view.Layout(minX: %d, minY: %d, maxX: %d, maxY: %d) %s", minX, minY, maxX, maxY, v.Name())
+
+	// unlike the filter pane, the timeline spans several rows and would otherwise overlap the panes above it
+	if v.hidden {
+		if v.view != nil {
+			_ = g.DeleteView(v.Name())
+			_ = g.DeleteView(v.Name() + "header")
+			v.view, v.header = nil, nil
+		}
+		return nil
+	}
+
+	// the header is the title row followed by the column row
+	headerSize := 2
+	header, headerErr := g.SetView(v.Name()+"header", minX, minY, maxX, minY+headerSize+1)
+	view, viewErr := g.SetView(v.Name(), minX, minY+headerSize, maxX, maxY)
+	if utils.IsNewView(viewErr, headerErr) {
+		err := v.Setup(view, header)
+		if err != nil {
+			logrus.Error("unable to setup history controller", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *History) RequestedSize(available int) *int {
+	// two header rows plus a row per entry (or a single row noting there are no entries)
+	height := 2 + len(v.history.Entries)
+	if len(v.history.Entries) == 0 {
+		height++
+	}
+	if height > maxHistoryHeight {
+		height = maxHistoryHeight
+	}
+	return &height
+}
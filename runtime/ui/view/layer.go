@@ -2,9 +2,11 @@ package view
 
 import (
 	"fmt"
+	"github.com/dustin/go-humanize"
 	"github.com/jroimartin/gocui"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/dive/filetree"
 	"github.com/wagoodman/dive/dive/image"
 	"github.com/wagoodman/dive/runtime/ui/format"
 	"github.com/wagoodman/dive/runtime/ui/key"
@@ -21,13 +23,18 @@ type Layer struct {
 	vm                    *viewmodel.LayerSetState
 	constrainedRealEstate bool
 
+	// trees are the layer trees, used to simulate squashing layers together (simulation is nil when not simulating)
+	trees      []*filetree.FileTree
+	simulation *viewmodel.LayerSimulation
+
 	listeners []LayerChangeListener
 
-	helpKeys []*key.Binding
+	helpKeys       []*key.Binding
+	simulationKeys []*key.Binding
 }
 
 // newLayerView creates a new view object attached the the global [gocui] screen object.
-func newLayerView(gui *gocui.Gui, layers []*image.Layer) (controller *Layer, err error) {
+func newLayerView(gui *gocui.Gui, layers []*image.Layer, trees []*filetree.FileTree) (controller *Layer, err error) {
 	controller = new(Layer)
 
 	controller.listeners = make([]LayerChangeListener, 0)
@@ -35,6 +42,7 @@ func newLayerView(gui *gocui.Gui, layers []*image.Layer) (controller *Layer, err
 	// populate main fields
 	controller.name = "layer"
 	controller.gui = gui
+	controller.trees = trees
 
 	var compareMode viewmodel.LayerCompareMode
 
@@ -107,6 +115,12 @@ func (v *Layer) Setup(view *gocui.View, header *gocui.View) error {
 			IsSelected: func() bool { return v.vm.CompareMode == viewmodel.CompareAllLayers },
 			Display:    "Show aggregated changes",
 		},
+		{
+			ConfigKeys: []string{"keybinding.toggle-simulation"},
+			OnAction:   v.toggleSimulation,
+			IsSelected: func() bool { return v.simulation != nil },
+			Display:    "Simulate",
+		},
 		{
 			Key:      gocui.KeyArrowDown,
 			Modifier: gocui.ModNone,
@@ -143,6 +157,27 @@ func (v *Layer) Setup(view *gocui.View, header *gocui.View) error {
 	}
 	v.helpKeys = helpKeys
 
+	// these are only shown while simulating
+	var simulationInfos = []key.BindingInfo{
+		{
+			ConfigKeys: []string{"keybinding.simulate-squash"},
+			OnAction:   v.toggleSquash,
+			Display:    "Squash with layer below",
+		},
+		{
+			ConfigKeys: []string{"keybinding.simulate-move"},
+			OnAction:   v.moveLayer,
+			IsSelected: func() bool { return v.simulation != nil && v.simulation.Marked >= 0 },
+			Display:    "Move layer",
+		},
+	}
+
+	simulationKeys, err := key.GenerateBindings(v.gui, v.name, simulationInfos)
+	if err != nil {
+		return err
+	}
+	v.simulationKeys = simulationKeys
+
 	return v.Render()
 }
 
@@ -241,6 +276,70 @@ func (v *Layer) setCompareMode(compareMode viewmodel.LayerCompareMode) error {
 	return v.notifyLayerChangeListeners()
 }
 
+// toggleSimulation starts (or stops) simulating how the image would look with its layers squashed together.
+func (v *Layer) toggleSimulation() error {
+	if v.simulation != nil {
+		v.simulation = nil
+	} else {
+		simulation, err := viewmodel.NewLayerSimulation(v.trees)
+		if err != nil {
+			return err
+		}
+		v.simulation = simulation
+	}
+	// note: the key help of the status pane changes as well
+	return v.notifyLayerChangeListeners()
+}
+
+// toggleSquash squashes the selected layer with the layer below it (or splits it back out) within the simulation.
+func (v *Layer) toggleSquash() error {
+	if v.simulation == nil {
+		return nil
+	}
+	err := v.simulation.ToggleSquash(v.vm.LayerIndex)
+	if err != nil {
+		return err
+	}
+	return v.Render()
+}
+
+// moveLayer marks the selected layer, or moves the marked layer into the group of the selected layer, within the
+// simulation.
+func (v *Layer) moveLayer() error {
+	if v.simulation == nil {
+		return nil
+	}
+	err := v.simulation.Move(v.vm.LayerIndex)
+	if err != nil {
+		return err
+	}
+	return v.Render()
+}
+
+// renderSimulatedLayer returns the formatted string for the given layer within the simulation: the size of a group is
+// shown on its first layer, and every layer of a group notes the group.
+func (v *Layer) renderSimulatedLayer(layerIdx int, layer *image.Layer) string {
+	result := v.simulation.Result
+	groupIdx := v.simulation.Group(layerIdx)
+	group := result.Groups[groupIdx]
+
+	var size string
+	if group[0] == layerIdx {
+		size = humanize.Bytes(result.LayerSizes[groupIdx])
+	}
+	command := layer.Command
+	if layerIdx == 0 {
+		command = "FROM " + layer.ShortId()
+	}
+	if len(group) > 1 {
+		command = fmt.Sprintf("[%s] %s", group, command)
+	}
+	if v.simulation.Marked == layerIdx {
+		command = "* " + command
+	}
+	return fmt.Sprintf(image.LayerFormat, size, command)
+}
+
 // renderCompareBar returns the formatted string for the given layer.
 func (v *Layer) renderCompareBar(layerIdx int) string {
 	bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop := v.vm.GetCompareIndexes()
@@ -296,6 +395,11 @@ func (v *Layer) Render() error {
 
 	v.gui.Update(func(g *gocui.Gui) error {
 		var err error
+		if v.simulation != nil {
+			original, result := v.simulation.Original, v.simulation.Result
+			title = fmt.Sprintf("Layers (simulated: %s, was %s; efficiency %d %%, was %d %%)",
+				humanize.Bytes(result.SizeBytes), humanize.Bytes(original.SizeBytes), int(100.0*result.Efficiency), int(100.0*original.Efficiency))
+		}
 		// update header
 		v.header.Clear()
 		width, _ := g.Size()
@@ -320,9 +424,12 @@ func (v *Layer) Render() error {
 		for idx, layer := range v.vm.Layers {
 
 			var layerStr string
-			if v.constrainedRealEstate {
+			switch {
+			case v.constrainedRealEstate:
 				layerStr = fmt.Sprintf("%-4d", layer.Index)
-			} else {
+			case v.simulation != nil:
+				layerStr = v.renderSimulatedLayer(idx, layer)
+			default:
 				layerStr = layer.String()
 			}
 
@@ -355,5 +462,10 @@ func (v *Layer) KeyHelp() string {
 	for _, binding := range v.helpKeys {
 		help += binding.RenderKeyHelp()
 	}
+	if v.simulation != nil {
+		for _, binding := range v.simulationKeys {
+			help += binding.RenderKeyHelp()
+		}
+	}
 	return help
 }
@@ -0,0 +1,290 @@
+package view
+
+import (
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"github.com/jroimartin/gocui"
+	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/dive/image"
+	"github.com/wagoodman/dive/runtime/ui/format"
+	"github.com/wagoodman/dive/runtime/ui/key"
+	"github.com/wagoodman/dive/utils"
+)
+
+// maxPackagesHeight is the most screen rows the packages pane will take (including the header).
+const maxPackagesHeight = 14
+
+// Packages holds the UI objects and data models for populating the packages pane above the status bar. Specifically
+// the pane that shows the OS packages installed within the image (largest first) and the files no package owns.
+type Packages struct {
+	name   string
+	gui    *gocui.Gui
+	view   *gocui.View
+	header *gocui.View
+	hidden bool
+
+	report *image.PackageReport
+	// selected is the highlighted row and offset is the first row shown (rows are each package followed by the
+	// unowned files summary and each unsupported package database)
+	selected int
+	offset   int
+
+	helpKeys []*key.Binding
+}
+
+// newPackagesView creates a new view object attached the the global [gocui] screen object.
+func newPackagesView(gui *gocui.Gui, report *image.PackageReport) (controller *Packages) {
+	controller = new(Packages)
+
+	// populate main fields
+	controller.name = "packages"
+	controller.gui = gui
+	controller.report = report
+	controller.hidden = true
+
+	return controller
+}
+
+func (v *Packages) Name() string {
+	return v.name
+}
+
+// Setup initializes the UI concerns within the context of a global [gocui] view object.
+func (v *Packages) Setup(view *gocui.View, header *gocui.View) error {
+	logrus.Tracef("view.Setup() %s", v.Name())
+
+	// set controller options
+	v.view = view
+	v.view.Editable = false
+	v.view.Wrap = false
+	v.view.Frame = false
+
+	v.header = header
+	v.header.Editable = false
+	v.header.Wrap = false
+	v.header.Frame = false
+
+	// note: the pane is deleted when hidden, so the bindings are regenerated each time it is shown
+	var infos = []key.BindingInfo{
+		{
+			Key:      gocui.KeyArrowDown,
+			Modifier: gocui.ModNone,
+			OnAction: func() error { return v.moveSelection(1) },
+		},
+		{
+			Key:      gocui.KeyArrowUp,
+			Modifier: gocui.ModNone,
+			OnAction: func() error { return v.moveSelection(-1) },
+		},
+		{
+			ConfigKeys: []string{"keybinding.page-up"},
+			OnAction:   func() error { return v.moveSelection(-v.height()) },
+		},
+		{
+			ConfigKeys: []string{"keybinding.page-down"},
+			OnAction:   func() error { return v.moveSelection(v.height()) },
+		},
+	}
+
+	helpKeys, err := key.GenerateBindings(v.gui, v.name, infos)
+	if err != nil {
+		return err
+	}
+	v.helpKeys = helpKeys
+
+	// the pane is only laid out once shown, at which point it takes focus
+	_, err = v.gui.SetCurrentView(v.name)
+	if err != nil {
+		return err
+	}
+
+	return v.Render()
+}
+
+// ToggleVisible shows/hides the packages pane.
+func (v *Packages) ToggleVisible() {
+	v.hidden = !v.hidden
+}
+
+// IsVisible indicates if the packages pane is currently shown.
+func (v *Packages) IsVisible() bool {
+	if v == nil {
+		return false
+	}
+	return !v.hidden
+}
+
+// rows is the number of selectable rows (each package, the unowned files summary, and each unsupported package
+// database).
+func (v *Packages) rows() int {
+	if v.report == nil {
+		return 0
+	}
+	return len(v.report.Packages) + v.summaryRows() + len(v.report.UnsupportedDatabases)
+}
+
+// summaryRows is the number of unowned files summary rows, which is not shown when no package database can be read.
+func (v *Packages) summaryRows() int {
+	if len(v.report.Packages) == 0 && len(v.report.UnsupportedDatabases) > 0 {
+		return 0
+	}
+	return 1
+}
+
+// height is the number of rows shown at once.
+func (v *Packages) height() int {
+	if v.view == nil {
+		return 1
+	}
+	_, height := v.view.Size()
+	if height < 1 {
+		return 1
+	}
+	return height
+}
+
+// moveSelection moves the highlighted row by the given number of rows, scrolling as needed.
+func (v *Packages) moveSelection(step int) error {
+	if v.rows() == 0 {
+		return nil
+	}
+	v.selected += step
+	if v.selected >= v.rows() {
+		v.selected = v.rows() - 1
+	}
+	if v.selected < 0 {
+		v.selected = 0
+	}
+	return v.Render()
+}
+
+// Update refreshes the state objects for future rendering (keeping the selected row within the pane).
+func (v *Packages) Update() error {
+	height := v.height()
+	if v.selected < v.offset {
+		v.offset = v.selected
+	}
+	if v.selected >= v.offset+height {
+		v.offset = v.selected - height + 1
+	}
+	return nil
+}
+
+// Render flushes the state objects to the screen. Currently this is every package (largest first), followed by the
+// total of the files that no package owns and the package databases that cannot be read.
+func (v *Packages) Render() error {
+	logrus.Tracef("view.Render() %s", v.Name())
+
+	if v.view == nil || v.header == nil {
+		return nil
+	}
+
+	err := v.Update()
+	if err != nil {
+		return err
+	}
+
+	isSelected := v.gui.CurrentView() == v.view
+
+	v.gui.Update(func(g *gocui.Gui) error {
+		v.header.Clear()
+		width, _ := g.Size()
+		title := "Packages"
+		if v.report != nil {
+			title = fmt.Sprintf("Packages (%d)", len(v.report.Packages))
+		}
+		headerStr := format.RenderHeader(title, width, isSelected)
+		headerStr += fmt.Sprintf(image.PackageFormat, "Type", "Layer", "Size", "Files", "Name", "Version")
+		_, err := fmt.Fprintln(v.header, headerStr)
+		if err != nil {
+			return err
+		}
+
+		v.view.Clear()
+		if v.report == nil {
+			_, err = fmt.Fprintln(v.view, " No package database found")
+			return err
+		}
+
+		height := v.height()
+		for idx := v.offset; idx < v.rows() && idx < v.offset+height; idx++ {
+			var row string
+			switch unsupportedIdx := idx - len(v.report.Packages) - v.summaryRows(); {
+			case idx < len(v.report.Packages):
+				row = v.report.Packages[idx].String()
+			case unsupportedIdx < 0:
+				row = fmt.Sprintf(image.PackageFormat, "-", "-", humanize.Bytes(v.report.UnownedSize), fmt.Sprintf("%d", len(v.report.UnownedFiles)), "(files not owned by any package)", "")
+			default:
+				row = fmt.Sprintf(image.PackageFormat, "-", "-", "-", "-", v.report.UnsupportedDatabases[unsupportedIdx], "(unsupported database format)")
+			}
+			if idx == v.selected && isSelected {
+				row = format.Selected(row)
+			}
+			_, err = fmt.Fprintln(v.view, row)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// KeyHelp indicates all the possible actions a user can take while the current pane is selected.
+func (v *Packages) KeyHelp() string {
+	var help string
+	for _, binding := range v.helpKeys {
+		help += binding.RenderKeyHelp()
+	}
+	return help
+}
+
+// OnLayoutChange is called whenever the screen dimensions are changed
+func (v *Packages) OnLayoutChange() error {
+	err := v.Update()
+	if err != nil {
+		return err
+	}
+	return v.Render()
+}
+
+func (v *Packages) Layout(g *gocui.Gui, minX, minY, maxX, maxY int) error {
+	logrus.Tracef("view.Layout(minX: %d, minY: %d, maxX: %d, maxY: %d) %s", minX, minY, maxX, maxY, v.Name())
+
+	// like the findings pane, the packages span several rows and would otherwise overlap the panes above it
+	if v.hidden {
+		if v.view != nil {
+			g.DeleteKeybindings(v.Name())
+			_ = g.DeleteView(v.Name())
+			_ = g.DeleteView(v.Name() + "header")
+			v.view, v.header = nil, nil
+		}
+		return nil
+	}
+
+	// the header is the title row followed by the column row
+	headerSize := 2
+	header, headerErr := g.SetView(v.Name()+"header", minX, minY, maxX, minY+headerSize+1)
+	view, viewErr := g.SetView(v.Name(), minX, minY+headerSize, maxX, maxY)
+	if utils.IsNewView(viewErr, headerErr) {
+		err := v.Setup(view, header)
+		if err != nil {
+			logrus.Error("unable to setup packages controller", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Packages) RequestedSize(available int) *int {
+	// two header rows plus a row per package and the unowned files (or a single row noting there are no packages)
+	height := 2 + v.rows()
+	if v.report == nil {
+		height++
+	}
+	if height > maxPackagesHeight {
+		height = maxPackagesHeight
+	}
+	return &height
+}
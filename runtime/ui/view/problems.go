@@ -0,0 +1,201 @@
+package view
+
+import (
+	"fmt"
+
+	"github.com/jroimartin/gocui"
+	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/dive/filetree"
+	"github.com/wagoodman/dive/runtime/ui/format"
+	"github.com/wagoodman/dive/utils"
+)
+
+// maxProblemsHeight is the most screen rows the problems pane will take (including the header).
+const maxProblemsHeight = 12
+
+// problemFormat is the column layout of the problems pane.
+const problemFormat = "%5s  %-23s %-10s %-6s  %s"
+
+// Problems holds the UI objects and data models for populating the problems pane above the status bar. Specifically
+// the pane that shows the layer entries that could not be stacked as given (and how each was recovered from), along
+// with any path errors found later on while building the layer trees shown.
+type Problems struct {
+	name   string
+	gui    *gocui.Gui
+	view   *gocui.View
+	header *gocui.View
+	hidden bool
+
+	analysisErrors filetree.PathErrors
+	cache          *filetree.Comparer
+	pathErrors     filetree.PathErrors
+}
+
+// newProblemsView creates a new view object attached the the global [gocui] screen object.
+func newProblemsView(gui *gocui.Gui, pathErrors filetree.PathErrors, cache *filetree.Comparer) (controller *Problems) {
+	controller = new(Problems)
+
+	// populate main fields
+	controller.name = "problems"
+	controller.gui = gui
+	controller.analysisErrors = pathErrors
+	controller.cache = cache
+	controller.pathErrors = pathErrors
+	controller.hidden = true
+
+	return controller
+}
+
+func (v *Problems) Name() string {
+	return v.name
+}
+
+// Setup initializes the UI concerns within the context of a global [gocui] view object.
+func (v *Problems) Setup(view *gocui.View, header *gocui.View) error {
+	logrus.Tracef("view.Setup() %s", v.Name())
+
+	// set controller options
+	v.view = view
+	v.view.Editable = false
+	v.view.Wrap = false
+	v.view.Frame = false
+
+	v.header = header
+	v.header.Editable = false
+	v.header.Wrap = false
+	v.header.Frame = false
+
+	return v.Render()
+}
+
+// ToggleVisible shows/hides the problems pane.
+func (v *Problems) ToggleVisible() {
+	v.hidden = !v.hidden
+}
+
+// IsVisible indicates if the problems pane is currently shown.
+func (v *Problems) IsVisible() bool {
+	if v == nil {
+		return false
+	}
+	return !v.hidden
+}
+
+// Count returns the number of path errors shown.
+func (v *Problems) Count() int {
+	return len(v.pathErrors)
+}
+
+// Update refreshes the state objects for future rendering, picking up the path errors found while building the layer
+// trees shown since (see filetree.Comparer.LazyPathErrors).
+func (v *Problems) Update() error {
+	if v.cache == nil {
+		return nil
+	}
+	lazyErrors := v.cache.LazyPathErrors()
+	if len(lazyErrors) == 0 {
+		return nil
+	}
+	pathErrors := append(append(filetree.PathErrors{}, v.analysisErrors...), lazyErrors...)
+	pathErrors.Sort()
+	v.pathErrors = pathErrors
+	return nil
+}
+
+// Render flushes the state objects to the screen. Currently this is every path error (by layer), truncated to the
+// height of the pane.
+func (v *Problems) Render() error {
+	logrus.Tracef("view.Render() %s", v.Name())
+
+	if v.view == nil || v.header == nil {
+		return nil
+	}
+
+	v.gui.Update(func(g *gocui.Gui) error {
+		v.header.Clear()
+		width, _ := g.Size()
+		headerStr := format.RenderHeader(fmt.Sprintf("Problems (%d)", len(v.pathErrors)), width, false)
+		headerStr += fmt.Sprintf(problemFormat+"  %s", "Layer", "Kind", "Policy", "Action", "Path", "Detail")
+		_, err := fmt.Fprintln(v.header, headerStr)
+		if err != nil {
+			return err
+		}
+
+		v.view.Clear()
+		if len(v.pathErrors) == 0 {
+			_, err = fmt.Fprintln(v.view, " No problems")
+			return err
+		}
+
+		_, height := v.view.Size()
+		for idx, pathErr := range v.pathErrors {
+			if idx == height-1 && len(v.pathErrors) > height {
+				_, err = fmt.Fprintf(v.view, " ...and %d more (see the --json export)\n", len(v.pathErrors)-idx)
+				return err
+			}
+			var detail string
+			if pathErr.Err != nil {
+				detail = pathErr.Err.Error()
+			}
+			_, err = fmt.Fprintf(v.view, problemFormat+"  %s\n", fmt.Sprintf("%d", pathErr.LayerIndex), pathErr.Kind, pathErr.Policy, pathErr.Action, pathErr.Path, detail)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// KeyHelp indicates all the possible actions a user can take while the current pane is selected.
+func (v *Problems) KeyHelp() string {
+	return ""
+}
+
+// OnLayoutChange is called whenever the screen dimensions are changed
+func (v *Problems) OnLayoutChange() error {
+	err := v.Update()
+	if err != nil {
+		return err
+	}
+	return v.Render()
+}
+
+func (v *Problems) Layout(g *gocui.Gui, minX, minY, maxX, maxY int) error {
+	logrus.Tracef("view.Layout(minX: %d, minY: %d, maxX: %d, maxY: %d) %s", minX, minY, maxX, maxY, v.Name())
+
+	// like the timeline pane, the problems span several rows and would otherwise overlap the panes above it
+	if v.hidden {
+		if v.view != nil {
+			_ = g.DeleteView(v.Name())
+			_ = g.DeleteView(v.Name() + "header")
+			v.view, v.header = nil, nil
+		}
+		return nil
+	}
+
+	// the header is the title row followed by the column row
+	headerSize := 2
+	header, headerErr := g.SetView(v.Name()+"header", minX, minY, maxX, minY+headerSize+1)
+	view, viewErr := g.SetView(v.Name(), minX, minY+headerSize, maxX, maxY)
+	if utils.IsNewView(viewErr, headerErr) {
+		err := v.Setup(view, header)
+		if err != nil {
+			logrus.Error("unable to setup problems controller", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Problems) RequestedSize(available int) *int {
+	// two header rows plus a row per problem (or a single row noting there are no problems)
+	height := 2 + len(v.pathErrors)
+	if len(v.pathErrors) == 0 {
+		height++
+	}
+	if height > maxProblemsHeight {
+		height = maxProblemsHeight
+	}
+	return &height
+}
@@ -20,6 +20,7 @@ type Status struct {
 
 	selectedView    Helper
 	requestedHeight int
+	notice          string
 
 	helpKeys []*key.Binding
 }
@@ -41,6 +42,11 @@ func (v *Status) SetCurrentView(r Helper) {
 	v.selectedView = r
 }
 
+// SetNotice shows the given message ahead of the key help (an empty message clears the notice).
+func (v *Status) SetNotice(notice string) {
+	v.notice = notice
+}
+
 func (v *Status) Name() string {
 	return v.name
 }
@@ -91,7 +97,12 @@ func (v *Status) Render() error {
 			selectedHelp = v.selectedView.KeyHelp()
 		}
 
-		_, err := fmt.Fprintln(v.view, v.KeyHelp()+selectedHelp+format.StatusNormal("▏"+strings.Repeat(" ", 1000)))
+		var notice string
+		if v.notice != "" {
+			notice = format.StatusSelected(" " + v.notice + " ")
+		}
+
+		_, err := fmt.Fprintln(v.view, notice+v.KeyHelp()+selectedHelp+format.StatusNormal("▏"+strings.Repeat(" ", 1000)))
 		if err != nil {
 			logrus.Debug("unable to write to buffer: ", err)
 		}
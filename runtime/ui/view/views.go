@@ -7,16 +7,20 @@ import (
 )
 
 type Views struct {
-	Tree    *FileTree
-	Layer   *Layer
-	Status  *Status
-	Filter  *Filter
-	Details *Details
-	Debug   *Debug
+	Tree     *FileTree
+	Layer    *Layer
+	Status   *Status
+	Filter   *Filter
+	Details  *Details
+	History  *History
+	Findings *Findings
+	Packages *Packages
+	Problems *Problems
+	Debug    *Debug
 }
 
-func NewViews(g *gocui.Gui, analysis *image.AnalysisResult, cache filetree.Comparer) (*Views, error) {
-	Layer, err := newLayerView(g, analysis.Layers)
+func NewViews(g *gocui.Gui, analysis *image.AnalysisResult, cache *filetree.Comparer) (*Views, error) {
+	Layer, err := newLayerView(g, analysis.Layers, analysis.RefTrees)
 	if err != nil {
 		return nil, err
 	}
@@ -34,17 +38,29 @@ func NewViews(g *gocui.Gui, analysis *image.AnalysisResult, cache filetree.Compa
 
 	Filter := newFilterView(g)
 
-	Details := newDetailsView(g, analysis.Efficiency, analysis.Inefficiencies, analysis.SizeBytes)
+	Details := newDetailsView(g, analysis.Efficiency, analysis.Inefficiencies, analysis.SizeBytes, analysis.RemovableBytes)
+
+	History := newHistoryView(g, analysis.Layers)
+
+	Findings := newFindingsView(g, analysis.Findings)
+
+	Packages := newPackagesView(g, analysis.Packages)
+
+	Problems := newProblemsView(g, analysis.PathErrors, cache)
 
 	Debug := newDebugView(g)
 
 	return &Views{
-		Tree:    Tree,
-		Layer:   Layer,
-		Status:  Status,
-		Filter:  Filter,
-		Details: Details,
-		Debug:   Debug,
+		Tree:     Tree,
+		Layer:    Layer,
+		Status:   Status,
+		Filter:   Filter,
+		Details:  Details,
+		History:  History,
+		Findings: Findings,
+		Packages: Packages,
+		Problems: Problems,
+		Debug:    Debug,
 	}, nil
 }
 
@@ -55,5 +71,9 @@ func (views *Views) All() []Renderer {
 		views.Status,
 		views.Filter,
 		views.Details,
+		views.History,
+		views.Findings,
+		views.Packages,
+		views.Problems,
 	}
 }
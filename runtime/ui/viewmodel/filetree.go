@@ -19,7 +19,13 @@ type FileTree struct {
 	ModelTree *filetree.FileTree
 	ViewTree  *filetree.FileTree
 	RefTrees  []*filetree.FileTree
-	cache     filetree.Comparer
+	cache     *filetree.Comparer
+	// view holds the collapsed and hidden nodes, apart from the (cached) trees shown
+	view *filetree.ViewState
+	// filterMatches are the lengths of the filter matches for each node of filterTree (see filterMatch)
+	filter        *regexp.Regexp
+	filterTree    *filetree.FileTree
+	filterMatches map[*filetree.FileNode]int
 
 	constrainedRealEstate bool
 
@@ -27,9 +33,11 @@ type FileTree struct {
 	ShowAttributes              bool
 	unconstrainedShowAttributes bool
 	HiddenDiffTypes             []bool
-	TreeIndex                   int
-	bufferIndex                 int
-	bufferIndexLowerBound       int
+	// OnlyCollisions hides every node that does not collide with a sibling (see FileTree.MarkCollisions)
+	OnlyCollisions        bool
+	TreeIndex             int
+	bufferIndex           int
+	bufferIndexLowerBound int
 
 	refHeight int
 	refWidth  int
@@ -38,7 +46,7 @@ type FileTree struct {
 }
 
 // NewFileTreeViewModel creates a new view object attached the the global [gocui] screen object.
-func NewFileTreeViewModel(tree *filetree.FileTree, refTrees []*filetree.FileTree, cache filetree.Comparer) (treeViewModel *FileTree, err error) {
+func NewFileTreeViewModel(tree *filetree.FileTree, refTrees []*filetree.FileTree, cache *filetree.Comparer) (treeViewModel *FileTree, err error) {
 	treeViewModel = new(FileTree)
 
 	// populate main fields
@@ -48,6 +56,7 @@ func NewFileTreeViewModel(tree *filetree.FileTree, refTrees []*filetree.FileTree
 	treeViewModel.ModelTree = tree
 	treeViewModel.RefTrees = refTrees
 	treeViewModel.cache = cache
+	treeViewModel.view = filetree.NewViewState(treeViewModel.CollapseAll)
 	treeViewModel.HiddenDiffTypes = make([]bool, 4)
 
 	hiddenTypes := viper.GetStringSlice("diff.hide")
@@ -100,6 +109,21 @@ func (vm *FileTree) ResetCursor() {
 	vm.bufferIndexLowerBound = 0
 }
 
+// IsTreeReady indicates if the indicated layer tree can be shown without waiting for it to be built.
+func (vm *FileTree) IsTreeReady(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop int) bool {
+	return vm.cache.IsReady(filetree.NewTreeIndexKey(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop))
+}
+
+// PrepareTree blocks until the indicated layer tree has been built, without altering the view model. This is safe to
+// call outside of the UI thread.
+func (vm *FileTree) PrepareTree(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop int) error {
+	if topTreeStop > len(vm.RefTrees)-1 {
+		return fmt.Errorf("invalid layer index given: %d of %d", topTreeStop, len(vm.RefTrees)-1)
+	}
+	_, err := vm.cache.GetTree(filetree.NewTreeIndexKey(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop))
+	return err
+}
+
 // SetTreeByLayer populates the view model by stacking the indicated image layer file trees.
 func (vm *FileTree) SetTreeByLayer(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop int) error {
 	if topTreeStop > len(vm.RefTrees)-1 {
@@ -111,19 +135,9 @@ func (vm *FileTree) SetTreeByLayer(bottomTreeStart, bottomTreeStop, topTreeStart
 		return err
 	}
 
-	// preserve vm state on copy
-	visitor := func(node *filetree.FileNode) error {
-		newNode, err := newTree.GetNode(node.Path())
-		if err == nil {
-			newNode.Data.ViewInfo = node.Data.ViewInfo
-		}
-		return nil
-	}
-	err = vm.ModelTree.VisitDepthChildFirst(visitor, nil)
-	if err != nil {
-		logrus.Errorf("unable to propagate layer tree: %+v", err)
-		return err
-	}
+	// note: the collapsed state is kept by path and carries over to the new tree, the hidden state is derived from the
+	// new tree on the next update
+	vm.view.ResetHidden()
 
 	vm.ModelTree = newTree
 	return nil
@@ -146,7 +160,7 @@ func (vm *FileTree) CursorUp() bool {
 
 // doCursorDown performs the internal view's buffer adjustments on cursor down. Note: this is independent of the gocui buffer.
 func (vm *FileTree) CursorDown() bool {
-	if vm.TreeIndex >= vm.ModelTree.VisibleSize() {
+	if vm.TreeIndex >= vm.ModelTree.VisibleSize(vm.view) {
 		return false
 	}
 	vm.TreeIndex++
@@ -171,10 +185,8 @@ func (vm *FileTree) CursorLeft(filterRegex *regexp.Regexp) error {
 	if currentNode == nil {
 		return nil
 	}
-	parentPath := currentNode.Parent.Path()
-
 	visitor = func(curNode *filetree.FileNode) error {
-		if strings.Compare(parentPath, curNode.Path()) == 0 {
+		if curNode == currentNode.Parent {
 			newIndex = dfsCounter
 		}
 		dfsCounter++
@@ -182,12 +194,8 @@ func (vm *FileTree) CursorLeft(filterRegex *regexp.Regexp) error {
 	}
 
 	evaluator = func(curNode *filetree.FileNode) bool {
-		regexMatch := true
-		if filterRegex != nil {
-			match := filterRegex.Find([]byte(curNode.Path()))
-			regexMatch = match != nil
-		}
-		return !curNode.Parent.Data.ViewInfo.Collapsed && !curNode.Data.ViewInfo.Hidden && regexMatch
+		regexMatch := filterRegex == nil || vm.filterMatch(curNode, filterRegex) >= 0
+		return !vm.view.IsCollapsed(curNode.Parent) && !vm.view.IsHidden(curNode) && regexMatch
 	}
 
 	err := vm.ModelTree.VisitDepthParentFirst(visitor, evaluator)
@@ -226,8 +234,8 @@ func (vm *FileTree) CursorRight(filterRegex *regexp.Regexp) error {
 		return nil
 	}
 
-	if node.Data.ViewInfo.Collapsed {
-		node.Data.ViewInfo.Collapsed = false
+	if vm.view.IsCollapsed(node) {
+		vm.view.SetCollapsed(node, false)
 	}
 
 	vm.TreeIndex++
@@ -249,7 +257,7 @@ func (vm *FileTree) PageDown() error {
 	nextBufferIndexUpperBound := nextBufferIndexLowerBound + vm.height()
 
 	// todo: this work should be saved or passed to render...
-	treeString := vm.ViewTree.StringBetween(nextBufferIndexLowerBound, nextBufferIndexUpperBound, vm.ShowAttributes)
+	treeString := vm.ViewTree.StringBetween(vm.view, nextBufferIndexLowerBound, nextBufferIndexUpperBound, vm.ShowAttributes)
 	lines := strings.Split(treeString, "\n")
 
 	newLines := len(lines) - 1
@@ -275,7 +283,7 @@ func (vm *FileTree) PageUp() error {
 	nextBufferIndexUpperBound := nextBufferIndexLowerBound + vm.height()
 
 	// todo: this work should be saved or passed to render...
-	treeString := vm.ViewTree.StringBetween(nextBufferIndexLowerBound, nextBufferIndexUpperBound, vm.ShowAttributes)
+	treeString := vm.ViewTree.StringBetween(vm.view, nextBufferIndexLowerBound, nextBufferIndexUpperBound, vm.ShowAttributes)
 	lines := strings.Split(treeString, "\n")
 
 	newLines := len(lines) - 2
@@ -294,6 +302,56 @@ func (vm *FileTree) PageUp() error {
 	return nil
 }
 
+// SelectedNode returns the FileNode under the screen cursor.
+func (vm *FileTree) SelectedNode(filterRegex *regexp.Regexp) *filetree.FileNode {
+	return vm.getAbsPositionNode(filterRegex)
+}
+
+// SelectPath moves the cursor to the node with the given path, expanding any collapsed parent directories. False is
+// returned if the path does not exist or is not visible (e.g. does not match the filter).
+func (vm *FileTree) SelectPath(p string, filterRegex *regexp.Regexp) bool {
+	node, err := vm.ModelTree.GetNode(p)
+	if err != nil || vm.view.IsHidden(node) {
+		return false
+	}
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		if vm.view.IsCollapsed(parent) {
+			vm.view.SetCollapsed(parent, false)
+		}
+	}
+
+	newIndex := -1
+	var dfsCounter int
+	visitor := func(curNode *filetree.FileNode) error {
+		if curNode == node {
+			newIndex = dfsCounter
+		}
+		dfsCounter++
+		return nil
+	}
+
+	evaluator := func(curNode *filetree.FileNode) bool {
+		regexMatch := filterRegex == nil || vm.filterMatch(curNode, filterRegex) >= 0
+		return !vm.view.IsCollapsed(curNode.Parent) && !vm.view.IsHidden(curNode) && regexMatch
+	}
+
+	err = vm.ModelTree.VisitDepthParentFirst(visitor, evaluator)
+	if err != nil {
+		logrus.Errorf("unable to propagate tree on SelectPath: %+v", err)
+		return false
+	}
+	if newIndex < 0 {
+		return false
+	}
+
+	vm.TreeIndex = newIndex
+	if newIndex < vm.bufferIndexLowerBound || newIndex > vm.bufferIndexUpperBound() {
+		vm.bufferIndexLowerBound = newIndex
+	}
+	vm.bufferIndex = newIndex - vm.bufferIndexLowerBound
+	return true
+}
+
 // getAbsPositionNode determines the selected screen cursor's location in the file tree, returning the selected FileNode.
 func (vm *FileTree) getAbsPositionNode(filterRegex *regexp.Regexp) (node *filetree.FileNode) {
 	var visitor func(*filetree.FileNode) error
@@ -309,12 +367,8 @@ func (vm *FileTree) getAbsPositionNode(filterRegex *regexp.Regexp) (node *filetr
 	}
 
 	evaluator = func(curNode *filetree.FileNode) bool {
-		regexMatch := true
-		if filterRegex != nil {
-			match := filterRegex.Find([]byte(curNode.Path()))
-			regexMatch = match != nil
-		}
-		return !curNode.Parent.Data.ViewInfo.Collapsed && !curNode.Data.ViewInfo.Hidden && regexMatch
+		regexMatch := filterRegex == nil || vm.filterMatch(curNode, filterRegex) >= 0
+		return !vm.view.IsCollapsed(curNode.Parent) && !vm.view.IsHidden(curNode) && regexMatch
 	}
 
 	err := vm.ModelTree.VisitDepthParentFirst(visitor, evaluator)
@@ -329,7 +383,7 @@ func (vm *FileTree) getAbsPositionNode(filterRegex *regexp.Regexp) (node *filetr
 func (vm *FileTree) ToggleCollapse(filterRegex *regexp.Regexp) error {
 	node := vm.getAbsPositionNode(filterRegex)
 	if node != nil && node.Data.FileInfo.IsDir {
-		node.Data.ViewInfo.Collapsed = !node.Data.ViewInfo.Collapsed
+		vm.view.SetCollapsed(node, !vm.view.IsCollapsed(node))
 	}
 	return nil
 }
@@ -337,21 +391,7 @@ func (vm *FileTree) ToggleCollapse(filterRegex *regexp.Regexp) error {
 // ToggleCollapseAll will collapse/expand the all directories.
 func (vm *FileTree) ToggleCollapseAll() error {
 	vm.CollapseAll = !vm.CollapseAll
-
-	visitor := func(curNode *filetree.FileNode) error {
-		curNode.Data.ViewInfo.Collapsed = vm.CollapseAll
-		return nil
-	}
-
-	evaluator := func(curNode *filetree.FileNode) bool {
-		return curNode.Data.FileInfo.IsDir
-	}
-
-	err := vm.ModelTree.VisitDepthChildFirst(visitor, evaluator)
-	if err != nil {
-		logrus.Errorf("unable to propagate tree on ToggleCollapseAll: %+v", err)
-	}
-
+	vm.view.SetCollapseAll(vm.CollapseAll)
 	return nil
 }
 
@@ -387,26 +427,65 @@ func (vm *FileTree) ToggleShowDiffType(diffType filetree.DiffType) {
 	vm.HiddenDiffTypes[diffType] = !vm.HiddenDiffTypes[diffType]
 }
 
+// ToggleOnlyCollisions will show only the nodes colliding with a sibling (or show every node) in the filetree pane.
+func (vm *FileTree) ToggleOnlyCollisions() {
+	vm.OnlyCollisions = !vm.OnlyCollisions
+}
+
+// filterMatch returns the length of the filter match for the path of the given node (-1 when the path does not match),
+// as noted by the last update when possible.
+func (vm *FileTree) filterMatch(node *filetree.FileNode, filterRegex *regexp.Regexp) int {
+	if filterRegex == vm.filter && node.Tree == vm.filterTree {
+		if length, exists := vm.filterMatches[node]; exists {
+			return length
+		}
+	}
+	return matchLength(filterRegex, node.Path())
+}
+
+func matchLength(filterRegex *regexp.Regexp, p string) int {
+	loc := filterRegex.FindStringIndex(p)
+	if loc == nil {
+		return -1
+	}
+	return loc[1] - loc[0]
+}
+
 // Update refreshes the state objects for future rendering.
 func (vm *FileTree) Update(filterRegex *regexp.Regexp, width, height int) error {
 	vm.refWidth = width
 	vm.refHeight = height
 
+	// match the filter once per node, deriving each path from the path of its parent
+	vm.filter, vm.filterTree, vm.filterMatches = filterRegex, vm.ModelTree, nil
+	if filterRegex != nil {
+		vm.filterMatches = make(map[*filetree.FileNode]int)
+		err := vm.ModelTree.VisitPaths(func(node *filetree.FileNode, p string) error {
+			vm.filterMatches[node] = matchLength(filterRegex, p)
+			return nil
+		})
+		if err != nil {
+			logrus.Errorf("unable to match the filter: %+v", err)
+			return err
+		}
+	}
+
 	// keep the vm selection in parity with the current DiffType selection
+	vm.view.ResetHidden()
 	err := vm.ModelTree.VisitDepthChildFirst(func(node *filetree.FileNode) error {
-		node.Data.ViewInfo.Hidden = vm.HiddenDiffTypes[node.Data.DiffType]
+		hidden := vm.HiddenDiffTypes[node.Data.DiffType] || (vm.OnlyCollisions && node.Data.Collision == filetree.NoCollision)
 		visibleChild := false
 		for _, child := range node.Children {
-			if !child.Data.ViewInfo.Hidden {
+			if !vm.view.IsHidden(child) {
 				visibleChild = true
-				node.Data.ViewInfo.Hidden = false
+				hidden = false
 			}
 		}
 		// hide nodes that do not match the current file filter regex (also don't unhide nodes that are already hidden)
-		if filterRegex != nil && !visibleChild && !node.Data.ViewInfo.Hidden {
-			match := filterRegex.FindString(node.Path())
-			node.Data.ViewInfo.Hidden = len(match) == 0
+		if filterRegex != nil && !visibleChild && !hidden {
+			hidden = vm.filterMatch(node, filterRegex) <= 0
 		}
+		vm.view.SetHidden(node, hidden)
 		return nil
 	}, nil)
 
@@ -416,16 +495,24 @@ func (vm *FileTree) Update(filterRegex *regexp.Regexp, width, height int) error
 	}
 
 	// make a new tree with only visible nodes
-	vm.ViewTree = vm.ModelTree.Copy()
-	err = vm.ViewTree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
-		if node.Data.ViewInfo.Hidden {
-			err1 := vm.ViewTree.RemovePath(node.Path())
-			if err1 != nil {
-				return err1
-			}
+	var hiddenPaths []string
+	err = vm.ModelTree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+		if vm.view.IsHidden(node) {
+			hiddenPaths = append(hiddenPaths, node.Path())
 		}
 		return nil
-	}, nil)
+	}, func(node *filetree.FileNode) bool {
+		// the children of a hidden node are removed along with it
+		return node == vm.ModelTree.Root || !vm.view.IsHidden(node.Parent)
+	})
+	if err == nil {
+		vm.ViewTree = vm.ModelTree.Copy()
+		for _, hiddenPath := range hiddenPaths {
+			if err = vm.ViewTree.RemovePath(hiddenPath); err != nil {
+				break
+			}
+		}
+	}
 
 	if err != nil {
 		logrus.Errorf("unable to propagate vm view tree: %+v", err)
@@ -437,7 +524,7 @@ func (vm *FileTree) Update(filterRegex *regexp.Regexp, width, height int) error
 
 // Render flushes the state objects (file tree) to the pane.
 func (vm *FileTree) Render() error {
-	treeString := vm.ViewTree.StringBetween(vm.bufferIndexLowerBound, vm.bufferIndexUpperBound(), vm.ShowAttributes)
+	treeString := vm.ViewTree.StringBetween(vm.view, vm.bufferIndexLowerBound, vm.bufferIndexUpperBound(), vm.ShowAttributes)
 	lines := strings.Split(treeString, "\n")
 
 	// update the contents
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/fatih/color"
@@ -78,6 +79,8 @@ func initializeTestViewModel(t *testing.T) *FileTree {
 
 	cache := filetree.NewComparer(result.RefTrees)
 	errors := cache.BuildCache()
+	// trees are built on request from here on, nothing is left building in the background between tests
+	cache.Close()
 	if len(errors) > 0 {
 		t.Fatalf("%s: unable to build cache: %d errors", t.Name(), len(errors))
 	}
@@ -396,3 +399,39 @@ func TestFileTreeHideTypeWithFilter(t *testing.T) {
 
 	runTestCase(t, vm, width, height, regex)
 }
+
+func TestFileTreeOnlyCollisions(t *testing.T) {
+	vm := initializeTestViewModel(t)
+
+	width, height := 100, 100
+	vm.Setup(0, height)
+
+	// select the 7th layer, compareMode = layer
+	err := vm.SetTreeByLayer(0, 0, 1, 7)
+	checkError(t, err, "unable to SetTreeByLayer")
+
+	// the test image has no colliding paths, so one is tagged as if it collided
+	node, err := vm.ModelTree.GetNode("/root/saved.txt")
+	checkError(t, err, "unable to get node")
+	node.Data.Collision = filetree.CollisionCase
+
+	vm.ToggleOnlyCollisions()
+	err = vm.Update(nil, width, height)
+	checkError(t, err, "unable to update")
+
+	var visible []string
+	err = vm.ViewTree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+		visible = append(visible, node.Path())
+		return nil
+	}, nil)
+	checkError(t, err, "unable to visit view tree")
+	if len(visible) != 2 || visible[0] != "/root" || visible[1] != "/root/saved.txt" {
+		t.Errorf("expected only the colliding path (and its parent) to be shown, got %v", visible)
+	}
+
+	err = vm.Render()
+	checkError(t, err, "unable to render")
+	if !strings.Contains(vm.Buffer.String(), "saved.txt [collides: case]") {
+		t.Errorf("expected the colliding path to be tagged, got:\n%s", vm.Buffer.String())
+	}
+}
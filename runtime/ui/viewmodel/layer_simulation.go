@@ -0,0 +1,127 @@
+package viewmodel
+
+import (
+	"github.com/wagoodman/dive/dive/filetree"
+)
+
+// LayerSimulation tracks the layers the user has regrouped within the layer pane, along with the resulting image (see
+// filetree.Simulate).
+type LayerSimulation struct {
+	trees []*filetree.FileTree
+	// groupOf is the group index of each layer
+	groupOf []int
+	// Marked is the layer chosen to be moved into another group (-1 when no layer is marked)
+	Marked   int
+	Original *filetree.Simulation
+	Result   *filetree.Simulation
+}
+
+// NewLayerSimulation creates a simulation where every layer is kept as-is.
+func NewLayerSimulation(trees []*filetree.FileTree) (*LayerSimulation, error) {
+	simulation := &LayerSimulation{
+		trees:   trees,
+		groupOf: make([]int, len(trees)),
+		Marked:  -1,
+	}
+	for layerIdx := range simulation.groupOf {
+		simulation.groupOf[layerIdx] = layerIdx
+	}
+
+	err := simulation.update()
+	if err != nil {
+		return nil, err
+	}
+	simulation.Original = simulation.Result
+	return simulation, nil
+}
+
+// Group returns the index of the simulated group (within Result.Groups) that holds the given layer.
+func (sim *LayerSimulation) Group(layerIdx int) int {
+	for groupIdx, group := range sim.Result.Groups {
+		for _, member := range group {
+			if member == layerIdx {
+				return groupIdx
+			}
+		}
+	}
+	return -1
+}
+
+// ToggleSquash squashes the group of the given layer into the group of the layer below it, or splits the group at the
+// given layer when it is already squashed with the layer below.
+func (sim *LayerSimulation) ToggleSquash(layerIdx int) error {
+	if layerIdx <= 0 || layerIdx >= len(sim.groupOf) {
+		return nil
+	}
+	source, target := sim.groupOf[layerIdx], sim.groupOf[layerIdx-1]
+	for member, group := range sim.groupOf {
+		switch {
+		case source == target && group == source && member >= layerIdx:
+			sim.groupOf[member] = layerIdx
+		case source != target && group == source:
+			sim.groupOf[member] = target
+		}
+	}
+	sim.normalize()
+	return sim.update()
+}
+
+// Move marks the given layer when no layer is marked, otherwise the marked layer is moved into the group of the given
+// layer (e.g. to run a cleanup within the same layer as the install).
+func (sim *LayerSimulation) Move(layerIdx int) error {
+	if layerIdx < 0 || layerIdx >= len(sim.groupOf) {
+		return nil
+	}
+	if sim.Marked < 0 {
+		sim.Marked = layerIdx
+		return nil
+	}
+	marked := sim.Marked
+	sim.Marked = -1
+	if sim.groupOf[marked] == sim.groupOf[layerIdx] {
+		return nil
+	}
+	sim.groupOf[marked] = sim.groupOf[layerIdx]
+	sim.normalize()
+	return sim.update()
+}
+
+// normalize names every group after its lowest layer.
+func (sim *LayerSimulation) normalize() {
+	lowest := make(map[int]int)
+	for member, group := range sim.groupOf {
+		if _, exists := lowest[group]; !exists {
+			lowest[group] = member
+		}
+	}
+	for member, group := range sim.groupOf {
+		sim.groupOf[member] = lowest[group]
+	}
+}
+
+// update simulates the image with the current groups.
+func (sim *LayerSimulation) update() error {
+	members := make(map[int]filetree.LayerGroup)
+	var order []int
+	for layerIdx, group := range sim.groupOf {
+		if _, exists := members[group]; !exists {
+			order = append(order, group)
+		}
+		members[group] = append(members[group], layerIdx)
+	}
+	groups := make([]filetree.LayerGroup, 0, len(order))
+	for _, group := range order {
+		groups = append(groups, members[group])
+	}
+
+	grouping, err := filetree.NewLayerGrouping(len(sim.trees), groups...)
+	if err != nil {
+		return err
+	}
+	result, err := filetree.Simulate(sim.trees, grouping)
+	if err != nil {
+		return err
+	}
+	sim.Result = result
+	return nil
+}
@@ -0,0 +1,54 @@
+package viewmodel
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wagoodman/dive/dive/filetree"
+)
+
+func TestLayerSimulation(t *testing.T) {
+	trees := make([]*filetree.FileTree, 5)
+	for idx := range trees {
+		trees[idx] = filetree.NewFileTree()
+	}
+	_, _, err := trees[1].AddPath("/var/cache/big", filetree.FileInfo{Size: 1000})
+	checkError(t, err, "could not setup test")
+	_, _, err = trees[3].AddPath("/var/cache/.wh.big", filetree.FileInfo{Path: "/var/cache/.wh.big"})
+	checkError(t, err, "could not setup test")
+
+	sim, err := NewLayerSimulation(trees)
+	checkError(t, err, "unable to simulate")
+
+	groups := func(expected ...filetree.LayerGroup) {
+		t.Helper()
+		if !reflect.DeepEqual(sim.Result.Groups, expected) {
+			t.Errorf("expected groups %v, got %v", expected, sim.Result.Groups)
+		}
+	}
+	groups(filetree.LayerGroup{0}, filetree.LayerGroup{1}, filetree.LayerGroup{2}, filetree.LayerGroup{3}, filetree.LayerGroup{4})
+
+	// squashing joins the whole group with the layer below
+	checkError(t, sim.ToggleSquash(4), "unable to squash")
+	checkError(t, sim.ToggleSquash(3), "unable to squash")
+	groups(filetree.LayerGroup{0}, filetree.LayerGroup{1}, filetree.LayerGroup{2, 3, 4})
+
+	// squashing again splits the group at the given layer
+	checkError(t, sim.ToggleSquash(3), "unable to split")
+	groups(filetree.LayerGroup{0}, filetree.LayerGroup{1}, filetree.LayerGroup{2}, filetree.LayerGroup{3, 4})
+
+	// move the cleanup into the same layer as the install
+	checkError(t, sim.Move(3), "unable to mark")
+	if sim.Marked != 3 {
+		t.Errorf("expected layer 3 to be marked, got %d", sim.Marked)
+	}
+	checkError(t, sim.Move(1), "unable to move")
+	groups(filetree.LayerGroup{0}, filetree.LayerGroup{1, 3}, filetree.LayerGroup{2}, filetree.LayerGroup{4})
+	if sim.Marked != -1 || sim.Group(3) != 1 {
+		t.Errorf("unexpected marked layer (%d) or group (%d)", sim.Marked, sim.Group(3))
+	}
+
+	if sim.Original.SizeBytes != 1000 || sim.Result.SizeBytes != 0 {
+		t.Errorf("unexpected sizes: %d -> %d", sim.Original.SizeBytes, sim.Result.SizeBytes)
+	}
+}